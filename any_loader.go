@@ -0,0 +1,163 @@
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// AnyBatchFunc is the batch function of an AnyLoader. It is a BatchFunc whose
+// keys may be of any type. The keys passed to this function have unique
+// identities.
+type AnyBatchFunc[K any, V any] func(context.Context, []K) []*Result[V]
+
+// KeyIdentity returns the identity of a key, which the keys of an AnyLoader
+// are deduped and cached by. Keys are equal if their identities are.
+type KeyIdentity[K any] func(K) string
+
+// keyString returns the default identity of a key, its default format (see
+// fmt.Sprint). The common kinds of keys are formatted without reflection,
+// which is slow, and limited under TinyGo.
+func keyString[K any](key K) string {
+	switch k := any(key).(type) {
+	case string:
+		return k
+	case int:
+		return strconv.Itoa(k)
+	case int64:
+		return strconv.FormatInt(k, 10)
+	case int32:
+		return strconv.FormatInt(int64(k), 10)
+	case uint:
+		return strconv.FormatUint(uint64(k), 10)
+	case uint64:
+		return strconv.FormatUint(k, 10)
+	case uint32:
+		return strconv.FormatUint(uint64(k), 10)
+	case error:
+		return k.Error()
+	case fmt.Stringer:
+		return k.String()
+	}
+	return fmt.Sprint(key)
+}
+
+// AnyLoader loads keys which are not comparable (i.e. slices, or protobuf
+// messages). It is backed by a Loader keyed by the identities of the keys,
+// which the options apply to.
+type AnyLoader[K any, V any] struct {
+	loader   *Loader[string, V]
+	identity KeyIdentity[K]
+
+	// the keys by identity, along with the number of loads of the identity
+	// which haven't resolved yet
+	mu   sync.Mutex
+	keys map[string]*trackedKey[K]
+}
+
+// trackedKey is a key of an AnyLoader, referenced by its pending loads.
+type trackedKey[K any] struct {
+	key  K
+	refs int
+}
+
+// NewBatchedLoaderFunc constructs a new AnyLoader with given options. If
+// identity is nil, the identity of a key is its default format (see
+// fmt.Sprint).
+func NewBatchedLoaderFunc[K any, V any](batchFn AnyBatchFunc[K, V], identity KeyIdentity[K], opts ...Option[string, V]) *AnyLoader[K, V] {
+	if identity == nil {
+		identity = keyString[K]
+	}
+	a := &AnyLoader[K, V]{
+		identity: identity,
+		keys:     make(map[string]*trackedKey[K]),
+	}
+	a.loader = NewBatchedLoader(func(ctx context.Context, ids []string) []*Result[V] {
+		keys := make([]K, len(ids))
+		a.mu.Lock()
+		for i, id := range ids {
+			// the entry is referenced by the load being batched
+			if t := a.keys[id]; t != nil {
+				keys[i] = t.key
+			}
+		}
+		a.mu.Unlock()
+		return batchFn(ctx, keys)
+	}, opts...)
+	return a
+}
+
+// Load loads a key, returning a `Thunk` for the value represented by that key.
+func (a *AnyLoader[K, V]) Load(ctx context.Context, key K) Thunk[V] {
+	id := a.track(key)
+	thunk := a.loader.Load(ctx, id)
+	go func() {
+		thunk()
+		a.untrack(id)
+	}()
+	return thunk
+}
+
+// LoadMany loads multiple keys, returning a thunk (type: ThunkMany) that will resolve the keys passed in.
+func (a *AnyLoader[K, V]) LoadMany(ctx context.Context, keys []K) ThunkMany[V] {
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = a.track(key)
+	}
+	thunk := a.loader.LoadMany(ctx, ids)
+	go func() {
+		thunk()
+		for _, id := range ids {
+			a.untrack(id)
+		}
+	}()
+	return thunk
+}
+
+// Clear clears the value at `key` from the cache, it it exists. Returns self for method chaining
+func (a *AnyLoader[K, V]) Clear(ctx context.Context, key K) *AnyLoader[K, V] {
+	a.loader.Clear(ctx, a.identity(key))
+	return a
+}
+
+// ClearAll clears the entire cache. Returns self for method chaining.
+func (a *AnyLoader[K, V]) ClearAll() *AnyLoader[K, V] {
+	a.loader.ClearAll()
+	return a
+}
+
+// Prime adds the provided key and value to the cache. If the key already exists, no change is made.
+// Returns self for method chaining
+func (a *AnyLoader[K, V]) Prime(ctx context.Context, key K, value V) *AnyLoader[K, V] {
+	a.loader.Prime(ctx, a.identity(key), value)
+	return a
+}
+
+// track records the key for a load, until the load has resolved (see
+// untrack), and returns its identity. The key stays available to every batch
+// the load may end up in, whether the key is cached or not.
+func (a *AnyLoader[K, V]) track(key K) string {
+	id := a.identity(key)
+	a.mu.Lock()
+	t, ok := a.keys[id]
+	if !ok {
+		t = &trackedKey[K]{key: key}
+		a.keys[id] = t
+	}
+	t.refs++
+	a.mu.Unlock()
+	return id
+}
+
+// untrack releases the key of a resolved load, and forgets it once none of
+// its loads is pending.
+func (a *AnyLoader[K, V]) untrack(id string) {
+	a.mu.Lock()
+	if t := a.keys[id]; t != nil {
+		if t.refs--; t.refs == 0 {
+			delete(a.keys, id)
+		}
+	}
+	a.mu.Unlock()
+}
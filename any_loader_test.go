@@ -0,0 +1,159 @@
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAnyLoader(t *testing.T) {
+	t.Run("dedupes and caches keys by identity", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := SumLoader()
+		ctx := context.Background()
+
+		future1 := loader.Load(ctx, []int{1, 2})
+		future2 := loader.Load(ctx, []int{1, 2})
+		future3 := loader.Load(ctx, []int{3})
+		for _, test := range []struct {
+			future   Thunk[int]
+			expected int
+		}{{future1, 3}, {future2, 3}, {future3, 3}} {
+			if sum, err := test.future(); err != nil || sum != test.expected {
+				t.Errorf("expected %d, got %d, %v", test.expected, sum, err)
+			}
+		}
+
+		if sums, errs := loader.LoadMany(ctx, [][]int{{1, 2}, {3}})(); errs != nil || !reflect.DeepEqual(sums, []int{3, 3}) {
+			t.Errorf("expected cached sums, got %#v, %v", sums, errs)
+		}
+		if calls, expected := loadCalls(), [][][]int{{{1, 2}, {3}}}; !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("clears and primes keys by identity", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := SumLoader()
+		ctx := context.Background()
+
+		loader.Prime(ctx, []int{1}, 10)
+		if sum, _ := loader.Load(ctx, []int{1})(); sum != 10 {
+			t.Errorf("expected the primed sum, got %d", sum)
+		}
+		loader.Clear(ctx, []int{1})
+		if sum, _ := loader.Load(ctx, []int{1})(); sum != 1 {
+			t.Errorf("expected the loaded sum, got %d", sum)
+		}
+		if calls := loadCalls(); len(calls) != 1 {
+			t.Errorf("expected 1 batch, got %#v", calls)
+		}
+	})
+}
+
+func TestAnyLoaderTracking(t *testing.T) {
+	// tracked returns the number of keys tracked by the loader, once its
+	// pending loads have settled.
+	tracked := func(loader *AnyLoader[[]int, int]) int {
+		deadline := time.Now().Add(time.Second)
+		for {
+			loader.mu.Lock()
+			n := len(loader.keys)
+			loader.mu.Unlock()
+			if n == 0 || time.Now().After(deadline) {
+				return n
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	t.Run("keeps the key of a load batched after another one", func(t *testing.T) {
+		t.Parallel()
+		loader, _ := SumLoader()
+		WithCache[string, int](&NoCache[string, int]{})(loader.loader)
+		WithManualDispatch[string, int]()(loader.loader)
+		ctx := context.Background()
+
+		first := loader.Load(ctx, []int{1, 2})
+		// a load tracking the key before the first batch, and queued after it
+		id := loader.track([]int{1, 2})
+		loader.loader.Dispatch()
+		if sum, err := first(); sum != 3 || err != nil {
+			t.Fatalf("expected 3, got %d, %v", sum, err)
+		}
+		second := loader.loader.Load(ctx, id)
+		loader.loader.Dispatch()
+		if sum, err := second(); sum != 3 || err != nil {
+			t.Errorf("expected the key to be batched again, got %d, %v", sum, err)
+		}
+		loader.untrack(id)
+
+		if n := tracked(loader); n != 0 {
+			t.Errorf("expected the keys to be forgotten, got %d", n)
+		}
+	})
+
+	t.Run("forgets the keys of the cache hits", func(t *testing.T) {
+		t.Parallel()
+		loader, _ := SumLoader()
+		ctx := context.Background()
+
+		for i := 0; i < 10; i++ {
+			if sum, err := loader.Load(ctx, []int{1, 2})(); sum != 3 || err != nil {
+				t.Fatalf("expected 3, got %d, %v", sum, err)
+			}
+		}
+		if sums, errs := loader.LoadMany(ctx, [][]int{{1, 2}, {3}})(); errs != nil || !reflect.DeepEqual(sums, []int{3, 3}) {
+			t.Fatalf("expected the sums, got %#v, %v", sums, errs)
+		}
+		if n := tracked(loader); n != 0 {
+			t.Errorf("expected the keys to be forgotten, got %d", n)
+		}
+	})
+}
+
+// SumLoader returns a loader of the sums of slices of ints, and a function
+// returning its batches so far.
+func SumLoader() (*AnyLoader[[]int, int], func() [][][]int) {
+	var mu sync.Mutex
+	var loadCalls [][][]int
+	loader := NewBatchedLoaderFunc(func(_ context.Context, keys [][]int) []*Result[int] {
+		mu.Lock()
+		loadCalls = append(loadCalls, keys)
+		mu.Unlock()
+		var results []*Result[int]
+		for _, key := range keys {
+			var sum int
+			for _, n := range key {
+				sum += n
+			}
+			results = append(results, &Result[int]{Data: sum})
+		}
+		return results
+	}, nil)
+	return loader, func() [][][]int {
+		mu.Lock()
+		defer mu.Unlock()
+		return loadCalls
+	}
+}
+
+// stringerKey is a key with its own String method.
+type stringerKey int
+
+func (k stringerKey) String() string { return fmt.Sprintf("key-%d", int(k)) }
+
+func TestKeyString(t *testing.T) {
+	keys := []any{"a", 42, int64(-7), int32(3), uint(5), uint64(1 << 63), uint32(9), stringerKey(1), fmt.Errorf("boom"), 1.5, [2]int{1, 2}}
+	for _, key := range keys {
+		if got, want := keyString(key), fmt.Sprint(key); got != want {
+			t.Errorf("expected %T key %q, got %q", key, want, got)
+		}
+	}
+	if got := keyString(stringerKey(2)); got != "key-2" {
+		t.Errorf("expected the String method to be used, got %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+package dataloader
+
+import (
+	"sync"
+	"time"
+)
+
+// AutoTuneOption configures the controller of WithAutoTune.
+type AutoTuneOption func(*tuneConfig)
+
+type tuneConfig struct {
+	minWait, maxWait time.Duration
+	minCap, maxCap   int
+	every            int
+}
+
+// TuneWait bounds the wait durations chosen by WithAutoTune. Default is
+// [0, 50ms].
+func TuneWait(min, max time.Duration) AutoTuneOption {
+	return func(c *tuneConfig) {
+		c.minWait, c.maxWait = min, max
+	}
+}
+
+// TuneCapacity bounds the batch capacities chosen by WithAutoTune. Default is
+// [10, 1000].
+func TuneCapacity(min, max int) AutoTuneOption {
+	return func(c *tuneConfig) {
+		c.minCap, c.maxCap = min, max
+	}
+}
+
+// TuneEvery sets the number of batches observed between two adjustments.
+// Default is 20.
+func TuneEvery(batches int) AutoTuneOption {
+	return func(c *tuneConfig) {
+		c.every = batches
+	}
+}
+
+// TuningReport reports the values chosen by WithAutoTune, along with the
+// observations of the last period they were chosen from.
+type TuningReport struct {
+	// Wait and BatchCapacity are the current settings of the loader.
+	Wait          time.Duration
+	BatchCapacity int
+	// Adjustments is the number of times the settings were adjusted.
+	Adjustments int
+	// Latency is the mean duration of the batch function.
+	Latency time.Duration
+	// ArrivalRate is the number of keys batched per second.
+	ArrivalRate float64
+	// FillRatio is the mean size of the batches over the batch capacity.
+	FillRatio float64
+}
+
+// WithAutoTune adjusts the wait duration and the batch capacity of the loader
+// from its observed behavior, within bounds. Every period (see TuneEvery):
+//
+//   - the wait duration moves towards a quarter of the mean latency of the
+//     batch function, since waiting longer than that costs callers more than
+//     batching saves; or to its lower bound if keys arrive too sparsely for
+//     a longer window to batch more of them.
+//   - the batch capacity doubles when the batches are mostly full (more than
+//     90% on average), and halves when they are mostly empty (under 25%).
+//
+// The values set by WithWait and WithBatchCapacity are the starting point,
+// brought within the bounds; an unbounded capacity starts at the upper bound.
+// Loader.Tuning reports the chosen values.
+func WithAutoTune[K comparable, V any](opts ...AutoTuneOption) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		conf := tuneConfig{
+			maxWait: 50 * time.Millisecond,
+			minCap:  10,
+			maxCap:  1000,
+			every:   20,
+		}
+		for _, apply := range opts {
+			apply(&conf)
+		}
+		l.tuner = &autoTuner[K, V]{loader: l, conf: conf}
+	}
+}
+
+// Tuning returns the report of WithAutoTune, or false if the loader isn't
+// auto tuned.
+func (l *Loader[K, V]) Tuning() (TuningReport, bool) {
+	if l.tuner == nil {
+		return TuningReport{}, false
+	}
+	l.tuner.mu.Lock()
+	report := l.tuner.report
+	l.tuner.mu.Unlock()
+
+	l.batchLock.Lock()
+	report.Wait, report.BatchCapacity = l.wait, l.batchCap
+	l.batchLock.Unlock()
+	return report, true
+}
+
+// autoTuner observes the batches of a loader and adjusts its settings.
+type autoTuner[K comparable, V any] struct {
+	loader *Loader[K, V]
+	conf   tuneConfig
+
+	// the observations of the current period, and the last report
+	mu      sync.Mutex
+	start   time.Time
+	batches int
+	keys    int
+	latency time.Duration
+	fill    float64
+	report  TuningReport
+}
+
+// observe records a batch of n keys and of the given capacity, for which the
+// batch function took the given duration, and adjusts the settings once the
+// period is over.
+func (t *autoTuner[K, V]) observe(n, capacity int, latency time.Duration) {
+	if capacity <= 0 {
+		capacity = t.conf.maxCap
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.batches == 0 {
+		t.start = t.loader.clock.Now().Add(-latency)
+	}
+	t.batches++
+	t.keys += n
+	t.latency += latency
+	t.fill += float64(n) / float64(capacity)
+	if t.batches < t.conf.every {
+		return
+	}
+
+	elapsed := t.loader.clock.Now().Sub(t.start)
+	report := TuningReport{
+		Adjustments: t.report.Adjustments + 1,
+		Latency:     t.latency / time.Duration(t.batches),
+		FillRatio:   t.fill / float64(t.batches),
+	}
+	if elapsed > 0 {
+		report.ArrivalRate = float64(t.keys) / elapsed.Seconds()
+	}
+	t.report = report
+	t.batches, t.keys, t.latency, t.fill = 0, 0, 0, 0
+
+	l := t.loader
+	l.batchLock.Lock()
+	defer l.batchLock.Unlock()
+	l.wait = t.nextWait(l.wait, report)
+	l.batchCap = t.nextCapacity(max(l.batchCap, capacity), report)
+}
+
+// nextWait returns the wait duration following the current one.
+func (t *autoTuner[K, V]) nextWait(current time.Duration, report TuningReport) time.Duration {
+	target := report.Latency / 4
+	// a window expected to batch less than one more key only adds latency
+	if report.ArrivalRate*target.Seconds() < 1 {
+		target = t.conf.minWait
+	}
+	// move halfway, so that a single noisy period doesn't swing the wait
+	next := (clampDuration(current, t.conf.minWait, t.conf.maxWait) + target) / 2
+	return clampDuration(next, t.conf.minWait, t.conf.maxWait)
+}
+
+// nextCapacity returns the batch capacity following the current one.
+func (t *autoTuner[K, V]) nextCapacity(current int, report TuningReport) int {
+	switch {
+	case report.FillRatio > 0.9:
+		current *= 2
+	case report.FillRatio < 0.25:
+		current /= 2
+	}
+	return max(t.conf.minCap, min(current, t.conf.maxCap))
+}
+
+func clampDuration(d, lo, hi time.Duration) time.Duration {
+	return max(lo, min(d, hi))
+}
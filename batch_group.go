@@ -0,0 +1,30 @@
+package dataloader
+
+import (
+	"context"
+)
+
+// batchGroup is the token of a batch group.
+type batchGroup struct {
+	// a batchGroup must not be zero-sized, so that every token is distinct
+	_ byte
+}
+
+type batchGroupKey struct{}
+
+// WithBatchGroup returns a copy of ctx starting a new batch group. The
+// requests made with a context of the batch group are batched together, in
+// their own batch windows: independent workflows sharing a loader (i.e.
+// background jobs and interactive requests) don't wait for the windows of each
+// other, while still sharing the cache.
+//
+// Requests made outside of any batch group are batched together.
+func WithBatchGroup(ctx context.Context) context.Context {
+	return context.WithValue(ctx, batchGroupKey{}, &batchGroup{})
+}
+
+// batchGroupOf returns the batch group of ctx, if any.
+func batchGroupOf(ctx context.Context) *batchGroup {
+	group, _ := ctx.Value(batchGroupKey{}).(*batchGroup)
+	return group
+}
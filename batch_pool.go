@@ -0,0 +1,46 @@
+package dataloader
+
+import "context"
+
+// BatchPool is a process wide batch window shared by per-request loaders: the
+// keys the loaders it constructs load within the window are batched together,
+// so that concurrent requests are coalesced like they would be by a process
+// wide loader. The cache of each loader stays its own.
+type BatchPool[K comparable, V any] struct {
+	loader *Loader[K, V]
+}
+
+// NewBatchPool constructs a new BatchPool with given options, which configure
+// the shared window (i.e. WithWait, WithBatchCapacity). The pool doesn't cache
+// anything, so the cache options are ignored.
+//
+// The batch function is called with the context of the first key of the
+// window, without its cancellation: one request going away doesn't fail the
+// keys of the others.
+func NewBatchPool[K comparable, V any](batchFn BatchFunc[K, V], opts ...Option[K, V]) *BatchPool[K, V] {
+	opts = append(opts, WithCache[K, V](&NoCache[K, V]{}))
+	return &BatchPool[K, V]{loader: NewBatchedLoader(batchFn, opts...)}
+}
+
+// NewLoader constructs a new loader (i.e. for a request) batching its keys in
+// the shared window of the pool, with given options. Since the keys wait for
+// the window of the pool, the loader doesn't wait for its own by default.
+func (p *BatchPool[K, V]) NewLoader(opts ...Option[K, V]) *Loader[K, V] {
+	opts = append([]Option[K, V]{WithWait[K, V](0)}, opts...)
+	return NewBatchedLoader(p.batch, opts...)
+}
+
+// batch loads the keys of a loader of the pool in the shared window.
+func (p *BatchPool[K, V]) batch(ctx context.Context, keys []K) []*Result[V] {
+	ctx = context.WithoutCancel(ctx)
+	thunks := make([]Thunk[V], len(keys))
+	for i, key := range keys {
+		thunks[i] = p.loader.Load(ctx, key)
+	}
+	results := make([]*Result[V], len(keys))
+	for i, thunk := range thunks {
+		data, err := thunk()
+		results[i] = &Result[V]{Data: data, Error: err}
+	}
+	return results
+}
@@ -0,0 +1,80 @@
+package dataloader
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchPool(t *testing.T) {
+	t.Run("batches the keys of several loaders together", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var calls [][]string
+		pool := NewBatchPool(func(_ context.Context, keys []string) []*Result[string] {
+			mu.Lock()
+			sorted := append([]string(nil), keys...)
+			sort.Strings(sorted)
+			calls = append(calls, sorted)
+			mu.Unlock()
+			return batchIdentity[string](context.Background(), keys)
+		}, WithWait[string, string](50*time.Millisecond))
+
+		ctx := context.Background()
+		a, b := pool.NewLoader(), pool.NewLoader()
+		thunks := []Thunk[string]{a.Load(ctx, "a"), b.Load(ctx, "b"), b.Load(ctx, "a")}
+		for i, want := range []string{"a", "b", "a"} {
+			if v, err := thunks[i](); v != want || err != nil {
+				t.Errorf("expected %s, got %q, %v", want, v, err)
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !reflect.DeepEqual(calls, [][]string{{"a", "b"}}) {
+			t.Errorf("expected a single batch of unique keys, got %v", calls)
+		}
+	})
+
+	t.Run("keeps the caches of the loaders isolated", func(t *testing.T) {
+		t.Parallel()
+		pool := NewBatchPool(batchIdentity[string], WithWait[string, string](time.Millisecond))
+		ctx := context.Background()
+		a, b := pool.NewLoader(), pool.NewLoader()
+
+		a.Prime(ctx, "key", "primed")
+		if v, _ := a.Load(ctx, "key")(); v != "primed" {
+			t.Errorf("expected the primed value, got %q", v)
+		}
+		if v, _ := b.Load(ctx, "key")(); v != "key" {
+			t.Errorf("expected the other loader to load the key, got %q", v)
+		}
+	})
+
+	t.Run("doesn't fail the keys of a canceled request", func(t *testing.T) {
+		t.Parallel()
+		pool := NewBatchPool(func(ctx context.Context, keys []string) []*Result[string] {
+			if err := ctx.Err(); err != nil {
+				results := make([]*Result[string], len(keys))
+				for i := range results {
+					results[i] = &Result[string]{Error: err}
+				}
+				return results
+			}
+			return batchIdentity[string](ctx, keys)
+		}, WithWait[string, string](50*time.Millisecond))
+
+		canceled, cancel := context.WithCancel(context.Background())
+		thunkA := pool.NewLoader().Load(canceled, "a")
+		thunkB := pool.NewLoader().Load(context.Background(), "b")
+		cancel()
+
+		if v, err := thunkB(); v != "b" || err != nil {
+			t.Errorf("expected b, got %q, %v", v, err)
+		}
+		thunkA()
+	})
+}
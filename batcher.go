@@ -0,0 +1,135 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DispatchFunc is a function, which when given a slice of items, processes
+// them as a single bulk operation (i.e. a batched publish, or a bulk index
+// update) and returns the outcome of each item. It's important that the length
+// of the returned results matches the length of the items.
+type DispatchFunc[K any, V any] func(context.Context, []K) []*Result[V]
+
+// Batcher is the batching core of the Loader without the rest of it: it
+// coalesces the items enqueued within a batch window (bounded by its capacity)
+// into a single call of the dispatch function. Unlike the keys of a Loader, the
+// items are neither deduped nor cached, and they don't need to be comparable.
+type Batcher[K any, V any] struct {
+	dispatch DispatchFunc[K, V]
+	loader   *Loader[uint64, V]
+
+	mu    sync.Mutex
+	seq   uint64
+	items map[uint64]pendingItem[K, V]
+}
+
+// pendingItem is an item enqueued on a Batcher, until it is dispatched.
+type pendingItem[K any, V any] struct {
+	item K
+	done func(*Result[V])
+}
+
+// batcherConfig is the configuration of a Batcher.
+type batcherConfig struct {
+	wait      time.Duration
+	capacity  int
+	inputCap  int
+	idleFlush time.Duration
+}
+
+// BatcherOption allows for configuration of Batcher fields.
+type BatcherOption func(*batcherConfig)
+
+// WithBatcherWait sets the amount of time to wait before dispatching a batch.
+// Default duration is 16 milliseconds.
+func WithBatcherWait(d time.Duration) BatcherOption {
+	return func(c *batcherConfig) {
+		c.wait = d
+	}
+}
+
+// WithBatcherCapacity sets the maximum number of items in a batch. Default is
+// 0 (unbounded).
+func WithBatcherCapacity(n int) BatcherOption {
+	return func(c *batcherConfig) {
+		c.capacity = n
+	}
+}
+
+// WithBatcherInputCapacity sets the input capacity, see WithInputCapacity.
+// Default is 1000.
+func WithBatcherInputCapacity(n int) BatcherOption {
+	return func(c *batcherConfig) {
+		c.inputCap = n
+	}
+}
+
+// WithBatcherIdleFlush dispatches a batch once no item has been enqueued for
+// the given duration, see WithIdleFlush. Default is 0 (disabled).
+func WithBatcherIdleFlush(d time.Duration) BatcherOption {
+	return func(c *batcherConfig) {
+		c.idleFlush = d
+	}
+}
+
+// NewBatcher constructs a new Batcher with given options.
+func NewBatcher[K any, V any](dispatch DispatchFunc[K, V], opts ...BatcherOption) *Batcher[K, V] {
+	c := batcherConfig{wait: 16 * time.Millisecond, inputCap: 1000}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	b := &Batcher[K, V]{
+		dispatch: dispatch,
+		items:    make(map[uint64]pendingItem[K, V]),
+	}
+	b.loader = NewBatchedLoader(b.batch,
+		WithCache[uint64, V](&NoCache[uint64, V]{}),
+		WithWait[uint64, V](c.wait),
+		WithBatchCapacity[uint64, V](c.capacity),
+		WithInputCapacity[uint64, V](c.inputCap),
+		WithIdleFlush[uint64, V](c.idleFlush),
+	)
+	return b
+}
+
+// Enqueue adds the item to the current batch. Once the batch is dispatched,
+// done (if not nil) is called with the outcome of the item; it is called from
+// the goroutine of the batch, so it should not block.
+//
+// The dispatch function is called with the context of the first item of the
+// batch.
+func (b *Batcher[K, V]) Enqueue(ctx context.Context, item K, done func(*Result[V])) {
+	b.mu.Lock()
+	b.seq++
+	seq := b.seq
+	b.items[seq] = pendingItem[K, V]{item: item, done: done}
+	b.mu.Unlock()
+
+	b.loader.Load(ctx, seq)
+}
+
+// batch dispatches the enqueued items, and reports their outcomes. A panic of
+// the dispatch function fails every item of the batch.
+func (b *Batcher[K, V]) batch(ctx context.Context, seqs []uint64) []*Result[V] {
+	pending := make([]pendingItem[K, V], len(seqs))
+	items := make([]K, len(seqs))
+	b.mu.Lock()
+	for i, seq := range seqs {
+		pending[i] = b.items[seq]
+		items[i] = pending[i].item
+		delete(b.items, seq)
+	}
+	b.mu.Unlock()
+
+	results := b.loader.call(ctx, func(ctx context.Context, _ []uint64) []*Result[V] {
+		return b.dispatch(ctx, items)
+	}, seqs)
+	for i, p := range pending {
+		if p.done != nil {
+			p.done(results[i])
+		}
+	}
+	return results
+}
@@ -0,0 +1,68 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatcher(t *testing.T) {
+	t.Run("dispatches the items of a window together", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var calls [][][]byte
+		batcher := NewBatcher(func(_ context.Context, items [][]byte) []*Result[int] {
+			mu.Lock()
+			calls = append(calls, items)
+			mu.Unlock()
+			results := make([]*Result[int], len(items))
+			for i, item := range items {
+				results[i] = &Result[int]{Data: len(item)}
+			}
+			return results
+		}, WithBatcherWait(10*time.Millisecond), WithBatcherCapacity(3))
+		ctx := context.Background()
+
+		var wg sync.WaitGroup
+		sizes := make([]int, 4)
+		for i, item := range []string{"a", "bb", "a", "dddd"} {
+			wg.Add(1)
+			batcher.Enqueue(ctx, []byte(item), func(result *Result[int]) {
+				defer wg.Done()
+				sizes[i] = result.Data
+			})
+		}
+		wg.Wait()
+
+		if want := []int{1, 2, 1, 4}; !reflect.DeepEqual(sizes, want) {
+			t.Errorf("expected %v, got %v", want, sizes)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		// the items are not deduped, and the capacity closes the first batch
+		want := [][][]byte{{[]byte("a"), []byte("bb"), []byte("a")}, {[]byte("dddd")}}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("expected %q, got %q", want, calls)
+		}
+	})
+
+	t.Run("fails the items of a panicking batch", func(t *testing.T) {
+		t.Parallel()
+		batcher := NewBatcher(func(context.Context, []string) []*Result[struct{}] {
+			panic("boom")
+		}, WithBatcherWait(time.Millisecond))
+		batcher.loader.silent = true
+
+		done := make(chan error, 1)
+		batcher.Enqueue(context.Background(), "a", func(result *Result[struct{}]) {
+			done <- result.Error
+		})
+		var panicErr *PanicErrorWrapper
+		if err := <-done; !errors.As(err, &panicErr) {
+			t.Errorf("expected a panic error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBudgetExceeded is returned by the thunks of keys loaded while the key
+// budget of their context is exhausted. See WithKeyBudget.
+var ErrBudgetExceeded = errors.New("dataloader: key budget exceeded")
+
+// WithKeyBudget limits the number of distinct keys the loads made within a
+// budget scope may queue on the loader to perContext. Loads past the budget
+// are not batched and resolve to ErrBudgetExceeded. Cache hits don't count
+// against the budget.
+//
+// Budget scopes are opened by ContextWithKeyBudget (i.e. for every http
+// request), and cover the contexts derived from the one it returns. The loads
+// made outside of any scope are not limited. Default is 0 (unlimited).
+func WithKeyBudget[K comparable, V any](perContext int) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.budget = &keyBudget[K]{limit: perContext}
+	}
+}
+
+type budgetKey struct{}
+
+// budgetScope holds the keys queued within a budget scope, by loader.
+type budgetScope struct {
+	mu   sync.Mutex
+	keys map[any]any
+}
+
+// ContextWithKeyBudget returns a copy of ctx opening a new budget scope (see
+// WithKeyBudget): the distinct keys loaded with it, or with any context derived
+// from it, count against the same budget of every loader. The keys are
+// forgotten along with the context.
+func ContextWithKeyBudget(ctx context.Context) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &budgetScope{keys: make(map[any]any)})
+}
+
+// keyBudget limits the distinct keys queued within each budget scope.
+type keyBudget[K comparable] struct {
+	limit int
+}
+
+// admit records the key against the budget of the scope of ctx, and reports
+// whether the budget allows it to be queued.
+func (b *keyBudget[K]) admit(ctx context.Context, key K) bool {
+	scope, ok := ctx.Value(budgetKey{}).(*budgetScope)
+	if b.limit <= 0 || !ok {
+		return true
+	}
+
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	keys, ok := scope.keys[b].(map[K]struct{})
+	if !ok {
+		keys = make(map[K]struct{})
+		scope.keys[b] = keys
+	}
+	if _, ok := keys[key]; ok {
+		return true
+	}
+	if len(keys) >= b.limit {
+		return false
+	}
+	keys[key] = struct{}{}
+	return true
+}
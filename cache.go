@@ -26,3 +26,26 @@ func (c *NoCache[K, V]) Delete(context.Context, K) bool { return false }
 
 // Clear is a NOOP
 func (c *NoCache[K, V]) Clear() { return }
+
+// The DataCache interface is implemented by caches storing the resolved
+// results of keys rather than their thunks (see WithValueCache). Since results
+// are plain values, they can be stored out of process, snapshotted, expired or
+// accounted for.
+type DataCache[K comparable, V any] interface {
+	Get(context.Context, K) (*Result[V], bool)
+	Set(context.Context, K, *Result[V])
+	Delete(context.Context, K) bool
+	Clear()
+}
+
+// The DataCacheMany interface is implemented by DataCaches which can get, set
+// and delete the results of many keys in a single round trip. The result of
+// GetMany for a key which isn't cached is nil, and DeleteMany returns the
+// number of deleted results. The batches of a loader WithValueCache get their
+// keys with GetMany and set their results with SetMany.
+type DataCacheMany[K comparable, V any] interface {
+	DataCache[K, V]
+	GetMany(context.Context, []K) []*Result[V]
+	SetMany(context.Context, []K, []*Result[V])
+	DeleteMany(context.Context, []K) int
+}
@@ -0,0 +1,178 @@
+// Package lru provides a bounded in process Cache evicting the least recently
+// used keys, without any dependency, for the long lived loaders whose caches
+// must not grow unbounded.
+package lru
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// Cache implements the dataloader.Cache interface with an LRU list. Only the
+// keys whose thunks have resolved are evicted: the keys still loading are
+// kept, so that their loads are not duplicated, and the cache may hold more
+// than its size until they resolve.
+//
+// It implements dataloader.EvictionNotifier, so that the evicted values are
+// reported to dataloader.WithOnEvict.
+type Cache[K comparable, V any] struct {
+	size int
+
+	mu      sync.Mutex
+	items   map[K]*list.Element
+	order   *list.List // the most recently used entry first
+	onEvict func(context.Context, K, dataloader.EvictReason)
+}
+
+var (
+	_ dataloader.Cache[string, any]       = &Cache[string, any]{}
+	_ dataloader.EvictionNotifier[string] = &Cache[string, any]{}
+)
+
+// entry is the entry of a key.
+type entry[K comparable, V any] struct {
+	key   K
+	thunk dataloader.Thunk[V]
+	// incremented every time the thunk is replaced, so that the resolution of
+	// a replaced thunk doesn't mark the entry resolved
+	gen      int
+	resolved bool
+}
+
+// New constructs a new Cache holding up to size keys. It panics if size is
+// not positive.
+func New[K comparable, V any](size int) *Cache[K, V] {
+	if size <= 0 {
+		panic(fmt.Sprintf("lru: the size must be positive: %d", size))
+	}
+	return &Cache[K, V]{size: size, items: make(map[K]*list.Element), order: list.New()}
+}
+
+// WithCache sets the cache of a loader to a new Cache holding up to size keys.
+// It panics if size is not positive.
+func WithCache[K comparable, V any](size int) dataloader.Option[K, V] {
+	return dataloader.WithCache[K, V](New[K, V](size))
+}
+
+// Get gets an item from the cache, which becomes the most recently used.
+func (c *Cache[K, V]) Get(_ context.Context, key K) (dataloader.Thunk[V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*entry[K, V]).thunk, true
+}
+
+// Set sets an item in the cache, evicting the least recently used keys which
+// have resolved if the cache is full.
+func (c *Cache[K, V]) Set(ctx context.Context, key K, value dataloader.Thunk[V]) {
+	c.mu.Lock()
+	el, ok := c.items[key]
+	if ok {
+		e := el.Value.(*entry[K, V])
+		e.thunk, e.resolved = value, false
+		e.gen++
+		c.order.MoveToFront(el)
+	} else {
+		el = c.order.PushFront(&entry[K, V]{key: key, thunk: value})
+		c.items[key] = el
+	}
+	gen := el.Value.(*entry[K, V]).gen
+	evicted := c.evict()
+	c.mu.Unlock()
+	c.notify(ctx, evicted)
+
+	go c.resolve(el, gen)
+}
+
+// resolve waits for the thunk of the entry, then marks it resolved, unless it
+// was replaced or deleted in the meantime.
+func (c *Cache[K, V]) resolve(el *list.Element, gen int) {
+	e := el.Value.(*entry[K, V])
+	c.mu.Lock()
+	thunk := e.thunk
+	c.mu.Unlock()
+	thunk()
+
+	c.mu.Lock()
+	if c.items[e.key] != el || e.gen != gen {
+		c.mu.Unlock()
+		return
+	}
+	e.resolved = true
+	evicted := c.evict()
+	c.mu.Unlock()
+	c.notify(context.Background(), evicted)
+}
+
+// evict removes the least recently used keys which have resolved, until the
+// cache holds up to its size, and returns them.
+// It must be called with the mu held.
+func (c *Cache[K, V]) evict() []K {
+	var evicted []K
+	for el := c.order.Back(); el != nil && len(c.items) > c.size; {
+		prev := el.Prev()
+		if e := el.Value.(*entry[K, V]); e.resolved {
+			c.order.Remove(el)
+			delete(c.items, e.key)
+			evicted = append(evicted, e.key)
+		}
+		el = prev
+	}
+	return evicted
+}
+
+// notify reports the evicted keys, see NotifyEvict.
+func (c *Cache[K, V]) notify(ctx context.Context, evicted []K) {
+	c.mu.Lock()
+	onEvict := c.onEvict
+	c.mu.Unlock()
+	if onEvict == nil {
+		return
+	}
+	for _, key := range evicted {
+		onEvict(ctx, key, dataloader.EvictCapacity)
+	}
+}
+
+// NotifyEvict registers fn, called with the keys evicted to bound the size of
+// the cache. It implements dataloader.EvictionNotifier.
+func (c *Cache[K, V]) NotifyEvict(fn func(ctx context.Context, key K, reason dataloader.EvictReason)) {
+	c.mu.Lock()
+	c.onEvict = fn
+	c.mu.Unlock()
+}
+
+// Delete deletes an item in the cache
+func (c *Cache[K, V]) Delete(_ context.Context, key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+	return ok
+}
+
+// Clear clears the cache
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.items)
+	c.order.Init()
+}
+
+// Len returns the number of keys in the cache.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
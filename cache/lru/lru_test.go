@@ -0,0 +1,152 @@
+package lru
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// resolved returns a resolved thunk of v.
+func resolved(v string) dataloader.Thunk[string] {
+	return func() (string, error) { return v, nil }
+}
+
+// settle sets the resolved thunk of key, and waits for the cache to see it
+// resolved.
+func settle(t *testing.T, c *Cache[string, string], key string) {
+	t.Helper()
+	c.Set(context.Background(), key, resolved(key))
+	waitResolved(t, c, key)
+}
+
+// waitResolved waits for the cache to see the thunk of key resolved.
+func waitResolved(t *testing.T, c *Cache[string, string], key string) {
+	t.Helper()
+	eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		el, ok := c.items[key]
+		return !ok || el.Value.(*entry[string, string]).resolved
+	})
+}
+
+// contains reports whether the key is cached, without using it.
+func contains(c *Cache[string, string], key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.items[key]
+	return ok
+}
+
+// eventually waits for cond to hold.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the condition to hold")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("get, set, delete and clear", func(t *testing.T) {
+		c := New[string, string](10)
+		c.Set(ctx, "a", resolved("a"))
+		c.Set(ctx, "b", resolved("b"))
+		thunk, ok := c.Get(ctx, "a")
+		if !ok {
+			t.Fatal("expected a hit")
+		}
+		if v, _ := thunk(); v != "a" {
+			t.Errorf("expected a, got %s", v)
+		}
+
+		if !c.Delete(ctx, "a") || c.Delete(ctx, "a") {
+			t.Error("expected the key to be deleted once")
+		}
+		c.Clear()
+		if _, ok := c.Get(ctx, "b"); ok || c.Len() != 0 {
+			t.Error("expected the cache to be cleared")
+		}
+	})
+
+	t.Run("evicts the least recently used keys once resolved", func(t *testing.T) {
+		c := New[string, string](2)
+		var mu sync.Mutex
+		var evicted []string
+		c.NotifyEvict(func(_ context.Context, key string, reason dataloader.EvictReason) {
+			mu.Lock()
+			defer mu.Unlock()
+			if reason == dataloader.EvictCapacity {
+				evicted = append(evicted, key)
+			}
+		})
+
+		// a pending key is kept over the size of the cache
+		release := make(chan struct{})
+		c.Set(ctx, "pending", func() (string, error) {
+			<-release
+			return "pending", nil
+		})
+		settle(t, c, "a")
+		settle(t, c, "b")
+		if c.Len() != 2 || !contains(c, "pending") {
+			t.Fatal("expected the pending key to be kept")
+		}
+		// a was used less recently than b
+		if contains(c, "a") {
+			t.Error("expected a to be evicted")
+		}
+
+		// the pending key is evicted once it has resolved, since it's the
+		// least recently used
+		close(release)
+		waitResolved(t, c, "pending")
+		settle(t, c, "c")
+		if contains(c, "pending") || !contains(c, "b") || !contains(c, "c") {
+			t.Error("expected pending to be evicted, and b and c to be kept")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(evicted) != 2 || evicted[0] != "a" || evicted[1] != "pending" {
+			t.Errorf("expected a and pending to be reported, got %v", evicted)
+		}
+	})
+
+	t.Run("bounds the cache of a loader", func(t *testing.T) {
+		c := New[string, string](10)
+		loader := dataloader.NewBatchedLoader(func(_ context.Context, keys []string) []*dataloader.Result[string] {
+			results := make([]*dataloader.Result[string], len(keys))
+			for i, key := range keys {
+				results[i] = &dataloader.Result[string]{Data: key}
+			}
+			return results
+		}, dataloader.WithCache[string, string](c))
+
+		keys := make([]string, 100)
+		for i := range keys {
+			keys[i] = strconv.Itoa(i)
+		}
+		if _, errs := loader.LoadMany(ctx, keys)(); errs != nil {
+			t.Fatal(errs)
+		}
+		eventually(t, func() bool { return c.Len() == 10 })
+	})
+
+	t.Run("panics on a size which is not positive", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		New[string, string](0)
+	})
+}
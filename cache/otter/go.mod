@@ -0,0 +1,15 @@
+module github.com/graph-gophers/dataloader/v7/cache/otter
+
+go 1.23
+
+require (
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+	github.com/maypok86/otter v1.2.4
+)
+
+require (
+	github.com/dolthub/maphash v0.1.0 // indirect
+	github.com/gammazero/deque v0.2.1 // indirect
+)
+
+replace github.com/graph-gophers/dataloader/v7 => ../..
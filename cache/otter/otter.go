@@ -0,0 +1,96 @@
+// Package otter provides a bounded in process Cache backed by otter, a
+// W-TinyLFU cache with a high hit ratio.
+package otter
+
+import (
+	"context"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/maypok86/otter"
+)
+
+// Cache implements the dataloader.Cache interface with otter.
+type Cache[K comparable, V any] struct {
+	cache otter.Cache[K, dataloader.Thunk[V]]
+}
+
+var _ dataloader.Cache[string, any] = &Cache[string, any]{}
+
+// Option allows for configuration of the cache.
+type Option func(*config)
+
+type config struct {
+	ttl time.Duration
+}
+
+// WithTTL sets the time to live of the entries. Default is 0: entries are
+// only evicted when the cache is full.
+func WithTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.ttl = d
+	}
+}
+
+// New constructs a new Cache holding up to capacity keys.
+func New[K comparable, V any](capacity int, opts ...Option) (*Cache[K, V], error) {
+	var conf config
+	for _, apply := range opts {
+		apply(&conf)
+	}
+
+	builder, err := otter.NewBuilder[K, dataloader.Thunk[V]](capacity)
+	if err != nil {
+		return nil, err
+	}
+	var c otter.Cache[K, dataloader.Thunk[V]]
+	if conf.ttl > 0 {
+		c, err = builder.WithTTL(conf.ttl).Build()
+	} else {
+		c, err = builder.Build()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Cache[K, V]{cache: c}, nil
+}
+
+// WithCache sets the cache of a loader to a new Cache holding up to capacity
+// keys. It panics if the capacity is not positive.
+func WithCache[K comparable, V any](capacity int, opts ...Option) dataloader.Option[K, V] {
+	c, err := New[K, V](capacity, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return dataloader.WithCache[K, V](c)
+}
+
+// Get gets an item from the cache
+func (c *Cache[K, V]) Get(_ context.Context, key K) (dataloader.Thunk[V], bool) {
+	return c.cache.Get(key)
+}
+
+// Set sets an item in the cache
+func (c *Cache[K, V]) Set(_ context.Context, key K, value dataloader.Thunk[V]) {
+	c.cache.Set(key, value)
+}
+
+// Delete deletes an item in the cache
+func (c *Cache[K, V]) Delete(_ context.Context, key K) bool {
+	if c.cache.Has(key) {
+		c.cache.Delete(key)
+		return true
+	}
+	return false
+}
+
+// Clear clears the cache
+func (c *Cache[K, V]) Clear() {
+	c.cache.Clear()
+}
+
+// Close stops the background goroutines of the cache.
+func (c *Cache[K, V]) Close() {
+	c.cache.Close()
+}
@@ -0,0 +1,76 @@
+package otter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("get, set, delete and clear", func(t *testing.T) {
+		c, err := New[string, string](10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		c.Set(ctx, "a", func() (string, error) { return "a", nil })
+		c.Set(ctx, "b", func() (string, error) { return "b", nil })
+		thunk, ok := c.Get(ctx, "a")
+		if !ok {
+			t.Fatal("expected a hit")
+		}
+		if v, _ := thunk(); v != "a" {
+			t.Errorf("expected a, got %s", v)
+		}
+
+		if !c.Delete(ctx, "a") || c.Delete(ctx, "a") {
+			t.Error("expected the key to be deleted once")
+		}
+		c.Clear()
+		if _, ok := c.Get(ctx, "b"); ok {
+			t.Error("expected the cache to be cleared")
+		}
+	})
+
+	t.Run("expires entries", func(t *testing.T) {
+		c, err := New[string, string](10, WithTTL(50*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		c.Set(ctx, "a", func() (string, error) { return "a", nil })
+		time.Sleep(2 * time.Second)
+		if _, ok := c.Get(ctx, "a"); ok {
+			t.Error("expected the entry to expire")
+		}
+	})
+
+	t.Run("rejects an invalid capacity", func(t *testing.T) {
+		if _, err := New[string, string](0); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("with a loader", func(t *testing.T) {
+		var calls int
+		loader := dataloader.NewBatchedLoader(func(_ context.Context, keys []string) []*dataloader.Result[string] {
+			calls++
+			results := make([]*dataloader.Result[string], len(keys))
+			for i, key := range keys {
+				results[i] = &dataloader.Result[string]{Data: key}
+			}
+			return results
+		}, WithCache[string, string](10))
+
+		loader.Load(ctx, "a")()
+		if v, err := loader.Load(ctx, "a")(); v != "a" || err != nil || calls != 1 {
+			t.Errorf("expected a cached a, got %q, %v after %d calls", v, err, calls)
+		}
+	})
+}
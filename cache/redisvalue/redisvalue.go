@@ -0,0 +1,239 @@
+// Package redisvalue provides a DataCache storing the resolved values of keys
+// in Redis, for use with dataloader.WithValueCache (see WithCache), so that
+// the loaders of several instances of a service share their cache.
+package redisvalue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graph-gophers/dataloader/v7/codec"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache implements the dataloader.DataCache interface with Redis. The results
+// are stored as entries of codec.EncodeEntry, one string key each.
+type Cache[K comparable, V any] struct {
+	client  redis.UniversalClient
+	codec   codec.ValueCodec[V]
+	prefix  string
+	keyOf   func(K) string
+	ttlOf   func(K, *dataloader.Result[V]) time.Duration
+	onError func(error)
+}
+
+var _ dataloader.DataCacheMany[string, any] = &Cache[string, any]{}
+
+// Option allows for configuration of Cache fields.
+type Option[K comparable, V any] func(*Cache[K, V])
+
+// WithPrefix sets the prefix of the Redis keys. It is also the scope of Clear,
+// which is a noop without a prefix.
+func WithPrefix[K comparable, V any](prefix string) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.prefix = prefix
+	}
+}
+
+// WithKeyFunc sets the function converting keys to Redis keys (before the
+// prefix is added). Default is fmt.Sprint.
+func WithKeyFunc[K comparable, V any](keyOf func(K) string) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.keyOf = keyOf
+	}
+}
+
+// WithTTL sets the expiration of the Redis keys of every entry, see
+// WithTTLFunc.
+func WithTTL[K comparable, V any](d time.Duration) Option[K, V] {
+	return WithTTLFunc(func(K, *dataloader.Result[V]) time.Duration {
+		return d
+	})
+}
+
+// WithTTLFunc sets the function returning the expiration of the Redis key of
+// each entry (i.e. shorter for not found results), or 0 for a key which
+// doesn't expire (the default).
+func WithTTLFunc[K comparable, V any](ttlOf func(K, *dataloader.Result[V]) time.Duration) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.ttlOf = ttlOf
+	}
+}
+
+// WithErrorHandler sets the function called with the errors of the Redis
+// commands and of the codec. A failed read is a miss and a failed write is
+// dropped, so they are ignored by default.
+func WithErrorHandler[K comparable, V any](onError func(error)) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.onError = onError
+	}
+}
+
+// New constructs a new Cache with given options.
+func New[K comparable, V any](client redis.UniversalClient, codec codec.ValueCodec[V], opts ...Option[K, V]) *Cache[K, V] {
+	c := &Cache[K, V]{
+		client: client,
+		codec:  codec,
+		keyOf: func(key K) string {
+			return fmt.Sprint(key)
+		},
+		ttlOf: func(K, *dataloader.Result[V]) time.Duration {
+			return 0
+		},
+		onError: func(error) {},
+	}
+	for _, apply := range opts {
+		apply(c)
+	}
+	return c
+}
+
+// WithCache sets the cache of a loader to a new Cache with given options,
+// with dataloader.WithValueCache: the keys being loaded are kept by the
+// loader, and their results are stored in Redis once resolved, so that the
+// loaders of several instances share their cache.
+func WithCache[K comparable, V any](client redis.UniversalClient, codec codec.ValueCodec[V], opts ...Option[K, V]) dataloader.Option[K, V] {
+	return dataloader.WithValueCache[K, V](New(client, codec, opts...))
+}
+
+// Get gets the result of key.
+func (c *Cache[K, V]) Get(ctx context.Context, key K) (*dataloader.Result[V], bool) {
+	data, err := c.client.Get(ctx, c.key(key)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.onError(err)
+		}
+		return nil, false
+	}
+	return c.decode(data)
+}
+
+// GetMany gets the results of keys with a single MGET. The result of a key
+// which isn't cached is nil.
+func (c *Cache[K, V]) GetMany(ctx context.Context, keys []K) []*dataloader.Result[V] {
+	results := make([]*dataloader.Result[V], len(keys))
+	if len(keys) == 0 {
+		return results
+	}
+
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		redisKeys[i] = c.key(key)
+	}
+	values, err := c.client.MGet(ctx, redisKeys...).Result()
+	if err != nil {
+		c.onError(err)
+		return results
+	}
+	for i, value := range values {
+		if s, ok := value.(string); ok {
+			results[i], _ = c.decode([]byte(s))
+		}
+	}
+	return results
+}
+
+// Set sets the result of key.
+func (c *Cache[K, V]) Set(ctx context.Context, key K, result *dataloader.Result[V]) {
+	data, ok := c.encode(result)
+	if !ok {
+		return
+	}
+	if err := c.client.Set(ctx, c.key(key), data, c.ttlOf(key, result)).Err(); err != nil {
+		c.onError(err)
+	}
+}
+
+// SetMany sets the results of keys in a single pipeline. The result at each
+// index is the result of the key at the same index.
+func (c *Cache[K, V]) SetMany(ctx context.Context, keys []K, results []*dataloader.Result[V]) {
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			if i >= len(results) {
+				break
+			}
+			if data, ok := c.encode(results[i]); ok {
+				pipe.Set(ctx, c.key(key), data, c.ttlOf(key, results[i]))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.onError(err)
+	}
+}
+
+// Delete deletes the result of key.
+func (c *Cache[K, V]) Delete(ctx context.Context, key K) bool {
+	n, err := c.client.Del(ctx, c.key(key)).Result()
+	if err != nil {
+		c.onError(err)
+	}
+	return n > 0
+}
+
+// DeleteMany deletes the results of keys in a single pipeline, and returns
+// the number of deleted results.
+func (c *Cache[K, V]) DeleteMany(ctx context.Context, keys []K) int {
+	cmds := make([]*redis.IntCmd, 0, len(keys))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			cmds = append(cmds, pipe.Del(ctx, c.key(key)))
+		}
+		return nil
+	})
+	if err != nil {
+		c.onError(err)
+	}
+	var deleted int
+	for _, cmd := range cmds {
+		deleted += int(cmd.Val())
+	}
+	return deleted
+}
+
+// Clear deletes every key under the prefix. It is a noop without a prefix,
+// rather than flushing the whole database.
+func (c *Cache[K, V]) Clear() {
+	if c.prefix == "" {
+		return
+	}
+	ctx := context.Background()
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		if err := c.client.Unlink(ctx, iter.Val()).Err(); err != nil {
+			c.onError(err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		c.onError(err)
+	}
+}
+
+func (c *Cache[K, V]) key(key K) string {
+	return c.prefix + c.keyOf(key)
+}
+
+// encode returns the entry of a result (see codec.EncodeEntry), or false if
+// the result isn't cached.
+func (c *Cache[K, V]) encode(result *dataloader.Result[V]) ([]byte, bool) {
+	data, ok, err := codec.EncodeEntry(nil, c.codec, result)
+	if err != nil {
+		c.onError(err)
+	}
+	return data, ok
+}
+
+// decode returns the result of an entry, or false if it can't be decoded.
+func (c *Cache[K, V]) decode(data []byte) (*dataloader.Result[V], bool) {
+	result, err := codec.DecodeEntry(c.codec, data)
+	if err != nil {
+		c.onError(err)
+		return nil, false
+	}
+	return result, true
+}
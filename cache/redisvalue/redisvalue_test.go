@@ -0,0 +1,194 @@
+package redisvalue
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graph-gophers/dataloader/v7/codec"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type user struct {
+	ID   string
+	Name string
+}
+
+func newCache(t *testing.T, opts ...Option[string, *user]) (*Cache[string, *user], *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	opts = append([]Option[string, *user]{WithPrefix[string, *user]("users:")}, opts...)
+	return New(client, codec.JSON[*user](), opts...), server
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("get, set and delete", func(t *testing.T) {
+		c, _ := newCache(t)
+		if _, ok := c.Get(ctx, "1"); ok {
+			t.Fatal("expected a miss")
+		}
+
+		c.Set(ctx, "1", &dataloader.Result[*user]{Data: &user{ID: "1", Name: "alice"}})
+		result, ok := c.Get(ctx, "1")
+		if !ok || !reflect.DeepEqual(result.Data, &user{ID: "1", Name: "alice"}) {
+			t.Fatalf("expected alice, got %#v", result)
+		}
+
+		if !c.Delete(ctx, "1") || c.Delete(ctx, "1") {
+			t.Error("expected the key to be deleted once")
+		}
+		if _, ok := c.Get(ctx, "1"); ok {
+			t.Error("expected a miss after delete")
+		}
+	})
+
+	t.Run("caches not found but not errors", func(t *testing.T) {
+		c, _ := newCache(t)
+		c.Set(ctx, "1", dataloader.NotFound[*user]())
+		c.Set(ctx, "2", &dataloader.Result[*user]{Error: errors.New("boom")})
+
+		if result, ok := c.Get(ctx, "1"); !ok || !result.NotFound() {
+			t.Errorf("expected a cached not found, got %#v", result)
+		}
+		if _, ok := c.Get(ctx, "2"); ok {
+			t.Error("expected the error not to be cached")
+		}
+	})
+
+	t.Run("expires entries", func(t *testing.T) {
+		c, server := newCache(t, WithTTL[string, *user](time.Minute))
+		c.Set(ctx, "1", &dataloader.Result[*user]{Data: &user{ID: "1"}})
+		if ttl := server.TTL("users:1"); ttl != time.Minute {
+			t.Errorf("expected a ttl of a minute, got %v", ttl)
+		}
+		server.FastForward(time.Minute)
+		if _, ok := c.Get(ctx, "1"); ok {
+			t.Error("expected the entry to expire")
+		}
+	})
+
+	t.Run("get, set and delete many", func(t *testing.T) {
+		c, _ := newCache(t)
+		c.SetMany(ctx, []string{"1", "2"}, []*dataloader.Result[*user]{
+			{Data: &user{ID: "1"}},
+			{Data: &user{ID: "2"}},
+		})
+
+		results := c.GetMany(ctx, []string{"1", "3", "2"})
+		if len(results) != 3 || results[0].Data.ID != "1" || results[1] != nil || results[2].Data.ID != "2" {
+			t.Fatalf("unexpected results %#v", results)
+		}
+
+		if n := c.DeleteMany(ctx, []string{"1", "2", "3"}); n != 2 {
+			t.Errorf("expected 2 deleted keys, got %d", n)
+		}
+	})
+
+	t.Run("clears the prefix only", func(t *testing.T) {
+		c, server := newCache(t)
+		c.Set(ctx, "1", &dataloader.Result[*user]{Data: &user{ID: "1"}})
+		server.Set("other", "value")
+
+		c.Clear()
+		if _, ok := c.Get(ctx, "1"); ok {
+			t.Error("expected the cache to be cleared")
+		}
+		if !server.Exists("other") {
+			t.Error("expected the keys outside the prefix to be kept")
+		}
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		var (
+			mu   sync.Mutex
+			errs []error
+		)
+		c, server := newCache(t, WithErrorHandler[string, *user](func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}))
+		server.Set("users:1", "garbage")
+		if _, ok := c.Get(ctx, "1"); ok {
+			t.Error("expected an invalid entry to be a miss")
+		}
+		if len(errs) != 1 {
+			t.Errorf("expected an error to be reported, got %v", errs)
+		}
+	})
+
+	t.Run("with a loader", func(t *testing.T) {
+		c, _ := newCache(t)
+		var calls int
+		loader := dataloader.NewBatchedLoader(func(_ context.Context, keys []string) []*dataloader.Result[*user] {
+			calls++
+			results := make([]*dataloader.Result[*user], len(keys))
+			for i, key := range keys {
+				results[i] = &dataloader.Result[*user]{Data: &user{ID: key}}
+			}
+			return results
+		}, dataloader.WithValueCache[string, *user](c))
+
+		if _, err := loader.Load(ctx, "1")(); err != nil {
+			t.Fatal(err)
+		}
+		// the result is moved to the cache asynchronously
+		for i := 0; i < 100; i++ {
+			if _, ok := c.Get(ctx, "1"); ok {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		u, err := loader.Load(ctx, "1")()
+		if err != nil || u.ID != "1" || calls != 1 {
+			t.Errorf("expected the cached user, got %#v, %v after %d calls", u, err, calls)
+		}
+	})
+
+	t.Run("shares the cache between the loaders of instances", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		var mu sync.Mutex
+		var calls int
+		newLoader := func() *dataloader.Loader[string, *user] {
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			t.Cleanup(func() { client.Close() })
+			return dataloader.NewBatchedLoader(func(_ context.Context, keys []string) []*dataloader.Result[*user] {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				results := make([]*dataloader.Result[*user], len(keys))
+				for i, key := range keys {
+					results[i] = &dataloader.Result[*user]{Data: &user{ID: key, Name: "loaded"}}
+				}
+				return results
+			}, WithCache[string, *user](client, codec.JSON[*user](), WithPrefix[string, *user]("users:")))
+		}
+		first, second := newLoader(), newLoader()
+
+		if _, err := first.Load(ctx, "1")(); err != nil {
+			t.Fatal(err)
+		}
+		// the result is moved to Redis asynchronously
+		for i := 0; i < 100 && !server.Exists("users:1"); i++ {
+			time.Sleep(time.Millisecond)
+		}
+
+		u, err := second.Load(ctx, "1")()
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil || u.Name != "loaded" || calls != 1 {
+			t.Errorf("expected the user cached by the other loader, got %#v, %v after %d calls", u, err, calls)
+		}
+	})
+}
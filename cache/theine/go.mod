@@ -0,0 +1,16 @@
+module github.com/graph-gophers/dataloader/v7/cache/theine
+
+go 1.23
+
+require (
+	github.com/Yiling-J/theine-go v0.6.0
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+)
+
+require (
+	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
+	github.com/zeebo/xxh3 v1.0.2 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+)
+
+replace github.com/graph-gophers/dataloader/v7 => ../..
@@ -0,0 +1,97 @@
+// Package theine provides a bounded in process Cache backed by theine, a
+// W-TinyLFU cache with a high hit ratio.
+package theine
+
+import (
+	"context"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/Yiling-J/theine-go"
+)
+
+// Cache implements the dataloader.Cache interface with theine.
+type Cache[K comparable, V any] struct {
+	cache *theine.Cache[K, dataloader.Thunk[V]]
+	ttl   time.Duration
+}
+
+var _ dataloader.Cache[string, any] = &Cache[string, any]{}
+
+// Option allows for configuration of the cache.
+type Option func(*config)
+
+type config struct {
+	ttl time.Duration
+}
+
+// WithTTL sets the time to live of the entries. Default is 0: entries are
+// only evicted when the cache is full.
+func WithTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.ttl = d
+	}
+}
+
+// New constructs a new Cache holding up to capacity keys.
+func New[K comparable, V any](capacity int, opts ...Option) (*Cache[K, V], error) {
+	var conf config
+	for _, apply := range opts {
+		apply(&conf)
+	}
+
+	c, err := theine.NewBuilder[K, dataloader.Thunk[V]](int64(capacity)).Build()
+	if err != nil {
+		return nil, err
+	}
+	return &Cache[K, V]{cache: c, ttl: conf.ttl}, nil
+}
+
+// WithCache sets the cache of a loader to a new Cache holding up to capacity
+// keys. It panics if the capacity is not positive.
+func WithCache[K comparable, V any](capacity int, opts ...Option) dataloader.Option[K, V] {
+	c, err := New[K, V](capacity, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return dataloader.WithCache[K, V](c)
+}
+
+// Get gets an item from the cache
+func (c *Cache[K, V]) Get(_ context.Context, key K) (dataloader.Thunk[V], bool) {
+	return c.cache.Get(key)
+}
+
+// Set sets an item in the cache
+func (c *Cache[K, V]) Set(_ context.Context, key K, value dataloader.Thunk[V]) {
+	if c.ttl > 0 {
+		c.cache.SetWithTTL(key, value, 1, c.ttl)
+		return
+	}
+	c.cache.Set(key, value, 1)
+}
+
+// Delete deletes an item in the cache
+func (c *Cache[K, V]) Delete(_ context.Context, key K) bool {
+	_, ok := c.cache.Get(key)
+	c.cache.Delete(key)
+	return ok
+}
+
+// Clear clears the cache
+func (c *Cache[K, V]) Clear() {
+	var keys []K
+	c.cache.Range(func(key K, _ dataloader.Thunk[V]) bool {
+		keys = append(keys, key)
+		return true
+	})
+	for _, key := range keys {
+		c.cache.Delete(key)
+	}
+}
+
+// Close stops the background goroutines of the cache.
+func (c *Cache[K, V]) Close() {
+	c.cache.Close()
+}
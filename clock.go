@@ -0,0 +1,78 @@
+package dataloader
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of a loader, see WithClock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer returns a new Timer firing once d has elapsed.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer of a Clock, like a time.Timer.
+type Timer interface {
+	// C returns the channel receiving the time once the timer fires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, and reports whether it did.
+	Stop() bool
+	// Reset changes the timer to fire once d has elapsed.
+	Reset(d time.Duration) bool
+}
+
+// WithClock sets the clock which times the batch windows (WithWait,
+// WithIdleFlush, WithMinBatchSize and ContextWithWait), along with the ages of
+// WithCacheMaxAge and WithBatchMemo, the TTLs of WithNotFoundTTL,
+// WithThunkWatchdog, WithThunkTimeout and the periods of WithAutoTune, so that
+// tests can drive them with a fake clock. The latencies of the batch function
+// are still timed by the system clock, and so are the deadlines of the
+// contexts: WithDeadlineAdmission compares the time they have left with the
+// window.
+// Default is the system clock, which the bubbles of testing/synctest already
+// fake.
+func WithClock[K comparable, V any](c Clock) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.clock = c
+	}
+}
+
+// systemClock is the Clock of the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+// afterFunc calls f in its own goroutine once d has elapsed on the clock, like
+// time.AfterFunc does. The returned function stops the timer, and reports
+// whether it did before f was called.
+func afterFunc(c Clock, d time.Duration, f func()) (stop func() bool) {
+	if _, ok := c.(systemClock); ok {
+		return time.AfterFunc(d, f).Stop
+	}
+	timer := c.NewTimer(d)
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-timer.C():
+			f()
+		case <-stopped:
+		}
+	}()
+	var once sync.Once
+	return func() bool {
+		once.Do(func() { close(stopped) })
+		return timer.Stop()
+	}
+}
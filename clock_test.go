@@ -0,0 +1,186 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves on Advance.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	c     chan time.Time
+	armed bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the time forward by d, firing the timers which are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+// fire fires the timers which are due.
+// It must be called with the mu held.
+func (c *fakeClock) fire() {
+	for _, t := range c.timers {
+		if t.armed && !t.at.After(c.now) {
+			t.armed = false
+			t.c <- c.now
+		}
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	armed := t.armed
+	t.armed = false
+	return armed
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	armed := t.armed
+	if !armed {
+		c.timers = append(c.timers, t)
+	}
+	t.at, t.armed = c.now.Add(d), true
+	c.fire()
+	return armed
+}
+
+func TestWithClock(t *testing.T) {
+	t.Run("the batch windows are timed by the clock", func(t *testing.T) {
+		t.Parallel()
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		loader := NewBatchedLoader(batchIdentity[string], WithWait[string, string](time.Minute), WithClock[string, string](clock))
+		ctx := context.Background()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			loader.Load(ctx, "1")()
+		}()
+		select {
+		case <-done:
+			t.Fatal("expected the batch to wait for the clock")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		clock.Advance(time.Minute)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected the batch to be dispatched once the window has elapsed")
+		}
+	})
+
+	t.Run("the thunk timeouts are timed by the clock", func(t *testing.T) {
+		t.Parallel()
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		loader := NewBatchedLoader(batchIdentity[string], WithManualDispatch[string, string](), WithThunkTimeout[string, string](time.Minute), WithClock[string, string](clock))
+
+		thunk := loader.Load(context.Background(), "1")
+		clock.Advance(time.Minute)
+		if _, err := thunk(); !errors.Is(err, ErrThunkTimeout) {
+			t.Errorf("expected the thunk to time out, got %v", err)
+		}
+	})
+
+	t.Run("the not found TTLs are timed by the clock", func(t *testing.T) {
+		t.Parallel()
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		cache := &deleteRecorder[string, string]{DataCache: NewDataCache[string, string]()}
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			results := make([]*Result[string], len(keys))
+			for i := range keys {
+				results[i] = NotFound[string]()
+			}
+			return results
+		}, WithNotFoundTTL[string, string](time.Minute), WithValueCache[string, string](cache), WithImmediateDispatch[string, string](), WithClock[string, string](clock))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		loader.Load(ctx, "1")()
+		cancel()
+		clock.Advance(time.Minute)
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			if err, ok := cache.deleted(); ok {
+				if err != nil {
+					t.Errorf("expected the key to be deleted with a live context, got %v", err)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("expected the key to expire once the clock has advanced")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("the deadlines are admitted in clock time", func(t *testing.T) {
+		t.Parallel()
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		loader := NewBatchedLoader(batchIdentity[string], WithWait[string, string](time.Minute), WithDeadlineAdmission[string, string](DeadlineDispatch), WithClock[string, string](clock))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		// the deadline is nearer than the end of the window on the clock
+		if v, err := loader.Load(ctx, "1")(); v != "1" || err != nil {
+			t.Errorf("expected the batch to be dispatched right away, got %q, %v", v, err)
+		}
+	})
+}
+
+// deleteRecorder is a DataCache recording the error of the context of its
+// first Delete.
+type deleteRecorder[K comparable, V any] struct {
+	DataCache[K, V]
+
+	mu     sync.Mutex
+	called bool
+	ctxErr error
+}
+
+func (c *deleteRecorder[K, V]) Delete(ctx context.Context, key K) bool {
+	c.mu.Lock()
+	if !c.called {
+		c.called, c.ctxErr = true, ctx.Err()
+	}
+	c.mu.Unlock()
+	return c.DataCache.Delete(ctx, key)
+}
+
+func (c *deleteRecorder[K, V]) deleted() (error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ctxErr, c.called
+}
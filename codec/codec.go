@@ -0,0 +1,31 @@
+// Package codec provides the serialization of values for the caches storing
+// them out of process.
+package codec
+
+import "encoding/json"
+
+// The ValueCodec interface serializes values to bytes and back.
+type ValueCodec[V any] interface {
+	Marshal(V) ([]byte, error)
+	Unmarshal([]byte) (V, error)
+}
+
+// JSONCodec implements ValueCodec with encoding/json.
+type JSONCodec[V any] struct{}
+
+// JSON returns a ValueCodec encoding values as JSON.
+func JSON[V any]() JSONCodec[V] {
+	return JSONCodec[V]{}
+}
+
+// Marshal encodes the value as JSON.
+func (JSONCodec[V]) Marshal(v V) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes a value from JSON.
+func (JSONCodec[V]) Unmarshal(data []byte) (V, error) {
+	var v V
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
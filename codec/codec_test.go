@@ -0,0 +1,93 @@
+package codec
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+func TestJSON(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+	c := JSON[*user]()
+
+	data, err := c.Marshal(&user{ID: 1, Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(u, &user{ID: 1, Name: "alice"}) {
+		t.Errorf("expected the value to round trip, got %#v", u)
+	}
+
+	if _, err := c.Unmarshal([]byte("{")); err == nil {
+		t.Error("expected invalid JSON to fail")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	c, err := Lookup[map[string]int]("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.Marshal(map[string]int{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Unmarshal(data); err != nil || v["a"] != 1 {
+		t.Errorf("expected the value to round trip, got %v, %v", v, err)
+	}
+
+	if _, err := Lookup[int]("unknown"); err == nil {
+		t.Error("expected an unknown codec to fail")
+	}
+
+	Register("test", jsonCodec{})
+	t.Cleanup(func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+		delete(registry, "test")
+	})
+	if names := Names(); !reflect.DeepEqual(names, []string{"json", "test"}) {
+		t.Errorf("unexpected names %v", names)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a codec twice to panic")
+		}
+	}()
+	Register("test", jsonCodec{})
+}
+
+func TestEntry(t *testing.T) {
+	c := JSON[string]()
+
+	for _, result := range []*dataloader.Result[string]{{Data: ""}, {Data: "a"}, dataloader.NotFound[string]()} {
+		entry, ok, err := EncodeEntry(nil, c, result)
+		if !ok || err != nil {
+			t.Fatalf("expected %#v to be encoded, got %v", result, err)
+		}
+		decoded, err := DecodeEntry(c, entry)
+		if err != nil || decoded.Data != result.Data || decoded.NotFound() != result.NotFound() {
+			t.Errorf("expected %#v to round trip, got %#v, %v", result, decoded, err)
+		}
+	}
+
+	for _, result := range []*dataloader.Result[string]{nil, {Error: errors.New("boom")}} {
+		if _, ok, err := EncodeEntry(nil, c, result); ok || err != nil {
+			t.Errorf("expected %#v not to be encoded, got %v", result, err)
+		}
+	}
+	for _, entry := range [][]byte{nil, []byte("x1")} {
+		if _, err := DecodeEntry(c, entry); !errors.Is(err, ErrInvalidEntry) {
+			t.Errorf("expected %q to be invalid, got %v", entry, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package compress provides a ValueCodec compressing the encoding of another
+// one with snappy or zstd, to reduce the memory used by external caches.
+//
+// Every encoding starts with a byte telling how it was compressed, so that
+// values encoded with a different algorithm (or none) can still be decoded:
+// the algorithm and the threshold can be changed without clearing the cache.
+package compress
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/graph-gophers/dataloader/v7/codec"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// An Algorithm is a compression algorithm.
+type Algorithm byte
+
+const (
+	// None leaves the encodings uncompressed.
+	None Algorithm = iota
+	// Snappy compresses fast, at a moderate ratio.
+	Snappy
+	// Zstd compresses at a higher ratio than Snappy, but slower.
+	Zstd
+)
+
+// DefaultThreshold is the default size of the encodings below which they are
+// left uncompressed.
+const DefaultThreshold = 1024
+
+// Codec implements codec.ValueCodec, compressing the encodings of another
+// ValueCodec.
+type Codec[V any] struct {
+	inner     codec.ValueCodec[V]
+	algorithm Algorithm
+	threshold int
+}
+
+// Option allows for configuration of Codec fields.
+type Option func(*options)
+
+type options struct {
+	threshold int
+}
+
+// WithThreshold sets the size of the encodings below which they are left
+// uncompressed, since compressing small payloads costs more than it saves.
+// Default is DefaultThreshold.
+func WithThreshold(n int) Option {
+	return func(o *options) {
+		o.threshold = n
+	}
+}
+
+// New returns a ValueCodec compressing the encodings of inner with the given
+// algorithm.
+func New[V any](inner codec.ValueCodec[V], algorithm Algorithm, opts ...Option) *Codec[V] {
+	o := options{threshold: DefaultThreshold}
+	for _, apply := range opts {
+		apply(&o)
+	}
+	return &Codec[V]{inner: inner, algorithm: algorithm, threshold: o.threshold}
+}
+
+// Marshal encodes the value with the inner codec, then compresses it if it is
+// at least as large as the threshold.
+func (c *Codec[V]) Marshal(v V) ([]byte, error) {
+	data, err := c.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := c.algorithm
+	if len(data) < c.threshold {
+		algorithm = None
+	}
+	switch algorithm {
+	case Snappy:
+		buf := make([]byte, 1+snappy.MaxEncodedLen(len(data)))
+		buf[0] = byte(Snappy)
+		return buf[:1+len(snappy.Encode(buf[1:], data))], nil
+	case Zstd:
+		return encoder().EncodeAll(data, []byte{byte(Zstd)}), nil
+	default:
+		return append([]byte{byte(None)}, data...), nil
+	}
+}
+
+// Unmarshal decompresses the encoding, then decodes it with the inner codec.
+func (c *Codec[V]) Unmarshal(data []byte) (V, error) {
+	var zero V
+	if len(data) == 0 {
+		return zero, fmt.Errorf("compress: empty encoding")
+	}
+
+	var (
+		raw []byte
+		err error
+	)
+	switch Algorithm(data[0]) {
+	case None:
+		raw = data[1:]
+	case Snappy:
+		raw, err = snappy.Decode(nil, data[1:])
+	case Zstd:
+		raw, err = decoder().DecodeAll(data[1:], nil)
+	default:
+		err = fmt.Errorf("compress: unknown algorithm %d", data[0])
+	}
+	if err != nil {
+		return zero, err
+	}
+	return c.inner.Unmarshal(raw)
+}
+
+// the zstd encoder and decoder are safe for concurrent use of EncodeAll and
+// DecodeAll, and costly to create
+var (
+	encoder = sync.OnceValue(func() *zstd.Encoder {
+		e, _ := zstd.NewWriter(nil)
+		return e
+	})
+	decoder = sync.OnceValue(func() *zstd.Decoder {
+		d, _ := zstd.NewReader(nil)
+		return d
+	})
+)
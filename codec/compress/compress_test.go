@@ -0,0 +1,57 @@
+package compress
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7/codec"
+)
+
+func TestCodec(t *testing.T) {
+	large := strings.Repeat("dataloader ", 1000)
+
+	for _, algorithm := range []Algorithm{None, Snappy, Zstd} {
+		c := New(codec.JSON[string](), algorithm)
+
+		data, err := c.Marshal(large)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Algorithm(data[0]) != algorithm {
+			t.Errorf("expected the encoding to be compressed with %d, got %d", algorithm, data[0])
+		}
+		if algorithm != None && len(data) >= len(large) {
+			t.Errorf("expected %d to compress, got %d bytes", algorithm, len(data))
+		}
+		if v, err := c.Unmarshal(data); err != nil || v != large {
+			t.Errorf("expected the value to round trip with %d, got %v", algorithm, err)
+		}
+
+		small, err := c.Marshal("small")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Algorithm(small[0]) != None {
+			t.Errorf("expected a small value to be left uncompressed, got %d", small[0])
+		}
+		if v, err := c.Unmarshal(small); err != nil || v != "small" {
+			t.Errorf("expected the small value to round trip, got %q, %v", v, err)
+		}
+	}
+
+	t.Run("decodes other algorithms", func(t *testing.T) {
+		data, _ := New(codec.JSON[string](), Zstd).Marshal(large)
+		if v, err := New(codec.JSON[string](), Snappy, WithThreshold(0)).Unmarshal(data); err != nil || v != large {
+			t.Errorf("expected a zstd encoding to be decoded, got %v", err)
+		}
+	})
+
+	t.Run("rejects invalid encodings", func(t *testing.T) {
+		c := New(codec.JSON[string](), Snappy)
+		for _, data := range [][]byte{nil, {42}, {byte(Snappy), 0xff}} {
+			if _, err := c.Unmarshal(data); err == nil {
+				t.Errorf("expected %v to fail", data)
+			}
+		}
+	})
+}
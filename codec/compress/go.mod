@@ -0,0 +1,10 @@
+module github.com/graph-gophers/dataloader/v7/codec/compress
+
+go 1.23
+
+require (
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+	github.com/klauspost/compress v1.17.11
+)
+
+replace github.com/graph-gophers/dataloader/v7 => ../..
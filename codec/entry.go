@@ -0,0 +1,55 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// The entries of results are tagged, so that not found results can be cached
+// and so that an empty encoding (i.e. of an empty protobuf message) is a valid
+// value.
+const (
+	tagValue    = 'v'
+	tagNotFound = 'n'
+)
+
+// ErrInvalidEntry is the error of DecodeEntry for data which isn't an entry.
+var ErrInvalidEntry = errors.New("codec: invalid entry")
+
+// EncodeEntry appends the entry of a result to dst, for the caches storing the
+// results of keys out of process (see dataloader.DataCache): the value of the
+// result encoded with c, or a not found result (see dataloader.ErrNotFound).
+// ok is false for a nil result or a result with another error, which isn't
+// cached, and for a value which c fails to encode.
+func EncodeEntry[V any](dst []byte, c ValueCodec[V], result *dataloader.Result[V]) (entry []byte, ok bool, err error) {
+	switch {
+	case result == nil:
+		return dst, false, nil
+	case result.NotFound():
+		return append(dst, tagNotFound), true, nil
+	case result.Error != nil:
+		return dst, false, nil
+	}
+	data, err := c.Marshal(result.Data)
+	if err != nil {
+		return dst, false, err
+	}
+	return append(append(dst, tagValue), data...), true, nil
+}
+
+// DecodeEntry decodes the result of an entry of EncodeEntry.
+func DecodeEntry[V any](c ValueCodec[V], entry []byte) (*dataloader.Result[V], error) {
+	switch {
+	case len(entry) == 1 && entry[0] == tagNotFound:
+		return dataloader.NotFound[V](), nil
+	case len(entry) == 0 || entry[0] != tagValue:
+		return nil, fmt.Errorf("%w %q", ErrInvalidEntry, entry)
+	}
+	v, err := c.Unmarshal(entry[1:])
+	if err != nil {
+		return nil, err
+	}
+	return &dataloader.Result[V]{Data: v}, nil
+}
@@ -0,0 +1,12 @@
+module github.com/graph-gophers/dataloader/v7/codec/msgpack
+
+go 1.23
+
+require (
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+
+replace github.com/graph-gophers/dataloader/v7 => ../..
@@ -0,0 +1,45 @@
+// Package msgpack provides a ValueCodec encoding values as MessagePack.
+package msgpack
+
+import (
+	"github.com/graph-gophers/dataloader/v7/codec"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec implements codec.ValueCodec with MessagePack.
+type Codec[V any] struct{}
+
+var _ codec.ValueCodec[any] = Codec[any]{}
+
+func init() {
+	codec.Register("msgpack", untyped{})
+}
+
+// New returns a ValueCodec encoding values as MessagePack.
+func New[V any]() Codec[V] {
+	return Codec[V]{}
+}
+
+// Marshal encodes the value as MessagePack.
+func (Codec[V]) Marshal(v V) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Unmarshal decodes a value from MessagePack.
+func (Codec[V]) Unmarshal(data []byte) (V, error) {
+	var v V
+	err := msgpack.Unmarshal(data, &v)
+	return v, err
+}
+
+// untyped is the codec of the registry.
+type untyped struct{}
+
+func (untyped) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (untyped) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
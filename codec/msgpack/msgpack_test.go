@@ -0,0 +1,46 @@
+package msgpack
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7/codec"
+)
+
+func TestCodec(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+	c := New[*user]()
+
+	data, err := c.Marshal(&user{ID: 1, Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(u, &user{ID: 1, Name: "alice"}) {
+		t.Errorf("expected the value to round trip, got %#v", u)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+	c, err := codec.Lookup[*user]("msgpack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.Marshal(&user{ID: 1, Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, err := c.Unmarshal(data); err != nil || !reflect.DeepEqual(u, &user{ID: 1, Name: "alice"}) {
+		t.Errorf("expected the value to round trip, got %#v, %v", u, err)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/graph-gophers/dataloader/v7/codec/protobuf
+
+go 1.23
+
+require (
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+	google.golang.org/protobuf v1.36.0
+)
+
+replace github.com/graph-gophers/dataloader/v7 => ../..
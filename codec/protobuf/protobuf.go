@@ -0,0 +1,71 @@
+// Package protobuf provides a ValueCodec encoding protocol buffer messages.
+package protobuf
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/graph-gophers/dataloader/v7/codec"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec implements codec.ValueCodec with the protocol buffers wire format.
+// V is the pointer type of a generated message (i.e. *pb.User).
+type Codec[V proto.Message] struct{}
+
+var _ codec.ValueCodec[proto.Message] = Codec[proto.Message]{}
+
+func init() {
+	codec.Register("protobuf", untyped{})
+}
+
+// New returns a ValueCodec encoding messages with the protocol buffers wire
+// format.
+func New[V proto.Message]() Codec[V] {
+	return Codec[V]{}
+}
+
+// Marshal encodes the message.
+func (Codec[V]) Marshal(v V) ([]byte, error) {
+	return proto.Marshal(v)
+}
+
+// Unmarshal decodes a message.
+func (Codec[V]) Unmarshal(data []byte) (V, error) {
+	var zero V
+	// the zero value of a generated message type is a typed nil pointer, which
+	// still knows its type
+	v := zero.ProtoReflect().New().Interface().(V)
+	err := proto.Unmarshal(data, v)
+	return v, err
+}
+
+// untyped is the codec of the registry. It only encodes messages, and decodes
+// to pointers to messages (i.e. a **pb.User).
+type untyped struct{}
+
+func (untyped) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (untyped) Unmarshal(data []byte, v any) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return fmt.Errorf("protobuf: cannot decode to %T", v)
+	}
+	m, ok := ptr.Elem().Interface().(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf: %T is not a pointer to a proto.Message", v)
+	}
+	m = m.ProtoReflect().New().Interface()
+	if err := proto.Unmarshal(data, m); err != nil {
+		return err
+	}
+	ptr.Elem().Set(reflect.ValueOf(m))
+	return nil
+}
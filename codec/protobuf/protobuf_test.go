@@ -0,0 +1,48 @@
+package protobuf
+
+import (
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7/codec"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCodec(t *testing.T) {
+	c := New[*wrapperspb.StringValue]()
+
+	data, err := c.Marshal(wrapperspb.String("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(v, wrapperspb.String("alice")) {
+		t.Errorf("expected the message to round trip, got %v", v)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	c, err := codec.Lookup[*wrapperspb.StringValue]("protobuf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.Marshal(wrapperspb.String("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Unmarshal(data); err != nil || !proto.Equal(v, wrapperspb.String("alice")) {
+		t.Errorf("expected the message to round trip, got %v, %v", v, err)
+	}
+
+	s, err := codec.Lookup[string]("protobuf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Marshal("alice"); err == nil {
+		t.Error("expected a value which isn't a message to fail")
+	}
+}
@@ -0,0 +1,88 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// The Codec interface is implemented by the codecs of the registry. Unlike a
+// ValueCodec, it isn't bound to a type: Unmarshal is given a pointer to the
+// value to decode, like encoding/json.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]Codec{
+		"json": jsonCodec{},
+	}
+)
+
+// Register makes a codec available by name. The codec/msgpack and
+// codec/protobuf packages register theirs ("msgpack" and "protobuf") when they
+// are imported; "json" is always available. It panics if the codec is nil or
+// if a codec is already registered with the same name.
+func Register(name string, c Codec) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if c == nil {
+		panic("codec: Register codec is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("codec: Register called twice for codec " + name)
+	}
+	registry[name] = c
+}
+
+// Names returns the sorted names of the registered codecs.
+func Names() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the ValueCodec of the codec registered by name, so that the
+// codec of a cache can be set by configuration.
+func Lookup[V any](name string) (ValueCodec[V], error) {
+	registryLock.RLock()
+	c, ok := registry[name]
+	registryLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("codec: unknown codec %q (forgotten import?)", name)
+	}
+	return typed[V]{c}, nil
+}
+
+// typed adapts a Codec to the ValueCodec interface.
+type typed[V any] struct {
+	c Codec
+}
+
+func (t typed[V]) Marshal(v V) ([]byte, error) {
+	return t.c.Marshal(v)
+}
+
+func (t typed[V]) Unmarshal(data []byte) (V, error) {
+	var v V
+	err := t.c.Unmarshal(data, &v)
+	return v, err
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
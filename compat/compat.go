@@ -0,0 +1,394 @@
+// Package compat exposes the string-keyed, non-generic API of the v5 and v6
+// major versions on top of the generic Loader, so that a codebase still on the
+// pre-generics API can swap its import path, and then migrate its call sites to
+// the generic API one loader at a time.
+//
+// The loaded keys are batched with their raw values, and deduped and cached by
+// their String(). The caches and the tracers are called with the StringKey of
+// the String() of the loaded keys.
+package compat
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	dataloader "github.com/graph-gophers/dataloader/v7"
+)
+
+// Key is the interface that all keys need to implement.
+type Key interface {
+	// String returns a guaranteed unique string that can be used to identify an object
+	String() string
+	// Raw returns the raw, underlaying value of the key
+	Raw() interface{}
+}
+
+// Keys wraps a slice of Key types to provide some convenience methods.
+type Keys []Key
+
+// Keys returns the list of strings. One for each "Key" in the list
+func (l Keys) Keys() []string {
+	list := make([]string, len(l))
+	for i := range l {
+		list[i] = l[i].String()
+	}
+	return list
+}
+
+// StringKey implements the Key interface for a string
+type StringKey string
+
+// String is an identity method. Used to implement String interface
+func (k StringKey) String() string { return string(k) }
+
+// Raw is an identity method. Used to implement Key Raw
+func (k StringKey) Raw() interface{} { return k }
+
+// NewKeysFromStrings converts a `[]strings` to a `Keys` ([]Key)
+func NewKeysFromStrings(strings []string) Keys {
+	list := make(Keys, len(strings))
+	for i := range strings {
+		list[i] = StringKey(strings[i])
+	}
+	return list
+}
+
+// valueKey is the Key of a value constructed by KeyOf.
+type valueKey struct {
+	s   string
+	raw interface{}
+}
+
+func (k valueKey) String() string   { return k.s }
+func (k valueKey) Raw() interface{} { return k.raw }
+
+// KeyOf returns the Key of a value. A Key is returned as is. The String() of
+// the values of the string and integer kinds (including the types derived from
+// them, such as `type UserID string`) is their plain value, and the String()
+// of the other values is their default format (see fmt.Sprint). The Raw() of
+// the key is the value, in its own type.
+//
+// A pointer is dereferenced, so that a *string or a *UserID is the key of the
+// value it points to, and a nil pointer is the key "<nil>" of a nil raw value.
+func KeyOf[T any](v T) Key {
+	if k, ok := any(v).(Key); ok {
+		return k
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return valueKey{s: "<nil>"}
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return valueKey{s: "<nil>"}
+	}
+	raw := rv.Interface()
+	if k, ok := raw.(Key); ok {
+		return k
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return valueKey{rv.String(), raw}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return valueKey{strconv.FormatInt(rv.Int(), 10), raw}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return valueKey{strconv.FormatUint(rv.Uint(), 10), raw}
+	default:
+		return valueKey{fmt.Sprint(raw), raw}
+	}
+}
+
+// KeysFromFunc returns the Keys of the items, extracted by key and converted
+// by KeyOf, i.e. the keys of the IDs of domain objects.
+func KeysFromFunc[T, K any](items []T, key func(T) K) Keys {
+	list := make(Keys, len(items))
+	for i, item := range items {
+		list[i] = KeyOf(key(item))
+	}
+	return list
+}
+
+// Result is the data structure that a BatchFunc returns.
+type Result = dataloader.Result[interface{}]
+
+// Thunk is a function that will block until the value (*Result) it contains is
+// resolved.
+type Thunk = dataloader.Thunk[interface{}]
+
+// ThunkMany is much like the Thunk func type but it contains a list of results.
+type ThunkMany = dataloader.ThunkMany[interface{}]
+
+// BatchFunc is a function, which when given a slice of keys (string), returns
+// a slice of `results`. It's important that the length of the input keys
+// matches the length of the output results.
+type BatchFunc func(context.Context, Keys) []*Result
+
+// Interface is a `DataLoader` Interface which defines a public API for loading
+// data from a particular data back-end with unique keys such as the `id`
+// column of a SQL table or document name in a MongoDB database, given a
+// batch loading function.
+type Interface interface {
+	Load(context.Context, Key) Thunk
+	LoadMany(context.Context, Keys) ThunkMany
+	Clear(context.Context, Key) Interface
+	ClearAll() Interface
+	Prime(ctx context.Context, key Key, value interface{}) Interface
+}
+
+// Loader implements the Interface on top of the generic loader.
+type Loader struct {
+	loader *dataloader.AnyLoader[Key, interface{}]
+}
+
+var _ Interface = &Loader{}
+
+// Option allows for configuration of Loader fields.
+type Option func(*options)
+
+// options are the options of the generic loader.
+type options struct {
+	opts []dataloader.Option[string, interface{}]
+}
+
+// WithOption applies an option of the generic loader, for the options this
+// package has no equivalent of.
+func WithOption(opt dataloader.Option[string, interface{}]) Option {
+	return func(o *options) {
+		o.opts = append(o.opts, opt)
+	}
+}
+
+// WithCache sets the BatchedLoader cache. Defaults to InMemoryCache if a Cache is not set.
+func WithCache(c Cache) Option {
+	return WithOption(dataloader.WithCache[string, interface{}](cacheAdapter{c}))
+}
+
+// WithBatchCapacity sets the batch capacity. Default is 0 (unbounded).
+func WithBatchCapacity(c int) Option {
+	return WithOption(dataloader.WithBatchCapacity[string, interface{}](c))
+}
+
+// WithInputCapacity sets the input capacity. Default is 1000.
+func WithInputCapacity(c int) Option {
+	return WithOption(dataloader.WithInputCapacity[string, interface{}](c))
+}
+
+// WithWait sets the amount of time to wait before triggering a batch.
+// Default duration is 16 milliseconds.
+func WithWait(d time.Duration) Option {
+	return WithOption(dataloader.WithWait[string, interface{}](d))
+}
+
+// WithClearCacheOnBatch allows batching of items but no long term caching.
+// It accomplishes this by clearing the cache after each batch operation.
+func WithClearCacheOnBatch() Option {
+	return WithOption(dataloader.WithClearCacheOnBatch[string, interface{}]())
+}
+
+// WithTracer allows tracing of calls to Load and LoadMany
+func WithTracer(tracer Tracer) Option {
+	return WithOption(dataloader.WithTracer[string, interface{}](tracerAdapter{tracer}))
+}
+
+// NewBatchedLoader constructs a new Loader with given options.
+func NewBatchedLoader(batchFn BatchFunc, opts ...Option) *Loader {
+	var o options
+	for _, apply := range opts {
+		apply(&o)
+	}
+	return &Loader{
+		loader: dataloader.NewBatchedLoaderFunc(func(ctx context.Context, keys []Key) []*Result {
+			return batchFn(ctx, keys)
+		}, func(key Key) string {
+			return key.String()
+		}, o.opts...),
+	}
+}
+
+// Load load/resolves the given key, returning a Thunk that will resolve the
+// value and error.
+func (l *Loader) Load(ctx context.Context, key Key) Thunk {
+	return l.loader.Load(ctx, key)
+}
+
+// LoadMany loads multiple keys, returning a thunk (type: ThunkMany) that will resolve the keys passed in.
+func (l *Loader) LoadMany(ctx context.Context, keys Keys) ThunkMany {
+	return l.loader.LoadMany(ctx, keys)
+}
+
+// Clear clears the value at `key` from the cache, it it exists. Returns self for method chaining
+func (l *Loader) Clear(ctx context.Context, key Key) Interface {
+	l.loader.Clear(ctx, key)
+	return l
+}
+
+// ClearAll clears the entire cache. To be used when some event results in
+// unknown invalidations. Returns self for method chaining.
+func (l *Loader) ClearAll() Interface {
+	l.loader.ClearAll()
+	return l
+}
+
+// Prime adds the provided key and value to the cache. If the key already
+// exists, no change is made. Returns self for method chaining
+func (l *Loader) Prime(ctx context.Context, key Key, value interface{}) Interface {
+	l.loader.Prime(ctx, key, value)
+	return l
+}
+
+// Cache is the interface of the caches of the v6 API.
+type Cache interface {
+	Get(context.Context, Key) (Thunk, bool)
+	Set(context.Context, Key, Thunk)
+	Delete(context.Context, Key) bool
+	Clear()
+}
+
+// InMemoryCache is an in memory implementation of Cache interface.
+// This simple implementation is well suited for
+// a "per-request" dataloader (i.e. one that only lives
+// for the life of an http request) but it's not well suited
+// for long lived cached items.
+type InMemoryCache struct {
+	items map[string]Thunk
+	mu    sync.RWMutex
+}
+
+// NewCache constructs a new InMemoryCache
+func NewCache() *InMemoryCache {
+	return &InMemoryCache{items: make(map[string]Thunk)}
+}
+
+// Set sets the `value` at `key` in the cache
+func (c *InMemoryCache) Set(_ context.Context, key Key, value Thunk) {
+	c.mu.Lock()
+	c.items[key.String()] = value
+	c.mu.Unlock()
+}
+
+// Get gets the value at `key` if it exists, returns value (or nil) and bool
+// indicating of value was found
+func (c *InMemoryCache) Get(_ context.Context, key Key) (Thunk, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	item, found := c.items[key.String()]
+	return item, found
+}
+
+// Delete deletes item at `key` from cache
+func (c *InMemoryCache) Delete(_ context.Context, key Key) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, found := c.items[key.String()]; found {
+		delete(c.items, key.String())
+		return true
+	}
+	return false
+}
+
+// Clear clears the entire cache
+func (c *InMemoryCache) Clear() {
+	c.mu.Lock()
+	c.items = make(map[string]Thunk)
+	c.mu.Unlock()
+}
+
+// NoCache implements Cache interface where all methods are noops.
+// This is useful for when you don't want to cache items but still
+// want to use a data loader
+type NoCache struct{}
+
+// Get is a NOOP
+func (c *NoCache) Get(context.Context, Key) (Thunk, bool) { return nil, false }
+
+// Set is a NOOP
+func (c *NoCache) Set(context.Context, Key, Thunk) { return }
+
+// Delete is a NOOP
+func (c *NoCache) Delete(context.Context, Key) bool { return false }
+
+// Clear is a NOOP
+func (c *NoCache) Clear() { return }
+
+// cacheAdapter is the generic cache of a Cache.
+type cacheAdapter struct {
+	c Cache
+}
+
+func (a cacheAdapter) Get(ctx context.Context, key string) (dataloader.Thunk[interface{}], bool) {
+	return a.c.Get(ctx, StringKey(key))
+}
+
+func (a cacheAdapter) Set(ctx context.Context, key string, value dataloader.Thunk[interface{}]) {
+	a.c.Set(ctx, StringKey(key), value)
+}
+
+func (a cacheAdapter) Delete(ctx context.Context, key string) bool {
+	return a.c.Delete(ctx, StringKey(key))
+}
+
+func (a cacheAdapter) Clear() {
+	a.c.Clear()
+}
+
+type TraceLoadFinishFunc func(Thunk)
+type TraceLoadManyFinishFunc func(ThunkMany)
+type TraceBatchFinishFunc func([]*Result)
+
+// Tracer is the interface of the tracers of the v6 API.
+type Tracer interface {
+	// TraceLoad will trace the calls to Load
+	TraceLoad(ctx context.Context, key Key) (context.Context, TraceLoadFinishFunc)
+	// TraceLoadMany will trace the calls to LoadMany
+	TraceLoadMany(ctx context.Context, keys Keys) (context.Context, TraceLoadManyFinishFunc)
+	// TraceBatch will trace data loader batches
+	TraceBatch(ctx context.Context, keys Keys) (context.Context, TraceBatchFinishFunc)
+}
+
+// NoopTracer is the default (noop) tracer
+type NoopTracer struct{}
+
+// TraceLoad is a noop function
+func (NoopTracer) TraceLoad(ctx context.Context, key Key) (context.Context, TraceLoadFinishFunc) {
+	return ctx, func(Thunk) {}
+}
+
+// TraceLoadMany is a noop function
+func (NoopTracer) TraceLoadMany(ctx context.Context, keys Keys) (context.Context, TraceLoadManyFinishFunc) {
+	return ctx, func(ThunkMany) {}
+}
+
+// TraceBatch is a noop function
+func (NoopTracer) TraceBatch(ctx context.Context, keys Keys) (context.Context, TraceBatchFinishFunc) {
+	return ctx, func(result []*Result) {}
+}
+
+// tracerAdapter is the generic tracer of a Tracer.
+type tracerAdapter struct {
+	t Tracer
+}
+
+func (a tracerAdapter) TraceLoad(ctx context.Context, key string) (context.Context, dataloader.TraceLoadFinishFunc[interface{}]) {
+	ctx, finish := a.t.TraceLoad(ctx, StringKey(key))
+	return ctx, func(r *dataloader.Result[interface{}]) {
+		finish(func() (interface{}, error) { return r.Data, r.Error })
+	}
+}
+
+func (a tracerAdapter) TraceLoadMany(ctx context.Context, keys []string) (context.Context, dataloader.TraceLoadManyFinishFunc[interface{}]) {
+	ctx, finish := a.t.TraceLoadMany(ctx, NewKeysFromStrings(keys))
+	return ctx, func(r *dataloader.ResultMany[interface{}]) {
+		finish(func() ([]interface{}, []error) { return r.Data, r.Error })
+	}
+}
+
+func (a tracerAdapter) TraceBatch(ctx context.Context, keys []string) (context.Context, dataloader.TraceBatchFinishFunc[interface{}]) {
+	ctx, finish := a.t.TraceBatch(ctx, NewKeysFromStrings(keys))
+	return ctx, dataloader.TraceBatchFinishFunc[interface{}](finish)
+}
@@ -0,0 +1,149 @@
+package compat
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// userKey is a key whose raw value is not a string.
+type userKey int
+
+func (k userKey) String() string   { return fmt.Sprint(int(k)) }
+func (k userKey) Raw() interface{} { return int(k) }
+
+// userID is a type derived from string, as the IDs of domain objects.
+type userID string
+
+func TestKeyOf(t *testing.T) {
+	id := userID("7")
+	n := 42
+	var nilID *userID
+	for _, test := range []struct {
+		key Key
+		s   string
+		raw interface{}
+	}{
+		{KeyOf("a"), "a", "a"},
+		{KeyOf(id), "7", id},
+		{KeyOf(&id), "7", id},
+		{KeyOf(&n), "42", 42},
+		{KeyOf(uint8(3)), "3", uint8(3)},
+		{KeyOf(nilID), "<nil>", nil},
+		{KeyOf(1.5), "1.5", 1.5},
+		{KeyOf(userKey(9)), "9", 9},
+	} {
+		if test.key.String() != test.s || test.key.Raw() != test.raw {
+			t.Errorf("expected %q of %#v, got %q of %#v", test.s, test.raw, test.key.String(), test.key.Raw())
+		}
+	}
+
+	type user struct{ ID userID }
+	keys := KeysFromFunc([]user{{"1"}, {"2"}}, func(u user) userID { return u.ID })
+	if want := []string{"1", "2"}; !reflect.DeepEqual(keys.Keys(), want) {
+		t.Errorf("expected %v, got %v", want, keys.Keys())
+	}
+}
+
+func TestLoader(t *testing.T) {
+	t.Run("batches and caches the keys", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var calls [][]interface{}
+		loader := NewBatchedLoader(func(_ context.Context, keys Keys) []*Result {
+			raws := make([]interface{}, len(keys))
+			results := make([]*Result, len(keys))
+			for i, key := range keys {
+				raws[i] = key.Raw()
+				results[i] = &Result{Data: key.String()}
+			}
+			// LoadMany queues its keys concurrently
+			sort.Slice(raws, func(i, j int) bool { return raws[i].(int) < raws[j].(int) })
+			mu.Lock()
+			calls = append(calls, raws)
+			mu.Unlock()
+			return results
+		})
+		ctx := context.Background()
+
+		var iface Interface = loader
+		values, errs := iface.LoadMany(ctx, Keys{userKey(1), userKey(2), userKey(1)})()
+		if errs != nil {
+			t.Fatal(errs)
+		}
+		if want := []interface{}{"1", "2", "1"}; !reflect.DeepEqual(values, want) {
+			t.Errorf("expected %v, got %v", want, values)
+		}
+		if v, err := iface.Load(ctx, userKey(2))(); v != "2" || err != nil {
+			t.Errorf("expected the cached value, got %v, %v", v, err)
+		}
+		iface.Prime(ctx, StringKey("3"), "primed")
+		if v, _ := iface.Load(ctx, userKey(3))(); v != "primed" {
+			t.Errorf("expected the primed value, got %v", v)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if want := [][]interface{}{{1, 2}}; !reflect.DeepEqual(calls, want) {
+			t.Errorf("expected the raw keys to be batched once, got %v", calls)
+		}
+	})
+
+	t.Run("uses the v6 cache and tracer", func(t *testing.T) {
+		t.Parallel()
+		cache := NewCache()
+		var mu sync.Mutex
+		var traced []string
+		loader := NewBatchedLoader(func(_ context.Context, keys Keys) []*Result {
+			results := make([]*Result, len(keys))
+			for i, key := range keys {
+				results[i] = &Result{Data: key.String()}
+			}
+			return results
+		}, WithCache(cache), WithTracer(tracer{mu: &mu, traced: &traced}))
+		ctx := context.Background()
+
+		if _, err := loader.Load(ctx, userKey(1))(); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cache.Get(ctx, StringKey("1")); !ok {
+			t.Error("expected the key to be cached by its String()")
+		}
+		loader.Clear(ctx, userKey(1))
+		if _, ok := cache.Get(ctx, StringKey("1")); ok {
+			t.Error("expected the key to be cleared")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if want := []string{"load 1", "batch [1]"}; !reflect.DeepEqual(traced, want) {
+			t.Errorf("expected %v, got %v", want, traced)
+		}
+	})
+}
+
+// tracer records the traced calls.
+type tracer struct {
+	NoopTracer
+	mu     *sync.Mutex
+	traced *[]string
+}
+
+func (t tracer) record(s string) {
+	t.mu.Lock()
+	*t.traced = append(*t.traced, s)
+	t.mu.Unlock()
+}
+
+func (t tracer) TraceLoad(ctx context.Context, key Key) (context.Context, TraceLoadFinishFunc) {
+	t.record("load " + key.String())
+	return ctx, func(Thunk) {}
+}
+
+func (t tracer) TraceBatch(ctx context.Context, keys Keys) (context.Context, TraceBatchFinishFunc) {
+	t.record(fmt.Sprint("batch ", keys.Keys()))
+	return ctx, func([]*Result) {}
+}
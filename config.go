@@ -0,0 +1,191 @@
+package dataloader
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the tunables of a Loader, so that they can be set from the
+// environment or the command line of each deployment rather than in code.
+// Start from DefaultConfig: every field is applied as is.
+type Config struct {
+	// Name of the loader, see WithName.
+	Name string
+	// Wait is the batch window duration, see WithWait.
+	Wait time.Duration
+	// BatchCapacity is the maximum number of keys in a batch, see
+	// WithBatchCapacity. 0 means unbounded.
+	BatchCapacity int
+	// InputCapacity is the size of the input queue, see WithInputCapacity.
+	InputCapacity int
+	// IdleFlush dispatches a batch once no key was queued for this long, see
+	// WithIdleFlush. 0 disables it.
+	IdleFlush time.Duration
+	// WaitJitter randomizes the batch windows by this fraction, see
+	// WithWaitJitter. 0 disables it.
+	WaitJitter float64
+	// ThunkTimeout resolves the thunks left unresolved for this long with
+	// ErrThunkTimeout, see WithThunkTimeout. 0 disables it.
+	ThunkTimeout time.Duration
+	// NotFoundTTL is how long not found results are cached, see
+	// WithNotFoundTTL. 0 caches them like any other result.
+	NotFoundTTL time.Duration
+	// TTLJitter randomizes the TTLs of the cached keys by this fraction, see
+	// WithTTLJitter. 0 disables it.
+	TTLJitter float64
+	// CacheMaxAge rotates the cache to a new epoch this often, see
+	// WithCacheMaxAge. 0 disables it.
+	CacheMaxAge time.Duration
+	// ClearCacheOnBatch clears the cache after each batch, see
+	// WithClearCacheOnBatch.
+	ClearCacheOnBatch bool
+}
+
+// DefaultConfig returns the configuration of a Loader constructed without
+// options.
+func DefaultConfig() Config {
+	return Config{
+		Wait:          16 * time.Millisecond,
+		InputCapacity: 1000,
+	}
+}
+
+// NewFromConfig constructs a new Loader from the configuration, followed by
+// the given options. The configuration is validated like NewBatchedLoaderE
+// does.
+func NewFromConfig[K comparable, V any](batchFn BatchFunc[K, V], cfg Config, opts ...Option[K, V]) (*Loader[K, V], error) {
+	return NewBatchedLoaderE(batchFn, append(ConfigOptions[K, V](cfg), opts...)...)
+}
+
+// ConfigOptions returns the options applying the configuration.
+func ConfigOptions[K comparable, V any](cfg Config) []Option[K, V] {
+	opts := []Option[K, V]{
+		WithName[K, V](cfg.Name),
+		WithWait[K, V](cfg.Wait),
+		WithBatchCapacity[K, V](cfg.BatchCapacity),
+		WithInputCapacity[K, V](cfg.InputCapacity),
+		WithIdleFlush[K, V](cfg.IdleFlush),
+		WithWaitJitter[K, V](cfg.WaitJitter),
+		WithThunkTimeout[K, V](cfg.ThunkTimeout),
+		WithNotFoundTTL[K, V](cfg.NotFoundTTL),
+		WithTTLJitter[K, V](cfg.TTLJitter),
+		WithCacheMaxAge[K, V](cfg.CacheMaxAge),
+	}
+	if cfg.ClearCacheOnBatch {
+		opts = append(opts, WithClearCacheOnBatch[K, V]())
+	}
+	return opts
+}
+
+// ApplyEnv overrides the configuration with the environment variables set
+// among the following ones, given the USERS prefix:
+//
+//	USERS_NAME, USERS_WAIT, USERS_BATCH_CAPACITY, USERS_INPUT_CAPACITY,
+//	USERS_IDLE_FLUSH, USERS_WAIT_JITTER, USERS_THUNK_TIMEOUT,
+//	USERS_NOT_FOUND_TTL, USERS_TTL_JITTER, USERS_CACHE_MAX_AGE,
+//	USERS_CLEAR_CACHE_ON_BATCH
+//
+// Durations are parsed by time.ParseDuration.
+func (c *Config) ApplyEnv(prefix string) error {
+	for _, v := range c.vars() {
+		name := strings.ToUpper(prefix + "_" + strings.ReplaceAll(v.name, "-", "_"))
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := v.value.Set(value); err != nil {
+			return fmt.Errorf("dataloader: invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RegisterFlags registers a flag for every field of the configuration on the
+// flag set, with the current values as defaults. Given the users prefix, the
+// flags are -users-wait, -users-batch-capacity, and so on.
+func (c *Config) RegisterFlags(fs *flag.FlagSet, prefix string) {
+	for _, v := range c.vars() {
+		fs.Var(v.value, prefix+"-"+v.name, v.usage)
+	}
+}
+
+// configVar is a field of the configuration, as a flag value.
+type configVar struct {
+	name  string
+	usage string
+	value flag.Value
+}
+
+func (c *Config) vars() []configVar {
+	return []configVar{
+		{"name", "name of the loader", (*stringValue)(&c.Name)},
+		{"wait", "batch window duration", (*durationValue)(&c.Wait)},
+		{"batch-capacity", "maximum number of keys in a batch (0 is unbounded)", (*intValue)(&c.BatchCapacity)},
+		{"input-capacity", "size of the input queue", (*intValue)(&c.InputCapacity)},
+		{"idle-flush", "dispatch a batch once idle for this long (0 disables it)", (*durationValue)(&c.IdleFlush)},
+		{"wait-jitter", "fraction by which batch windows are randomized", (*floatValue)(&c.WaitJitter)},
+		{"thunk-timeout", "resolve the thunks unresolved for this long (0 disables it)", (*durationValue)(&c.ThunkTimeout)},
+		{"not-found-ttl", "how long not found results are cached (0 caches them)", (*durationValue)(&c.NotFoundTTL)},
+		{"ttl-jitter", "fraction by which the TTLs of cached keys are randomized", (*floatValue)(&c.TTLJitter)},
+		{"cache-max-age", "rotate the cache this often (0 disables it)", (*durationValue)(&c.CacheMaxAge)},
+		{"clear-cache-on-batch", "clear the cache after each batch", (*boolValue)(&c.ClearCacheOnBatch)},
+	}
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*v = durationValue(d)
+	return nil
+}
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v = intValue(n)
+	return nil
+}
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*v = floatValue(f)
+	return nil
+}
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v = boolValue(b)
+	return nil
+}
+func (v *boolValue) String() string   { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) IsBoolFlag() bool { return true }
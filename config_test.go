@@ -0,0 +1,70 @@
+package dataloader
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("constructs a loader from the configuration", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Name = "users"
+		cfg.BatchCapacity = 2
+
+		loader, err := NewFromConfig(batchIdentity[string], cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loader.Name() != "users" || loader.batchCap != 2 || loader.wait != 16*time.Millisecond {
+			t.Errorf("the configuration was not applied: %#v", cfg)
+		}
+		if value, err := loader.Load(context.Background(), "1")(); err != nil || value != "1" {
+			t.Errorf("expected 1, got %q, %v", value, err)
+		}
+
+		cfg.BatchCapacity = -1
+		if _, err := NewFromConfig(batchIdentity[string], cfg); err == nil {
+			t.Error("expected an invalid configuration")
+		}
+	})
+
+	t.Run("overrides the configuration from the environment", func(t *testing.T) {
+		t.Setenv("USERS_WAIT", "5ms")
+		t.Setenv("USERS_BATCH_CAPACITY", "100")
+		t.Setenv("USERS_CLEAR_CACHE_ON_BATCH", "true")
+
+		cfg := DefaultConfig()
+		if err := cfg.ApplyEnv("users"); err != nil {
+			t.Fatal(err)
+		}
+		expected := DefaultConfig()
+		expected.Wait = 5 * time.Millisecond
+		expected.BatchCapacity = 100
+		expected.ClearCacheOnBatch = true
+		if cfg != expected {
+			t.Errorf("expected %#v, got %#v", expected, cfg)
+		}
+
+		t.Setenv("USERS_WAIT", "soon")
+		if err := cfg.ApplyEnv("users"); err == nil {
+			t.Error("expected an invalid duration to be reported")
+		}
+		if cfg.Wait != 5*time.Millisecond {
+			t.Errorf("expected an invalid duration to keep the configured one, got %v", cfg.Wait)
+		}
+	})
+
+	t.Run("registers flags", func(t *testing.T) {
+		cfg := DefaultConfig()
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		cfg.RegisterFlags(fs, "users")
+		if err := fs.Parse([]string{"-users-wait=1s", "-users-wait-jitter=0.1", "-users-clear-cache-on-batch"}); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Wait != time.Second || cfg.WaitJitter != 0.1 || !cfg.ClearCacheOnBatch || cfg.InputCapacity != 1000 {
+			t.Errorf("the flags were not applied: %#v", cfg)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package dataloader
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type (
+	waitKey      struct{}
+	skipCacheKey struct{}
+	priorityKey  struct{}
+	readKey      struct{}
+)
+
+// ContextWithWait returns a copy of ctx overriding the wait duration of the
+// loads made with it: the batch window they are queued on closes at most d
+// after they are queued, and right away if d is 0. The windows of the other
+// loads are only shortened as a result, never extended. Loaders with a
+// Scheduler only honor a wait of 0.
+func ContextWithWait(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, waitKey{}, d)
+}
+
+// ContextWithSkipCache returns a copy of ctx making the loads made with it
+// skip the cache, like WithCacheBypass does: their keys are neither looked up
+// nor stored in the cache.
+func ContextWithSkipCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+// ContextWithPriority returns a copy of ctx setting the priority of the loads
+// made with it. The keys of a batch are passed to the batch function by
+// decreasing priority, so that the keys of lower priorities are the ones
+// spilled over to the next batch by WithTenantCap. Default priority is 0.
+func ContextWithPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// ContextWithReadPolicy returns a copy of ctx overriding the read policy of
+// the TieredCaches the loads made with it read from (i.e. ReadLocalOnly on a
+// latency sensitive path).
+func ContextWithReadPolicy(ctx context.Context, policy ReadPolicy) context.Context {
+	return context.WithValue(ctx, readKey{}, policy)
+}
+
+// waitOf returns the wait duration override of ctx, if any.
+func waitOf(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Value(waitKey{}).(time.Duration)
+	return d, ok
+}
+
+// skipsCache reports whether the loads made with ctx skip the cache.
+func skipsCache(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipCacheKey{}).(bool)
+	return skip
+}
+
+// priorityOf returns the priority of the loads made with ctx.
+func priorityOf(ctx context.Context) int {
+	priority, _ := ctx.Value(priorityKey{}).(int)
+	return priority
+}
+
+// readPolicyOf returns the read policy override of ctx, if any.
+func readPolicyOf(ctx context.Context) (ReadPolicy, bool) {
+	policy, ok := ctx.Value(readKey{}).(ReadPolicy)
+	return policy, ok
+}
+
+// prioritize sorts the requests by decreasing priority, keeping the order of
+// the requests of the same priority.
+func prioritize[K comparable, V any](reqs []*batchRequest[K, V]) {
+	prioritized := false
+	for _, req := range reqs {
+		if req.priority != 0 {
+			prioritized = true
+			break
+		}
+	}
+	if !prioritized {
+		return
+	}
+	sort.SliceStable(reqs, func(i, j int) bool {
+		return reqs[i].priority > reqs[j].priority
+	})
+}
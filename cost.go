@@ -0,0 +1,44 @@
+package dataloader
+
+// WithBatchCostLimit dispatches the batches once the cost of their keys
+// reaches the limit (i.e. when the backend charges by the partition scanned
+// rather than by the key). A key which would bring a batch over the limit is
+// queued on the next batch instead, unless the batch is empty: a key costing
+// more than the limit gets a batch of its own. Duplicate keys of a batch are
+// only charged once. It coexists with WithBatchCapacity: a batch is dispatched
+// by whichever limit it reaches first.
+//
+// The cost function is called with the lock of the batches held: it must be
+// fast, and must not use the loader.
+func WithBatchCostLimit[K comparable, V any](limit int, cost func(K) int) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.costLimit = limit
+		l.costOf = cost
+	}
+}
+
+// costOfRequest returns the cost of the key of the request for the batch, which
+// is 0 if the batch was already charged for it.
+// It must be called with the batchLock held.
+func (l *Loader[K, V]) costOfRequest(b *batcher[K, V], req *batchRequest[K, V]) int {
+	if l.costLimit <= 0 || l.costOf == nil {
+		return 0
+	}
+	if _, ok := b.charged[req.key]; ok {
+		return 0
+	}
+	return l.costOf(req.key)
+}
+
+// charge adds the cost of key to the batch.
+// It must be called with the batchLock held.
+func (b *batcher[K, V]) charge(key K, cost int) {
+	if b.loader.costLimit <= 0 || b.loader.costOf == nil {
+		return
+	}
+	if b.charged == nil {
+		b.charged = make(map[K]struct{})
+	}
+	b.charged[key] = struct{}{}
+	b.cost += cost
+}
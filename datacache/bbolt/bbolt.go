@@ -0,0 +1,310 @@
+// Package bbolt provides a DataCache persisting the resolved values of keys
+// in a local bbolt database, so that they survive process restarts without an
+// external service (i.e. for CLI tools and edge deployments).
+package bbolt
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graph-gophers/dataloader/v7/codec"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// the entries of results (see codec.EncodeEntry) are preceded by their
+// expiration time in nanoseconds since the epoch (0 if the entry doesn't
+// expire)
+const headerSize = 8
+
+// Cache implements the dataloader.DataCacheMany interface with bbolt. The
+// results are stored as entries of codec.EncodeEntry, in a single bucket.
+type Cache[K comparable, V any] struct {
+	db      *bolt.DB
+	owned   bool
+	bucket  []byte
+	codec   codec.ValueCodec[V]
+	keyOf   func(K) string
+	ttlOf   func(K, *dataloader.Result[V]) time.Duration
+	onError func(error)
+	now     func() time.Time
+}
+
+var _ dataloader.DataCacheMany[string, any] = &Cache[string, any]{}
+
+// Option allows for configuration of Cache fields.
+type Option[K comparable, V any] func(*Cache[K, V])
+
+// WithBucket sets the name of the bucket holding the entries. Default is
+// "dataloader".
+func WithBucket[K comparable, V any](name string) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.bucket = []byte(name)
+	}
+}
+
+// WithKeyFunc sets the function converting keys to the keys of the bucket.
+// Default is fmt.Sprint.
+func WithKeyFunc[K comparable, V any](keyOf func(K) string) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.keyOf = keyOf
+	}
+}
+
+// WithTTL sets the time to live of every entry, see WithTTLFunc.
+func WithTTL[K comparable, V any](d time.Duration) Option[K, V] {
+	return WithTTLFunc(func(K, *dataloader.Result[V]) time.Duration {
+		return d
+	})
+}
+
+// WithTTLFunc sets the function returning the time to live of each entry
+// (i.e. shorter for not found results), or 0 for an entry which doesn't expire
+// (the default). Reads ignore the expired entries, which stay in the database
+// until DeleteExpired is called.
+func WithTTLFunc[K comparable, V any](ttlOf func(K, *dataloader.Result[V]) time.Duration) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.ttlOf = ttlOf
+	}
+}
+
+// WithErrorHandler sets the function called with the errors of the database
+// transactions and of the codec, which are ignored by default: the failed
+// reads are misses, and the failed writes are dropped.
+func WithErrorHandler[K comparable, V any](onError func(error)) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.onError = onError
+	}
+}
+
+// Open opens (or creates) the database at path and constructs a new Cache
+// with given options. Closing the cache closes the database.
+func Open[K comparable, V any](path string, codec codec.ValueCodec[V], opts ...Option[K, V]) (*Cache[K, V], error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	c, err := New(db, codec, opts...)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	c.owned = true
+	return c, nil
+}
+
+// New constructs a new Cache in a bucket of an open database with given
+// options. The bucket is created if it doesn't exist.
+func New[K comparable, V any](db *bolt.DB, codec codec.ValueCodec[V], opts ...Option[K, V]) (*Cache[K, V], error) {
+	c := &Cache[K, V]{
+		db:     db,
+		bucket: []byte("dataloader"),
+		codec:  codec,
+		keyOf: func(key K) string {
+			return fmt.Sprint(key)
+		},
+		ttlOf: func(K, *dataloader.Result[V]) time.Duration {
+			return 0
+		},
+		onError: func(error) {},
+		now:     time.Now,
+	}
+	for _, apply := range opts {
+		apply(c)
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(c.bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get gets the result of key.
+func (c *Cache[K, V]) Get(ctx context.Context, key K) (*dataloader.Result[V], bool) {
+	result := c.GetMany(ctx, []K{key})[0]
+	return result, result != nil
+}
+
+// GetMany gets the results of keys in a single transaction. The result of a
+// key which isn't cached is nil.
+func (c *Cache[K, V]) GetMany(_ context.Context, keys []K) []*dataloader.Result[V] {
+	results := make([]*dataloader.Result[V], len(keys))
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(c.bucket)
+		now := c.now()
+		for i, key := range keys {
+			if entry := b.Get([]byte(c.keyOf(key))); entry != nil {
+				results[i] = c.decode(entry, now)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.onError(err)
+	}
+	return results
+}
+
+// Set sets the result of key.
+func (c *Cache[K, V]) Set(ctx context.Context, key K, result *dataloader.Result[V]) {
+	c.SetMany(ctx, []K{key}, []*dataloader.Result[V]{result})
+}
+
+// SetMany sets the results of keys in a single transaction. The result at
+// each index is the result of the key at the same index.
+func (c *Cache[K, V]) SetMany(_ context.Context, keys []K, results []*dataloader.Result[V]) {
+	now := c.now()
+	entries := make(map[string][]byte, len(keys))
+	for i, key := range keys {
+		if i >= len(results) {
+			break
+		}
+		if entry, ok := c.encode(key, results[i], now); ok {
+			entries[c.keyOf(key)] = entry
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(c.bucket)
+		for k, entry := range entries {
+			if err := b.Put([]byte(k), entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.onError(err)
+	}
+}
+
+// Delete deletes the result of key.
+func (c *Cache[K, V]) Delete(ctx context.Context, key K) bool {
+	return c.DeleteMany(ctx, []K{key}) > 0
+}
+
+// DeleteMany deletes the results of keys in a single transaction, and returns
+// the number of deleted results.
+func (c *Cache[K, V]) DeleteMany(_ context.Context, keys []K) int {
+	var deleted int
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		deleted = 0
+		b := tx.Bucket(c.bucket)
+		for _, key := range keys {
+			k := []byte(c.keyOf(key))
+			if b.Get(k) == nil {
+				continue
+			}
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		c.onError(err)
+		return 0
+	}
+	return deleted
+}
+
+// Clear deletes every entry of the bucket.
+func (c *Cache[K, V]) Clear() {
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(c.bucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(c.bucket)
+		return err
+	})
+	if err != nil {
+		c.onError(err)
+	}
+}
+
+// DeleteExpired deletes the expired entries, and returns their number.
+// Expired entries are ignored by reads, but they are only deleted by this
+// method, by Delete and by Clear.
+func (c *Cache[K, V]) DeleteExpired() (int, error) {
+	var deleted int
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		deleted = 0
+		now := c.now()
+		cur := tx.Bucket(c.bucket).Cursor()
+		for k, entry := cur.First(); k != nil; {
+			if !expired(entry, now) {
+				k, entry = cur.Next()
+				continue
+			}
+			// deleting moves the cursor to the next entry
+			if err := cur.Delete(); err != nil {
+				return err
+			}
+			deleted++
+			k, entry = cur.Seek(k)
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// Close closes the database if it was opened by Open.
+func (c *Cache[K, V]) Close() error {
+	if !c.owned {
+		return nil
+	}
+	return c.db.Close()
+}
+
+// encode returns the entry of a result, or false if the result isn't cached.
+func (c *Cache[K, V]) encode(key K, result *dataloader.Result[V], now time.Time) ([]byte, bool) {
+	entry, ok, err := codec.EncodeEntry(make([]byte, headerSize, headerSize+64), c.codec, result)
+	if err != nil {
+		c.onError(err)
+	}
+	if !ok {
+		return nil, false
+	}
+	if ttl := c.ttlOf(key, result); ttl > 0 {
+		binary.BigEndian.PutUint64(entry, uint64(now.Add(ttl).UnixNano()))
+	}
+	return entry, true
+}
+
+// decode returns the result of an entry, or nil if it expired or can't be
+// decoded. The entry is only valid within the transaction.
+func (c *Cache[K, V]) decode(entry []byte, now time.Time) *dataloader.Result[V] {
+	switch {
+	case len(entry) < headerSize:
+		c.onError(fmt.Errorf("bbolt: invalid entry %q", entry))
+		return nil
+	case expired(entry, now):
+		return nil
+	}
+	result, err := codec.DecodeEntry(c.codec, entry[headerSize:])
+	if err != nil {
+		c.onError(err)
+		return nil
+	}
+	return result
+}
+
+// expired reports whether the entry is expired.
+func expired(entry []byte, now time.Time) bool {
+	if len(entry) < headerSize {
+		return false
+	}
+	expires := int64(binary.BigEndian.Uint64(entry[:headerSize]))
+	return expires != 0 && now.UnixNano() >= expires
+}
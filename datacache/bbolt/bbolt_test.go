@@ -0,0 +1,115 @@
+package bbolt
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graph-gophers/dataloader/v7/codec"
+)
+
+type user struct {
+	ID   string
+	Name string
+}
+
+func open(t *testing.T, path string, opts ...Option[string, *user]) *Cache[string, *user] {
+	t.Helper()
+	c, err := Open(path, codec.JSON[*user](), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("get, set and delete", func(t *testing.T) {
+		c := open(t, filepath.Join(t.TempDir(), "cache.db"))
+		defer c.Close()
+		if _, ok := c.Get(ctx, "1"); ok {
+			t.Fatal("expected a miss")
+		}
+
+		c.Set(ctx, "1", &dataloader.Result[*user]{Data: &user{ID: "1", Name: "alice"}})
+		result, ok := c.Get(ctx, "1")
+		if !ok || !reflect.DeepEqual(result.Data, &user{ID: "1", Name: "alice"}) {
+			t.Fatalf("expected alice, got %#v", result)
+		}
+
+		if !c.Delete(ctx, "1") || c.Delete(ctx, "1") {
+			t.Error("expected the key to be deleted once")
+		}
+	})
+
+	t.Run("persists across restarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.db")
+		c := open(t, path)
+		c.Set(ctx, "1", &dataloader.Result[*user]{Data: &user{ID: "1"}})
+		c.Set(ctx, "2", dataloader.NotFound[*user]())
+		c.Set(ctx, "3", &dataloader.Result[*user]{Error: errors.New("boom")})
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		c = open(t, path)
+		defer c.Close()
+		results := c.GetMany(ctx, []string{"1", "2", "3"})
+		if results[0] == nil || results[0].Data.ID != "1" {
+			t.Errorf("expected the persisted user, got %#v", results[0])
+		}
+		if !results[1].NotFound() {
+			t.Errorf("expected a persisted not found, got %#v", results[1])
+		}
+		if results[2] != nil {
+			t.Errorf("expected the error not to be cached, got %#v", results[2])
+		}
+	})
+
+	t.Run("expires entries", func(t *testing.T) {
+		c := open(t, filepath.Join(t.TempDir(), "cache.db"), WithTTL[string, *user](time.Minute))
+		defer c.Close()
+		now := time.Unix(1000, 0)
+		c.now = func() time.Time { return now }
+
+		c.SetMany(ctx, []string{"1", "2", "3"}, []*dataloader.Result[*user]{
+			{Data: &user{ID: "1"}},
+			{Data: &user{ID: "2"}},
+			{Data: &user{ID: "3"}},
+		})
+		if _, ok := c.Get(ctx, "1"); !ok {
+			t.Error("expected a hit before the expiration")
+		}
+
+		now = now.Add(time.Minute)
+		if _, ok := c.Get(ctx, "1"); ok {
+			t.Error("expected the expired entry to be ignored")
+		}
+		if n, err := c.DeleteExpired(); n != 3 || err != nil {
+			t.Errorf("expected 3 expired entries, got %d, %v", n, err)
+		}
+	})
+
+	t.Run("delete many and clear", func(t *testing.T) {
+		c := open(t, filepath.Join(t.TempDir(), "cache.db"))
+		defer c.Close()
+		c.SetMany(ctx, []string{"1", "2", "3"}, []*dataloader.Result[*user]{
+			{Data: &user{ID: "1"}},
+			{Data: &user{ID: "2"}},
+			{Data: &user{ID: "3"}},
+		})
+
+		if n := c.DeleteMany(ctx, []string{"1", "4"}); n != 1 {
+			t.Errorf("expected 1 deleted entry, got %d", n)
+		}
+		c.Clear()
+		if results := c.GetMany(ctx, []string{"2", "3"}); results[0] != nil || results[1] != nil {
+			t.Errorf("expected the cache to be cleared, got %#v", results)
+		}
+	})
+}
@@ -0,0 +1,12 @@
+module github.com/graph-gophers/dataloader/v7/datacache/bbolt
+
+go 1.23
+
+require (
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+	go.etcd.io/bbolt v1.3.11
+)
+
+require golang.org/x/sys v0.26.0 // indirect
+
+replace github.com/graph-gophers/dataloader/v7 => ../..
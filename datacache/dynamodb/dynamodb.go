@@ -0,0 +1,396 @@
+// Package dynamodb provides a DataCache storing the resolved values of keys in
+// a DynamoDB table, for use with dataloader.WithValueCache.
+//
+// The table has a string partition key (the "key" attribute by default).
+// Entries expire with a number attribute holding their expiration time in
+// seconds since the epoch ("ttl" by default): enable the time to live of the
+// table on it so that DynamoDB deletes the expired items. Since DynamoDB
+// deletes them lazily, expired items are also ignored by reads.
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graph-gophers/dataloader/v7/codec"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// the limits of DynamoDB on the number of items in a batch request
+const (
+	maxBatchGet   = 100
+	maxBatchWrite = 25
+)
+
+// maxAttempts is the number of attempts of a batch request with unprocessed
+// items.
+const maxAttempts = 3
+
+// ErrUnprocessed is reported to the error handler with the number of items of
+// a batch request still unprocessed once it has been retried (i.e. because the
+// table is throttled).
+var ErrUnprocessed = errors.New("dynamodb: unprocessed items")
+
+// The Client interface is the subset of the DynamoDB client used by Cache.
+// It is implemented by *dynamodb.Client.
+type Client interface {
+	GetItem(context.Context, *awsdynamodb.GetItemInput, ...func(*awsdynamodb.Options)) (*awsdynamodb.GetItemOutput, error)
+	PutItem(context.Context, *awsdynamodb.PutItemInput, ...func(*awsdynamodb.Options)) (*awsdynamodb.PutItemOutput, error)
+	DeleteItem(context.Context, *awsdynamodb.DeleteItemInput, ...func(*awsdynamodb.Options)) (*awsdynamodb.DeleteItemOutput, error)
+	BatchGetItem(context.Context, *awsdynamodb.BatchGetItemInput, ...func(*awsdynamodb.Options)) (*awsdynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(context.Context, *awsdynamodb.BatchWriteItemInput, ...func(*awsdynamodb.Options)) (*awsdynamodb.BatchWriteItemOutput, error)
+}
+
+var _ Client = &awsdynamodb.Client{}
+
+// Cache implements the dataloader.DataCacheMany interface with DynamoDB. The
+// results are stored as entries of codec.EncodeEntry, in a binary attribute.
+type Cache[K comparable, V any] struct {
+	client    Client
+	table     string
+	codec     codec.ValueCodec[V]
+	keyAttr   string
+	valueAttr string
+	ttlAttr   string
+	keyOf     func(K) string
+	ttlOf     func(K, *dataloader.Result[V]) time.Duration
+	onError   func(error)
+	backoff   time.Duration
+	now       func() time.Time
+}
+
+var _ dataloader.DataCacheMany[string, any] = &Cache[string, any]{}
+
+// Option allows for configuration of Cache fields.
+type Option[K comparable, V any] func(*Cache[K, V])
+
+// WithAttributes sets the names of the key, value and time to live attributes.
+// Default is "key", "value" and "ttl".
+func WithAttributes[K comparable, V any](key, value, ttl string) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.keyAttr, c.valueAttr, c.ttlAttr = key, value, ttl
+	}
+}
+
+// WithKeyFunc sets the function converting keys to the values of the
+// partition key attribute. Default is fmt.Sprint.
+func WithKeyFunc[K comparable, V any](keyOf func(K) string) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.keyOf = keyOf
+	}
+}
+
+// WithTTL sets the time to live of every item, see WithTTLFunc.
+func WithTTL[K comparable, V any](d time.Duration) Option[K, V] {
+	return WithTTLFunc(func(K, *dataloader.Result[V]) time.Duration {
+		return d
+	})
+}
+
+// WithTTLFunc sets the function returning the time to live of each item
+// (i.e. shorter for not found results), stored in the ttl attribute. Items
+// with a time to live of 0 (the default) have no ttl attribute.
+func WithTTLFunc[K comparable, V any](ttlOf func(K, *dataloader.Result[V]) time.Duration) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.ttlOf = ttlOf
+	}
+}
+
+// WithErrorHandler sets the function called with the errors of the DynamoDB
+// requests and of the codec (see also ErrUnprocessed). Nothing is retried once
+// reported: the keys which failed to be read are misses, and the writes
+// are dropped. The errors are ignored by default.
+func WithErrorHandler[K comparable, V any](onError func(error)) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.onError = onError
+	}
+}
+
+// WithRetryBackoff sets the base delay before retrying the unprocessed items
+// of a batch request. It doubles on every attempt, and is jittered so that the
+// throttled clients don't retry in lockstep. Default is 50ms.
+func WithRetryBackoff[K comparable, V any](d time.Duration) Option[K, V] {
+	return func(c *Cache[K, V]) {
+		c.backoff = d
+	}
+}
+
+// New constructs a new Cache of the given table with given options.
+func New[K comparable, V any](client Client, table string, codec codec.ValueCodec[V], opts ...Option[K, V]) *Cache[K, V] {
+	c := &Cache[K, V]{
+		client:    client,
+		table:     table,
+		codec:     codec,
+		keyAttr:   "key",
+		valueAttr: "value",
+		ttlAttr:   "ttl",
+		keyOf: func(key K) string {
+			return fmt.Sprint(key)
+		},
+		ttlOf: func(K, *dataloader.Result[V]) time.Duration {
+			return 0
+		},
+		onError: func(error) {},
+		backoff: 50 * time.Millisecond,
+		now:     time.Now,
+	}
+	for _, apply := range opts {
+		apply(c)
+	}
+	return c
+}
+
+// Get gets the result of key.
+func (c *Cache[K, V]) Get(ctx context.Context, key K) (*dataloader.Result[V], bool) {
+	out, err := c.client.GetItem(ctx, &awsdynamodb.GetItemInput{
+		TableName: aws.String(c.table),
+		Key:       c.key(key),
+	})
+	if err != nil {
+		c.onError(err)
+		return nil, false
+	}
+	if out.Item == nil {
+		return nil, false
+	}
+	return c.decode(out.Item)
+}
+
+// GetMany gets the results of keys with BatchGetItem requests. The result of a
+// key which isn't cached is nil.
+func (c *Cache[K, V]) GetMany(ctx context.Context, keys []K) []*dataloader.Result[V] {
+	results := make([]*dataloader.Result[V], len(keys))
+
+	// BatchGetItem rejects duplicate keys
+	indexes := make(map[string][]int, len(keys))
+	var unique []map[string]types.AttributeValue
+	for i, key := range keys {
+		k := c.keyOf(key)
+		if _, ok := indexes[k]; !ok {
+			unique = append(unique, c.key(key))
+		}
+		indexes[k] = append(indexes[k], i)
+	}
+
+	for start := 0; start < len(unique); start += maxBatchGet {
+		pending := unique[start:min(start+maxBatchGet, len(unique))]
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if !c.retry(ctx, attempt, len(pending)) {
+				break
+			}
+			out, err := c.client.BatchGetItem(ctx, &awsdynamodb.BatchGetItemInput{
+				RequestItems: map[string]types.KeysAndAttributes{
+					c.table: {Keys: pending},
+				},
+			})
+			if err != nil {
+				c.onError(err)
+				break
+			}
+			for _, item := range out.Responses[c.table] {
+				k, ok := item[c.keyAttr].(*types.AttributeValueMemberS)
+				if !ok {
+					continue
+				}
+				result, ok := c.decode(item)
+				if !ok {
+					continue
+				}
+				for _, i := range indexes[k.Value] {
+					results[i] = result
+				}
+			}
+			pending = out.UnprocessedKeys[c.table].Keys
+		}
+	}
+	return results
+}
+
+// Set sets the result of key.
+func (c *Cache[K, V]) Set(ctx context.Context, key K, result *dataloader.Result[V]) {
+	item, ok := c.encode(key, result)
+	if !ok {
+		return
+	}
+	_, err := c.client.PutItem(ctx, &awsdynamodb.PutItemInput{
+		TableName: aws.String(c.table),
+		Item:      item,
+	})
+	if err != nil {
+		c.onError(err)
+	}
+}
+
+// SetMany sets the results of keys with BatchWriteItem requests. The result
+// at each index is the result of the key at the same index.
+func (c *Cache[K, V]) SetMany(ctx context.Context, keys []K, results []*dataloader.Result[V]) {
+	// BatchWriteItem rejects duplicate keys: the last result of a key wins
+	writes := make(map[string]types.WriteRequest, len(keys))
+	var order []string
+	for i, key := range keys {
+		if i >= len(results) {
+			break
+		}
+		item, ok := c.encode(key, results[i])
+		if !ok {
+			continue
+		}
+		k := c.keyOf(key)
+		if _, ok := writes[k]; !ok {
+			order = append(order, k)
+		}
+		writes[k] = types.WriteRequest{PutRequest: &types.PutRequest{Item: item}}
+	}
+
+	requests := make([]types.WriteRequest, len(order))
+	for i, k := range order {
+		requests[i] = writes[k]
+	}
+	c.write(ctx, requests)
+}
+
+// Delete deletes the result of key.
+func (c *Cache[K, V]) Delete(ctx context.Context, key K) bool {
+	out, err := c.client.DeleteItem(ctx, &awsdynamodb.DeleteItemInput{
+		TableName:    aws.String(c.table),
+		Key:          c.key(key),
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		c.onError(err)
+		return false
+	}
+	return len(out.Attributes) > 0
+}
+
+// DeleteMany deletes the results of keys with BatchWriteItem requests.
+// BatchWriteItem doesn't tell which items existed, so it returns the number of
+// processed deletes.
+func (c *Cache[K, V]) DeleteMany(ctx context.Context, keys []K) int {
+	seen := make(map[string]bool, len(keys))
+	var requests []types.WriteRequest
+	for _, key := range keys {
+		k := c.keyOf(key)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: c.key(key)}})
+	}
+	return c.write(ctx, requests)
+}
+
+// Clear is a noop: clearing a table requires scanning it, which is too
+// costly for a cache. Use the time to live of the entries instead.
+func (c *Cache[K, V]) Clear() {}
+
+// write performs the write requests with BatchWriteItem requests, retrying
+// the unprocessed ones. It returns the number of processed requests.
+func (c *Cache[K, V]) write(ctx context.Context, requests []types.WriteRequest) int {
+	var processed int
+	for start := 0; start < len(requests); start += maxBatchWrite {
+		pending := requests[start:min(start+maxBatchWrite, len(requests))]
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if !c.retry(ctx, attempt, len(pending)) {
+				break
+			}
+			out, err := c.client.BatchWriteItem(ctx, &awsdynamodb.BatchWriteItemInput{
+				RequestItems: map[string][]types.WriteRequest{c.table: pending},
+			})
+			if err != nil {
+				c.onError(err)
+				break
+			}
+			unprocessed := out.UnprocessedItems[c.table]
+			processed += len(pending) - len(unprocessed)
+			pending = unprocessed
+		}
+	}
+	return processed
+}
+
+// retry waits before the given attempt of a batch request with n pending
+// items, for a jittered exponential backoff. It returns false once the
+// attempts are exhausted or ctx is done, reporting the items left unprocessed.
+func (c *Cache[K, V]) retry(ctx context.Context, attempt, n int) bool {
+	if attempt == 0 {
+		return true
+	}
+	if attempt >= maxAttempts {
+		c.onError(fmt.Errorf("%w: %d left after %d attempts", ErrUnprocessed, n, attempt))
+		return false
+	}
+	var wait time.Duration
+	if d := c.backoff << (attempt - 1); d > 0 {
+		wait = d/2 + rand.N(d/2+1)
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		c.onError(fmt.Errorf("%w: %d left: %w", ErrUnprocessed, n, ctx.Err()))
+		return false
+	}
+}
+
+func (c *Cache[K, V]) key(key K) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		c.keyAttr: &types.AttributeValueMemberS{Value: c.keyOf(key)},
+	}
+}
+
+// encode returns the item of a result, or false if the result isn't cached.
+func (c *Cache[K, V]) encode(key K, result *dataloader.Result[V]) (map[string]types.AttributeValue, bool) {
+	value, ok, err := codec.EncodeEntry(nil, c.codec, result)
+	if err != nil {
+		c.onError(err)
+	}
+	if !ok {
+		return nil, false
+	}
+
+	item := c.key(key)
+	item[c.valueAttr] = &types.AttributeValueMemberB{Value: value}
+	if ttl := c.ttlOf(key, result); ttl > 0 {
+		expires := c.now().Add(ttl).Unix()
+		item[c.ttlAttr] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expires, 10)}
+	}
+	return item, true
+}
+
+// decode returns the result of an item, or false if it expired or can't be
+// decoded.
+func (c *Cache[K, V]) decode(item map[string]types.AttributeValue) (*dataloader.Result[V], bool) {
+	if ttl, ok := item[c.ttlAttr].(*types.AttributeValueMemberN); ok {
+		expires, err := strconv.ParseInt(ttl.Value, 10, 64)
+		if err != nil {
+			c.onError(fmt.Errorf("dynamodb: invalid %s attribute %q", c.ttlAttr, ttl.Value))
+			return nil, false
+		}
+		if c.now().Unix() >= expires {
+			return nil, false
+		}
+	}
+
+	value, ok := item[c.valueAttr].(*types.AttributeValueMemberB)
+	if !ok {
+		c.onError(fmt.Errorf("dynamodb: missing %s attribute", c.valueAttr))
+		return nil, false
+	}
+	result, err := codec.DecodeEntry(c.codec, value.Value)
+	if err != nil {
+		c.onError(err)
+		return nil, false
+	}
+	return result, true
+}
@@ -0,0 +1,273 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graph-gophers/dataloader/v7/codec"
+
+	awsdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fakeClient is a single table in memory. It leaves the last key of every
+// batch request with more than one key unprocessed once, and every key of the
+// batch requests unprocessed once throttled.
+type fakeClient struct {
+	mu        sync.Mutex
+	items     map[string]map[string]types.AttributeValue
+	deferred  map[string]bool
+	throttled bool
+	batches   int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		items:    make(map[string]map[string]types.AttributeValue),
+		deferred: make(map[string]bool),
+	}
+}
+
+func keyOf(key map[string]types.AttributeValue) string {
+	return key["key"].(*types.AttributeValueMemberS).Value
+}
+
+func (f *fakeClient) deferOnce(k string, n int) bool {
+	if n > 1 && !f.deferred[k] {
+		f.deferred[k] = true
+		return true
+	}
+	return false
+}
+
+func (f *fakeClient) GetItem(_ context.Context, in *awsdynamodb.GetItemInput, _ ...func(*awsdynamodb.Options)) (*awsdynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &awsdynamodb.GetItemOutput{Item: f.items[keyOf(in.Key)]}, nil
+}
+
+func (f *fakeClient) PutItem(_ context.Context, in *awsdynamodb.PutItemInput, _ ...func(*awsdynamodb.Options)) (*awsdynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items[keyOf(in.Item)] = in.Item
+	return &awsdynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeClient) DeleteItem(_ context.Context, in *awsdynamodb.DeleteItemInput, _ ...func(*awsdynamodb.Options)) (*awsdynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	old := f.items[keyOf(in.Key)]
+	delete(f.items, keyOf(in.Key))
+	return &awsdynamodb.DeleteItemOutput{Attributes: old}, nil
+}
+
+func (f *fakeClient) BatchGetItem(_ context.Context, in *awsdynamodb.BatchGetItemInput, _ ...func(*awsdynamodb.Options)) (*awsdynamodb.BatchGetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches++
+	out := &awsdynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]types.AttributeValue{},
+		UnprocessedKeys: map[string]types.KeysAndAttributes{},
+	}
+	for table, keys := range in.RequestItems {
+		seen := make(map[string]bool)
+		for i, key := range keys.Keys {
+			k := keyOf(key)
+			if seen[k] {
+				return nil, errors.New("duplicate key")
+			}
+			seen[k] = true
+			if f.throttled || i == len(keys.Keys)-1 && f.deferOnce(k, len(keys.Keys)) {
+				out.UnprocessedKeys[table] = types.KeysAndAttributes{Keys: keys.Keys[i:]}
+				continue
+			}
+			if item, ok := f.items[k]; ok {
+				out.Responses[table] = append(out.Responses[table], item)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeClient) BatchWriteItem(_ context.Context, in *awsdynamodb.BatchWriteItemInput, _ ...func(*awsdynamodb.Options)) (*awsdynamodb.BatchWriteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches++
+	out := &awsdynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{}}
+	for table, requests := range in.RequestItems {
+		if len(requests) > maxBatchWrite {
+			return nil, errors.New("too many requests")
+		}
+		for i, request := range requests {
+			var k string
+			if request.PutRequest != nil {
+				k = keyOf(request.PutRequest.Item)
+			} else {
+				k = keyOf(request.DeleteRequest.Key)
+			}
+			if f.throttled || i == len(requests)-1 && f.deferOnce(k, len(requests)) {
+				out.UnprocessedItems[table] = requests[i:]
+				continue
+			}
+			if request.PutRequest != nil {
+				f.items[k] = request.PutRequest.Item
+			} else {
+				delete(f.items, k)
+			}
+		}
+	}
+	return out, nil
+}
+
+type user struct {
+	ID   string
+	Name string
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("get, set and delete", func(t *testing.T) {
+		c := New[string](newFakeClient(), "cache", codec.JSON[*user]())
+		if _, ok := c.Get(ctx, "1"); ok {
+			t.Fatal("expected a miss")
+		}
+
+		c.Set(ctx, "1", &dataloader.Result[*user]{Data: &user{ID: "1", Name: "alice"}})
+		result, ok := c.Get(ctx, "1")
+		if !ok || !reflect.DeepEqual(result.Data, &user{ID: "1", Name: "alice"}) {
+			t.Fatalf("expected alice, got %#v", result)
+		}
+
+		if !c.Delete(ctx, "1") || c.Delete(ctx, "1") {
+			t.Error("expected the key to be deleted once")
+		}
+	})
+
+	t.Run("caches not found but not errors", func(t *testing.T) {
+		c := New[string](newFakeClient(), "cache", codec.JSON[*user]())
+		c.Set(ctx, "1", dataloader.NotFound[*user]())
+		c.Set(ctx, "2", &dataloader.Result[*user]{Error: errors.New("boom")})
+
+		if result, ok := c.Get(ctx, "1"); !ok || !result.NotFound() {
+			t.Errorf("expected a cached not found, got %#v", result)
+		}
+		if _, ok := c.Get(ctx, "2"); ok {
+			t.Error("expected the error not to be cached")
+		}
+	})
+
+	t.Run("ignores expired items", func(t *testing.T) {
+		client := newFakeClient()
+		c := New(client, "cache", codec.JSON[*user](), WithTTL[string, *user](time.Minute))
+		now := time.Unix(1000, 0)
+		c.now = func() time.Time { return now }
+
+		c.Set(ctx, "1", &dataloader.Result[*user]{Data: &user{ID: "1"}})
+		if ttl := client.items["1"]["ttl"].(*types.AttributeValueMemberN).Value; ttl != "1060" {
+			t.Errorf("expected the item to expire at 1060, got %s", ttl)
+		}
+		if _, ok := c.Get(ctx, "1"); !ok {
+			t.Error("expected a hit before the expiration")
+		}
+		now = now.Add(time.Minute)
+		if _, ok := c.Get(ctx, "1"); ok {
+			t.Error("expected the expired item to be ignored")
+		}
+	})
+
+	t.Run("get, set and delete many", func(t *testing.T) {
+		client := newFakeClient()
+		c := New[string](client, "cache", codec.JSON[*user]())
+
+		keys := make([]string, 30)
+		results := make([]*dataloader.Result[*user], 30)
+		for i := range keys {
+			keys[i] = string(rune('a' + i))
+			results[i] = &dataloader.Result[*user]{Data: &user{ID: keys[i]}}
+		}
+		c.SetMany(ctx, keys, results)
+		if len(client.items) != 30 {
+			t.Fatalf("expected 30 items, got %d", len(client.items))
+		}
+
+		got := c.GetMany(ctx, []string{"a", "unknown", "b", "a"})
+		if got[0].Data.ID != "a" || got[1] != nil || got[2].Data.ID != "b" || got[3].Data.ID != "a" {
+			t.Errorf("unexpected results %#v", got)
+		}
+
+		if n := c.DeleteMany(ctx, keys); n != 30 || len(client.items) != 0 {
+			t.Errorf("expected 30 deletes, got %d with %d items left", n, len(client.items))
+		}
+	})
+
+	t.Run("reports the items left unprocessed", func(t *testing.T) {
+		client := newFakeClient()
+		client.throttled = true
+		var errs []error
+		c := New(client, "cache", codec.JSON[*user](), WithRetryBackoff[string, *user](time.Millisecond), WithErrorHandler[string, *user](func(err error) {
+			errs = append(errs, err)
+		}))
+
+		c.SetMany(ctx, []string{"a", "b"}, []*dataloader.Result[*user]{{Data: &user{ID: "a"}}, {Data: &user{ID: "b"}}})
+		if got := c.GetMany(ctx, []string{"a", "b"}); got[0] != nil || got[1] != nil {
+			t.Errorf("expected no results, got %#v", got)
+		}
+		if client.batches != 2*maxAttempts {
+			t.Errorf("expected %d attempts, got %d", 2*maxAttempts, client.batches)
+		}
+		if len(errs) != 2 || !errors.Is(errs[0], ErrUnprocessed) || !errors.Is(errs[1], ErrUnprocessed) {
+			t.Errorf("expected the unprocessed items to be reported, got %v", errs)
+		}
+	})
+
+	t.Run("stops retrying once the context is done", func(t *testing.T) {
+		client := newFakeClient()
+		client.throttled = true
+		var errs []error
+		c := New(client, "cache", codec.JSON[*user](), WithRetryBackoff[string, *user](time.Hour), WithErrorHandler[string, *user](func(err error) {
+			errs = append(errs, err)
+		}))
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		c.GetMany(ctx, []string{"a", "b"})
+		if client.batches != 1 {
+			t.Errorf("expected 1 attempt, got %d", client.batches)
+		}
+		if len(errs) != 1 || !errors.Is(errs[0], ErrUnprocessed) || !errors.Is(errs[0], context.DeadlineExceeded) {
+			t.Errorf("expected the unprocessed items to be reported, got %v", errs)
+		}
+	})
+
+	t.Run("with a loader", func(t *testing.T) {
+		c := New[string](newFakeClient(), "cache", codec.JSON[*user]())
+		loader := dataloader.NewBatchedLoader(func(_ context.Context, keys []string) []*dataloader.Result[*user] {
+			results := make([]*dataloader.Result[*user], len(keys))
+			for i, key := range keys {
+				results[i] = &dataloader.Result[*user]{Data: &user{ID: key}}
+			}
+			return results
+		}, dataloader.WithValueCache[string, *user](c))
+
+		if _, err := loader.Load(ctx, "1")(); err != nil {
+			t.Fatal(err)
+		}
+		// the result is moved to the cache asynchronously
+		for i := 0; i < 100; i++ {
+			if result, ok := c.Get(ctx, "1"); ok {
+				if result.Data.ID != "1" {
+					t.Errorf("expected the cached user, got %#v", result.Data)
+				}
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Error("expected the result to be cached")
+	})
+}
@@ -0,0 +1,20 @@
+module github.com/graph-gophers/dataloader/v7/datacache/dynamodb
+
+go 1.23
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.32.6
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.38.0
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.25 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.25 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.12.1 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.10.6 // indirect
+	github.com/aws/smithy-go v1.22.1 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+)
+
+replace github.com/graph-gophers/dataloader/v7 => ../..
@@ -1,14 +1,46 @@
 // Package dataloader is an implementation of facebook's dataloader in go.
 // See https://github.com/facebook/dataloader for more information
+//
+// The Loader implements Interface, which consumers can depend on to decorate
+// or mock their loaders.
+//
+// # Memory model
+//
+// A Loader provides the following guarantees, in the terms of the Go memory
+// model, whatever its Cache and Tracer:
+//
+//   - The Load of every key of a batch happens before the call of the batch
+//     function.
+//   - The writes of the batch function to its results (and to the values
+//     they point to) happen before any thunk of their keys returns: every
+//     waiter of a key sees the whole result, from any goroutine, and every
+//     time the thunk is called.
+//   - Prime, PrimeIfNewer, Clear and ClearAll happen before the loads which
+//     start once they have returned: these loads see the primed value, or
+//     load the cleared key again.
+//   - The methods of the Cache of a loader are called one at a time, with a
+//     lock of the loader held, so a cache used by a single loader needs no
+//     synchronization of its own. They must not call the loader back. The
+//     thunks stored in the cache may be called concurrently.
+//
+// The other extension points are called concurrently, and must be safe for
+// concurrent use: the methods of a DataCache (see WithValueCache) and of a
+// Tracer, along with the finish functions returned by the Tracer, and the
+// hooks set by options.
+//
+// The waiters of a key share the value of its result: writing to it is a data
+// race, unless it is synchronized by its type (see WithMutationDetection).
 package dataloader
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"log"
-	"runtime"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,6 +52,10 @@ import (
 // used in long-lived applications or those which serve many users with
 // different access permissions and consider creating a new instance per
 // web request.
+//
+// Interface is implemented by *Loader and by the loaders composing loaders,
+// like ShardedLoader. Code depending on it can be handed a decorated loader
+// (i.e. one adding logging or authorization checks), or a mock in tests.
 type Interface[K comparable, V any] interface {
 	Load(context.Context, K) Thunk[V]
 	LoadMany(context.Context, []K) ThunkMany[V]
@@ -31,7 +67,13 @@ type Interface[K comparable, V any] interface {
 // BatchFunc is a function, which when given a slice of keys (string), returns a slice of `results`.
 // It's important that the length of the input keys matches the length of the output results.
 //
-// The keys passed to this function are guaranteed to be unique
+// The keys passed to this function are guaranteed to be unique, whatever the
+// cache: the requests of the same key within a batch all receive its result.
+//
+// The function runs on its own goroutine, without holding any lock of the
+// loader: it may load keys from other loaders, or from its own loader, and
+// wait for them. Waiting for one of its own keys never returns though, since
+// that key only resolves once the function has returned.
 type BatchFunc[K comparable, V any] func(context.Context, []K) []*Result[V]
 
 // Result is the data structure that a BatchFunc returns.
@@ -60,8 +102,16 @@ func (p *PanicErrorWrapper) Error() string {
 	return p.panicError.Error()
 }
 
-// Loader implements the dataloader.Interface.
+// Unwrap returns the wrapped *PanicError.
+func (p *PanicErrorWrapper) Unwrap() error {
+	return p.panicError
+}
+
+// Loader implements Interface.
 type Loader[K comparable, V any] struct {
+	// the name of the loader, see WithName
+	name string
+
 	// the batch function to be used by this loader
 	batchFn BatchFunc[K, V]
 
@@ -76,29 +126,197 @@ type Loader[K comparable, V any] struct {
 	// this would allow batching but no long term caching
 	clearCacheOnBatch bool
 
-	// count of queued up items
-	count int
-
 	// the maximum input queue size. Set to 0 if you want it to be unbounded.
 	inputCap int
 
+	// what to do when the input queue is full
+	overflow OverflowPolicy
+
 	// the amount of time to wait before triggering a batch
 	wait time.Duration
 
+	// the amount of idle time (no new Loads) after which the batch is
+	// triggered early. Set to 0 to disable.
+	idleFlush time.Duration
+
+	// fraction of the wait duration by which batch windows are randomized
+	waitJitter float64
+
+	// fraction by which the TTLs of the cached keys are randomized
+	ttlJitter float64
+
+	// if set, the cache is rotated to a new epoch when it's maxAge old (at
+	// rotateAt), and the values of the previous epoch are kept as a fallback
+	// if staleFallback is set, see WithCacheMaxAge. rotateAt, fresh and stale
+	// are protected by the cacheLock
+	maxAge        time.Duration
+	rotateAt      time.Time
+	staleFallback bool
+	fresh, stale  map[K]V
+
+	// times the batch windows, see WithClock
+	clock Clock
+
+	// if set, batch windows close on multiples of this duration on the clock
+	alignment time.Duration
+
+	// batches smaller than minBatchSize may wait up to minBatchWait longer
+	// for more keys before being dispatched
+	minBatchSize int
+	minBatchWait time.Duration
+
 	// lock to protect the batching operations
 	batchLock sync.Mutex
 
+	// disables batching, protected by the batchLock
+	immediate bool
+
 	// current batcher
 	curBatcher *batcher[K, V]
 
-	// used to close the sleeper of the current batcher
-	endSleeper chan bool
+	// current batchers of the batch groups, see WithBatchGroup
+	groups map[*batchGroup]*batcher[K, V]
+
+	// if set, decides when batch windows close instead of the sleeper.
+	// unscheduled are the windows opened by start yet to be handed to it,
+	// protected by the batchLock
+	scheduler   Scheduler
+	unscheduled []func()
+
+	// if set, batch windows only close on Dispatch, see WithManualDispatch
+	manual bool
+
+	// the number of batches (and shadow batches) which are yet to finish, and
+	// the channel closed once they all have (see Drain), protected by the
+	// batchLock
+	running int
+	idle    chan struct{}
+
+	// set once the loader is closed, under the batchLock, see Close
+	closed atomic.Bool
 
 	// used by tests to prevent logs
 	silent bool
 
+	// if set, short batch results only fail the keys missing a result
+	lenient bool
+
+	// if set, loads with a done context fail right away
+	failFast bool
+
+	// if set, the keys are forgotten once their batch has resolved, see
+	// WithInFlightDedupe
+	inFlight bool
+
+	// if set, applied to the values handed to the callers of Load
+	transform func(context.Context, K, V) V
+
+	// if set, returns the context of the batches, see WithBatchContext
+	batchContext func() context.Context
+
+	// if set, detects the mutations of cached values
+	mutations *mutationDetector[K, V]
+
+	// if set, adjusts the wait duration and the batch capacity
+	tuner *autoTuner[K, V]
+
+	// if set, the batches are dispatched once the cost of their keys reaches
+	// the limit
+	costLimit int
+	costOf    func(K) int
+
+	// if set, predicts the keys to prefetch along with a key, up to the
+	// budget of each batch
+	predict           func(context.Context, K) []K
+	speculationBudget int
+
+	// if set, bounds the keys fetched concurrently, see NewLoaderFunc.
+	// fetchSem and planSem are protected by the batchLock
+	fetchSem chan struct{}
+
+	// if set, plans the calls of the batch function for the keys of a batch,
+	// which run concurrently up to the capacity of the planSem
+	planner func(context.Context, []K) [][]K
+	planSem chan struct{}
+
+	// if set, the keys of a sample of the batches are also loaded by the
+	// shadow batch function, and its results compared with the primary's
+	shadow *shadowBatch[K, V]
+
+	// if set, keys for which it returns true skip the cache
+	cacheBypass func(context.Context, K) bool
+
+	// if set, toggles the behaviors of the loader per request
+	flagger Flagger
+
+	// if set, memoizes the results of whole batches, see WithBatchMemo
+	memo *batchMemo[K, V]
+
+	// what to do with the keys whose deadline would be exceeded by the end
+	// of the batch window, and the expected latency of the batches
+	deadlinePolicy DeadlinePolicy
+	latency        latencyAverage
+
+	// if set, limits the number of distinct keys queued per context
+	budget *keyBudget[K]
+
+	// if set, a batch holds at most tenantCap keys of any given tenant
+	tenantOf  func(K) string
+	tenantCap int
+
 	// can be set to trace calls to dataloader
 	tracer Tracer[K, V]
+
+	// if set, receives the measurements of the loader, see WithMetrics
+	metrics Metrics
+	// the number of loads queued on the batch windows not yet dispatched
+	queued atomic.Int64
+
+	// the cache epoch, incremented by ClearAll under the cacheLock
+	epoch atomic.Uint64
+
+	// if set, reports the requests unresolved after watchdogAfter
+	watchdogAfter time.Duration
+	onStuck       func(K, time.Duration)
+	// if set, resolves the requests unresolved after thunkTimeout
+	thunkTimeout time.Duration
+
+	// pending expiries of cached keys, protected by the cacheLock
+	expiries map[K]*expiry
+
+	// if set, returns the version of a value, see PrimeIfNewer
+	versionOf func(V) int64
+	// versions of cached values, protected by the cacheLock
+	versions map[K]int64
+
+	// if set, called with the evicted values, see WithOnEvict. evictable are
+	// the values of the cached keys, and evictions the evictions yet to be
+	// reported, protected by the evictLock
+	onEvict   func(context.Context, K, V, EvictReason)
+	evictLock sync.Mutex
+	evictable map[K]V
+	evictions []eviction[K, V]
+
+	// hooks called with the results of every batch
+	hookLock sync.RWMutex
+	hooks    []registeredHook[K, V]
+
+	// watchers of keys, see Watch
+	watchLock sync.Mutex
+	watchOnce sync.Once
+	watchers  map[K][]*watcher[V]
+}
+
+// resolveHook is called with the result of a key loaded by a batch.
+type resolveHook[K comparable, V any] func(context.Context, K, *Result[V])
+
+// registeredHook is a hook of the loader. The hooks keeping the state of the
+// cached keys are cached only, and don't see the loads bypassing the cache.
+// The delivered hooks are called once the results have been delivered.
+type registeredHook[K comparable, V any] struct {
+	hook       resolveHook[K, V]
+	cachedOnly bool
+	delivered  bool
 }
 
 // Thunk is a function that will block until the value (*Result) it contains is resolved.
@@ -110,10 +328,47 @@ type Thunk[V any] func() (V, error)
 // ThunkMany is much like the Thunk func type but it contains a list of results.
 type ThunkMany[V any] func() ([]V, []error)
 
+// ThunkMap is much like the ThunkMany func type but it contains the results by
+// key. It is returned by LoadMap.
+type ThunkMap[K comparable, V any] func() map[K]*Result[V]
+
 // type used to on input channel
 type batchRequest[K comparable, V any] struct {
 	key     K
 	channel chan *Result[V]
+	// true if the request is not stored in the cache
+	uncached bool
+	// the cache epoch the request was made in
+	epoch uint64
+	// see ContextWithPriority
+	priority int
+	// see ContextWithUsage
+	usage *Usage
+
+	// whether the request was resolved, and its watchdog timers, protected by mu
+	mu       sync.Mutex
+	resolved bool
+	timers   []func() bool
+}
+
+// resolve sends the result of the request, unless it was already resolved.
+// It reports whether the result was sent.
+func (r *batchRequest[K, V]) resolve(result *Result[V]) bool {
+	r.mu.Lock()
+	if r.resolved {
+		r.mu.Unlock()
+		return false
+	}
+	r.resolved = true
+	timers := r.timers
+	r.mu.Unlock()
+
+	for _, stop := range timers {
+		stop()
+	}
+	r.channel <- result
+	close(r.channel)
+	return true
 }
 
 // Option allows for configuration of Loader fields.
@@ -140,6 +395,14 @@ func WithInputCapacity[K comparable, V any](c int) Option[K, V] {
 	}
 }
 
+// WithOverflowPolicy sets what Load does when the input queue of the current
+// batch is full (see WithInputCapacity). Default is OverflowBlock.
+func WithOverflowPolicy[K comparable, V any](p OverflowPolicy) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.overflow = p
+	}
+}
+
 // WithWait sets the amount of time to wait before triggering a batch.
 // Default duration is 16 milliseconds.
 func WithWait[K comparable, V any](d time.Duration) Option[K, V] {
@@ -148,6 +411,106 @@ func WithWait[K comparable, V any](d time.Duration) Option[K, V] {
 	}
 }
 
+// WithIdleFlush triggers the batch once no new Load has been queued for the
+// given duration, even if the wait duration set with WithWait hasn't elapsed
+// yet. This cuts latency for workloads which queue their keys in a short
+// burst. Default is 0 (disabled).
+func WithIdleFlush[K comparable, V any](d time.Duration) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.idleFlush = d
+	}
+}
+
+// WithWaitJitter randomizes the wait duration of every batch window by up to
+// the given fraction in either direction (i.e. 0.1 makes a 10ms wait last
+// between 9ms and 11ms). This keeps loaders created at the same time from
+// dispatching their batches in lockstep. Default is 0 (disabled).
+func WithWaitJitter[K comparable, V any](fraction float64) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.waitJitter = fraction
+	}
+}
+
+// WithWindowAlignment aligns the end of every batch window to the next multiple
+// of the given duration on the wall clock, at or after the wait duration has
+// elapsed. Concurrent loaders using the same alignment, even across processes,
+// dispatch their batches at the same instants, which improves connection reuse
+// and downstream batching. Use WithWait with a zero duration to dispatch at the
+// very next boundary. Default is 0 (disabled).
+func WithWindowAlignment[K comparable, V any](d time.Duration) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.alignment = d
+	}
+}
+
+// WithMinBatchSize lets a batch holding less than n keys once the wait duration
+// has elapsed wait up to maxExtraWait longer for more keys. The batch is
+// dispatched as soon as it reaches n keys. This favors bigger batches while
+// bounding the added latency. Default is 0 (disabled).
+func WithMinBatchSize[K comparable, V any](n int, maxExtraWait time.Duration) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.minBatchSize = n
+		l.minBatchWait = maxExtraWait
+	}
+}
+
+// WithTenantCap limits the number of keys of any single tenant in a batch.
+// The tenant of a key is given by the tenantOf function. Keys in excess are
+// spilled over to the next batch, so that a tenant loading many keys at once
+// doesn't starve the others sharing the same loader. Default is 0 (disabled).
+func WithTenantCap[K comparable, V any](tenantOf func(K) string, cap int) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.tenantOf = tenantOf
+		l.tenantCap = cap
+	}
+}
+
+// WithImmediateDispatch disables batching: every Load which misses the cache is
+// dispatched right away in a batch of its own. Caching is not affected.
+// This is meant for debugging, to rule batching out as the cause of a bug.
+// It can be toggled at runtime with SetImmediateDispatch.
+func WithImmediateDispatch[K comparable, V any]() Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.immediate = true
+	}
+}
+
+// WithPrefetch sets a function returning the keys likely to be loaded next
+// once a value has resolved (i.e. the children of a parent). These keys are
+// queued right away on the next batch, instead of waiting for the caller to
+// load them, which saves a batch window of latency. prefetch is called once
+// the value has been delivered to its loads, so that it doesn't delay them.
+func WithPrefetch[K comparable, V any](prefetch func(context.Context, V) []K) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.addDeliveredHook(func(ctx context.Context, _ K, result *Result[V]) {
+			if result.Error != nil {
+				return
+			}
+			for _, key := range prefetch(ctx, result.Data) {
+				l.Load(ctx, key)
+			}
+		})
+	}
+}
+
+// WithScheduler sets the Scheduler which decides when a batch window closes.
+// When set, the wait and idle flush durations are ignored.
+func WithScheduler[K comparable, V any](s Scheduler) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.scheduler = s
+	}
+}
+
+// WithCacheBypass sets a function deciding which keys skip the cache (i.e. the
+// record of the authenticated user, or entities loaded in preview mode).
+// Loads of keys for which it returns true always go to the batch function, and
+// their results aren't cached. Every other key is cached normally.
+func WithCacheBypass[K comparable, V any](bypass func(context.Context, K) bool) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.cacheBypass = bypass
+	}
+}
+
 // WithClearCacheOnBatch allows batching of items but no long term caching.
 // It accomplishes this by clearing the cache after each batch operation.
 func WithClearCacheOnBatch[K comparable, V any]() Option[K, V] {
@@ -158,6 +521,51 @@ func WithClearCacheOnBatch[K comparable, V any]() Option[K, V] {
 	}
 }
 
+// WithFailFastOnCanceledContext makes loads with a context which is already
+// done resolve right away with the error of the context. Their keys are
+// neither queued nor cached.
+func WithFailFastOnCanceledContext[K comparable, V any]() Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.failFast = true
+	}
+}
+
+// WithBatchContext sets the function returning the context the batches are
+// run with, instead of the context of the first load of each batch. The batch
+// function then sees the same deadline, cancellation and values whichever
+// request happens to open a batch shared by several of them (i.e. a context
+// holding the credentials of the service, without a deadline of a request).
+// The spans of the tracer are then children of the returned context too.
+func WithBatchContext[K comparable, V any](fn func() context.Context) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.batchContext = fn
+	}
+}
+
+// WithResultTransform sets a function applied to a value every time it is
+// handed to a caller of Load, with the context of that call. The cache keeps
+// the original value. It can be used to deep-clone cached values, so that a
+// caller can't mutate the value seen by the others, or to redact values per
+// caller.
+func WithResultTransform[K comparable, V any](transform func(context.Context, K, V) V) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.transform = transform
+	}
+}
+
+// WithName sets the name of the loader (i.e. the entity it loads), which
+// identifies it in logs and metrics.
+func WithName[K comparable, V any](name string) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.name = name
+	}
+}
+
+// Name returns the name of the loader, see WithName.
+func (l *Loader[K, V]) Name() string {
+	return l.name
+}
+
 // withSilentLogger turns of log messages. It's used by the tests
 func withSilentLogger[K comparable, V any]() Option[K, V] {
 	return func(l *Loader[K, V]) {
@@ -194,6 +602,11 @@ func NewBatchedLoader[K comparable, V any](batchFn BatchFunc[K, V], opts ...Opti
 		loader.tracer = NoopTracer[K, V]{}
 	}
 
+	if loader.clock == nil {
+		loader.clock = systemClock{}
+	}
+
+	loader.watchEvictions()
 	return loader
 }
 
@@ -201,7 +614,34 @@ func NewBatchedLoader[K comparable, V any](batchFn BatchFunc[K, V], opts ...Opti
 // The first context passed to this function within a given batch window will be provided to
 // the registered BatchFunc.
 func (l *Loader[K, V]) Load(originalContext context.Context, key K) Thunk[V] {
+	return l.LoadWith(originalContext, key)
+}
+
+// LoadWith loads the given key like Load does, with options applying to this
+// call only (see LoadOption).
+func (l *Loader[K, V]) LoadWith(originalContext context.Context, key K, opts ...LoadOption) Thunk[V] {
 	ctx, finish := l.tracer.TraceLoad(originalContext, key)
+	config := newLoadConfig(opts)
+
+	if l.closed.Load() {
+		thunk := func() (V, error) {
+			var zero V
+			return zero, ErrLoaderClosed
+		}
+		finish(&Result[V]{Error: ErrLoaderClosed})
+		return thunk
+	}
+
+	if l.failFast {
+		if err := originalContext.Err(); err != nil {
+			thunk := func() (V, error) {
+				var zero V
+				return zero, err
+			}
+			finish(&Result[V]{Error: err})
+			return thunk
+		}
+	}
 
 	c := make(chan *Result[V], 1)
 	var result struct {
@@ -209,14 +649,39 @@ func (l *Loader[K, V]) Load(originalContext context.Context, key K) Thunk[V] {
 		value *Result[V]
 	}
 
+	// keys bypassing the cache are neither looked up nor stored in the cache
+	bypass := config.skipCache || skipsCache(originalContext) || l.cacheBypass != nil && l.cacheBypass(originalContext, key) || !l.enabled(originalContext, FlagCache)
+
 	// lock to prevent duplicate keys coming in before item has been added to cache.
+	usage, _ := UsageFromContext(originalContext)
+	l.rotate()
+
 	l.cacheLock.Lock()
-	if v, ok := l.cache.Get(ctx, key); ok {
-		defer finish(v)
-		defer l.cacheLock.Unlock()
-		return v
+	// a refreshed key isn't looked up, but its thunk replaces the cached one
+	if !bypass && !config.refresh {
+		if v, ok := l.cache.Get(ctx, key); ok {
+			if usage != nil {
+				usage.load(l.usageName(), key, true)
+			}
+			l.observeCache(ctx, true)
+			defer l.traced(v, finish)
+			defer l.cacheLock.Unlock()
+			return l.transformed(originalContext, key, v, true)
+		}
+		l.observeCache(ctx, false)
+	}
+
+	if l.budget != nil && !l.budget.admit(originalContext, key) {
+		l.cacheLock.Unlock()
+		thunk := func() (V, error) {
+			var zero V
+			return zero, ErrBudgetExceeded
+		}
+		finish(&Result[V]{Error: ErrBudgetExceeded})
+		return thunk
 	}
 
+	epoch := l.epoch.Load()
 	thunk := func() (V, error) {
 		result.mu.RLock()
 		resultNotSet := result.value == nil
@@ -232,51 +697,90 @@ func (l *Loader[K, V]) Load(originalContext context.Context, key K) Thunk[V] {
 		result.mu.RLock()
 		defer result.mu.RUnlock()
 		var ev *PanicErrorWrapper
-		if result.value.Error != nil && errors.As(result.value.Error, &ev) {
-			l.Clear(ctx, key)
+		if !bypass && result.value.Error != nil && errors.As(result.value.Error, &ev) {
+			l.drop(ctx, key, epoch)
 		}
 		return result.value.Data, result.value.Error
 	}
-	defer finish(thunk)
-
-	l.cache.Set(ctx, key, thunk)
+	defer l.traced(thunk, finish)
+
+	if !bypass {
+		if config.refresh {
+			// the refreshed key starts afresh, its replaced value is evicted
+			l.delete(ctx, key)
+		} else {
+			l.stopExpiry(key)
+		}
+		l.cache.Set(ctx, key, thunk)
+	}
 	l.cacheLock.Unlock()
+	if config.refresh {
+		l.flushEvictions(ctx)
+	}
 
 	// this is sent to batch fn. It contains the key and the channel to return
 	// the result on
-	req := &batchRequest[K, V]{key, c}
+	priority := priorityOf(originalContext)
+	if config.prioritized {
+		priority = config.priority
+	}
+	req := &batchRequest[K, V]{key: key, channel: c, uncached: bypass, epoch: epoch, priority: priority, usage: usage}
+	l.guard(ctx, req)
+	if usage != nil {
+		usage.load(l.usageName(), key, false)
+	}
 
-	l.batchLock.Lock()
-	// start the batch window if it hasn't already started.
-	if l.curBatcher == nil {
-		l.curBatcher = l.newBatcher(l.silent, l.tracer)
-		// start the current batcher batch function
-		go l.curBatcher.batch(originalContext)
-		// start a sleeper for the current batcher
-		l.endSleeper = make(chan bool)
-		go l.sleeper(l.curBatcher, l.endSleeper)
+	queueContext := originalContext
+	if !l.enabled(originalContext, FlagBatching) {
+		queueContext = ContextWithWait(originalContext, 0)
+	}
+	if err := l.enqueue(queueContext, req); err != nil {
+		l.fail(ctx, req, err)
+	}
+	if !bypass {
+		l.speculate(originalContext, key)
 	}
 
-	l.curBatcher.input <- req
+	return l.transformed(originalContext, key, thunk, !bypass)
+}
 
-	// if we need to keep track of the count (max batch), then do so.
-	if l.batchCap > 0 {
-		l.count++
-		// if we hit our limit, force the batch to start
-		if l.count == l.batchCap {
-			// end the batcher synchronously here because another call to Load
-			// may concurrently happen and needs to go to a new batcher.
-			l.curBatcher.end()
-			// end the sleeper for the current batcher.
-			// this is to stop the goroutine without waiting for the
-			// sleeper timeout.
-			close(l.endSleeper)
-			l.reset()
-		}
+// traced calls the finish function of the trace of a load with the result of
+// its thunk once it has resolved, without blocking the caller.
+func (l *Loader[K, V]) traced(thunk Thunk[V], finish TraceLoadFinishFunc[V]) {
+	if _, noop := l.tracer.(NoopTracer[K, V]); noop {
+		return
 	}
-	l.batchLock.Unlock()
+	go func() {
+		data, err := thunk()
+		finish(&Result[V]{Data: data, Error: err})
+	}()
+}
 
-	return thunk
+// transformed returns the thunk handing values to the caller of Load, which
+// applies the result transform (if any) to the value of the cached thunk. The
+// values of a load bypassing the cache (cached is false) aren't checked for
+// mutations, as they aren't the cached values of their key.
+func (l *Loader[K, V]) transformed(ctx context.Context, key K, thunk Thunk[V], cached bool) Thunk[V] {
+	if l.transform == nil && l.mutations == nil && !l.staleFallback {
+		return thunk
+	}
+	return func() (V, error) {
+		v, err := thunk()
+		if err != nil {
+			stale, ok := l.staleOf(key)
+			if !ok || IsNotFound(err) {
+				return v, err
+			}
+			v = stale
+		}
+		if l.mutations != nil && cached {
+			l.mutations.check(key, v)
+		}
+		if l.transform != nil {
+			v = l.transform(ctx, key, v)
+		}
+		return v, nil
+	}
 }
 
 // LoadMany loads multiple keys, returning a thunk (type: ThunkMany) that will resolve the keys passed in.
@@ -317,7 +821,9 @@ func (l *Loader[K, V]) LoadMany(originalContext context.Context, keys []K) Thunk
 			}
 		}
 
-		c <- &ResultMany[V]{Data: data, Error: errs}
+		resultMany := &ResultMany[V]{Data: data, Error: errs}
+		finish(resultMany)
+		c <- resultMany
 		close(c)
 	}()
 
@@ -343,73 +849,684 @@ func (l *Loader[K, V]) LoadMany(originalContext context.Context, keys []K) Thunk
 		return result.value.Data, result.value.Error
 	}
 
-	defer finish(thunkMany)
 	return thunkMany
 }
 
+// LoadManyIter loads multiple keys, returning an iterator which yields the
+// result of each key as soon as it has resolved, rather than once every key
+// has. Results are yielded in the order in which they resolve. The keys are
+// queued right away, before the iterator is used.
+func (l *Loader[K, V]) LoadManyIter(ctx context.Context, keys []K) iter.Seq2[K, *Result[V]] {
+	thunks := make([]Thunk[V], len(keys))
+	for i, key := range keys {
+		thunks[i] = l.Load(ctx, key)
+	}
+
+	return func(yield func(K, *Result[V]) bool) {
+		type resolved struct {
+			index  int
+			result *Result[V]
+		}
+		// buffered so that the goroutines don't leak if iteration stops early
+		c := make(chan resolved, len(thunks))
+		for i, thunk := range thunks {
+			go func() {
+				data, err := thunk()
+				c <- resolved{i, &Result[V]{Data: data, Error: err}}
+			}()
+		}
+		for range thunks {
+			r := <-c
+			if !yield(keys[r.index], r.result) {
+				return
+			}
+		}
+	}
+}
+
+// LoadMap loads multiple keys, returning a thunk which resolves to their
+// results by key, so that the callers stitching the results back to their
+// parents don't have to index them by position. A key loaded several times
+// has a single entry. The keys are queued right away.
+func (l *Loader[K, V]) LoadMap(ctx context.Context, keys []K) ThunkMap[K, V] {
+	thunks := make(map[K]Thunk[V], len(keys))
+	for _, key := range keys {
+		if _, ok := thunks[key]; !ok {
+			thunks[key] = l.Load(ctx, key)
+		}
+	}
+
+	return sync.OnceValue(func() map[K]*Result[V] {
+		results := make(map[K]*Result[V], len(thunks))
+		for key, thunk := range thunks {
+			data, err := thunk()
+			results[key] = &Result[V]{Data: data, Error: err}
+		}
+		return results
+	})
+}
+
+// defaultSeqWindow is the number of keys LoadSeq queues at a time when the
+// batch capacity is unbounded.
+const defaultSeqWindow = 100
+
+// LoadSeq loads the keys produced by the sequence, returning an iterator which
+// yields the result of each key in the order of the sequence. The keys are
+// pulled from the sequence as the iterator is used, a window at a time: the
+// window is the batch capacity, or 100 keys if it is unbounded. Every key of a
+// window is queued before the first of their results is yielded.
+func (l *Loader[K, V]) LoadSeq(ctx context.Context, keys iter.Seq[K]) iter.Seq2[K, *Result[V]] {
+	l.batchLock.Lock()
+	window := l.batchCap
+	l.batchLock.Unlock()
+	if window <= 0 {
+		window = defaultSeqWindow
+	}
+
+	return func(yield func(K, *Result[V]) bool) {
+		pending := make([]K, 0, window)
+		thunks := make([]Thunk[V], 0, window)
+		flush := func() bool {
+			for i, thunk := range thunks {
+				data, err := thunk()
+				if !yield(pending[i], &Result[V]{Data: data, Error: err}) {
+					return false
+				}
+			}
+			pending, thunks = pending[:0], thunks[:0]
+			return true
+		}
+
+		for key := range keys {
+			pending = append(pending, key)
+			thunks = append(thunks, l.Load(ctx, key))
+			if len(thunks) == window && !flush() {
+				return
+			}
+		}
+		flush()
+	}
+}
+
 // Clear clears the value at `key` from the cache, it it exists. Returns self for method chaining
 func (l *Loader[K, V]) Clear(ctx context.Context, key K) Interface[K, V] {
+	l.forget(ctx, key)
+	l.rewatch(ctx, []K{key})
+	return l
+}
+
+// forget removes the key from the cache without reloading it for its watchers.
+func (l *Loader[K, V]) forget(ctx context.Context, key K) {
 	l.cacheLock.Lock()
-	l.cache.Delete(ctx, key)
+	l.delete(ctx, key)
 	l.cacheLock.Unlock()
-	return l
+	l.flushEvictions(ctx)
+}
+
+// drop removes the key from the cache, unless the cache was cleared since the
+// given epoch: the key then belongs to the new epoch.
+func (l *Loader[K, V]) drop(ctx context.Context, key K, epoch uint64) {
+	l.cacheLock.Lock()
+	if l.epoch.Load() == epoch {
+		l.delete(ctx, key)
+	}
+	l.cacheLock.Unlock()
+	l.flushEvictions(ctx)
+}
+
+// delete removes the key from the cache.
+// It must be called with the cacheLock held.
+func (l *Loader[K, V]) delete(ctx context.Context, key K) {
+	l.stopExpiry(key)
+	delete(l.versions, key)
+	delete(l.fresh, key)
+	delete(l.stale, key)
+	l.cache.Delete(ctx, key)
+	l.evict(key, EvictCleared)
+}
+
+// clearCache removes every key from the cache, for the given reason.
+// It must be called with the cacheLock held.
+func (l *Loader[K, V]) clearCache(reason EvictReason) {
+	l.stopExpiries()
+	clear(l.versions)
+	l.cache.Clear()
+	l.evictAll(reason)
 }
 
 // ClearAll clears the entire cache. To be used when some event results in unknown invalidations.
 // Returns self for method chaining.
+//
+// ClearAll starts a new cache epoch: the loads made once it has returned never
+// share the requests made before, even if their batch is still in flight.
+// These batches still resolve their callers, but their results no longer
+// affect the cache: they don't clear, expire or version the keys of the new
+// epoch, and the configured hooks (i.e. WithPrefetch, Watch) don't see them.
 func (l *Loader[K, V]) ClearAll() Interface[K, V] {
 	l.cacheLock.Lock()
-	l.cache.Clear()
+	l.epoch.Add(1)
+	l.clearCache(EvictClearAll)
+	l.fresh, l.stale = nil, nil
 	l.cacheLock.Unlock()
+	l.flushEvictions(context.Background())
+	l.rewatch(context.Background(), nil)
 	return l
 }
 
 // Prime adds the provided key and value to the cache. If the key already exists, no change is made.
 // Returns self for method chaining
 func (l *Loader[K, V]) Prime(ctx context.Context, key K, value V) Interface[K, V] {
-	if _, ok := l.cache.Get(ctx, key); !ok {
-		thunk := func() (V, error) {
-			return value, nil
+	l.prime(ctx, key, &Result[V]{Data: value}, l.versionOf, func() bool {
+		_, ok := l.cache.Get(ctx, key)
+		return !ok
+	})
+	return l
+}
+
+// ForcePrime adds the provided key and value to the cache, replacing the
+// cached value if the key already exists (i.e. once a mutation has resolved
+// the updated value), so that the following loads of the key see it without
+// clearing it first. Returns self for method chaining
+func (l *Loader[K, V]) ForcePrime(ctx context.Context, key K, value V) Interface[K, V] {
+	l.prime(ctx, key, &Result[V]{Data: value}, l.versionOf, func() bool { return true })
+	return l
+}
+
+// PrimeError adds the provided key and error to the cache, so that the loads
+// of the key resolve with the error without calling the batch function (i.e.
+// a permission denied known ahead). If the key already exists, no change is
+// made. Returns self for method chaining
+func (l *Loader[K, V]) PrimeError(ctx context.Context, key K, err error) Interface[K, V] {
+	l.prime(ctx, key, &Result[V]{Error: err}, nil, func() bool {
+		_, ok := l.cache.Get(ctx, key)
+		return !ok
+	})
+	return l
+}
+
+// prime caches the result for key if admit, called with the cacheLock held,
+// allows it. The value it replaces is reported to WithOnEvict, the primed
+// value is versioned by versionOf (if not nil) and sent to the watchers of
+// the key. It reports whether the result was primed.
+func (l *Loader[K, V]) prime(ctx context.Context, key K, result *Result[V], versionOf func(V) int64, admit func() bool) bool {
+	l.cacheLock.Lock()
+	if !admit() {
+		l.cacheLock.Unlock()
+		return false
+	}
+	l.stopExpiry(key)
+	l.evict(key, EvictCleared)
+	l.cache.Set(ctx, key, func() (V, error) {
+		return result.Data, result.Error
+	})
+	if result.Error == nil {
+		l.retain(key, result.Data)
+		if l.mutations != nil {
+			l.mutations.record(key, result.Data)
 		}
-		l.cache.Set(ctx, key, thunk)
 	}
-	return l
+	// a value of unknown version is always replaced by PrimeIfNewer
+	if versionOf != nil && result.Error == nil {
+		l.setVersion(key, versionOf(result.Data))
+	} else {
+		delete(l.versions, key)
+	}
+	l.cacheLock.Unlock()
+
+	l.flushEvictions(ctx)
+	if result.Error == nil {
+		l.notify(key, result.Data)
+	}
+	return true
+}
+
+// PrimeFrom primes the target loader with every value resolved by a batch of
+// the source loader, i.e. to prime a loader by id from the results of a
+// loader by slug. The extract function returns the key and value to prime the
+// target with. Failed results are ignored.
+func PrimeFrom[K1 comparable, V1 any, K2 comparable, V2 any](source *Loader[K1, V1], extract func(V1) (K2, V2), target *Loader[K2, V2]) {
+	source.addHook(func(ctx context.Context, _ K1, result *Result[V1]) {
+		if result.Error != nil {
+			return
+		}
+		key, value := extract(result.Data)
+		target.Prime(ctx, key, value)
+	})
+}
+
+// enqueue queues the request on the current batcher, opening a new batch
+// window if there is none. An error is returned if the request couldn't be
+// queued because of the overflow policy.
+//
+// No blocking operation is performed with the batchLock held: when the input
+// of the batcher is full, a slot is reserved on the batcher instead, which
+// keeps it from running until the request has been sent.
+func (l *Loader[K, V]) enqueue(ctx context.Context, req *batchRequest[K, V]) error {
+	l.batchLock.Lock()
+	b, queued, dispatched, err := l.queue(ctx, req)
+	unscheduled := l.unscheduled
+	l.unscheduled = nil
+	l.batchLock.Unlock()
+
+	if dispatched {
+		l.batched()
+	}
+	if err == nil && !queued {
+		b.send(req)
+	}
+	// the scheduler may dispatch the windows right away, which takes the
+	// batchLock
+	for _, schedule := range unscheduled {
+		schedule()
+	}
+	return err
 }
 
-func (l *Loader[K, V]) reset() {
-	l.count = 0
-	l.curBatcher = nil
+// queue queues the request on the current batcher if its input isn't full,
+// or reserves a slot for it otherwise. dispatched is true if the batcher was
+// dispatched right away (i.e. its capacity was reached).
+// It must be called with the batchLock held.
+func (l *Loader[K, V]) queue(ctx context.Context, req *batchRequest[K, V]) (b *batcher[K, V], queued, dispatched bool, err error) {
+	// the loads racing with Close are not queued once it has started draining
+	if l.closed.Load() {
+		return nil, false, false, ErrLoaderClosed
+	}
+
+	// start the batch window if it hasn't already started.
+	group := batchGroupOf(ctx)
+	if b = l.current(group); b == nil {
+		b = l.start(ctx, group)
+	}
+	cost := l.costOfRequest(b, req)
+	if cost > 0 && b.cost > 0 && b.cost+cost > l.costLimit {
+		// dispatch the batch rather than bringing it over its cost limit
+		l.closeCurrent(b, DispatchCapacity)
+		dispatched = true
+		b = l.start(ctx, group)
+	}
 
+	dispatchNow, err := l.admit(ctx, b)
+	if err != nil {
+		return nil, false, dispatched, err
+	}
+
+	queued = b.offer(req)
+	// an unbuffered input always blocks until the batcher receives
+	if !queued && l.inputCap > 0 {
+		switch l.overflow {
+		case OverflowFail:
+			return nil, false, false, ErrInputQueueFull
+		case OverflowRetry:
+			// dispatch the full batch and queue the request on a new one
+			l.closeCurrent(b, DispatchCapacity)
+			dispatched = true
+			b = l.start(ctx, group)
+			queued = b.offer(req)
+		}
+	}
+	if !queued {
+		b.reserve()
+	}
+	b.count++
+	l.observeQueue(ctx, 1)
+	b.charge(req.key, cost)
+	if d, ok := waitOf(ctx); ok {
+		if d <= 0 {
+			l.closeCurrent(b, DispatchDeadline)
+			return b, queued, true, nil
+		}
+		if deadline := l.clock.Now().Add(d); b.deadline.IsZero() || deadline.Before(b.deadline) {
+			b.deadline = deadline
+		}
+	}
+	b.touch()
+
+	// every request gets its own batch in immediate dispatch mode, and the
+	// batch of a request which can't wait is dispatched right away
+	if l.immediate || dispatchNow {
+		reason := DispatchDeadline
+		if l.immediate {
+			reason = DispatchImmediate
+		}
+		l.closeCurrent(b, reason)
+		return b, queued, true, nil
+	}
+
+	// if we need to keep track of the count (max batch), then do so.
+	// if we hit our limit, force the batch to start
+	if b.capacity > 0 && b.count == b.capacity || l.costLimit > 0 && b.cost >= l.costLimit {
+		l.closeCurrent(b, DispatchCapacity)
+		dispatched = true
+	}
+	return b, queued, dispatched, nil
+}
+
+// start opens a new batch window for the batch group, which is nil for the
+// requests made outside of any batch group.
+// It must be called with the batchLock held.
+func (l *Loader[K, V]) start(ctx context.Context, group *batchGroup) *batcher[K, V] {
+	b := l.newBatcher(l.silent, l.tracer)
+	b.group = group
+	b.capacity = l.batchCap
+	b.endSleeper = make(chan bool)
+	b.opened = l.clock.Now()
+	l.setCurrent(group, b)
+	// start the current batcher batch function
+	l.track()
+	batchCtx := ctx
+	if l.batchContext != nil {
+		batchCtx = l.batchContext()
+	}
+	go b.batch(batchCtx)
+	// start a sleeper for the current batcher, or hand the window over
+	// to the scheduler once the batchLock is released. In manual dispatch
+	// mode, the window stays open until Dispatch is called
+	switch {
+	case l.manual:
+	case l.scheduler != nil:
+		l.unscheduled = append(l.unscheduled, func() {
+			l.scheduler.Schedule(ctx, func() { l.dispatch(b, DispatchScheduler) })
+		})
+	default:
+		// the window is computed with the batchLock held, since WithAutoTune
+		// changes the wait duration with it
+		now := l.clock.Now()
+		b.closesAt = now.Add(l.windowDuration(now))
+		go l.sleeper(b, b.endSleeper, b.closesAt)
+	}
+	return b
+}
+
+// closeCurrent dispatches the provided current batcher right away, for the
+// given reason.
+// It must be called with the batchLock held.
+func (l *Loader[K, V]) closeCurrent(b *batcher[K, V], reason DispatchReason) {
+	// end the batcher synchronously here because another call to Load
+	// may concurrently happen and needs to go to a new batcher.
+	b.end(reason)
+	// end the sleeper for the current batcher.
+	// this is to stop the goroutine without waiting for the
+	// sleeper timeout.
+	close(b.endSleeper)
+	l.reset(b)
+}
+
+// current returns the current batcher of the batch group, if any.
+// It must be called with the batchLock held.
+func (l *Loader[K, V]) current(group *batchGroup) *batcher[K, V] {
+	if group == nil {
+		return l.curBatcher
+	}
+	return l.groups[group]
+}
+
+// setCurrent sets the current batcher of the batch group.
+// It must be called with the batchLock held.
+func (l *Loader[K, V]) setCurrent(group *batchGroup, b *batcher[K, V]) {
+	switch {
+	case group == nil:
+		l.curBatcher = b
+	case b == nil:
+		delete(l.groups, group)
+	default:
+		if l.groups == nil {
+			l.groups = make(map[*batchGroup]*batcher[K, V])
+		}
+		l.groups[group] = b
+	}
+}
+
+// fail resolves a request which could not be queued (or timed out) with the
+// given error, and removes it from the cache so that the key can be loaded
+// again. Requests which are already resolved are left untouched.
+func (l *Loader[K, V]) fail(ctx context.Context, req *batchRequest[K, V], err error) {
+	if req.resolve(&Result[V]{Error: err}) && !req.uncached {
+		l.drop(ctx, req.key, req.epoch)
+	}
+}
+
+// SetImmediateDispatch enables or disables immediate dispatch at runtime.
+// See WithImmediateDispatch. It doesn't affect the pending batch.
+func (l *Loader[K, V]) SetImmediateDispatch(enabled bool) {
+	l.batchLock.Lock()
+	l.immediate = enabled
+	l.batchLock.Unlock()
+}
+
+// update replaces the thunk cached at key with the result of fn applied to it.
+// The cache is left untouched if the key isn't cached.
+func (l *Loader[K, V]) update(ctx context.Context, key K, fn func(Thunk[V]) Thunk[V]) bool {
+	l.cacheLock.Lock()
+	defer l.cacheLock.Unlock()
+	thunk, ok := l.cache.Get(ctx, key)
+	if !ok {
+		return false
+	}
+	l.cache.Set(ctx, key, fn(thunk))
+	return true
+}
+
+// addHook registers a hook called with the results of every batch.
+func (l *Loader[K, V]) addHook(hook resolveHook[K, V]) {
+	l.hookLock.Lock()
+	l.hooks = append(l.hooks, registeredHook[K, V]{hook: hook})
+	l.hookLock.Unlock()
+}
+
+// addCacheHook registers a hook called with the results of the keys cached by
+// every batch, skipping the loads bypassing the cache (see SkipCache), whose
+// results are not the cached values of their keys.
+func (l *Loader[K, V]) addCacheHook(hook resolveHook[K, V]) {
+	l.hookLock.Lock()
+	l.hooks = append(l.hooks, registeredHook[K, V]{hook: hook, cachedOnly: true})
+	l.hookLock.Unlock()
+}
+
+// addDeliveredHook registers a hook called with the results of every batch
+// once they have been delivered to the loads, so that a slow hook (i.e. the
+// function of WithPrefetch) doesn't delay them.
+func (l *Loader[K, V]) addDeliveredHook(hook resolveHook[K, V]) {
+	l.hookLock.Lock()
+	l.hooks = append(l.hooks, registeredHook[K, V]{hook: hook, delivered: true})
+	l.hookLock.Unlock()
+}
+
+// resolved calls the registered hooks with the results of a batch: the
+// delivered hooks if delivered is true, the other ones otherwise.
+func (l *Loader[K, V]) resolved(ctx context.Context, reqs []*batchRequest[K, V], results []*Result[V], delivered bool) {
+	l.hookLock.RLock()
+	hooks := l.hooks
+	l.hookLock.RUnlock()
+
+	epoch := l.epoch.Load()
+	for _, h := range hooks {
+		if h.delivered != delivered {
+			continue
+		}
+		for i, req := range reqs {
+			// the results of an epoch cleared by ClearAll are stale
+			if results[i] == nil || req.epoch != epoch || (h.cachedOnly && req.uncached) {
+				continue
+			}
+			h.hook(ctx, req.key, results[i])
+		}
+	}
+}
+
+// reset detaches the provided current batcher, so that the next request of
+// its batch group opens a new batch window.
+// It must be called with the batchLock held.
+func (l *Loader[K, V]) reset(b *batcher[K, V]) {
+	l.setCurrent(b.group, nil)
+}
+
+// batched clears the cache once a batch has been dispatched, if the loader
+// was configured WithClearCacheOnBatch.
+// It must be called without the batchLock held.
+func (l *Loader[K, V]) batched() {
 	if l.clearCacheOnBatch {
-		l.cache.Clear()
+		l.cacheLock.Lock()
+		l.clearCache(EvictClearAll)
+		l.cacheLock.Unlock()
+		l.flushEvictions(context.Background())
 	}
 }
 
 type batcher[K comparable, V any] struct {
+	loader   *Loader[K, V]
 	input    chan *batchRequest[K, V]
+	activity chan struct{}
+	count    int
 	batchFn  BatchFunc[K, V]
 	finished bool
 	silent   bool
 	tracer   Tracer[K, V]
+
+	// the batch group of the batcher, nil outside of any batch group
+	group *batchGroup
+	// if set, the batch window closes at the deadline at the latest (see
+	// ContextWithWait), protected by the batchLock
+	deadline time.Time
+	// used to close the sleeper of the batcher
+	endSleeper chan bool
+	// when the batch window opened, and why it was dispatched (set by end,
+	// with the batchLock held)
+	opened time.Time
+	reason DispatchReason
+	// when the batch window closes, if known (see WithDeadlineAdmission),
+	// protected by the batchLock
+	closesAt time.Time
+	// the batch capacity when the window opened, since WithAutoTune changes
+	// the capacity of the loader
+	capacity int
+	// the cost of the keys of the batch, and the keys which were charged for
+	// (see WithBatchCostLimit), protected by the batchLock
+	cost    int
+	charged map[K]struct{}
+	// the number of predicted keys added to the batch (see
+	// WithSpeculativePrefetch), protected by the batchLock
+	speculated int
+
+	// requests with a reserved slot which are yet to be sent on the input,
+	// and whether the input is to be closed once they are, protected by mu
+	mu      sync.Mutex
+	senders int
+	closing bool
 }
 
 // newBatcher returns a batcher for the current requests
 // all the batcher methods must be protected by a global batchLock
 func (l *Loader[K, V]) newBatcher(silent bool, tracer Tracer[K, V]) *batcher[K, V] {
 	return &batcher[K, V]{
-		input:   make(chan *batchRequest[K, V], l.inputCap),
-		batchFn: l.batchFn,
-		silent:  silent,
-		tracer:  tracer,
+		loader:   l,
+		input:    make(chan *batchRequest[K, V], l.inputCap),
+		activity: make(chan struct{}, 1),
+		batchFn:  l.batchFn,
+		silent:   silent,
+		tracer:   tracer,
+	}
+}
+
+// touch notifies the sleeper that a new request was queued.
+func (b *batcher[K, V]) touch() {
+	select {
+	case b.activity <- struct{}{}:
+	default:
 	}
 }
 
-// stop receiving input and process batch function
-func (b *batcher[K, V]) end() {
+// stop receiving input and process batch function, for the given reason
+func (b *batcher[K, V]) end(reason DispatchReason) {
 	if !b.finished {
-		close(b.input)
 		b.finished = true
+		b.reason = reason
+		b.mu.Lock()
+		b.closing = true
+		if b.senders == 0 {
+			close(b.input)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// offer queues the request on the input if it isn't full, and reports
+// whether it did. It must be called with the batchLock held.
+func (b *batcher[K, V]) offer(req *batchRequest[K, V]) bool {
+	select {
+	case b.input <- req:
+		return true
+	default:
+		return false
+	}
+}
+
+// reserve reserves a slot for a request to be sent later on.
+// It must be called with the batchLock held.
+func (b *batcher[K, V]) reserve() {
+	b.mu.Lock()
+	b.senders++
+	b.mu.Unlock()
+}
+
+// send sends a request on the input, in the slot reserved for it. The last
+// request sent on a finished batcher closes its input.
+// It must be called without the batchLock held.
+func (b *batcher[K, V]) send(req *batchRequest[K, V]) {
+	b.input <- req
+	b.mu.Lock()
+	b.senders--
+	if b.closing && b.senders == 0 {
+		close(b.input)
+	}
+	b.mu.Unlock()
+}
+
+// spill keeps at most tenantCap requests of each tenant and queues the others
+// on the next batch.
+func (b *batcher[K, V]) spill(ctx context.Context, reqs []*batchRequest[K, V], waiters map[K][]*batchRequest[K, V]) []*batchRequest[K, V] {
+	var (
+		kept    = make([]*batchRequest[K, V], 0, len(reqs))
+		tenants = make(map[string]int)
+	)
+	for _, req := range reqs {
+		tenant := b.loader.tenantOf(req.key)
+		if tenants[tenant] >= b.loader.tenantCap {
+			for _, req := range append([]*batchRequest[K, V]{req}, waiters[req.key]...) {
+				if err := b.loader.enqueue(ctx, req); err != nil {
+					b.loader.fail(ctx, req, err)
+				}
+			}
+			delete(waiters, req.key)
+			continue
+		}
+		tenants[tenant]++
+		kept = append(kept, req)
+	}
+	return kept
+}
+
+// dedupe returns the first request of every key, along with the other
+// requests of these keys. When requests of a key were made in different cache
+// epochs, the request of the newest one is returned first.
+func dedupe[K comparable, V any](reqs []*batchRequest[K, V]) ([]*batchRequest[K, V], map[K][]*batchRequest[K, V]) {
+	var (
+		unique  = make([]*batchRequest[K, V], 0, len(reqs))
+		indexes = make(map[K]int, len(reqs))
+		waiters = make(map[K][]*batchRequest[K, V])
+	)
+	for _, req := range reqs {
+		i, ok := indexes[req.key]
+		if !ok {
+			indexes[req.key] = len(unique)
+			unique = append(unique, req)
+			continue
+		}
+		if req.epoch > unique[i].epoch {
+			req, unique[i] = unique[i], req
+		}
+		waiters[req.key] = append(waiters[req.key], req)
 	}
+	return unique, waiters
 }
 
 // execute the batch of all items in queue
@@ -418,41 +1535,93 @@ func (b *batcher[K, V]) batch(originalContext context.Context) {
 		keys     = make([]K, 0)
 		reqs     = make([]*batchRequest[K, V], 0)
 		items    = make([]*Result[V], 0)
-		panicErr interface{}
+		panicErr *PanicError
 	)
 
+	defer b.loader.untrack()
+
 	for item := range b.input {
-		keys = append(keys, item.key)
 		reqs = append(reqs, item)
 	}
+	b.loader.observeQueue(originalContext, -len(reqs))
+	window := b.loader.clock.Now().Sub(b.opened)
+
+	prioritize(reqs)
+	reqs, waiters := dedupe(reqs)
+	if b.loader.tenantCap > 0 {
+		reqs = b.spill(originalContext, reqs, waiters)
+	}
+	// a window may close without keys (i.e. when its first key was rejected by
+	// the deadline policy), and the batch function is never called without any
+	if len(reqs) == 0 {
+		return
+	}
+	// deliver sends the result of a request to every request of its key
+	deliver := func(req *batchRequest[K, V], result *Result[V]) {
+		if b.loader.inFlight {
+			b.loader.settled(originalContext, req)
+		}
+		for _, req := range append([]*batchRequest[K, V]{req}, waiters[req.key]...) {
+			req.resolve(result)
+		}
+	}
+	for _, req := range reqs {
+		keys = append(keys, req.key)
+	}
 
 	ctx, finish := b.tracer.TraceBatch(originalContext, keys)
-	defer finish(items)
+	// items is only set once the batch function has returned
+	defer func() { finish(items) }()
 
+	started := time.Now()
+	if tuner := b.loader.tuner; tuner != nil {
+		defer func() {
+			tuner.observe(len(keys), b.capacity, time.Since(started))
+		}()
+	}
 	func() {
 		defer func() {
 			if r := recover(); r != nil {
-				panicErr = r
+				panicErr = newPanicError("batch", r)
 				if b.silent {
 					return
 				}
-				const size = 64 << 10
-				buf := make([]byte, size)
-				buf = buf[:runtime.Stack(buf, false)]
-				log.Printf("Dataloader: Panic received in batch function: %v\n%s", panicErr, buf)
+				log.Printf("Dataloader: %v\n%s", panicErr, panicErr.Stack)
 			}
 		}()
-		items = b.batchFn(ctx, keys)
+		batchFn := b.batchFn
+		if memo := b.loader.memo; memo != nil {
+			batchFn = memo.memoized(batchFn, b.loader.clock)
+		}
+		if b.loader.planner != nil {
+			planned := batchFn
+			batchFn = func(ctx context.Context, keys []K) []*Result[V] {
+				return b.loader.planned(ctx, planned, keys)
+			}
+		}
+		if vc := b.loader.valueCache(); vc != nil {
+			batchFn = vc.batched(batchFn, cachedKeys(reqs, waiters), b.loader.lenient)
+		}
+		items = batchFn(ctx, keys)
 	}()
+	latency := time.Since(started)
+	b.observeBatch(originalContext, len(keys), window, latency, items)
+	b.loader.recordUsage(reqs, waiters, latency)
+	if b.loader.deadlinePolicy != DeadlineIgnore {
+		b.loader.latency.observe(time.Since(started))
+	}
 
 	if panicErr != nil {
 		for _, req := range reqs {
-			req.channel <- &Result[V]{Error: &PanicErrorWrapper{panicError: fmt.Errorf("Panic received in batch function: %v", panicErr)}}
-			close(req.channel)
+			deliver(req, &Result[V]{Error: &PanicErrorWrapper{panicError: panicErr}})
 		}
 		return
 	}
 
+	if b.loader.lenient && len(items) <= len(keys) {
+		items = complete(keys, items)
+	}
+
 	if len(items) != len(keys) {
 		err := &Result[V]{Error: fmt.Errorf(`
 			The batch function supplied did not return an array of responses
@@ -466,40 +1635,161 @@ func (b *batcher[K, V]) batch(originalContext context.Context) {
 		`, keys, items)}
 
 		for _, req := range reqs {
-			req.channel <- err
-			close(req.channel)
+			deliver(req, err)
 		}
 
 		return
 	}
 
+	if shadow := b.loader.shadow; shadow != nil && shadow.sampled() {
+		b.loader.batchLock.Lock()
+		b.loader.track()
+		b.loader.batchLock.Unlock()
+		go func() {
+			defer b.loader.untrack()
+			b.loader.shadowed(originalContext, keys, items)
+		}()
+	}
+
+	b.loader.resolved(originalContext, reqs, items, false)
+
 	for i, req := range reqs {
-		req.channel <- items[i]
-		close(req.channel)
+		deliver(req, items[i])
 	}
+
+	b.loader.resolved(originalContext, reqs, items, true)
 }
 
-// wait the appropriate amount of time for the provided batcher
-func (l *Loader[K, V]) sleeper(b *batcher[K, V], close chan bool) {
-	select {
-	// used by batch to close early. usually triggered by max batch size
-	case <-close:
-		return
-	// this will move this goroutine to the back of the callstack?
-	case <-time.After(l.wait):
+// wait the appropriate amount of time for the provided batcher, which is
+// until end unless the window is shortened
+func (l *Loader[K, V]) sleeper(b *batcher[K, V], close chan bool, end time.Time) {
+	timer := l.clock.NewTimer(end.Sub(l.clock.Now()))
+	defer timer.Stop()
+
+	// the idle timer is only armed when WithIdleFlush is used; it is pushed
+	// back every time a new request is queued on the batcher.
+	var (
+		idleTimer Timer
+		idle      <-chan time.Time
+	)
+	if l.idleFlush > 0 {
+		idleTimer = l.clock.NewTimer(l.idleFlush)
+		defer idleTimer.Stop()
+		idle = idleTimer.C()
+	}
+	reason := DispatchWindow
+
+wait:
+	for {
+		select {
+		// used by batch to close early. usually triggered by max batch size
+		case <-close:
+			return
+		case <-timer.C():
+			break wait
+		case <-idle:
+			reason = DispatchIdle
+			break wait
+		case <-b.activity:
+			if idleTimer != nil {
+				idleTimer.Stop()
+				select {
+				case <-idleTimer.C():
+				default:
+				}
+				idleTimer.Reset(l.idleFlush)
+			}
+			// a request may have shortened the window
+			if deadline := l.deadlineOf(b); !deadline.IsZero() && deadline.Before(end) {
+				end = deadline
+				reason = DispatchDeadline
+				timer.Stop()
+				select {
+				case <-timer.C():
+				default:
+				}
+				timer.Reset(end.Sub(l.clock.Now()))
+			}
+		}
+	}
+
+	// give small batches some extra time to fill up
+	if l.minBatchSize > 0 && l.batchSize(b) < l.minBatchSize {
+		extra := l.clock.NewTimer(l.minBatchWait)
+		defer extra.Stop()
+
+	fill:
+		for {
+			select {
+			case <-close:
+				return
+			case <-extra.C():
+				break fill
+			case <-b.activity:
+				if l.batchSize(b) >= l.minBatchSize {
+					break fill
+				}
+			}
+		}
+	}
+
+	l.dispatch(b, reason)
+}
+
+// deadlineOf returns the deadline of the provided batcher, if any.
+func (l *Loader[K, V]) deadlineOf(b *batcher[K, V]) time.Time {
+	l.batchLock.Lock()
+	defer l.batchLock.Unlock()
+	return b.deadline
+}
+
+// batchSize returns the number of requests queued on the provided batcher.
+func (l *Loader[K, V]) batchSize(b *batcher[K, V]) int {
+	l.batchLock.Lock()
+	defer l.batchLock.Unlock()
+	return b.count
+}
+
+// windowDuration returns how long a batch window opened at the given time
+// should stay open.
+func (l *Loader[K, V]) windowDuration(now time.Time) time.Duration {
+	wait := l.wait
+	if l.waitJitter > 0 {
+		wait += time.Duration((rand.Float64()*2 - 1) * l.waitJitter * float64(wait))
+		if wait < 0 {
+			wait = 0
+		}
+	}
+	if l.alignment <= 0 {
+		return wait
+	}
+	end := now.Add(wait)
+	aligned := end.Truncate(l.alignment)
+	if aligned.Before(end) {
+		aligned = aligned.Add(l.alignment)
 	}
+	return aligned.Sub(now)
+}
 
-	// reset
+// dispatch closes the batch window of the provided batcher for the given
+// reason, which triggers its batch function. It is safe to call dispatch more
+// than once.
+func (l *Loader[K, V]) dispatch(b *batcher[K, V], reason DispatchReason) {
 	// this is protected by the batchLock to avoid closing the batcher input
 	// channel while Load is inserting a request
 	l.batchLock.Lock()
-	b.end()
+	b.end(reason)
 
 	// We can end here also if the batcher has already been closed and a
 	// new one has been created. So reset the loader state only if the batcher
 	// is the current one
-	if l.curBatcher == b {
-		l.reset()
+	current := l.current(b.group) == b
+	if current {
+		l.reset(b)
 	}
 	l.batchLock.Unlock()
+
+	if current {
+		l.batched()
+	}
 }
@@ -6,15 +6,56 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 ///////////////////////////////////////////////////
 // Tests
 ///////////////////////////////////////////////////
+
+// the loaders implement Interface
+var (
+	_ Interface[string, string] = &Loader[string, string]{}
+	_ Interface[string, string] = &RolloutLoader[string, string]{}
+	_ Interface[string, string] = &countingLoader{}
+)
+
+// countingLoader decorates an Interface, counting the loaded keys.
+type countingLoader struct {
+	Interface[string, string]
+	loads atomic.Int32
+}
+
+func (c *countingLoader) Load(ctx context.Context, key string) Thunk[string] {
+	c.loads.Add(1)
+	return c.Interface.Load(ctx, key)
+}
+
 func TestLoader(t *testing.T) {
+	t.Run("test Interface can be decorated", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, _ := IDLoader[string](0)
+		var loader Interface[string, string] = &countingLoader{Interface: identityLoader}
+		ctx := context.Background()
+
+		if v, err := loader.Load(ctx, "1")(); v != "1" || err != nil {
+			t.Fatalf("expected 1, got %q, %v", v, err)
+		}
+		loader.Prime(ctx, "2", "primed")
+		if v, _ := loader.Load(ctx, "2")(); v != "primed" {
+			t.Errorf("expected the primed value, got %q", v)
+		}
+		if n := loader.(*countingLoader).loads.Load(); n != 2 {
+			t.Errorf("expected 2 loads, got %d", n)
+		}
+	})
+
 	t.Run("test Load method", func(t *testing.T) {
 		t.Parallel()
 		identityLoader, _ := IDLoader[string](0)
@@ -55,6 +96,24 @@ func TestLoader(t *testing.T) {
 		}
 	})
 
+	t.Run("test Load Method Panic carries a PanicError", func(t *testing.T) {
+		t.Parallel()
+		panicLoader, _ := PanicLoader[string](0)
+		ctx := context.Background()
+		_, err := panicLoader.Load(ctx, "1")()
+
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("expected a *PanicError, got %#v", err)
+		}
+		if panicErr.Value != "Programming error" {
+			t.Errorf("expected the recovered value, got %#v", panicErr.Value)
+		}
+		if !strings.Contains(string(panicErr.Stack), "PanicLoader") {
+			t.Errorf("expected the stack of the batch function, got %s", panicErr.Stack)
+		}
+	})
+
 	t.Run("test Load Method cache error", func(t *testing.T) {
 		t.Parallel()
 		errorCacheLoader, _ := ErrorCacheLoader[string](0)
@@ -113,6 +172,76 @@ func TestLoader(t *testing.T) {
 		}
 	})
 
+	t.Run("expires not found results after their TTL", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var loadCalls [][]string
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			var results []*Result[string]
+			mu.Lock()
+			loadCalls = append(loadCalls, keys)
+			mu.Unlock()
+			for _, key := range keys {
+				if key == "missing" {
+					results = append(results, NotFound[string]())
+					continue
+				}
+				results = append(results, &Result[string]{key, nil})
+			}
+			return results
+		}, WithNotFoundTTL[string, string](20*time.Millisecond))
+		ctx := context.Background()
+
+		future1 := loader.Load(ctx, "missing")
+		future2 := loader.Load(ctx, "1")
+		if _, err := future1(); !IsNotFound(err) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		if _, err := future2(); err != nil {
+			t.Error(err.Error())
+		}
+
+		// not found results are cached until their TTL
+		if _, err := loader.Load(ctx, "missing")(); !IsNotFound(err) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if _, found := loader.cache.Get(ctx, "missing"); found {
+			t.Error("not found result did not expire")
+		}
+		if _, found := loader.cache.Get(ctx, "1"); !found {
+			t.Error("found result should not expire")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		expected := [][]string{{"missing", "1"}}
+		if !reflect.DeepEqual(loadCalls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, loadCalls)
+		}
+	})
+
+	t.Run("doesn't expire the cached key of a not found result bypassing the cache", func(t *testing.T) {
+		t.Parallel()
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			results := make([]*Result[string], len(keys))
+			for i := range keys {
+				results[i] = NotFound[string]()
+			}
+			return results
+		}, WithNotFoundTTL[string, string](10*time.Millisecond))
+		ctx := context.Background()
+
+		loader.Prime(ctx, "1", "primed")
+		if _, err := loader.LoadWith(ctx, "1", SkipCache())(); !IsNotFound(err) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		time.Sleep(30 * time.Millisecond)
+		if v, err := loader.Load(ctx, "1")(); v != "primed" || err != nil {
+			t.Errorf("expected the primed value to be kept, got %q, %v", v, err)
+		}
+	})
+
 	t.Run("test LoadMany returns errors", func(t *testing.T) {
 		t.Parallel()
 		errorLoader, _ := ErrorLoader[string](0)
@@ -152,58 +281,1754 @@ func TestLoader(t *testing.T) {
 		}
 	})
 
-	t.Run("test LoadMany returns nil []error when no errors occurred", func(t *testing.T) {
+	t.Run("test JoinKeyed aggregates LoadMany errors", func(t *testing.T) {
+		t.Parallel()
+		loader, _ := OneErrorLoader[string](3)
+		ctx := context.Background()
+		keys := []string{"1", "2", "3"}
+		_, errs := loader.LoadMany(ctx, keys)()
+
+		err := JoinKeyed(keys, errs)
+		var keyed *KeyedError[string]
+		if !errors.As(err, &keyed) {
+			t.Fatalf("expected a *KeyedError, got %#v", err)
+		}
+		if keys[keyed.Index] != keyed.Key || keyed.Err != errs[keyed.Index] {
+			t.Errorf("unexpected keyed error %#v", keyed)
+		}
+		if !errors.Is(err, errs[keyed.Index]) {
+			t.Error("expected the aggregate to wrap the error of the key")
+		}
+
+		if err := JoinKeyed(keys, nil); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("test ErrorsOf associates LoadMany errors with their keys", func(t *testing.T) {
+		t.Parallel()
+		loader, _ := OneErrorLoader[string](3)
+		ctx := context.Background()
+		keys := []string{"1", "2", "3", "1"}
+		_, errs := loader.LoadMany(ctx, keys)()
+
+		e := ErrorsOf(keys, errs)
+		if !e.Any() || e.First() == nil {
+			t.Fatal("expected an error")
+		}
+		failed := 0
+		for _, key := range keys[:3] {
+			if err := e.ByKey(key); err != nil {
+				failed++
+				if !errors.Is(e, err) {
+					t.Errorf("expected the slice to wrap the error of %s", key)
+				}
+			}
+		}
+		if failed != 1 {
+			t.Errorf("expected one failed key, got %d", failed)
+		}
+
+		if e := ErrorsOf(keys, nil); e.Any() || e.ByKey("1") != nil || e.Unwrap() != nil {
+			t.Errorf("expected no error, got %v", e)
+		}
+	})
+
+	t.Run("test LoadMany returns nil []error when no errors occurred", func(t *testing.T) {
+		t.Parallel()
+		loader, _ := IDLoader[string](0)
+		ctx := context.Background()
+		_, err := loader.LoadMany(ctx, []string{"1", "2", "3"})()
+		if err != nil {
+			t.Errorf("Expected LoadMany() to return nil error slice when no errors occurred")
+		}
+	})
+
+	t.Run("test thunkmany does not contain race conditions", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, _ := IDLoader[string](0)
+		ctx := context.Background()
+		future := identityLoader.LoadMany(ctx, []string{"1", "2", "3"})
+		go future()
+		go future()
+	})
+
+	t.Run("test Load Many Method Panic Safety", func(t *testing.T) {
+		t.Parallel()
+		defer func() {
+			r := recover()
+			if r != nil {
+				t.Error("Panic Loader's panic should have been handled'")
+			}
+		}()
+		panicLoader, _ := PanicCacheLoader[string](0)
+		ctx := context.Background()
+		future := panicLoader.LoadMany(ctx, []string{"1", "2"})
+		_, errs := future()
+		if len(errs) < 2 || errs[0].Error() != "Panic received in batch function: Programming error" {
+			t.Error("Panic was not propagated as an error.")
+		}
+
+		future = panicLoader.LoadMany(ctx, []string{"1"})
+		_, errs = future()
+
+		if len(errs) > 0 {
+			t.Error("Panic error from batch function was cached")
+		}
+
+	})
+
+	t.Run("test LoadMany method", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, _ := IDLoader[string](0)
+		ctx := context.Background()
+		future := identityLoader.LoadMany(ctx, []string{"1", "2", "3"})
+		results, _ := future()
+		if results[0] != "1" || results[1] != "2" || results[2] != "3" {
+			t.Error("loadmany didn't return the right value")
+		}
+	})
+
+	t.Run("test LoadMap returns the results by key", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := OneErrorLoader[string](3)
+		ctx := context.Background()
+
+		results := loader.LoadMap(ctx, []string{"1", "2", "1", "3"})()
+		if len(results) != 3 {
+			t.Fatalf("expected a result per key, got %v", results)
+		}
+		failed := 0
+		for key, r := range results {
+			if r.Error != nil {
+				failed++
+			} else if r.Data != key {
+				t.Errorf("expected %s, got %q", key, r.Data)
+			}
+		}
+		if failed != 1 || len(*loadCalls) != 1 {
+			t.Errorf("expected a single batch with one failed key, got %d failed of %v", failed, *loadCalls)
+		}
+	})
+
+	t.Run("test LoadManyIter yields results as they resolve", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](2)
+		ctx := context.Background()
+
+		results := map[string]string{}
+		for key, result := range identityLoader.LoadManyIter(ctx, []string{"1", "2", "3"}) {
+			if result.Error != nil {
+				t.Error(result.Error.Error())
+			}
+			results[key] = result.Data
+		}
+		expected := map[string]string{"1": "1", "2": "2", "3": "3"}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, results)
+		}
+		if calls := *loadCalls; len(calls) != 2 {
+			t.Errorf("expected 2 batches, got %#v", calls)
+		}
+
+		// iteration can stop early
+		for range identityLoader.LoadManyIter(ctx, []string{"4", "5"}) {
+			break
+		}
+	})
+
+	t.Run("test Watch re-delivers fresh values", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var version int
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			mu.Lock()
+			version++
+			v := version
+			mu.Unlock()
+			var results []*Result[string]
+			for _, key := range keys {
+				results = append(results, &Result[string]{Data: fmt.Sprintf("%s@%d", key, v)})
+			}
+			return results
+		}, WithWait[string, string](time.Millisecond))
+		ctx, cancel := context.WithCancel(context.Background())
+
+		values := loader.Watch(ctx, "1")
+		receive := func(expected string) {
+			t.Helper()
+			select {
+			case value := <-values:
+				if value != expected {
+					t.Errorf("expected %s, got %s", expected, value)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("expected %s to be delivered", expected)
+			}
+		}
+		receive("1@1")
+
+		// invalidation reloads the key
+		loader.Clear(ctx, "1")
+		receive("1@2")
+
+		// priming a cleared key delivers the primed value
+		loader.forget(ctx, "1")
+		loader.Prime(ctx, "1", "primed")
+		receive("primed")
+
+		// other keys aren't delivered
+		loader.Load(ctx, "2")()
+		loader.ClearAll()
+		receive("1@4")
+
+		cancel()
+		select {
+		case _, ok := <-values:
+			if ok {
+				t.Error("expected no more values")
+			}
+		case <-time.After(time.Second):
+			t.Error("expected the channel to be closed")
+		}
+	})
+
+	t.Run("test PrimeError caches the error", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		ctx := context.Background()
+		denied := errors.New("permission denied")
+
+		identityLoader.PrimeError(ctx, "A", denied)
+		identityLoader.Prime(ctx, "B", "B")
+		identityLoader.PrimeError(ctx, "B", denied)
+
+		if _, err := identityLoader.Load(ctx, "A")(); !errors.Is(err, denied) {
+			t.Errorf("expected the primed error, got %v", err)
+		}
+		if v, err := identityLoader.Load(ctx, "B")(); v != "B" || err != nil {
+			t.Errorf("expected the cached value to be kept, got %q, %v", v, err)
+		}
+		if len(*loadCalls) != 0 {
+			t.Errorf("expected no batch, got %v", *loadCalls)
+		}
+	})
+
+	t.Run("test ForcePrime replaces the cached value", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		ctx := context.Background()
+
+		if v, err := identityLoader.Load(ctx, "A")(); v != "A" || err != nil {
+			t.Fatalf("expected A, got %q, %v", v, err)
+		}
+		identityLoader.ForcePrime(ctx, "A", "updated")
+		identityLoader.ForcePrime(ctx, "B", "primed")
+
+		if v, err := identityLoader.Load(ctx, "A")(); v != "updated" || err != nil {
+			t.Errorf("expected the replaced value, got %q, %v", v, err)
+		}
+		if v, err := identityLoader.Load(ctx, "B")(); v != "primed" || err != nil {
+			t.Errorf("expected the primed value, got %q, %v", v, err)
+		}
+		if expected := [][]string{{"A"}}; !reflect.DeepEqual(*loadCalls, expected) {
+			t.Errorf("expected %#v, got %#v", expected, *loadCalls)
+		}
+	})
+
+	t.Run("test ForcePrime versions the primed value", func(t *testing.T) {
+		t.Parallel()
+		type record struct {
+			Name    string
+			Version int64
+		}
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[record] {
+			var results []*Result[record]
+			for _, key := range keys {
+				results = append(results, &Result[record]{Data: record{Name: key, Version: 1}})
+			}
+			return results
+		}, WithVersion[string, record](func(r record) int64 { return r.Version }))
+		ctx := context.Background()
+
+		if _, err := loader.Load(ctx, "a")(); err != nil {
+			t.Fatal(err)
+		}
+		loader.ForcePrime(ctx, "a", record{Name: "updated", Version: 3})
+		if loader.PrimeIfNewer(ctx, "a", record{Name: "stale", Version: 2}, 2) {
+			t.Error("expected the version of the forced value to be tracked")
+		}
+		if r, _ := loader.Load(ctx, "a")(); r.Name != "updated" {
+			t.Errorf("expected the forced value to be cached, got %#v", r)
+		}
+	})
+
+	t.Run("test PrimeIfNewer ignores stale values", func(t *testing.T) {
+		t.Parallel()
+		type record struct {
+			Name    string
+			Version int64
+		}
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[record] {
+			var results []*Result[record]
+			for _, key := range keys {
+				results = append(results, &Result[record]{Data: record{Name: key, Version: 2}})
+			}
+			return results
+		}, WithVersion[string, record](func(r record) int64 { return r.Version }))
+		ctx := context.Background()
+
+		if _, err := loader.Load(ctx, "a")(); err != nil {
+			t.Fatal(err)
+		}
+		if loader.PrimeIfNewer(ctx, "a", record{Name: "stale", Version: 1}, 1) {
+			t.Error("expected a stale value not to be primed")
+		}
+		if !loader.PrimeIfNewer(ctx, "a", record{Name: "fresh", Version: 3}, 3) {
+			t.Error("expected a newer value to be primed")
+		}
+		if r, _ := loader.Load(ctx, "a")(); r.Name != "fresh" {
+			t.Errorf("expected the fresh value to be cached, got %#v", r)
+		}
+
+		// concurrent primes leave the newest value in the cache
+		var wg sync.WaitGroup
+		for i := int64(1); i <= 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				loader.PrimeIfNewer(ctx, "b", record{Name: "b", Version: i}, i)
+			}()
+		}
+		wg.Wait()
+		if r, _ := loader.Load(ctx, "b")(); r.Version != 50 {
+			t.Errorf("expected the newest value to be cached, got %#v", r)
+		}
+
+		// clearing forgets the version
+		loader.Clear(ctx, "b")
+		if !loader.PrimeIfNewer(ctx, "b", record{Name: "b", Version: 1}, 1) {
+			t.Error("expected a cleared key to be primed")
+		}
+	})
+
+	t.Run("test ClearAll during an active batch", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		var mu sync.Mutex
+		var calls int
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			mu.Lock()
+			calls++
+			first := calls == 1
+			mu.Unlock()
+			if first {
+				<-release
+				panic("stale batch")
+			}
+			var results []*Result[string]
+			for _, key := range keys {
+				results = append(results, &Result[string]{Data: key})
+			}
+			return results
+		}, WithWait[string, string](time.Millisecond), withSilentLogger[string, string]())
+		ctx := context.Background()
+
+		stale := loader.Load(ctx, "1")
+		time.Sleep(10 * time.Millisecond)
+		loader.ClearAll()
+
+		if v, err := loader.Load(ctx, "1")(); err != nil || v != "1" {
+			t.Fatalf("expected the new epoch to load 1, got %q, %v", v, err)
+		}
+
+		close(release)
+		if _, err := stale(); err == nil {
+			t.Error("expected the stale batch to fail")
+		}
+
+		// the failure of the stale batch doesn't clear the key of the new epoch
+		loader.Load(ctx, "1")()
+		mu.Lock()
+		defer mu.Unlock()
+		if calls != 2 {
+			t.Errorf("expected 2 batches, got %d", calls)
+		}
+	})
+
+	t.Run("test WithLenientResults only fails the missing keys", func(t *testing.T) {
+		t.Parallel()
+		badLoader, _ := BadLoader[string](0)
+		WithLenientResults[string, string]()(badLoader)
+		ctx := context.Background()
+
+		// keys are queued in order, so that only the first one gets a result
+		thunks := []Thunk[string]{
+			badLoader.Load(ctx, "1"),
+			badLoader.Load(ctx, "2"),
+			badLoader.Load(ctx, "3"),
+		}
+		if value, err := thunks[0](); err != nil || value != "1" {
+			t.Errorf("expected the first key to resolve, got %q, %v", value, err)
+		}
+		for i, key := range []string{"2", "3"} {
+			var missing *ErrMissingResult[string]
+			if _, err := thunks[i+1](); !errors.As(err, &missing) || missing.Key != key {
+				t.Errorf("expected a missing result error for %s, got %v", key, err)
+			}
+		}
+	})
+
+	t.Run("test WithValueCache stores resolved results", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		cache := NewDataCache[string, string]()
+		WithValueCache[string, string](cache)(identityLoader)
+		ctx := context.Background()
+
+		identityLoader.Prime(ctx, "A", "primed")
+		future1 := identityLoader.Load(ctx, "1")
+		future2 := identityLoader.Load(ctx, "1")
+		if _, err := future1(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := future2(); err != nil {
+			t.Fatal(err)
+		}
+
+		// results are stored once their thunk has resolved
+		deadline := time.Now().Add(time.Second)
+		for {
+			_, ok1 := cache.Get(ctx, "1")
+			_, okA := cache.Get(ctx, "A")
+			if ok1 && okA {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("expected the results to be stored in the data cache")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if result, _ := cache.Get(ctx, "1"); result.Data != "1" {
+			t.Errorf("expected 1 to be cached, got %#v", result)
+		}
+
+		// stored results are served without calling the batch function
+		if value, err := identityLoader.Load(ctx, "A")(); err != nil || value != "primed" {
+			t.Errorf("expected the primed value, got %q, %v", value, err)
+		}
+		identityLoader.Load(ctx, "1")()
+		if calls := *loadCalls; len(calls) != 1 {
+			t.Errorf("expected 1 batch, got %#v", calls)
+		}
+
+		identityLoader.Clear(ctx, "1")
+		if _, ok := cache.Get(ctx, "1"); ok {
+			t.Error("expected the cleared key to be deleted from the data cache")
+		}
+	})
+
+	t.Run("test WithValueCache reads the data cache outside of Load", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		release := make(chan struct{})
+		cache := &slowDataCache[string, string]{DataCache: NewDataCache[string, string](), release: release}
+		cache.Set(context.Background(), "1", &Result[string]{Data: "stored"})
+		WithValueCache[string, string](cache)(identityLoader)
+		ctx := context.Background()
+
+		// the loads don't wait for the data cache
+		loaded := make(chan Thunk[string])
+		go func() {
+			loaded <- identityLoader.Load(ctx, "1")
+			loaded <- identityLoader.Load(ctx, "2")
+		}()
+		var thunks []Thunk[string]
+		for i := 0; i < 2; i++ {
+			select {
+			case thunk := <-loaded:
+				thunks = append(thunks, thunk)
+			case <-time.After(time.Second):
+				t.Fatal("expected Load not to wait for the data cache")
+			}
+		}
+
+		close(release)
+		for i, expected := range []string{"stored", "2"} {
+			if v, err := thunks[i](); v != expected || err != nil {
+				t.Errorf("expected %s, got %q, %v", expected, v, err)
+			}
+		}
+		if calls, expected := *loadCalls, [][]string{{"2"}}; !reflect.DeepEqual(calls, expected) {
+			t.Errorf("expected only the missing key to be loaded, got %#v", calls)
+		}
+	})
+
+	t.Run("test WithValueCache gets and sets the keys of a batch at once", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		cache := &manyDataCache[string, string]{DataCache: NewDataCache[string, string]()}
+		cache.Set(context.Background(), "1", &Result[string]{Data: "stored"})
+		WithValueCache[string, string](cache)(identityLoader)
+		ctx := context.Background()
+
+		values, errs := identityLoader.LoadMany(ctx, []string{"1", "2", "3"})()
+		if errs != nil || !reflect.DeepEqual(values, []string{"stored", "2", "3"}) {
+			t.Fatalf("expected the stored and loaded values, got %#v, %v", values, errs)
+		}
+		if calls := *loadCalls; len(calls) != 1 || len(calls[0]) != 2 {
+			t.Errorf("expected the missing keys to be loaded, got %#v", calls)
+		}
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		if len(cache.gets) != 1 || len(cache.gets[0]) != 3 {
+			t.Errorf("expected a single GetMany, got %#v", cache.gets)
+		}
+		if len(cache.sets) != 1 || len(cache.sets[0]) != 2 {
+			t.Errorf("expected the loaded keys to be set at once, got %#v", cache.sets)
+		}
+	})
+
+	t.Run("stress capacity-triggered dispatch", func(t *testing.T) {
+		t.Parallel()
+		for name, opts := range map[string][]Option[string, string]{
+			"unbuffered input":   {WithInputCapacity[string, string](0)},
+			"small input":        {WithInputCapacity[string, string](2)},
+			"retry overflow":     {WithInputCapacity[string, string](2), WithOverflowPolicy[string, string](OverflowRetry)},
+			"clear cache":        {WithInputCapacity[string, string](1), WithClearCacheOnBatch[string, string]()},
+			"immediate dispatch": {WithInputCapacity[string, string](1), WithImmediateDispatch[string, string]()},
+		} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				identityLoader, _ := IDLoader[string](3)
+				for _, opt := range opts {
+					opt(identityLoader)
+				}
+				WithWait[string, string](time.Millisecond)(identityLoader)
+				ctx := context.Background()
+
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					var wg sync.WaitGroup
+					for i := 0; i < 50; i++ {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							for j := 0; j < 20; j++ {
+								key := strconv.Itoa((i * j) % 37)
+								if value, err := identityLoader.Load(ctx, key)(); err != nil || value != key {
+									t.Errorf("expected %s, got %q, %v", key, value, err)
+								}
+								if j%5 == 0 {
+									identityLoader.Clear(ctx, key)
+								}
+							}
+						}()
+					}
+					wg.Wait()
+				}()
+
+				select {
+				case <-done:
+				case <-time.After(10 * time.Second):
+					t.Fatal("loads did not complete, the loader is deadlocked")
+				}
+			})
+		}
+	})
+
+	t.Run("test nested loads from within batch functions", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		entityLoader, _ := IDLoader[string](2)
+
+		// the children of "n" are "n0" and "n1", down to three levels
+		var treeLoader *Loader[string, string]
+		treeLoader = NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+			var results []*Result[string]
+			for _, key := range keys {
+				entity, err := entityLoader.Load(ctx, key)()
+				if err != nil {
+					results = append(results, &Result[string]{Error: err})
+					continue
+				}
+				if len(key) < 3 {
+					children, errs := treeLoader.LoadMany(ctx, []string{key + "0", key + "1"})()
+					if len(errs) > 0 {
+						results = append(results, &Result[string]{Error: errs[0]})
+						continue
+					}
+					entity += "(" + children[0] + "," + children[1] + ")"
+				}
+				results = append(results, &Result[string]{Data: entity})
+			}
+			return results
+		}, WithBatchCapacity[string, string](2), WithWait[string, string](time.Millisecond))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			value, err := treeLoader.Load(ctx, "r")()
+			if err != nil {
+				t.Error(err)
+			}
+			if expected := "r(r0(r00,r01),r1(r10,r11))"; value != expected {
+				t.Errorf("expected %s, got %s", expected, value)
+			}
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("nested loads deadlocked")
+		}
+	})
+
+	t.Run("test WithBatchGroup isolates batch windows", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		ctx := context.Background()
+		background := WithBatchGroup(ctx)
+		interactive := WithBatchGroup(ctx)
+
+		future1 := identityLoader.Load(background, "1")
+		future2 := identityLoader.Load(interactive, "2")
+		future3 := identityLoader.Load(background, "3")
+		for _, future := range []Thunk[string]{future1, future2, future3} {
+			if _, err := future(); err != nil {
+				t.Error(err.Error())
+			}
+		}
+
+		// the cache is shared by the batch groups
+		if _, err := identityLoader.Load(interactive, "1")(); err != nil {
+			t.Error(err.Error())
+		}
+
+		calls := *loadCalls
+		if len(calls) != 2 {
+			t.Fatalf("expected a batch per group, got %#v", calls)
+		}
+		for _, call := range calls {
+			if !reflect.DeepEqual(call, []string{"1", "3"}) && !reflect.DeepEqual(call, []string{"2"}) {
+				t.Errorf("unexpected batch %#v", call)
+			}
+		}
+	})
+
+	t.Run("test thunk watchdog and timeout", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		var mu sync.Mutex
+		var calls int
+		var stuck []string
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			mu.Lock()
+			calls++
+			first := calls == 1
+			mu.Unlock()
+			if first {
+				<-release
+			}
+			var results []*Result[string]
+			for _, key := range keys {
+				results = append(results, &Result[string]{Data: key})
+			}
+			return results
+		},
+			WithWait[string, string](time.Millisecond),
+			WithThunkWatchdog[string, string](10*time.Millisecond, func(key string, _ time.Duration) {
+				mu.Lock()
+				stuck = append(stuck, key)
+				mu.Unlock()
+			}),
+			WithThunkTimeout[string, string](50*time.Millisecond),
+		)
+		ctx := context.Background()
+
+		if _, err := loader.Load(ctx, "1")(); !errors.Is(err, ErrThunkTimeout) {
+			t.Errorf("expected ErrThunkTimeout, got %v", err)
+		}
+		close(release)
+
+		// the key which timed out is loaded again
+		if value, err := loader.Load(ctx, "1")(); err != nil || value != "1" {
+			t.Errorf("expected 1, got %q, %v", value, err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !reflect.DeepEqual(stuck, []string{"1"}) {
+			t.Errorf("expected the stuck key to be reported, got %#v", stuck)
+		}
+		if calls != 2 {
+			t.Errorf("expected 2 batches, got %d", calls)
+		}
+	})
+
+	t.Run("test WithFailFastOnCanceledContext", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithFailFastOnCanceledContext[string, string]()(identityLoader)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := identityLoader.Load(ctx, "1")(); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if _, found := identityLoader.cache.Get(ctx, "1"); found {
+			t.Error("expected the canceled load not to be cached")
+		}
+
+		if value, err := identityLoader.Load(context.Background(), "1")(); err != nil || value != "1" {
+			t.Errorf("expected 1, got %q, %v", value, err)
+		}
+		if calls := *loadCalls; !reflect.DeepEqual(calls, [][]string{{"1"}}) {
+			t.Errorf("expected the canceled load not to be queued, got %#v", calls)
+		}
+	})
+
+	t.Run("test WithResultTransform clones cached values", func(t *testing.T) {
+		t.Parallel()
+		type user struct{ Name string }
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[*user] {
+			var results []*Result[*user]
+			for _, key := range keys {
+				results = append(results, &Result[*user]{Data: &user{Name: key}})
+			}
+			return results
+		}, WithResultTransform(func(_ context.Context, _ string, u *user) *user {
+			clone := *u
+			return &clone
+		}))
+		ctx := context.Background()
+
+		u, err := loader.Load(ctx, "alice")()
+		if err != nil {
+			t.Fatal(err)
+		}
+		u.Name = "mallory"
+
+		if u, _ := loader.Load(ctx, "alice")(); u.Name != "alice" {
+			t.Errorf("expected the cached value to be left untouched, got %#v", u)
+		}
+	})
+
+	t.Run("test WithMutationDetection reports mutated cached values", func(t *testing.T) {
+		t.Parallel()
+		type user struct {
+			Name string
+			Tags []string
+		}
+		var mutated []string
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[*user] {
+			var results []*Result[*user]
+			for _, key := range keys {
+				results = append(results, &Result[*user]{Data: &user{Name: key, Tags: []string{"a"}}})
+			}
+			return results
+		}, WithMutationDetection[string, *user](func(key string) {
+			mutated = append(mutated, key)
+		}))
+		ctx := context.Background()
+
+		u, err := loader.Load(ctx, "alice")()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loader.Load(ctx, "alice")(); err != nil || len(mutated) != 0 {
+			t.Fatalf("expected no mutation, got %#v", mutated)
+		}
+
+		u.Tags[0] = "b"
+		loader.Load(ctx, "alice")()
+		if !reflect.DeepEqual(mutated, []string{"alice"}) {
+			t.Errorf("expected the mutation of alice to be reported, got %#v", mutated)
+		}
+
+		loader.Prime(ctx, "bob", &user{Name: "bob"})
+		loader.Load(ctx, "bob")()
+		if len(mutated) != 1 {
+			t.Errorf("expected a primed value not to be reported, got %#v", mutated)
+		}
+
+		loader.PrimeIfNewer(ctx, "alice", &user{Name: "alice", Tags: []string{"c"}}, 1)
+		loader.Load(ctx, "alice")()
+		if len(mutated) != 1 {
+			t.Errorf("expected a value primed if newer not to be reported, got %#v", mutated)
+		}
+	})
+
+	t.Run("test WithMutationDetection ignores the loads bypassing the cache", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		var mutated []string
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[[]int] {
+			n := int(calls.Add(1))
+			results := make([]*Result[[]int], len(keys))
+			for i := range keys {
+				results[i] = &Result[[]int]{Data: []int{n}}
+			}
+			return results
+		}, WithMutationDetection[string, []int](func(key string) {
+			mutated = append(mutated, key)
+		}))
+		ctx := context.Background()
+
+		if _, err := loader.Load(ctx, "1")(); err != nil {
+			t.Fatal(err)
+		}
+		// the bypassed load gets a fresher value than the cached one
+		if v, err := loader.LoadWith(ctx, "1", SkipCache())(); err != nil || v[0] != 2 {
+			t.Fatalf("expected the fresh value, got %v, %v", v, err)
+		}
+		if v, err := loader.Load(ctx, "1")(); err != nil || v[0] != 1 {
+			t.Fatalf("expected the cached value, got %v, %v", v, err)
+		}
+		if len(mutated) != 0 {
+			t.Errorf("expected no mutation to be reported, got %#v", mutated)
+		}
+	})
+
+	t.Run("test WithSpeculativePrefetch adds predicted keys to the batch", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var calls [][]string
+		loader := NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+			mu.Lock()
+			calls = append(calls, keys)
+			mu.Unlock()
+			return batchIdentity[string](ctx, keys)
+		}, WithSpeculativePrefetch[string, string](func(_ context.Context, key string) []string {
+			return []string{key + "1", key + "2", key + "3"}
+		}, 2))
+		ctx := context.Background()
+
+		if v, err := loader.Load(ctx, "a")(); v != "a" || err != nil {
+			t.Fatalf("expected a, got %q, %v", v, err)
+		}
+		if v, _ := loader.Load(ctx, "a1")(); v != "a1" {
+			t.Errorf("expected a1, got %q", v)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !reflect.DeepEqual(calls, [][]string{{"a", "a1", "a2"}}) {
+			t.Errorf("expected the predicted keys within the budget to be batched, got %v", calls)
+		}
+	})
+
+	t.Run("test WithAutoTune adjusts the wait and the capacity", func(t *testing.T) {
+		t.Parallel()
+		loader := NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+			time.Sleep(20 * time.Millisecond)
+			return batchIdentity[string](ctx, keys)
+		},
+			WithBatchCapacity[string, string](2),
+			WithAutoTune[string, string](TuneCapacity(2, 8), TuneEvery(3)),
+		)
+		if _, ok := loader.Tuning(); !ok {
+			t.Fatal("expected the loader to be auto tuned")
+		}
+
+		keys := make([]string, 12)
+		for i := range keys {
+			keys[i] = strconv.Itoa(i)
+		}
+		if _, errs := loader.LoadMany(context.Background(), keys)(); errs != nil {
+			t.Fatal(errs)
+		}
+
+		report, _ := loader.Tuning()
+		if report.Adjustments == 0 {
+			t.Fatalf("expected the settings to be adjusted, got %+v", report)
+		}
+		if report.BatchCapacity <= 2 {
+			t.Errorf("expected the capacity of full batches to grow, got %+v", report)
+		}
+		if report.Wait >= 16*time.Millisecond {
+			t.Errorf("expected the wait to move towards a quarter of the latency, got %+v", report)
+		}
+		if report.Latency < 20*time.Millisecond || report.FillRatio < 0.9 {
+			t.Errorf("unexpected observations %+v", report)
+		}
+
+		if _, ok := NewBatchedLoader(batchIdentity[string]).Tuning(); ok {
+			t.Error("expected a loader without WithAutoTune not to be auto tuned")
+		}
+	})
+
+	t.Run("test WithBatchCostLimit dispatches batches by cost", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var calls [][]string
+		loader := NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+			mu.Lock()
+			calls = append(calls, keys)
+			mu.Unlock()
+			return batchIdentity[string](ctx, keys)
+		},
+			WithWait[string, string](50*time.Millisecond),
+			WithCache[string, string](&NoCache[string, string]{}),
+			WithBatchCostLimit[string, string](5, func(key string) int { return len(key) }),
+		)
+		ctx := context.Background()
+
+		var thunks []Thunk[string]
+		for _, key := range []string{"aa", "bb", "aa", "ccc", "d", "eeeeee"} {
+			thunks = append(thunks, loader.Load(ctx, key))
+		}
+		for _, thunk := range thunks {
+			if _, err := thunk(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		sort.Slice(calls, func(i, j int) bool { return calls[i][0] < calls[j][0] })
+		want := [][]string{{"aa", "bb"}, {"ccc", "d"}, {"eeeeee"}}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("expected batches within the cost limit %v, got %v", want, calls)
+		}
+	})
+
+	t.Run("test WithBatchPlanner splits batches into planned calls", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var calls [][]string
+		loader := NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+			mu.Lock()
+			calls = append(calls, keys)
+			mu.Unlock()
+			if keys[0] == "panic" {
+				panic("boom")
+			}
+			return batchIdentity[string](ctx, keys)
+		},
+			WithWait[string, string](10*time.Millisecond),
+			WithBatchPlanner[string, string](func(_ context.Context, keys []string) [][]string {
+				// group the keys by their first letter, and leave "dropped" out
+				var plan [][]string
+				groups := make(map[byte]int)
+				for _, key := range keys {
+					if key == "dropped" {
+						continue
+					}
+					i, ok := groups[key[0]]
+					if !ok {
+						i = len(plan)
+						groups[key[0]] = i
+						plan = append(plan, nil)
+					}
+					plan[i] = append(plan[i], key)
+				}
+				return plan
+			}),
+		)
+		loader.silent = true
+		ctx := context.Background()
+
+		keys := []string{"a1", "b1", "a2", "dropped", "panic"}
+		var thunks []Thunk[string]
+		for _, key := range keys {
+			thunks = append(thunks, loader.Load(ctx, key))
+		}
+		for i, key := range keys[:3] {
+			if v, err := thunks[i](); v != key || err != nil {
+				t.Errorf("expected %s, got %q, %v", key, v, err)
+			}
+		}
+		if _, err := thunks[3](); err == nil || !strings.Contains(err.Error(), "left out") {
+			t.Errorf("expected the dropped key to fail, got %v", err)
+		}
+		var panicErr *PanicErrorWrapper
+		if _, err := thunks[4](); !errors.As(err, &panicErr) {
+			t.Errorf("expected the key of the panicking call to fail, got %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		sort.Slice(calls, func(i, j int) bool { return calls[i][0] < calls[j][0] })
+		want := [][]string{{"a1", "a2"}, {"b1"}, {"panic"}}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("expected the planned calls %v, got %v", want, calls)
+		}
+	})
+
+	t.Run("test WithShadowBatch compares the shadow results", func(t *testing.T) {
+		t.Parallel()
+		type divergence struct {
+			key             string
+			primary, shadow string
+		}
+		divergences := make(chan divergence, 10)
+		loader := NewBatchedLoader(batchIdentity[string],
+			WithShadowBatch(func(ctx context.Context, keys []string) []*Result[string] {
+				results := batchIdentity[string](ctx, keys)
+				for i, key := range keys {
+					if key == "1" {
+						results[i] = &Result[string]{Data: "stale"}
+					}
+				}
+				return results
+			}, func(key string, primary, shadow *Result[string]) {
+				if primary.Data != shadow.Data {
+					divergences <- divergence{key, primary.Data, shadow.Data}
+				}
+			}),
+		)
+		ctx := context.Background()
+
+		// the primary results are always served
+		thunks := loader.LoadMany(ctx, []string{"1", "2"})
+		values, errs := thunks()
+		if errs != nil || !reflect.DeepEqual(values, []string{"1", "2"}) {
+			t.Fatalf("expected the primary results, got %v, %v", values, errs)
+		}
+
+		select {
+		case d := <-divergences:
+			if want := (divergence{"1", "1", "stale"}); d != want {
+				t.Errorf("expected the divergence %v, got %v", want, d)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the divergence to be reported")
+		}
+		select {
+		case d := <-divergences:
+			t.Errorf("expected a single divergence, got %v", d)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		// no batch is sampled with a zero rate
+		var calls atomic.Int32
+		unsampled := NewBatchedLoader(batchIdentity[string],
+			WithShadowBatch(func(ctx context.Context, keys []string) []*Result[string] {
+				calls.Add(1)
+				return batchIdentity[string](ctx, keys)
+			}, func(string, *Result[string], *Result[string]) {}),
+			WithShadowSampleRate[string, string](0),
+		)
+		if _, err := unsampled.Load(ctx, "1")(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if n := calls.Load(); n != 0 {
+			t.Errorf("expected no shadow batch, got %d", n)
+		}
+	})
+
+	t.Run("test UpdateOptions updates a running loader", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithWait[string, string](time.Minute)(identityLoader)
+		ctx := context.Background()
+
+		if err := identityLoader.UpdateOptions(UpdateWait(-1), UpdateBatchCapacity(2)); err == nil {
+			t.Fatal("expected the invalid wait to be reported")
+		}
+		if err := identityLoader.UpdateOptions(UpdateWait(time.Millisecond), UpdateBatchCapacity(2)); err != nil {
+			t.Fatal(err)
+		}
+		if _, errs := identityLoader.LoadMany(ctx, []string{"1", "2", "3"})(); errs != nil {
+			t.Fatal(errs)
+		}
+		if calls := *loadCalls; len(calls) != 2 || len(calls[0])+len(calls[1]) != 3 {
+			t.Errorf("expected the keys in two batches of at most 2, got %v", calls)
+		}
+
+		// the concurrency limit is replaced for the following batches
+		var running, peak atomic.Int32
+		fetchLoader := NewLoaderFunc(func(_ context.Context, key string) (string, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(5 * time.Millisecond)
+			return key, nil
+		}, WithConcurrencyLimit[string, string](10))
+		if err := fetchLoader.UpdateOptions(UpdateConcurrencyLimit(1)); err != nil {
+			t.Fatal(err)
+		}
+		if _, errs := fetchLoader.LoadMany(ctx, []string{"1", "2", "3", "4"})(); errs != nil {
+			t.Fatal(errs)
+		}
+		if p := peak.Load(); p != 1 {
+			t.Errorf("expected a key fetched at a time, got %d", p)
+		}
+	})
+
+	t.Run("test InMemoryCache with and without a sync.Map", func(t *testing.T) {
+		t.Parallel()
+		for _, cache := range []*InMemoryCache[string, string]{NewCache[string, string](), NewCache[string, string](WithSyncMap())} {
+			identityLoader, loadCalls := IDLoader[string](0)
+			WithCache[string, string](cache)(identityLoader)
+			ctx := context.Background()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if v, err := identityLoader.Load(ctx, "1")(); v != "1" || err != nil {
+						t.Errorf("expected 1, got %q, %v", v, err)
+					}
+				}()
+			}
+			wg.Wait()
+			if _, ok := cache.Get(ctx, "1"); !ok {
+				t.Error("expected the key to be cached")
+			}
+			if !cache.Delete(ctx, "1") || cache.Delete(ctx, "1") {
+				t.Error("expected the key to be deleted once")
+			}
+			identityLoader.Prime(ctx, "2", "2")
+			cache.Clear()
+			if _, ok := cache.Get(ctx, "2"); ok {
+				t.Error("expected the cache to be cleared")
+			}
+			if calls := *loadCalls; len(calls) != 1 {
+				t.Errorf("expected a single batch, got %v", calls)
+			}
+		}
+	})
+
+	t.Run("test OptionSet builds the options without type parameters", func(t *testing.T) {
+		t.Parallel()
+		base := OptionsFor(batchIdentity[string]).Name("users").Wait(time.Millisecond)
+		small := base.BatchCapacity(1)
+		large := base.BatchCapacity(10).ClearCacheOnBatch()
+		if len(base) != 2 || len(small) != 3 || len(large) != 4 {
+			t.Fatalf("expected the sets not to share their options, got %d, %d and %d options", len(base), len(small), len(large))
+		}
+
+		loader := NewBatchedLoader(batchIdentity[string], small...)
+		if loader.Name() != "users" || loader.wait != time.Millisecond || loader.batchCap != 1 {
+			t.Errorf("expected the options to be applied, got %q, %v and %d", loader.Name(), loader.wait, loader.batchCap)
+		}
+		if loader := NewBatchedLoader(batchIdentity[string], large...); loader.batchCap != 10 || !loader.clearCacheOnBatch {
+			t.Errorf("expected the options to be applied, got %d and %v", loader.batchCap, loader.clearCacheOnBatch)
+		}
+	})
+
+	t.Run("test WithManualDispatch only dispatches on Dispatch", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithManualDispatch[string, string]()(identityLoader)
+		ctx := context.Background()
+
+		first := identityLoader.Load(ctx, "1")
+		second := identityLoader.Load(ctx, "2")
+		time.Sleep(20 * time.Millisecond)
+		if calls := *loadCalls; len(calls) != 0 {
+			t.Fatalf("expected no batch before Dispatch, got %v", calls)
+		}
+
+		identityLoader.Dispatch()
+		if v, err := first(); v != "1" || err != nil {
+			t.Errorf("expected 1, got %q, %v", v, err)
+		}
+		if v, err := second(); v != "2" || err != nil {
+			t.Errorf("expected 2, got %q, %v", v, err)
+		}
+		if calls := *loadCalls; len(calls) != 1 || len(calls[0]) != 2 {
+			t.Errorf("expected a single batch of both keys, got %v", calls)
+		}
+		// nothing is pending anymore
+		identityLoader.Dispatch()
+	})
+
+	t.Run("test Dispatch closes the batch windows right away", func(t *testing.T) {
+		t.Parallel()
+		key := NewLoaderKey(func(context.Context) *Loader[string, string] {
+			return NewBatchedLoader(batchIdentity[string], WithWait[string, string](time.Hour))
+		})
+		ctx, registry := ContextWithLoaderRegistry(context.Background())
+
+		thunk := key.From(ctx).Load(ctx, "1")
+		registry.Dispatch()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if v, err := thunk(); v != "1" || err != nil {
+				t.Errorf("expected 1, got %q, %v", v, err)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected the batch to be dispatched")
+		}
+	})
+
+	t.Run("test Poll dispatches the keys of the messages at once", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithManualDispatch[string, string]()(identityLoader)
+		ctx := context.Background()
+
+		var mu sync.Mutex
+		var processed []string
+		process := func(ctx context.Context, key string) func() error {
+			thunk := identityLoader.Load(ctx, key)
+			return func() error {
+				v, err := thunk()
+				mu.Lock()
+				processed = append(processed, v)
+				mu.Unlock()
+				return err
+			}
+		}
+
+		errs := Poll(ctx, []string{"1", "2", "1"}, process, identityLoader)
+		if len(errs) != 3 || errs[0] != nil || errs[1] != nil || errs[2] != nil {
+			t.Errorf("expected no error, got %v", errs)
+		}
+		if len(processed) != 3 {
+			t.Errorf("expected every message to be processed, got %v", processed)
+		}
+		Poll(ctx, []string{"1"}, process, identityLoader)
+		if calls := *loadCalls; len(calls) != 2 || len(calls[0]) != 2 || len(calls[1]) != 1 {
+			t.Errorf("expected a batch per poll, with the cache reset in between, got %v", calls)
+		}
+	})
+
+	t.Run("test Drain waits for the batches to finish", func(t *testing.T) {
+		t.Parallel()
+		var finished atomic.Int32
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			time.Sleep(20 * time.Millisecond)
+			finished.Add(1)
+			return batchIdentity[string](context.Background(), keys)
+		}, WithWait[string, string](time.Hour))
+		ctx := context.Background()
+
+		if err := loader.Drain(ctx); err != nil {
+			t.Fatalf("expected an idle loader to be drained, got %v", err)
+		}
+		thunk := loader.Load(ctx, "1")
+		if err := loader.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if finished.Load() != 1 {
+			t.Error("expected the batch to be finished")
+		}
+		if v, err := thunk(); v != "1" || err != nil {
+			t.Errorf("expected 1, got %q, %v", v, err)
+		}
+
+		loader.Load(ctx, "2")
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if err := loader.Drain(canceled); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the error of the context, got %v", err)
+		}
+	})
+
+	t.Run("test Invoke drains the loaders once the handler returns", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithWait[string, string](time.Hour)(identityLoader)
+		ctx := context.Background()
+
+		var thunk Thunk[string]
+		fail := errors.New("the handler failed")
+		err := Invoke(ctx, func(ctx context.Context) error {
+			thunk = identityLoader.Load(ctx, "1")
+			return fail
+		}, identityLoader)
+		if !errors.Is(err, fail) {
+			t.Errorf("expected the error of the handler, got %v", err)
+		}
+		if calls := *loadCalls; len(calls) != 1 {
+			t.Errorf("expected the batch to be finished, got %v", calls)
+		}
+		if v, err := thunk(); v != "1" || err != nil {
+			t.Errorf("expected 1, got %q, %v", v, err)
+		}
+	})
+
+	t.Run("test ContextWithUsage reports the usage of the loaders", func(t *testing.T) {
+		t.Parallel()
+		users := NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+			time.Sleep(5 * time.Millisecond)
+			return batchIdentity[string](ctx, keys)
+		}, WithName[string, string]("users"))
+		posts := NewBatchedLoader(batchIdentity[int])
+		ctx, usage := ContextWithUsage(context.Background())
+
+		if _, errs := users.LoadMany(ctx, []string{"1", "2", "1"})(); errs != nil {
+			t.Fatal(errs)
+		}
+		if v, err := users.Load(ctx, "2")(); v != "2" || err != nil {
+			t.Fatalf("expected 2, got %q, %v", v, err)
+		}
+		posts.Load(ctx, 1)()
+		// the loads made without the context are not reported
+		users.Load(context.Background(), "3")()
+
+		report := usage.Report()
+		if len(report) != 2 {
+			t.Fatalf("expected the usage of both loaders, got %+v", report)
+		}
+		u := report[1]
+		if u.Name != "users" || u.Loads != 4 || u.UniqueKeys != 2 || u.CacheHits != 2 || u.Batches != 1 || u.BackendTime < 5*time.Millisecond {
+			t.Errorf("unexpected usage of users: %+v", u)
+		}
+		if u.HitRatio() != 0.5 {
+			t.Errorf("expected a hit ratio of 0.5, got %v", u.HitRatio())
+		}
+		if p := report[0]; p.Name != "*dataloader.Loader[int,int]" || p.Loads != 1 || p.Batches != 1 {
+			t.Errorf("unexpected usage of posts: %+v", p)
+		}
+		if _, ok := usage.Extensions()["dataloader"]; !ok {
+			t.Error("expected the report in the extensions")
+		}
+	})
+
+	t.Run("test LoaderRegistry holds the loaders of a request", func(t *testing.T) {
+		t.Parallel()
+		var constructed atomic.Int32
+		key := NewLoaderKey(func(context.Context) *Loader[string, string] {
+			constructed.Add(1)
+			return NewBatchedLoader(batchIdentity[string], WithWait[string, string](time.Hour))
+		})
+
+		ctx, registry := ContextWithLoaderRegistry(context.Background())
+		loader := key.From(ctx)
+		if key.From(ctx) != loader || constructed.Load() != 1 {
+			t.Fatal("expected the loader to be constructed once per registry")
+		}
+		thunk := loader.Load(ctx, "1")
+		if err := registry.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := thunk(); v != "1" || err != nil {
+			t.Errorf("expected 1, got %q, %v", v, err)
+		}
+
+		if err := registry.Reset(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if key.From(ctx) == loader {
+			t.Error("expected a new loader once the registry is reset")
+		}
+		if key.From(context.Background()) == key.From(context.Background()) {
+			t.Error("expected a new loader on every lookup without a registry")
+		}
+	})
+
+	t.Run("test WithFlagger toggles the cache and the batching per request", func(t *testing.T) {
+		t.Parallel()
+		type flagsKey struct{}
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithName[string, string]("users")(identityLoader)
+		WithWait[string, string](time.Hour)(identityLoader)
+		WithFlagger[string, string](FlaggerFunc(func(ctx context.Context, loader string, flag Flag, def bool) bool {
+			if off, ok := ctx.Value(flagsKey{}).(Flag); ok && off == flag && loader == "users" {
+				return false
+			}
+			return def
+		}))(identityLoader)
+		ctx := context.Background()
+
+		// the batch window of an hour is skipped
+		if v, err := identityLoader.Load(context.WithValue(ctx, flagsKey{}, FlagBatching), "1")(); v != "1" || err != nil {
+			t.Errorf("expected 1, got %q, %v", v, err)
+		}
+		noCache := context.WithValue(ctx, flagsKey{}, FlagCache)
+		thunk := identityLoader.Load(noCache, "1")
+		identityLoader.Dispatch()
+		if v, err := thunk(); v != "1" || err != nil {
+			t.Errorf("expected 1, got %q, %v", v, err)
+		}
+		if calls := *loadCalls; len(calls) != 2 {
+			t.Errorf("expected the key to be loaded again without the cache, got %v", calls)
+		}
+	})
+
+	t.Run("test WithBatchMemo memoizes the results of identical batches", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		loader := NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+			calls.Add(1)
+			results := batchIdentity[string](ctx, keys)
+			for i, key := range keys {
+				if key == "error" {
+					results[i] = &Result[string]{Error: errors.New("boom")}
+				}
+			}
+			return results
+		}, WithCache[string, string](&NoCache[string, string]{}), WithBatchMemo[string, string](time.Hour, 10))
+		ctx := context.Background()
+
+		for _, keys := range [][]string{{"1", "2"}, {"2", "1"}, {"1"}, {"error", "1"}, {"1", "error"}} {
+			values, _ := loader.LoadMany(ctx, keys)()
+			for i, key := range keys {
+				if key != "error" && values[i] != key {
+					t.Errorf("expected %s, got %s", key, values[i])
+				}
+			}
+		}
+		// the batches of the same keys in another order are memoized, but
+		// not the batches with an error
+		if n := calls.Load(); n != 4 {
+			t.Errorf("expected 4 calls of the batch function, got %d", n)
+		}
+	})
+
+	t.Run("test WithCacheMaxAge rotates the cache", func(t *testing.T) {
+		t.Parallel()
+		var down atomic.Bool
+		var calls atomic.Int32
+		loader := NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+			calls.Add(1)
+			if down.Load() {
+				results := make([]*Result[string], len(keys))
+				for i := range keys {
+					results[i] = &Result[string]{Error: errors.New("the backend is down")}
+				}
+				return results
+			}
+			return batchIdentity[string](ctx, keys)
+		}, WithCacheMaxAge[string, string](20*time.Millisecond), WithStaleFallback[string, string]())
+		ctx := context.Background()
+
+		loader.Load(ctx, "1")()
+		loader.Load(ctx, "1")()
+		if n := calls.Load(); n != 1 {
+			t.Fatalf("expected the key to be cached, got %d calls", n)
+		}
+
+		time.Sleep(30 * time.Millisecond)
+		down.Store(true)
+		if v, err := loader.Load(ctx, "1")(); v != "1" || err != nil {
+			t.Errorf("expected the stale value, got %q, %v", v, err)
+		}
+		if n := calls.Load(); n != 2 {
+			t.Errorf("expected the key to be loaded again once rotated, got %d calls", n)
+		}
+		if _, err := loader.Load(ctx, "2")(); err == nil {
+			t.Error("expected the error of a key without a stale value")
+		}
+	})
+
+	t.Run("test WithDeadlineAdmission dispatches or fails the keys which can't wait", func(t *testing.T) {
+		t.Parallel()
+		newLoader := func(p DeadlinePolicy) *Loader[string, string] {
+			return NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+				time.Sleep(20 * time.Millisecond)
+				return batchIdentity[string](ctx, keys)
+			}, WithWait[string, string](time.Hour), WithDeadlineAdmission[string, string](p))
+		}
+		ctx := context.Background()
+
+		loader := newLoader(DeadlineDispatch)
+		short, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		if v, err := loader.Load(short, "1")(); v != "1" || err != nil {
+			t.Errorf("expected the batch to be dispatched right away, got %q, %v", v, err)
+		}
+		// the key without a deadline waits for the end of the window
+		loader.Load(ctx, "2")
+		if err := loader.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		loader = newLoader(DeadlineFailFast)
+		loader.Load(short, "1")()
+		tooShort, cancel := context.WithTimeout(ctx, time.Millisecond)
+		defer cancel()
+		if _, err := loader.Load(tooShort, "2")(); !errors.Is(err, ErrDeadlineUnreachable) {
+			t.Errorf("expected ErrDeadlineUnreachable once the latency is known, got %v", err)
+		}
+	})
+
+	t.Run("test WithDeadlineAdmission doesn't batch the windows of the failed keys", func(t *testing.T) {
+		t.Parallel()
+		var empty atomic.Int32
+		loader := NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+			if len(keys) == 0 {
+				empty.Add(1)
+			}
+			time.Sleep(20 * time.Millisecond)
+			return batchIdentity[string](ctx, keys)
+		}, WithWait[string, string](5*time.Millisecond), WithDeadlineAdmission[string, string](DeadlineFailFast))
+		ctx := context.Background()
+
+		loader.Load(ctx, "1")()
+		tooShort, cancel := context.WithTimeout(ctx, time.Millisecond)
+		defer cancel()
+		// the key opens a window, and is rejected
+		if _, err := loader.Load(tooShort, "2")(); !errors.Is(err, ErrDeadlineUnreachable) {
+			t.Fatalf("expected ErrDeadlineUnreachable, got %v", err)
+		}
+		if err := loader.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if n := empty.Load(); n != 0 {
+			t.Errorf("expected the batch function never to be called without keys, got %d calls", n)
+		}
+	})
+
+	t.Run("test WithInFlightDedupe only dedupes the pending loads", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithInFlightDedupe[string, string]()(identityLoader)
+		ctx := context.Background()
+
+		first, second := identityLoader.Load(ctx, "1"), identityLoader.Load(ctx, "1")
+		if v, err := first(); v != "1" || err != nil {
+			t.Fatalf("expected 1, got %q, %v", v, err)
+		}
+		second()
+		if v, err := identityLoader.Load(ctx, "1")(); v != "1" || err != nil {
+			t.Fatalf("expected 1, got %q, %v", v, err)
+		}
+
+		if want := [][]string{{"1"}, {"1"}}; !reflect.DeepEqual(*loadCalls, want) {
+			t.Errorf("expected the key to be loaded again once resolved, got %v", *loadCalls)
+		}
+	})
+
+	t.Run("test WithBatchContext runs the batches with the given context", func(t *testing.T) {
+		t.Parallel()
+		type key struct{}
+		base := context.WithValue(context.Background(), key{}, "base")
+		loader := NewBatchedLoader(func(ctx context.Context, keys []string) []*Result[string] {
+			results := make([]*Result[string], len(keys))
+			for i := range keys {
+				v, _ := ctx.Value(key{}).(string)
+				results[i] = &Result[string]{Data: v, Error: ctx.Err()}
+			}
+			return results
+		}, WithBatchContext[string, string](func() context.Context { return base }))
+
+		// the context of the first load is neither seen nor able to cancel
+		// the batch
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "caller"))
+		thunk := loader.Load(ctx, "1")
+		cancel()
+		if v, err := thunk(); v != "base" || err != nil {
+			t.Errorf("expected the value of the batch context, got %q, %v", v, err)
+		}
+	})
+
+	t.Run("test Close drains the loader and fails the following loads", func(t *testing.T) {
+		t.Parallel()
+		loader := NewBatchedLoader(batchIdentity[string], WithWait[string, string](time.Hour))
+		ctx := context.Background()
+
+		pending := loader.Load(ctx, "1")
+		if err := loader.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := pending(); v != "1" || err != nil {
+			t.Errorf("expected the pending load to resolve, got %q, %v", v, err)
+		}
+		for _, key := range []string{"1", "2"} {
+			if _, err := loader.Load(ctx, key)(); !errors.Is(err, ErrLoaderClosed) {
+				t.Errorf("expected ErrLoaderClosed for %s, got %v", key, err)
+			}
+		}
+		if err := loader.Close(ctx); err != nil {
+			t.Errorf("expected closing twice to be harmless, got %v", err)
+		}
+	})
+
+	t.Run("test NewBatchedLoaderE validates the configuration", func(t *testing.T) {
+		t.Parallel()
+		loader, err := NewBatchedLoaderE(batchIdentity[string], WithBatchCapacity[string, string](10))
+		if err != nil || loader == nil {
+			t.Fatalf("expected a valid configuration, got %v", err)
+		}
+
+		_, err = NewBatchedLoaderE[string, string](nil,
+			WithBatchCapacity[string, string](-1),
+			WithCache[string, string](&NoCache[string, string]{}),
+			WithClearCacheOnBatch[string, string](),
+		)
+		if err == nil {
+			t.Fatal("expected an invalid configuration")
+		}
+		for _, problem := range []string{"batch function is nil", "batch capacity is negative", "WithClearCacheOnBatch"} {
+			if !strings.Contains(err.Error(), problem) {
+				t.Errorf("expected %q to be reported, got %v", problem, err)
+			}
+		}
+	})
+
+	t.Run("test per-context option overrides", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithWait[string, string](time.Minute)(identityLoader)
+		ctx := context.Background()
+
+		// the wait override shortens the window
+		start := time.Now()
+		if _, err := identityLoader.Load(ContextWithWait(ctx, 5*time.Millisecond), "1")(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := identityLoader.Load(ContextWithWait(ctx, 0), "2")(); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("expected the wait override to be honored, waited %v", elapsed)
+		}
+
+		// skipped keys are loaded again
+		if _, err := identityLoader.Load(ContextWithWait(ContextWithSkipCache(ctx), 0), "1")(); err != nil {
+			t.Fatal(err)
+		}
+
+		// keys of higher priority come first
+		low := identityLoader.Load(ctx, "low")
+		high := identityLoader.Load(ContextWithPriority(ctx, 1), "high")
+		identityLoader.Load(ContextWithWait(ctx, 0), "default")
+		low()
+		high()
+
+		calls := *loadCalls
+		expected := [][]string{{"1"}, {"2"}, {"1"}, {"high", "low", "default"}}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("test per-call load options", func(t *testing.T) {
+		t.Parallel()
+		var fetches atomic.Int32
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			n := fetches.Add(1)
+			results := make([]*Result[string], len(keys))
+			for i, key := range keys {
+				results[i] = &Result[string]{Data: fmt.Sprintf("%s@%d", key, n)}
+			}
+			return results
+		}, WithWait[string, string](time.Millisecond))
+		ctx := context.Background()
+
+		load := func(opts ...LoadOption) string {
+			v, err := loader.LoadWith(ctx, "1", opts...)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		}
+		if v := load(); v != "1@1" {
+			t.Fatalf("expected 1@1, got %s", v)
+		}
+		// the skipped load leaves the cached value to the others
+		if v := load(SkipCache()); v != "1@2" {
+			t.Errorf("expected the skipped load to fetch the key, got %s", v)
+		}
+		if v := load(); v != "1@1" {
+			t.Errorf("expected the cached value, got %s", v)
+		}
+		// the refreshed value replaces the cached one
+		if v := load(ForceRefresh()); v != "1@3" {
+			t.Errorf("expected the refreshed load to fetch the key, got %s", v)
+		}
+		if v := load(); v != "1@3" {
+			t.Errorf("expected the refreshed value to be cached, got %s", v)
+		}
+
+		// the priority of the call overrides the one of the context
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithWait[string, string](time.Minute)(identityLoader)
+		low := identityLoader.LoadWith(ContextWithPriority(ctx, 1), "low", Priority(-1))
+		high := identityLoader.LoadWith(ctx, "high", Priority(2))
+		identityLoader.Load(ContextWithWait(ctx, 0), "default")
+		low()
+		high()
+		if expected := [][]string{{"high", "default", "low"}}; !reflect.DeepEqual(*loadCalls, expected) {
+			t.Errorf("expected %#v, got %#v", expected, *loadCalls)
+		}
+	})
+
+	t.Run("test ForceRefresh resets the state of the key", func(t *testing.T) {
 		t.Parallel()
-		loader, _ := IDLoader[string](0)
+		type record struct {
+			Name    string
+			Version int64
+		}
+		var (
+			fetches atomic.Int64
+			mu      sync.Mutex
+			evicted []record
+		)
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[record] {
+			n := fetches.Add(1)
+			results := make([]*Result[record], len(keys))
+			for i, key := range keys {
+				results[i] = &Result[record]{Data: record{Name: key, Version: n}}
+			}
+			return results
+		}, WithVersion[string, record](func(r record) int64 { return r.Version }),
+			WithOnEvict[string, record](func(_ context.Context, _ string, r record, reason EvictReason) {
+				if reason == EvictCleared {
+					mu.Lock()
+					evicted = append(evicted, r)
+					mu.Unlock()
+				}
+			}))
 		ctx := context.Background()
-		_, err := loader.LoadMany(ctx, []string{"1", "2", "3"})()
-		if err != nil {
-			t.Errorf("Expected LoadMany() to return nil error slice when no errors occurred")
+
+		if _, err := loader.Load(ctx, "a")(); err != nil {
+			t.Fatal(err)
+		}
+		if r, err := loader.LoadWith(ctx, "a", ForceRefresh())(); r.Version != 2 || err != nil {
+			t.Fatalf("expected the refreshed value, got %#v, %v", r, err)
+		}
+		// the refreshed value has its own version
+		if loader.PrimeIfNewer(ctx, "a", record{Name: "stale", Version: 2}, 2) {
+			t.Error("expected the version of the refreshed value to be tracked")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(evicted) != 1 || evicted[0].Version != 1 {
+			t.Errorf("expected the replaced value to be evicted, got %#v", evicted)
 		}
 	})
 
-	t.Run("test thunkmany does not contain race conditions", func(t *testing.T) {
+	t.Run("test LoadSeq pulls keys a window at a time", func(t *testing.T) {
 		t.Parallel()
-		identityLoader, _ := IDLoader[string](0)
+		identityLoader, loadCalls := IDLoader[string](2)
 		ctx := context.Background()
-		future := identityLoader.LoadMany(ctx, []string{"1", "2", "3"})
-		go future()
-		go future()
-	})
 
-	t.Run("test Load Many Method Panic Safety", func(t *testing.T) {
-		t.Parallel()
-		defer func() {
-			r := recover()
-			if r != nil {
-				t.Error("Panic Loader's panic should have been handled'")
+		var pulled int
+		keys := func(yield func(string) bool) {
+			for i := 0; i < 5; i++ {
+				pulled++
+				if !yield(strconv.Itoa(i)) {
+					return
+				}
 			}
-		}()
-		panicLoader, _ := PanicCacheLoader[string](0)
-		ctx := context.Background()
-		future := panicLoader.LoadMany(ctx, []string{"1", "2"})
-		_, errs := future()
-		if len(errs) < 2 || errs[0].Error() != "Panic received in batch function: Programming error" {
-			t.Error("Panic was not propagated as an error.")
 		}
 
-		future = panicLoader.LoadMany(ctx, []string{"1"})
-		_, errs = future()
-
-		if len(errs) > 0 {
-			t.Error("Panic error from batch function was cached")
+		var results []string
+		for key, result := range identityLoader.LoadSeq(ctx, keys) {
+			if result.Error != nil || result.Data != key {
+				t.Errorf("expected %s, got %#v", key, result)
+			}
+			results = append(results, key)
+		}
+		if expected := []string{"0", "1", "2", "3", "4"}; !reflect.DeepEqual(results, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, results)
+		}
+		if calls := *loadCalls; len(calls) != 3 {
+			t.Errorf("expected 3 batches, got %#v", calls)
 		}
 
-	})
-
-	t.Run("test LoadMany method", func(t *testing.T) {
-		t.Parallel()
-		identityLoader, _ := IDLoader[string](0)
-		ctx := context.Background()
-		future := identityLoader.LoadMany(ctx, []string{"1", "2", "3"})
-		results, _ := future()
-		if results[0] != "1" || results[1] != "2" || results[2] != "3" {
-			t.Error("loadmany didn't return the right value")
+		// stopping early leaves the rest of the sequence unpulled
+		pulled = 0
+		for range identityLoader.LoadSeq(ctx, keys) {
+			break
+		}
+		if pulled != 2 {
+			t.Errorf("expected a single window to be pulled, got %d keys", pulled)
 		}
 	})
 
@@ -285,6 +2110,343 @@ func TestLoader(t *testing.T) {
 		}
 	})
 
+	t.Run("idle flush dispatches before the wait elapses", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var loadCalls [][]string
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			var results []*Result[string]
+			mu.Lock()
+			loadCalls = append(loadCalls, keys)
+			mu.Unlock()
+			for _, key := range keys {
+				results = append(results, &Result[string]{key, nil})
+			}
+			return results
+		}, WithWait[string, string](time.Minute), WithIdleFlush[string, string](10*time.Millisecond))
+
+		ctx := context.Background()
+		start := time.Now()
+		future1 := loader.Load(ctx, "1")
+		future2 := loader.Load(ctx, "2")
+
+		if _, err := future1(); err != nil {
+			t.Error(err.Error())
+		}
+		if _, err := future2(); err != nil {
+			t.Error(err.Error())
+		}
+
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("idle flush did not trigger the batch, took %v", elapsed)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		expected := [][]string{{"1", "2"}}
+		if !reflect.DeepEqual(loadCalls, expected) {
+			t.Errorf("did not batch queries. Expected %#v, got %#v", expected, loadCalls)
+		}
+	})
+
+	t.Run("aligns batch windows to the clock", func(t *testing.T) {
+		t.Parallel()
+		loader := NewBatchedLoader(batchIdentity[string], WithWait[string, string](0), WithWindowAlignment[string, string](time.Hour))
+
+		now := time.Date(2020, 1, 1, 10, 20, 0, 0, time.UTC)
+		if d := loader.windowDuration(now); d != 40*time.Minute {
+			t.Errorf("expected window to end on the next boundary, got %v", d)
+		}
+		loader.wait = 40 * time.Minute
+		if d := loader.windowDuration(now); d != 40*time.Minute {
+			t.Errorf("expected window to end on the boundary, got %v", d)
+		}
+		loader.wait = 41 * time.Minute
+		if d := loader.windowDuration(now); d != 100*time.Minute {
+			t.Errorf("expected window to end on the boundary after the wait, got %v", d)
+		}
+	})
+
+	t.Run("randomizes batch windows", func(t *testing.T) {
+		t.Parallel()
+		loader := NewBatchedLoader(batchIdentity[string], WithWait[string, string](100*time.Millisecond), WithWaitJitter[string, string](0.2))
+
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 100; i++ {
+			d := loader.windowDuration(time.Now())
+			if d < 80*time.Millisecond || d > 120*time.Millisecond {
+				t.Fatalf("window duration %v is out of the jitter bounds", d)
+			}
+			seen[d] = true
+		}
+		if len(seen) < 2 {
+			t.Error("window duration was not randomized")
+		}
+	})
+
+	t.Run("randomizes TTLs", func(t *testing.T) {
+		t.Parallel()
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 100; i++ {
+			d := JitterTTL(10*time.Minute, 0.1)
+			if d < 9*time.Minute || d > 11*time.Minute {
+				t.Fatalf("TTL %v is out of the jitter bounds", d)
+			}
+			seen[d] = true
+		}
+		if len(seen) < 2 {
+			t.Error("TTL was not randomized")
+		}
+		if d := JitterTTL(time.Minute, 0); d != time.Minute {
+			t.Errorf("expected the TTL to be kept without jitter, got %v", d)
+		}
+
+		// the jittered not found TTL still expires the key
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			return []*Result[string]{NotFound[string]()}
+		}, WithNotFoundTTL[string, string](10*time.Millisecond), WithTTLJitter[string, string](0.5))
+		ctx := context.Background()
+		if _, err := loader.Load(ctx, "missing")(); !IsNotFound(err) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if _, found := loader.cache.Get(ctx, "missing"); found {
+			t.Error("not found result did not expire")
+		}
+	})
+
+	t.Run("small batches wait for more keys", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithWait[string, string](time.Millisecond)(identityLoader)
+		WithMinBatchSize[string, string](3, time.Minute)(identityLoader)
+		ctx := context.Background()
+
+		future1 := identityLoader.Load(ctx, "1")
+		time.Sleep(20 * time.Millisecond)
+		future2 := identityLoader.Load(ctx, "2")
+		future3 := identityLoader.Load(ctx, "3")
+
+		for _, future := range []Thunk[string]{future1, future2, future3} {
+			if _, err := future(); err != nil {
+				t.Error(err.Error())
+			}
+		}
+
+		calls := *loadCalls
+		expected := [][]string{{"1", "2", "3"}}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("did not wait for the batch to fill. Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("spills keys over the tenant cap to the next batch", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithTenantCap[string, string](func(key string) string { return key[:1] }, 2)(identityLoader)
+		ctx := context.Background()
+
+		keys := []string{"a1", "a2", "a3", "a4", "a5", "b1"}
+		results, errs := identityLoader.LoadMany(ctx, keys)()
+		if errs != nil {
+			t.Fatalf("unexpected errors %v", errs)
+		}
+		if !reflect.DeepEqual(results, keys) {
+			t.Errorf("expected %#v, got %#v", keys, results)
+		}
+
+		calls := *loadCalls
+		if len(calls) != 3 {
+			t.Fatalf("expected 3 batches, got %#v", calls)
+		}
+		for _, call := range calls {
+			tenants := map[string]int{}
+			for _, key := range call {
+				tenants[key[:1]]++
+			}
+			if tenants["a"] > 2 || tenants["b"] > 2 {
+				t.Errorf("batch %#v exceeds the tenant cap", call)
+			}
+		}
+		if len(calls[0]) != 3 {
+			t.Errorf("expected the first batch to hold both tenants, got %#v", calls[0])
+		}
+	})
+
+	t.Run("enforces the per context key budget", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithKeyBudget[string, string](2)(identityLoader)
+		ctx := ContextWithKeyBudget(context.Background())
+
+		future1 := identityLoader.Load(ctx, "1")
+		// the contexts derived from the scope share its budget
+		type requestKey struct{}
+		derived, cancel := context.WithTimeout(context.WithValue(ctx, requestKey{}, true), time.Minute)
+		defer cancel()
+		future2 := identityLoader.Load(derived, "2")
+		future3 := identityLoader.Load(derived, "3")
+
+		if _, err := future1(); err != nil {
+			t.Error(err.Error())
+		}
+		if _, err := future2(); err != nil {
+			t.Error(err.Error())
+		}
+		if _, err := future3(); !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("expected ErrBudgetExceeded, got %v", err)
+		}
+		if _, err := identityLoader.Load(ctx, "1")(); err != nil {
+			t.Errorf("cache hits should not count against the budget, got %v", err)
+		}
+
+		if _, err := identityLoader.Load(ContextWithKeyBudget(context.Background()), "3")(); err != nil {
+			t.Errorf("budget should be tracked per scope, got %v", err)
+		}
+		if _, err := identityLoader.Load(context.Background(), "4")(); err != nil {
+			t.Errorf("loads outside of any scope should not be limited, got %v", err)
+		}
+
+		calls := *loadCalls
+		expected := [][]string{{"1", "2"}, {"3"}, {"4"}}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("applies the overflow policy when the input queue is full", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		// stall returns a loader whose current batcher holds a single
+		// request and is not being drained yet.
+		stall := func(p OverflowPolicy) (*Loader[string, string], *batcher[string, string]) {
+			loader, _ := IDLoader[string](0)
+			WithInputCapacity[string, string](1)(loader)
+			WithOverflowPolicy[string, string](p)(loader)
+			loader.curBatcher = loader.newBatcher(true, loader.tracer)
+			loader.curBatcher.endSleeper = make(chan bool)
+			loader.curBatcher.input <- &batchRequest[string, string]{key: "0", channel: make(chan *Result[string], 1)}
+			return loader, loader.curBatcher
+		}
+
+		failing, stalled := stall(OverflowFail)
+		if _, err := failing.Load(ctx, "1")(); !errors.Is(err, ErrInputQueueFull) {
+			t.Errorf("expected ErrInputQueueFull, got %v", err)
+		}
+		if _, found := failing.cache.Get(ctx, "1"); found {
+			t.Error("failed key should not be cached")
+		}
+		go stalled.batch(ctx)
+
+		retrying, stalled := stall(OverflowRetry)
+		if v, err := retrying.Load(ctx, "1")(); err != nil || v != "1" {
+			t.Errorf("expected key to be loaded on the next batch, got %q, %v", v, err)
+		}
+		if !stalled.finished {
+			t.Error("expected full batch to be dispatched")
+		}
+		go stalled.batch(ctx)
+	})
+
+	t.Run("immediate dispatch disables batching", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithImmediateDispatch[string, string]()(identityLoader)
+		ctx := context.Background()
+
+		future1 := identityLoader.Load(ctx, "1")
+		future2 := identityLoader.Load(ctx, "2")
+		future3 := identityLoader.Load(ctx, "1")
+		for _, future := range []Thunk[string]{future1, future2, future3} {
+			if _, err := future(); err != nil {
+				t.Error(err.Error())
+			}
+		}
+
+		identityLoader.SetImmediateDispatch(false)
+		future4 := identityLoader.Load(ctx, "3")
+		future5 := identityLoader.Load(ctx, "4")
+		for _, future := range []Thunk[string]{future4, future5} {
+			if _, err := future(); err != nil {
+				t.Error(err.Error())
+			}
+		}
+
+		calls := *loadCalls
+		if len(calls) != 3 || len(calls[0]) != 1 || len(calls[1]) != 1 || !reflect.DeepEqual(calls[2], []string{"3", "4"}) {
+			t.Errorf("expected one batch per key, then batching again, got %#v", calls)
+		}
+	})
+
+	t.Run("prefetches the keys likely to be loaded next", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithPrefetch[string, string](func(_ context.Context, v string) []string {
+			if len(v) > 1 {
+				return nil
+			}
+			return []string{v + "a", v + "b"}
+		})(identityLoader)
+		ctx := context.Background()
+
+		if _, err := identityLoader.Load(ctx, "1")(); err != nil {
+			t.Error(err.Error())
+		}
+		// the keys are prefetched once the value has been delivered
+		deadline := time.Now().Add(time.Second)
+		for {
+			identityLoader.cacheLock.Lock()
+			_, found := identityLoader.cache.Get(ctx, "1b")
+			identityLoader.cacheLock.Unlock()
+			if found {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("expected key to be prefetched")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		future1 := identityLoader.Load(ctx, "1a")
+		future2 := identityLoader.Load(ctx, "1b")
+		for _, future := range []Thunk[string]{future1, future2} {
+			if _, err := future(); err != nil {
+				t.Error(err.Error())
+			}
+		}
+
+		calls := *loadCalls
+		expected := [][]string{{"1"}, {"1a", "1b"}}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("delivers the values before prefetching", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, _ := IDLoader[string](0)
+		release := make(chan struct{})
+		WithPrefetch[string, string](func(_ context.Context, v string) []string {
+			<-release
+			return nil
+		})(identityLoader)
+		defer close(release)
+
+		thunk := identityLoader.Load(context.Background(), "1")
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if _, err := thunk(); err != nil {
+				t.Error(err.Error())
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected the value to be delivered before the prefetch returns")
+		}
+	})
+
 	t.Run("caches repeated requests", func(t *testing.T) {
 		t.Parallel()
 		identityLoader, loadCalls := IDLoader[string](0)
@@ -338,6 +2500,31 @@ func TestLoader(t *testing.T) {
 		}
 	})
 
+	t.Run("primes a loader from the results of another", func(t *testing.T) {
+		t.Parallel()
+		bySlug, _ := IDLoader[string](0)
+		byID, loadCalls := IDLoader[int](0)
+		PrimeFrom(bySlug, func(slug string) (int, int) {
+			id, _ := strconv.Atoi(slug)
+			return id, id * 10
+		}, byID)
+		ctx := context.Background()
+
+		if _, err := bySlug.Load(ctx, "1")(); err != nil {
+			t.Error(err.Error())
+		}
+		value, err := byID.Load(ctx, 1)()
+		if err != nil {
+			t.Error(err.Error())
+		}
+		if value != 10 {
+			t.Errorf("did not use primed value. Expected %d, got %d", 10, value)
+		}
+		if calls := *loadCalls; len(calls) != 0 {
+			t.Errorf("expected no batch on the primed loader, got %#v", calls)
+		}
+	})
+
 	t.Run("allows clear value in cache", func(t *testing.T) {
 		t.Parallel()
 		identityLoader, loadCalls := IDLoader[string](0)
@@ -373,6 +2560,34 @@ func TestLoader(t *testing.T) {
 		}
 	})
 
+	t.Run("bypasses the cache for selected keys", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		WithCacheBypass[string, string](func(_ context.Context, key string) bool {
+			return key == "me"
+		})(identityLoader)
+		ctx := context.Background()
+
+		for i := 0; i < 2; i++ {
+			future1 := identityLoader.Load(ctx, "me")
+			future2 := identityLoader.Load(ctx, "1")
+			for _, future := range []Thunk[string]{future1, future2} {
+				if _, err := future(); err != nil {
+					t.Error(err.Error())
+				}
+			}
+		}
+
+		calls := *loadCalls
+		expected := [][]string{{"me", "1"}, {"me"}}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+		if _, found := identityLoader.cache.Get(ctx, "me"); found {
+			t.Error("bypassed key should not be cached")
+		}
+	})
+
 	t.Run("clears cache on batch with WithClearCacheOnBatch", func(t *testing.T) {
 		t.Parallel()
 		batchOnlyLoader, loadCalls := BatchOnlyLoader[string](0)
@@ -501,6 +2716,35 @@ func TestLoader(t *testing.T) {
 		}
 	})
 
+	t.Run("no cache dedupes the keys of a batch", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := NoCacheLoader[string](0)
+		ctx := context.Background()
+
+		var values []string
+		for _, thunk := range []Thunk[string]{
+			identityLoader.Load(ctx, "1"),
+			identityLoader.Load(ctx, "2"),
+			identityLoader.Load(ctx, "1"),
+			identityLoader.Load(ctx, "1"),
+		} {
+			value, err := thunk()
+			if err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, value)
+		}
+		if expected := []string{"1", "2", "1", "1"}; !reflect.DeepEqual(values, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, values)
+		}
+
+		calls := *loadCalls
+		expected := [][]string{{"1", "2"}}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("expected unique keys. Expected %#v, got %#v", expected, calls)
+		}
+	})
+
 }
 
 // test helpers
@@ -725,3 +2969,53 @@ func (a *Avg) Avg() float64 {
 	}
 	return a.total / a.length
 }
+
+// slowDataCache is a DataCache whose reads wait until release is closed.
+type slowDataCache[K comparable, V any] struct {
+	DataCache[K, V]
+	release chan struct{}
+}
+
+func (c *slowDataCache[K, V]) Get(ctx context.Context, key K) (*Result[V], bool) {
+	<-c.release
+	return c.DataCache.Get(ctx, key)
+}
+
+// manyDataCache is a DataCacheMany recording its calls of GetMany and SetMany.
+type manyDataCache[K comparable, V any] struct {
+	DataCache[K, V]
+
+	mu   sync.Mutex
+	gets [][]K
+	sets [][]K
+}
+
+func (c *manyDataCache[K, V]) GetMany(ctx context.Context, keys []K) []*Result[V] {
+	c.mu.Lock()
+	c.gets = append(c.gets, keys)
+	c.mu.Unlock()
+	results := make([]*Result[V], len(keys))
+	for i, key := range keys {
+		results[i], _ = c.DataCache.Get(ctx, key)
+	}
+	return results
+}
+
+func (c *manyDataCache[K, V]) SetMany(ctx context.Context, keys []K, results []*Result[V]) {
+	c.mu.Lock()
+	c.sets = append(c.sets, keys)
+	c.mu.Unlock()
+	for i, key := range keys {
+		c.DataCache.Set(ctx, key, results[i])
+	}
+}
+
+func (c *manyDataCache[K, V]) DeleteMany(ctx context.Context, keys []K) int {
+	var n int
+	for _, key := range keys {
+		if c.DataCache.Delete(ctx, key) {
+			n++
+		}
+	}
+	return n
+}
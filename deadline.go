@@ -0,0 +1,96 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDeadlineUnreachable is returned by the thunks of keys which were not
+// queued because the deadline of their context would be exceeded by the time
+// the batch function returns, when using the DeadlineFailFast policy.
+var ErrDeadlineUnreachable = errors.New("dataloader: the deadline of the context is unreachable")
+
+// DeadlinePolicy defines what Load does when the deadline of the context of a
+// key is nearer than the end of the batch window plus the expected latency of
+// the batch function, which the loader tracks.
+type DeadlinePolicy int
+
+const (
+	// DeadlineIgnore queues the key regardless of its deadline.
+	DeadlineIgnore DeadlinePolicy = iota
+	// DeadlineDispatch dispatches the batch of the key right away rather
+	// than waiting for the end of the window.
+	DeadlineDispatch
+	// DeadlineFailFast works like DeadlineDispatch, but if the deadline is
+	// nearer than the expected latency itself, it doesn't queue the key and
+	// resolves its thunk to ErrDeadlineUnreachable. The key isn't cached.
+	DeadlineFailFast
+)
+
+// WithDeadlineAdmission sets the policy applied to the keys whose context
+// deadline would be exceeded by waiting for their batch. The expected latency
+// of the batch function is a moving average of the durations of its calls,
+// so the first batches are only admitted by the end of their window. Default
+// is DeadlineIgnore.
+func WithDeadlineAdmission[K comparable, V any](p DeadlinePolicy) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.deadlinePolicy = p
+	}
+}
+
+// latencyAverage is an exponentially weighted moving average of the durations
+// of the batches.
+type latencyAverage struct {
+	nanos atomic.Int64
+}
+
+// observe adds the duration of a batch to the average.
+func (a *latencyAverage) observe(d time.Duration) {
+	for {
+		old := a.nanos.Load()
+		avg := int64(d)
+		if old > 0 {
+			// the weight of the new duration is 1/8
+			avg = old + (int64(d)-old)/8
+		}
+		if a.nanos.CompareAndSwap(old, avg) {
+			return
+		}
+	}
+}
+
+// expected returns the expected duration of a batch.
+func (a *latencyAverage) expected() time.Duration {
+	return time.Duration(a.nanos.Load())
+}
+
+// admit applies the deadline policy to a key queued on the batcher with ctx:
+// dispatch is true if the batch must be dispatched right away.
+// It must be called with the batchLock held.
+func (l *Loader[K, V]) admit(ctx context.Context, b *batcher[K, V]) (dispatch bool, err error) {
+	if l.deadlinePolicy == DeadlineIgnore {
+		return false, nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return false, nil
+	}
+	// the deadline is timed by the system clock: it's compared with the
+	// window in clock time, by the time it has left
+	left := time.Until(deadline)
+	deadline = l.clock.Now().Add(left)
+	latency := l.latency.expected()
+	if l.deadlinePolicy == DeadlineFailFast && left < latency {
+		return false, ErrDeadlineUnreachable
+	}
+
+	// the end of the window is unknown to the loaders with a scheduler or in
+	// manual dispatch mode, unless ContextWithWait shortened it
+	closes := b.closesAt
+	if !b.deadline.IsZero() && (closes.IsZero() || b.deadline.Before(closes)) {
+		closes = b.deadline
+	}
+	return !closes.IsZero() && deadline.Before(closes.Add(latency)), nil
+}
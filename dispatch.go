@@ -0,0 +1,99 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+)
+
+// WithManualDispatch disables the batch windows: the keys accumulate until
+// Dispatch is called (or the batch capacity is reached), for the consumers of
+// message queues which dispatch the keys of the messages of a poll at once.
+// The thunks of the keys don't resolve before then, so they must not be
+// called before Dispatch, see Poll.
+func WithManualDispatch[K comparable, V any]() Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.manual = true
+	}
+}
+
+// Dispatch dispatches the pending batches right away, along with the ones of
+// the batch groups, rather than waiting for their windows to close (i.e. once
+// a GraphQL executor knows that the resolvers of a level have all run, see
+// LoaderRegistry.Dispatch). It works with any loader, but it's the only way to
+// dispatch the batches of a loader WithManualDispatch. The batches run with
+// the context of their first load, or the one of WithBatchContext.
+func (l *Loader[K, V]) Dispatch() {
+	l.batchLock.Lock()
+	pending := make([]*batcher[K, V], 0, len(l.groups)+1)
+	if l.curBatcher != nil {
+		pending = append(pending, l.curBatcher)
+	}
+	for _, b := range l.groups {
+		pending = append(pending, b)
+	}
+	for _, b := range pending {
+		l.closeCurrent(b, DispatchManual)
+	}
+	l.batchLock.Unlock()
+
+	if len(pending) > 0 {
+		l.batched()
+	}
+}
+
+// Reset clears the cache, like ClearAll, for the loaders reused from a poll
+// to the next.
+func (l *Loader[K, V]) Reset() {
+	l.ClearAll()
+}
+
+// Dispatcher is a loader whose batches can be dispatched explicitly, i.e. a
+// Loader WithManualDispatch.
+type Dispatcher interface {
+	// Dispatch dispatches the pending batches.
+	Dispatch()
+	// Reset clears the cache.
+	Reset()
+}
+
+var _ Dispatcher = &Loader[string, string]{}
+
+// Poll processes the messages of a poll of a message consumer with loaders
+// WithManualDispatch: prepare is called with every message, which queues the
+// keys of the message and returns the function completing its processing
+// with their thunks. Once every message is prepared, the loaders are
+// dispatched, and the completions run concurrently. Finally, the caches of
+// the loaders are reset for the next poll.
+//
+// The errors of the completions are returned in the order of the messages,
+// and the messages without an error have a nil one. The keys loaded by the
+// completions themselves are not dispatched by Poll, so the loaders they use
+// must not be WithManualDispatch.
+func Poll[M any](ctx context.Context, messages []M, prepare func(context.Context, M) func() error, loaders ...Dispatcher) []error {
+	completions := make([]func() error, len(messages))
+	for i, m := range messages {
+		completions[i] = prepare(ctx, m)
+	}
+	for _, l := range loaders {
+		l.Dispatch()
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(messages))
+	for i, complete := range completions {
+		if complete == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = complete()
+		}()
+	}
+	wg.Wait()
+
+	for _, l := range loaders {
+		l.Reset()
+	}
+	return errs
+}
@@ -0,0 +1,95 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrLoaderClosed is returned by the thunks of keys loaded once the loader is
+// closed. See Loader.Close.
+var ErrLoaderClosed = errors.New("dataloader: loader is closed")
+
+// track counts a batch which is yet to finish.
+// It must be called with the batchLock held.
+func (l *Loader[K, V]) track() {
+	if l.running == 0 {
+		l.idle = make(chan struct{})
+	}
+	l.running++
+}
+
+// untrack counts a finished batch.
+// It must be called without the batchLock held.
+func (l *Loader[K, V]) untrack() {
+	l.batchLock.Lock()
+	l.running--
+	if l.running == 0 {
+		close(l.idle)
+		l.idle = nil
+	}
+	l.batchLock.Unlock()
+}
+
+// Drain dispatches the pending batches (see Dispatch) and waits until every
+// batch of the loader has finished: their batch functions have returned, and
+// the thunks of their keys are resolved. It returns the error of the context
+// if it is done first.
+//
+// Once Drain returns nil, no batch of the loader is left running, and no batch
+// window is left open whose timer could fire late: on a serverless platform which freezes
+// the process between the invocations (i.e. AWS Lambda), a batch doesn't stall
+// until the next invocation thaws it. See Invoke.
+func (l *Loader[K, V]) Drain(ctx context.Context) error {
+	l.Dispatch()
+	l.batchLock.Lock()
+	idle := l.idle
+	l.batchLock.Unlock()
+	if idle == nil {
+		return nil
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the loader, i.e. at the shutdown of the process: the loads
+// made from now on resolve right away with ErrLoaderClosed (even the ones of
+// cached keys), and the pending batches are drained like Drain does, so that
+// the thunks of the keys loaded before then resolve. It returns the error of
+// the context if it is done first. Closing a loader twice is harmless.
+func (l *Loader[K, V]) Close(ctx context.Context) error {
+	l.batchLock.Lock()
+	l.closed.Store(true)
+	l.batchLock.Unlock()
+	return l.Drain(ctx)
+}
+
+// Drainer is a loader which can be drained, see Loader.Drain.
+type Drainer interface {
+	Drain(ctx context.Context) error
+}
+
+var _ Drainer = &Loader[string, string]{}
+
+// Invoke runs the handler of a serverless invocation, then drains the
+// loaders, so that no batch work remains pending once the handler returns.
+// The error of the handler is returned, or else the first error draining
+// the loaders.
+//
+//	lambda.Start(func(ctx context.Context, event Event) error {
+//		return dataloader.Invoke(ctx, func(ctx context.Context) error {
+//			return handle(ctx, event)
+//		}, users, orders)
+//	})
+func Invoke(ctx context.Context, handler func(context.Context) error, loaders ...Drainer) error {
+	err := handler(ctx)
+	for _, l := range loaders {
+		if drainErr := l.Drain(ctx); err == nil {
+			err = drainErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package dataloader
+
+import (
+	"context"
+	"fmt"
+)
+
+// EvictReason is why a value left the cache of a loader, see WithOnEvict.
+type EvictReason int
+
+const (
+	// EvictCleared is the eviction of a key by Clear, or of the value
+	// replaced by a load with ForceRefresh, by ForcePrime or PrimeIfNewer.
+	EvictCleared EvictReason = iota
+	// EvictClearAll is the eviction of every key by ClearAll (or
+	// WithClearCacheOnBatch).
+	EvictClearAll
+	// EvictRotated is the eviction of every key by the rotation of the cache
+	// to a new epoch, see WithCacheMaxAge.
+	EvictRotated
+	// EvictExpired is the eviction of a key whose TTL has elapsed.
+	EvictExpired
+	// EvictCapacity is the eviction of a key by a cache bounded in size (i.e.
+	// the least recently used key of an LRU cache).
+	EvictCapacity
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCleared:
+		return "cleared"
+	case EvictClearAll:
+		return "clear-all"
+	case EvictRotated:
+		return "rotated"
+	case EvictExpired:
+		return "expired"
+	case EvictCapacity:
+		return "capacity"
+	default:
+		return fmt.Sprintf("EvictReason(%d)", int(r))
+	}
+}
+
+// EvictionNotifier is implemented by the caches which evict keys on their own
+// (i.e. to bound their size, or once their TTL has elapsed), so that the
+// loader can report these evictions to WithOnEvict.
+type EvictionNotifier[K comparable] interface {
+	// NotifyEvict registers fn, which the cache calls with the keys it
+	// evicts. fn may be called during a call of the cache (i.e. by Set), or
+	// from a goroutine of the cache.
+	NotifyEvict(fn func(ctx context.Context, key K, reason EvictReason))
+}
+
+// eviction is an eviction yet to be reported.
+type eviction[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+// WithOnEvict calls fn with the values which leave the cache of the loader:
+// the keys cleared by Clear and ClearAll, the rotations of WithCacheMaxAge, and
+// the keys evicted by a cache implementing EvictionNotifier (i.e. an LRU or TTL
+// cache), so that the application can maintain indexes derived from the
+// cached values, or publish invalidations downstream.
+//
+// Only the values cached by a successful load or by Prime are reported, since
+// the keys which failed have no value. The loader keeps the values of the
+// cached keys to report them, as the Cache interface only holds their thunks.
+// fn is called once the cache is unlocked, and may be called concurrently.
+func WithOnEvict[K comparable, V any](fn func(ctx context.Context, key K, value V, reason EvictReason)) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.onEvict = fn
+		l.addCacheHook(func(ctx context.Context, key K, result *Result[V]) {
+			if result.Error != nil {
+				return
+			}
+			// the key may have been cleared while it was loading
+			l.cacheLock.Lock()
+			if _, ok := l.cache.Get(ctx, key); ok {
+				l.retain(key, result.Data)
+			}
+			l.cacheLock.Unlock()
+		})
+	}
+}
+
+// watchEvictions registers the loader to the evictions of its cache, if it
+// reports them.
+func (l *Loader[K, V]) watchEvictions() {
+	notifier, ok := l.cache.(EvictionNotifier[K])
+	if l.onEvict == nil || !ok {
+		return
+	}
+	notifier.NotifyEvict(func(ctx context.Context, key K, reason EvictReason) {
+		l.evict(key, reason)
+		// the cache may be evicting with the cacheLock held
+		go l.flushEvictions(ctx)
+	})
+}
+
+// retain keeps the value of a cached key, to report its eviction.
+func (l *Loader[K, V]) retain(key K, value V) {
+	if l.onEvict == nil {
+		return
+	}
+	l.evictLock.Lock()
+	if l.evictable == nil {
+		l.evictable = make(map[K]V)
+	}
+	l.evictable[key] = value
+	l.evictLock.Unlock()
+}
+
+// evict records the eviction of key, if its value is retained.
+func (l *Loader[K, V]) evict(key K, reason EvictReason) {
+	if l.onEvict == nil {
+		return
+	}
+	l.evictLock.Lock()
+	if v, ok := l.evictable[key]; ok {
+		delete(l.evictable, key)
+		l.evictions = append(l.evictions, eviction[K, V]{key, v, reason})
+	}
+	l.evictLock.Unlock()
+}
+
+// evictAll records the eviction of every retained value.
+func (l *Loader[K, V]) evictAll(reason EvictReason) {
+	if l.onEvict == nil {
+		return
+	}
+	l.evictLock.Lock()
+	for key, v := range l.evictable {
+		l.evictions = append(l.evictions, eviction[K, V]{key, v, reason})
+	}
+	l.evictable = nil
+	l.evictLock.Unlock()
+}
+
+// flushEvictions reports the recorded evictions.
+// It must be called without the cacheLock held.
+func (l *Loader[K, V]) flushEvictions(ctx context.Context) {
+	if l.onEvict == nil {
+		return
+	}
+	l.evictLock.Lock()
+	evictions := l.evictions
+	l.evictions = nil
+	l.evictLock.Unlock()
+	for _, e := range evictions {
+		l.onEvict(ctx, e.key, e.value, e.reason)
+	}
+}
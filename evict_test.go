@@ -0,0 +1,158 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// capacityCache is a cache of a single key, which reports the key it evicts
+// to make room for another.
+type capacityCache[K comparable, V any] struct {
+	*InMemoryCache[K, V]
+	last    *K
+	onEvict func(context.Context, K, EvictReason)
+}
+
+func (c *capacityCache[K, V]) NotifyEvict(fn func(context.Context, K, EvictReason)) {
+	c.onEvict = fn
+}
+
+func (c *capacityCache[K, V]) Set(ctx context.Context, key K, thunk Thunk[V]) {
+	if c.last != nil && *c.last != key {
+		c.InMemoryCache.Delete(ctx, *c.last)
+		c.onEvict(ctx, *c.last, EvictCapacity)
+	}
+	c.last = &key
+	c.InMemoryCache.Set(ctx, key, thunk)
+}
+
+// evictions records the evictions reported to WithOnEvict.
+type evictions struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (e *evictions) record(_ context.Context, key, value string, reason EvictReason) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.seen = append(e.seen, key+"="+value+" "+reason.String())
+}
+
+// take returns the recorded evictions, sorted, once there are n of them.
+func (e *evictions) take(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		e.mu.Lock()
+		if len(e.seen) >= n || time.Now().After(deadline) {
+			seen := e.seen
+			e.seen = nil
+			e.mu.Unlock()
+			sort.Strings(seen)
+			return seen
+		}
+		e.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOnEvict(t *testing.T) {
+	t.Run("reports the cleared values", func(t *testing.T) {
+		t.Parallel()
+		var evicted evictions
+		loader := NewBatchedLoader(batchIdentity[string], WithOnEvict[string, string](evicted.record))
+		ctx := context.Background()
+
+		loader.LoadMany(ctx, []string{"1", "2"})()
+		loader.Prime(ctx, "3", "three")
+		loader.Clear(ctx, "1")
+		if got, want := evicted.take(t, 1), []string{"1=1 cleared"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		loader.ClearAll()
+		if got, want := evicted.take(t, 2), []string{"2=2 clear-all", "3=three clear-all"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+
+		// the keys which aren't cached have no value to report
+		loader.Clear(ctx, "1")
+		loader.ClearAll()
+		if got := evicted.take(t, 0); len(got) != 0 {
+			t.Errorf("expected no eviction, got %v", got)
+		}
+	})
+
+	t.Run("reports the values replaced by ForcePrime", func(t *testing.T) {
+		t.Parallel()
+		var evicted evictions
+		loader := NewBatchedLoader(batchIdentity[string], WithOnEvict[string, string](evicted.record))
+		ctx := context.Background()
+
+		loader.Load(ctx, "1")()
+		loader.ForcePrime(ctx, "1", "one")
+		loader.ForcePrime(ctx, "1", "uno")
+		if got, want := evicted.take(t, 2), []string{"1=1 cleared", "1=one cleared"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		// PrimeError caches no value to report
+		loader.Clear(ctx, "1")
+		loader.PrimeError(ctx, "2", errors.New("denied"))
+		loader.Clear(ctx, "2")
+		if got, want := evicted.take(t, 1), []string{"1=uno cleared"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("reports the values replaced by PrimeIfNewer", func(t *testing.T) {
+		t.Parallel()
+		var evicted evictions
+		loader := NewBatchedLoader(batchIdentity[string], WithOnEvict[string, string](evicted.record))
+		ctx := context.Background()
+
+		loader.Load(ctx, "1")()
+		loader.PrimeIfNewer(ctx, "1", "one", 2)
+		// the stale value isn't primed, and evicts nothing
+		loader.PrimeIfNewer(ctx, "1", "uno", 1)
+		if got, want := evicted.take(t, 2), []string{"1=1 cleared"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("reports the evictions of the cache", func(t *testing.T) {
+		t.Parallel()
+		var evicted evictions
+		loader := NewBatchedLoader(batchIdentity[string],
+			WithCache[string, string](&capacityCache[string, string]{InMemoryCache: NewCache[string, string]()}),
+			WithOnEvict[string, string](evicted.record),
+		)
+		ctx := context.Background()
+
+		loader.Load(ctx, "1")()
+		loader.Load(ctx, "2")()
+		if got, want := evicted.take(t, 1), []string{"1=1 capacity"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("reports the rotations of the cache", func(t *testing.T) {
+		t.Parallel()
+		var evicted evictions
+		loader := NewBatchedLoader(batchIdentity[string],
+			WithCacheMaxAge[string, string](10*time.Millisecond),
+			WithOnEvict[string, string](evicted.record),
+		)
+		ctx := context.Background()
+
+		loader.Load(ctx, "1")()
+		time.Sleep(20 * time.Millisecond)
+		loader.Load(ctx, "2")()
+		if got, want := evicted.take(t, 1), []string{"1=1 rotated"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package consumer_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	dataloader "github.com/graph-gophers/dataloader/v7"
+)
+
+// Order is the payload of the messages of a queue (i.e. Kafka or SQS).
+type Order struct {
+	ID     int
+	UserID int
+}
+
+func ExamplePoll() {
+	type User struct {
+		ID   int
+		Name string
+	}
+
+	users := map[int]*User{
+		1: {ID: 1, Name: "John"},
+		2: {ID: 2, Name: "Jane"},
+	}
+
+	batchFunc := func(_ context.Context, keys []int) []*dataloader.Result[*User] {
+		fmt.Printf("loading users %v\n", keys)
+		results := make([]*dataloader.Result[*User], len(keys))
+		for i, k := range keys {
+			results[i] = &dataloader.Result[*User]{Data: users[k]}
+		}
+		return results
+	}
+
+	// the keys are only dispatched once every message of the poll is prepared
+	loader := dataloader.NewBatchedLoader(batchFunc, dataloader.WithManualDispatch[int, *User]())
+
+	var (
+		mu      sync.Mutex
+		shipped []string
+	)
+	// poll returns a batch of messages from the queue
+	poll := func() []Order {
+		return []Order{{ID: 100, UserID: 1}, {ID: 101, UserID: 2}, {ID: 102, UserID: 1}}
+	}
+	for range 2 {
+		dataloader.Poll(context.Background(), poll(), func(ctx context.Context, o Order) func() error {
+			thunk := loader.Load(ctx, o.UserID)
+			return func() error {
+				user, err := thunk()
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				shipped = append(shipped, fmt.Sprintf("%d to %s", o.ID, user.Name))
+				mu.Unlock()
+				return nil
+			}
+		}, loader)
+	}
+
+	sort.Strings(shipped)
+	fmt.Println(strings.Join(shipped, ", "))
+	// Output:
+	// loading users [1 2]
+	// loading users [1 2]
+	// 100 to John, 100 to John, 101 to Jane, 101 to Jane, 102 to John, 102 to John
+}
@@ -0,0 +1,25 @@
+module github.com/graph-gophers/dataloader/v7/example
+
+go 1.23
+
+require (
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+	github.com/graph-gophers/dataloader/v7/trace/otel v0.0.0
+	github.com/graph-gophers/graphql-go v1.5.0
+	github.com/hashicorp/golang-lru v0.5.4
+	github.com/patrickmn/go-cache v2.1.0+incompatible
+	go.opentelemetry.io/otel v1.6.3
+	go.opentelemetry.io/otel/sdk v1.6.3
+	go.opentelemetry.io/otel/trace v1.6.3
+)
+
+require (
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	golang.org/x/sys v0.0.0-20210423185535-09eb48e85fd7 // indirect
+)
+
+replace (
+	github.com/graph-gophers/dataloader/v7 => ..
+	github.com/graph-gophers/dataloader/v7/trace/otel => ../trace/otel
+)
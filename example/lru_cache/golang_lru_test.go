@@ -43,7 +43,7 @@ func (c *cache[K, V]) Clear() {
 	c.ARCCache.Purge()
 }
 
-func ExampleGolangLRU() {
+func Example_golangLRU() {
 	type User struct {
 		ID        int
 		Email     string
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+
+	"github.com/graph-gophers/dataloader/v7"
+	dlotel "github.com/graph-gophers/dataloader/v7/trace/otel"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// loaders holds the keys of the loaders of the service, which are constructed
+// once per request by its registry.
+type loaders struct {
+	authors       *dataloader.LoaderKey[string, *Author]
+	books         *dataloader.LoaderKey[string, *Book]
+	booksByAuthor *dataloader.LoaderKey[string, []*Book]
+}
+
+func newLoaders(db *store, tracer trace.Tracer) *loaders {
+	return &loaders{
+		authors: newLoaderKey("authors", tracer, byID(db.authorsByIDs, func(a *Author) string { return a.ID })),
+		books:   newLoaderKey("books", tracer, byID(db.booksByIDs, func(b *Book) string { return b.ID })),
+		// a grouped loader: each key loads the group of books of an author
+		booksByAuthor: newLoaderKey("books-by-author", tracer, groupedBy(db.booksByAuthorIDs, func(b *Book) string { return b.AuthorID })),
+	}
+}
+
+// newLoaderKey returns the key of the loaders of the batch function, traced
+// with OpenTelemetry.
+func newLoaderKey[V any](name string, tracer trace.Tracer, batchFn dataloader.BatchFunc[string, V]) *dataloader.LoaderKey[string, V] {
+	return dataloader.NewLoaderKey(func(context.Context) *dataloader.Loader[string, V] {
+		return dataloader.NewBatchedLoader(batchFn,
+			dataloader.WithName[string, V](name),
+			dataloader.WithTracer[string, V](dlotel.NewTracer[string, V](tracer)),
+		)
+	})
+}
+
+// byID returns the batch function of a query of rows by ID.
+func byID[V any](query func(context.Context, []string) ([]V, error), id func(V) string) dataloader.BatchFunc[string, V] {
+	return func(ctx context.Context, ids []string) []*dataloader.Result[V] {
+		rows, err := query(ctx, ids)
+		if err != nil {
+			return failed[V](len(ids), err)
+		}
+		byID := make(map[string]V, len(rows))
+		for _, row := range rows {
+			byID[id(row)] = row
+		}
+		results := make([]*dataloader.Result[V], len(ids))
+		for i, id := range ids {
+			if row, ok := byID[id]; ok {
+				results[i] = &dataloader.Result[V]{Data: row}
+			} else {
+				results[i] = dataloader.NotFound[V]()
+			}
+		}
+		return results
+	}
+}
+
+// groupedBy returns the batch function of a query of the rows of groups.
+func groupedBy[V any](query func(context.Context, []string) ([]V, error), group func(V) string) dataloader.BatchFunc[string, []V] {
+	return func(ctx context.Context, ids []string) []*dataloader.Result[[]V] {
+		rows, err := query(ctx, ids)
+		if err != nil {
+			return failed[[]V](len(ids), err)
+		}
+		groups := make(map[string][]V, len(ids))
+		for _, row := range rows {
+			groups[group(row)] = append(groups[group(row)], row)
+		}
+		results := make([]*dataloader.Result[[]V], len(ids))
+		for i, id := range ids {
+			results[i] = &dataloader.Result[[]V]{Data: groups[id]}
+		}
+		return results
+	}
+}
+
+// failed returns the results of the keys of a failed query.
+func failed[V any](n int, err error) []*dataloader.Result[V] {
+	results := make([]*dataloader.Result[V], n)
+	for i := range results {
+		results[i] = &dataloader.Result[V]{Error: err}
+	}
+	return results
+}
@@ -0,0 +1,58 @@
+// Command server is a small GraphQL service wiring the features of the
+// dataloader together: the loaders of each request are held by a registry,
+// the books of the authors are loaded by a grouped loader, the mutations prime
+// the loaders, the loaders are traced with OpenTelemetry, and the usage of
+// the loaders is reported in the extensions of every response.
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/graph-gophers/graphql-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func main() {
+	handler := newHandler(newStore(), otel.Tracer("example/server"))
+	log.Println("listening on :8080")
+	log.Fatal(http.ListenAndServe(":8080", handler))
+}
+
+// request is a GraphQL request.
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// newHandler returns the handler of the GraphQL requests.
+func newHandler(db *store, tracer trace.Tracer) http.Handler {
+	s := graphql.MustParseSchema(schema, &resolver{db: db, loaders: newLoaders(db, tracer)}, graphql.UseFieldResolvers())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// the loaders of the request are constructed by its registry, and
+		// their usage is collected for the extensions of the response
+		ctx, registry := dataloader.ContextWithLoaderRegistry(r.Context())
+		ctx, usage := dataloader.ContextWithUsage(ctx)
+		resp := s.Exec(ctx, req.Query, req.OperationName, req.Variables)
+		if err := registry.Drain(ctx); err != nil {
+			log.Printf("draining the loaders: %v", err)
+		}
+		resp.Extensions = usage.Extensions()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("encoding the response: %v", err)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// response is a GraphQL response of the server.
+type response struct {
+	Data       json.RawMessage `json:"data"`
+	Errors     []any           `json:"errors"`
+	Extensions struct {
+		Dataloader []dataloader.LoaderUsage `json:"dataloader"`
+	} `json:"extensions"`
+}
+
+func post(t *testing.T, url, query string, variables map[string]any) response {
+	t.Helper()
+	body, err := json.Marshal(request{Query: query, Variables: variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var resp response
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("expected no errors, got %v", resp.Errors)
+	}
+	return resp
+}
+
+func TestServer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	db := newStore()
+	srv := httptest.NewServer(newHandler(db, tp.Tracer("test")))
+	defer srv.Close()
+
+	t.Run("loads the authors and their books by one batch per loader", func(t *testing.T) {
+		queries := db.queryCount()
+		resp := post(t, srv.URL, `{
+			authors(ids: ["1", "2"]) { name books { title author { name } } }
+		}`, nil)
+
+		var data struct {
+			Authors []struct {
+				Name  string
+				Books []struct {
+					Title  string
+					Author struct{ Name string }
+				}
+			}
+		}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatal(err)
+		}
+		if len(data.Authors) != 2 || len(data.Authors[0].Books) == 0 || data.Authors[0].Books[0].Author.Name != data.Authors[0].Name {
+			t.Errorf("expected the authors and their books, got %s", resp.Data)
+		}
+		// the authors of the books are primed by the books of the author
+		if got := db.queryCount() - queries; got != 2 {
+			t.Errorf("expected 2 queries, got %d", got)
+		}
+		for _, u := range resp.Extensions.Dataloader {
+			if u.Batches != 1 {
+				t.Errorf("expected 1 batch of %s, got %d", u.Name, u.Batches)
+			}
+		}
+		if len(resp.Extensions.Dataloader) != 2 {
+			t.Errorf("expected the usage of 2 loaders, got %+v", resp.Extensions.Dataloader)
+		}
+		if len(recorder.Ended()) == 0 {
+			t.Error("expected the loaders to be traced")
+		}
+	})
+
+	t.Run("primes and clears the loaders after a mutation", func(t *testing.T) {
+		queries := db.queryCount()
+		resp := post(t, srv.URL, `mutation($title: String!) {
+			addBook(authorID: "1", title: $title) { title author { books { title } } }
+		}`, map[string]any{"title": "The Left Hand of Darkness"})
+
+		var data struct {
+			AddBook struct {
+				Title  string
+				Author struct {
+					Books []struct{ Title string }
+				}
+			}
+		}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatal(err)
+		}
+		books := data.AddBook.Author.Books
+		if len(books) == 0 || books[len(books)-1].Title != "The Left Hand of Darkness" {
+			t.Errorf("expected the books of the author to include the new book, got %s", resp.Data)
+		}
+		// the book is inserted, and the author and its books are loaded, while
+		// the new book is not
+		if got := db.queryCount() - queries; got != 3 {
+			t.Errorf("expected 3 queries, got %d", got)
+		}
+	})
+}
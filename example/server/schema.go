@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+const schema = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+
+type Query {
+	author(id: ID!): Author
+	authors(ids: [ID!]!): [Author]!
+	book(id: ID!): Book
+}
+
+type Mutation {
+	addBook(authorID: ID!, title: String!): Book!
+}
+
+type Author {
+	id: ID!
+	name: String!
+	books: [Book!]!
+}
+
+type Book {
+	id: ID!
+	title: String!
+	author: Author
+}
+`
+
+// resolver is the root resolver of the schema.
+type resolver struct {
+	db      *store
+	loaders *loaders
+}
+
+func (r *resolver) Author(ctx context.Context, args struct{ ID graphql.ID }) (*authorResolver, error) {
+	return r.author(ctx, string(args.ID))
+}
+
+func (r *resolver) Authors(ctx context.Context, args struct{ IDs []graphql.ID }) ([]*authorResolver, error) {
+	// the authors are queued at once, so that they are loaded by one batch
+	thunks := make([]dataloader.Thunk[*Author], len(args.IDs))
+	for i, id := range args.IDs {
+		thunks[i] = r.loaders.authors.From(ctx).Load(ctx, string(id))
+	}
+	authors := make([]*authorResolver, len(thunks))
+	for i, thunk := range thunks {
+		a, err := thunk()
+		if err != nil && !dataloader.IsNotFound(err) {
+			return nil, err
+		}
+		if a != nil {
+			authors[i] = &authorResolver{r, a}
+		}
+	}
+	return authors, nil
+}
+
+func (r *resolver) AddBook(ctx context.Context, args struct {
+	AuthorID graphql.ID
+	Title    string
+}) (*bookResolver, error) {
+	book := r.db.addBook(ctx, string(args.AuthorID), args.Title)
+	// the new book is primed, so that the rest of the request doesn't load it
+	// again, and the group of books of its author is stale: it is cleared, so
+	// that the response of the mutation includes the new book
+	r.loaders.books.From(ctx).Prime(ctx, book.ID, book)
+	r.loaders.booksByAuthor.From(ctx).Clear(ctx, book.AuthorID)
+	return &bookResolver{r, book}, nil
+}
+
+func (r *resolver) Book(ctx context.Context, args struct{ ID graphql.ID }) (*bookResolver, error) {
+	b, err := r.loaders.books.From(ctx).Load(ctx, string(args.ID))()
+	if dataloader.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bookResolver{r, b}, nil
+}
+
+func (r *resolver) author(ctx context.Context, id string) (*authorResolver, error) {
+	a, err := r.loaders.authors.From(ctx).Load(ctx, id)()
+	if dataloader.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authorResolver{r, a}, nil
+}
+
+type authorResolver struct {
+	root   *resolver
+	author *Author
+}
+
+func (a *authorResolver) ID() graphql.ID { return graphql.ID(a.author.ID) }
+func (a *authorResolver) Name() string   { return a.author.Name }
+
+func (a *authorResolver) Books(ctx context.Context) ([]*bookResolver, error) {
+	books, err := a.root.loaders.booksByAuthor.From(ctx).Load(ctx, a.author.ID)()
+	if err != nil {
+		return nil, err
+	}
+	// the authors of the books are known: they are primed, so that resolving
+	// them doesn't load them again
+	authors := a.root.loaders.authors.From(ctx)
+	resolvers := make([]*bookResolver, len(books))
+	for i, b := range books {
+		authors.Prime(ctx, b.AuthorID, a.author)
+		resolvers[i] = &bookResolver{a.root, b}
+	}
+	return resolvers, nil
+}
+
+type bookResolver struct {
+	root *resolver
+	book *Book
+}
+
+func (b *bookResolver) ID() graphql.ID { return graphql.ID(b.book.ID) }
+func (b *bookResolver) Title() string  { return b.book.Title }
+
+func (b *bookResolver) Author(ctx context.Context) (*authorResolver, error) {
+	return b.root.author(ctx, b.book.AuthorID)
+}
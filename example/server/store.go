@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// Author is an author of books.
+type Author struct {
+	ID   string
+	Name string
+}
+
+// Book is a book of an author.
+type Book struct {
+	ID       string
+	AuthorID string
+	Title    string
+}
+
+// store is the database of the service, with bulk queries only.
+type store struct {
+	mu      sync.Mutex
+	authors map[string]*Author
+	books   []*Book
+	// the number of queries, for the tests
+	queries int
+}
+
+func newStore() *store {
+	return &store{
+		authors: map[string]*Author{
+			"1": {ID: "1", Name: "Ursula K. Le Guin"},
+			"2": {ID: "2", Name: "Iain M. Banks"},
+		},
+		books: []*Book{
+			{ID: "1", AuthorID: "1", Title: "The Dispossessed"},
+			{ID: "2", AuthorID: "1", Title: "The Lathe of Heaven"},
+			{ID: "3", AuthorID: "2", Title: "Excession"},
+		},
+	}
+}
+
+// authorsByIDs returns the authors of the IDs, in any order.
+func (s *store) authorsByIDs(_ context.Context, ids []string) ([]*Author, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	var authors []*Author
+	for _, id := range ids {
+		if a, ok := s.authors[id]; ok {
+			authors = append(authors, a)
+		}
+	}
+	return authors, nil
+}
+
+// booksByIDs returns the books of the IDs, in any order.
+func (s *store) booksByIDs(_ context.Context, ids []string) ([]*Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	var books []*Book
+	for _, b := range s.books {
+		for _, id := range ids {
+			if b.ID == id {
+				books = append(books, b)
+			}
+		}
+	}
+	return books, nil
+}
+
+// booksByAuthorIDs returns the books of the authors, in any order.
+func (s *store) booksByAuthorIDs(_ context.Context, ids []string) ([]*Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	var books []*Book
+	for _, b := range s.books {
+		for _, id := range ids {
+			if b.AuthorID == id {
+				books = append(books, b)
+			}
+		}
+	}
+	return books, nil
+}
+
+// addBook inserts a new book.
+func (s *store) addBook(_ context.Context, authorID, title string) *Book {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	b := &Book{ID: strconv.Itoa(len(s.books) + 1), AuthorID: authorID, Title: title}
+	s.books = append(s.books, b)
+	return b
+}
+
+// queryCount returns the number of queries so far.
+func (s *store) queryCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
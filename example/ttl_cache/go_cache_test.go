@@ -14,6 +14,10 @@ import (
 // Cache implements the dataloader.Cache interface
 type Cache[K comparable, V any] struct {
 	c *cache.Cache
+	// ttl of the entries, randomized by the jitter fraction so that the
+	// entries set together don't all expire at the same instant
+	ttl    time.Duration
+	jitter float64
 }
 
 // Get gets a value from the cache
@@ -29,7 +33,7 @@ func (c *Cache[K, V]) Get(_ context.Context, key K) (dataloader.Thunk[V], bool)
 // Set sets a value in the cache
 func (c *Cache[K, V]) Set(_ context.Context, key K, value dataloader.Thunk[V]) {
 	k := fmt.Sprintf("%v", key) // convert the key to string because the underlying library doesn't support Generics yet
-	c.c.Set(k, value, 0)
+	c.c.Set(k, value, dataloader.JitterTTL(c.ttl, c.jitter))
 }
 
 // Delete deletes and item in the cache
@@ -47,7 +51,7 @@ func (c *Cache[K, V]) Clear() {
 	c.c.Flush()
 }
 
-func ExampleTTLCache() {
+func Example_ttlCache() {
 	type User struct {
 		ID        int
 		Email     string
@@ -70,7 +74,7 @@ func ExampleTTLCache() {
 
 	// go-cache will automatically cleanup expired items on given duration
 	c := cache.New(15*time.Minute, 15*time.Minute)
-	cache := &Cache[int, *User]{c}
+	cache := &Cache[int, *User]{c: c, ttl: 15 * time.Minute, jitter: 0.1}
 	loader := dataloader.NewBatchedLoader(batchFunc, dataloader.WithCache[int, *User](cache))
 
 	// immediately call the future function from loader
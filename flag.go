@@ -0,0 +1,52 @@
+package dataloader
+
+import "context"
+
+// Flag is a behavior of a loader which a Flagger can toggle per request.
+type Flag string
+
+const (
+	// FlagCache toggles the cache: when it's off, the loads skip the cache
+	// like with ContextWithSkipCache.
+	FlagCache Flag = "dataloader.cache"
+	// FlagBatching toggles the batch window: when it's off, the loads are
+	// dispatched right away like with ContextWithWait(ctx, 0).
+	FlagBatching Flag = "dataloader.batching"
+	// FlagRollout toggles the routing of a RolloutLoader: when it's off,
+	// every key of a batch is loaded from SourceLegacy.
+	FlagRollout Flag = "dataloader.rollout"
+)
+
+// Flagger decides whether the behaviors of a loader are enabled, i.e. by
+// evaluating the flags of an external flag system, so that they can be
+// rolled out without deploys. See integration/openfeature for an adapter of
+// OpenFeature.
+type Flagger interface {
+	// Enabled reports whether the flag is enabled for the loader of the given
+	// name (see WithName), where ctx is the context of the load (or of the
+	// batch, for FlagRollout). It returns def if the flag isn't set.
+	// Enabled is called on every load, so it must not block.
+	Enabled(ctx context.Context, loader string, flag Flag, def bool) bool
+}
+
+// WithFlagger sets the Flagger consulted by the loader, at load time for
+// FlagCache and FlagBatching, and at dispatch time for FlagRollout. The
+// flags are enabled by default.
+func WithFlagger[K comparable, V any](f Flagger) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.flagger = f
+	}
+}
+
+// enabled reports whether the flag is enabled for the context.
+func (l *Loader[K, V]) enabled(ctx context.Context, flag Flag) bool {
+	return l.flagger == nil || l.flagger.Enabled(ctx, l.name, flag, true)
+}
+
+// FlaggerFunc is a function implementing Flagger.
+type FlaggerFunc func(ctx context.Context, loader string, flag Flag, def bool) bool
+
+// Enabled calls f.
+func (f FlaggerFunc) Enabled(ctx context.Context, loader string, flag Flag, def bool) bool {
+	return f(ctx, loader, flag, def)
+}
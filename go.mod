@@ -1,16 +1,3 @@
 module github.com/graph-gophers/dataloader/v7
 
-go 1.18
-
-require (
-	github.com/hashicorp/golang-lru v0.5.4
-	github.com/opentracing/opentracing-go v1.2.0
-	github.com/patrickmn/go-cache v2.1.0+incompatible
-	go.opentelemetry.io/otel v1.6.3
-	go.opentelemetry.io/otel/trace v1.6.3
-)
-
-require (
-	github.com/go-logr/logr v1.2.3 // indirect
-	github.com/go-logr/stdr v1.2.2 // indirect
-)
+go 1.23
@@ -0,0 +1,110 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+)
+
+// GroupBatchFunc is a function, which when given a slice of keys, returns a
+// slice of results where the data of each result is the group of values for
+// the key at the same index.
+type GroupBatchFunc[K comparable, V any] func(context.Context, []K) []*Result[[]V]
+
+// GroupLoader loads groups of values, where a single key maps to a set of
+// values (i.e. the comments of a post). Groups are cached and invalidated as
+// a whole, but can also be updated in place after a mutation with
+// AppendToGroup and RemoveFromGroup.
+type GroupLoader[K comparable, V any] struct {
+	loader *Loader[K, []V]
+}
+
+// NewGroupLoader constructs a new GroupLoader with given options.
+func NewGroupLoader[K comparable, V any](batchFn GroupBatchFunc[K, V], opts ...Option[K, []V]) *GroupLoader[K, V] {
+	return &GroupLoader[K, V]{
+		loader: NewBatchedLoader(BatchFunc[K, []V](batchFn), opts...),
+	}
+}
+
+// LoadGroup loads the group of values for the given key. If the group fails to
+// load, the error slice returned by the thunk holds that single error.
+func (g *GroupLoader[K, V]) LoadGroup(ctx context.Context, key K) ThunkMany[V] {
+	thunk := g.loader.Load(ctx, key)
+	return func() ([]V, []error) {
+		values, err := thunk()
+		if err != nil {
+			return nil, []error{err}
+		}
+		return values, nil
+	}
+}
+
+// PrimeGroup adds the provided group of values to the cache. If the group is
+// already cached, no change is made. Returns self for method chaining.
+func (g *GroupLoader[K, V]) PrimeGroup(ctx context.Context, key K, values []V) *GroupLoader[K, V] {
+	g.loader.Prime(ctx, key, values)
+	return g
+}
+
+// AppendToGroup appends the provided values to the cached group of key, i.e.
+// after they were created by a mutation. If the group is not cached, no change
+// is made: the next load fetches it with the new values. Returns self for
+// method chaining.
+func (g *GroupLoader[K, V]) AppendToGroup(ctx context.Context, key K, values ...V) *GroupLoader[K, V] {
+	g.update(ctx, key, func(group []V) []V {
+		updated := make([]V, 0, len(group)+len(values))
+		updated = append(updated, group...)
+		return append(updated, values...)
+	})
+	return g
+}
+
+// RemoveFromGroup removes the values for which match returns true from the
+// cached group of key, i.e. after they were deleted by a mutation. If the group
+// is not cached, no change is made. Returns self for method chaining.
+func (g *GroupLoader[K, V]) RemoveFromGroup(ctx context.Context, key K, match func(V) bool) *GroupLoader[K, V] {
+	g.update(ctx, key, func(group []V) []V {
+		updated := make([]V, 0, len(group))
+		for _, v := range group {
+			if !match(v) {
+				updated = append(updated, v)
+			}
+		}
+		return updated
+	})
+	return g
+}
+
+// ClearGroup clears the group of key from the cache, if it exists. Returns
+// self for method chaining.
+func (g *GroupLoader[K, V]) ClearGroup(ctx context.Context, key K) *GroupLoader[K, V] {
+	g.loader.Clear(ctx, key)
+	return g
+}
+
+// ClearAll clears every group from the cache. Returns self for method chaining.
+func (g *GroupLoader[K, V]) ClearAll() *GroupLoader[K, V] {
+	g.loader.ClearAll()
+	return g
+}
+
+// update replaces the cached group of key with the result of fn applied to it.
+// The cached group may still be loading, so fn is applied lazily once it has
+// resolved. Failed groups are left untouched.
+func (g *GroupLoader[K, V]) update(ctx context.Context, key K, fn func([]V) []V) {
+	g.loader.update(ctx, key, func(thunk Thunk[[]V]) Thunk[[]V] {
+		var (
+			once   sync.Once
+			values []V
+			err    error
+		)
+		return func() ([]V, error) {
+			once.Do(func() {
+				values, err = thunk()
+				if err == nil {
+					values = fn(values)
+				}
+			})
+			return values, err
+		}
+	})
+}
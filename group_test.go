@@ -0,0 +1,119 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGroupLoader(t *testing.T) {
+	t.Run("loads groups", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := LetterLoader()
+		ctx := context.Background()
+
+		future1 := loader.LoadGroup(ctx, "ab")
+		future2 := loader.LoadGroup(ctx, "c")
+
+		values, errs := future1()
+		if errs != nil {
+			t.Fatalf("unexpected errors %v", errs)
+		}
+		if !reflect.DeepEqual(values, []string{"a", "b"}) {
+			t.Errorf("unexpected group %#v", values)
+		}
+		if values, _ := future2(); !reflect.DeepEqual(values, []string{"c"}) {
+			t.Errorf("unexpected group %#v", values)
+		}
+
+		expected := [][]string{{"ab", "c"}}
+		if calls := loadCalls(); !reflect.DeepEqual(calls, expected) {
+			t.Errorf("did not batch groups. Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("returns group errors", func(t *testing.T) {
+		t.Parallel()
+		loader, _ := LetterLoader()
+		_, errs := loader.LoadGroup(context.Background(), "")()
+		if len(errs) != 1 || errs[0] == nil {
+			t.Errorf("expected a single error, got %v", errs)
+		}
+	})
+
+	t.Run("updates cached groups", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := LetterLoader()
+		ctx := context.Background()
+
+		future := loader.LoadGroup(ctx, "abc")
+		loader.AppendToGroup(ctx, "abc", "d", "e").RemoveFromGroup(ctx, "abc", func(v string) bool { return v == "b" })
+
+		if values, _ := loader.LoadGroup(ctx, "abc")(); !reflect.DeepEqual(values, []string{"a", "c", "d", "e"}) {
+			t.Errorf("unexpected group %#v", values)
+		}
+		if values, _ := future(); !reflect.DeepEqual(values, []string{"a", "b", "c"}) {
+			t.Errorf("thunks loaded before the update should not change, got %#v", values)
+		}
+
+		// groups which aren't cached are not updated
+		loader.AppendToGroup(ctx, "xy", "z")
+		if values, _ := loader.LoadGroup(ctx, "xy")(); !reflect.DeepEqual(values, []string{"x", "y"}) {
+			t.Errorf("unexpected group %#v", values)
+		}
+
+		expected := [][]string{{"abc"}, {"xy"}}
+		if calls := loadCalls(); !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("primes and clears groups", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := LetterLoader()
+		ctx := context.Background()
+
+		loader.PrimeGroup(ctx, "ab", []string{"primed"})
+		if values, _ := loader.LoadGroup(ctx, "ab")(); !reflect.DeepEqual(values, []string{"primed"}) {
+			t.Errorf("did not use primed group, got %#v", values)
+		}
+
+		loader.ClearGroup(ctx, "ab")
+		if values, _ := loader.LoadGroup(ctx, "ab")(); !reflect.DeepEqual(values, []string{"a", "b"}) {
+			t.Errorf("unexpected group %#v", values)
+		}
+
+		expected := [][]string{{"ab"}}
+		if calls := loadCalls(); !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+}
+
+// LetterLoader loads the letters of its keys as a group.
+func LetterLoader() (*GroupLoader[string, string], func() [][]string) {
+	var mu sync.Mutex
+	var loadCalls [][]string
+	loader := NewGroupLoader(func(_ context.Context, keys []string) []*Result[[]string] {
+		var results []*Result[[]string]
+		mu.Lock()
+		loadCalls = append(loadCalls, keys)
+		mu.Unlock()
+		for _, key := range keys {
+			if key == "" {
+				results = append(results, &Result[[]string]{Error: errors.New("empty key")})
+				continue
+			}
+			results = append(results, &Result[[]string]{Data: strings.Split(key, "")})
+		}
+		return results
+	})
+	return loader, func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return loadCalls
+	}
+}
@@ -13,10 +13,37 @@ import (
 type InMemoryCache[K comparable, V any] struct {
 	items map[K]Thunk[V]
 	mu    sync.RWMutex
+
+	// if set, the items are stored in the sync.Map instead, see WithSyncMap
+	syncMap *sync.Map
+}
+
+// InMemoryCacheOption allows for configuration of InMemoryCache fields.
+type InMemoryCacheOption func(*inMemoryCacheConfig)
+
+// inMemoryCacheConfig is the configuration of an InMemoryCache.
+type inMemoryCacheConfig struct {
+	syncMap bool
+}
+
+// WithSyncMap stores the items of the cache in a sync.Map rather than in a map
+// guarded by a lock. It performs better for long lived loaders whose keys are
+// loaded once and read many times by concurrent goroutines.
+func WithSyncMap() InMemoryCacheOption {
+	return func(c *inMemoryCacheConfig) {
+		c.syncMap = true
+	}
 }
 
 // NewCache constructs a new InMemoryCache
-func NewCache[K comparable, V any]() *InMemoryCache[K, V] {
+func NewCache[K comparable, V any](opts ...InMemoryCacheOption) *InMemoryCache[K, V] {
+	var conf inMemoryCacheConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	if conf.syncMap {
+		return &InMemoryCache[K, V]{syncMap: &sync.Map{}}
+	}
 	items := make(map[K]Thunk[V])
 	return &InMemoryCache[K, V]{
 		items: items,
@@ -25,6 +52,10 @@ func NewCache[K comparable, V any]() *InMemoryCache[K, V] {
 
 // Set sets the `value` at `key` in the cache
 func (c *InMemoryCache[K, V]) Set(_ context.Context, key K, value Thunk[V]) {
+	if c.syncMap != nil {
+		c.syncMap.Store(key, value)
+		return
+	}
 	c.mu.Lock()
 	c.items[key] = value
 	c.mu.Unlock()
@@ -33,6 +64,14 @@ func (c *InMemoryCache[K, V]) Set(_ context.Context, key K, value Thunk[V]) {
 // Get gets the value at `key` if it exists, returns value (or nil) and bool
 // indicating of value was found
 func (c *InMemoryCache[K, V]) Get(_ context.Context, key K) (Thunk[V], bool) {
+	if c.syncMap != nil {
+		item, found := c.syncMap.Load(key)
+		if !found {
+			return nil, false
+		}
+		return item.(Thunk[V]), true
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -45,19 +84,80 @@ func (c *InMemoryCache[K, V]) Get(_ context.Context, key K) (Thunk[V], bool) {
 }
 
 // Delete deletes item at `key` from cache
-func (c *InMemoryCache[K, V]) Delete(ctx context.Context, key K) bool {
-	if _, found := c.Get(ctx, key); found {
-		c.mu.Lock()
-		defer c.mu.Unlock()
-		delete(c.items, key)
-		return true
+func (c *InMemoryCache[K, V]) Delete(_ context.Context, key K) bool {
+	if c.syncMap != nil {
+		_, found := c.syncMap.LoadAndDelete(key)
+		return found
 	}
-	return false
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, found := c.items[key]
+	delete(c.items, key)
+	return found
 }
 
 // Clear clears the entire cache
 func (c *InMemoryCache[K, V]) Clear() {
+	if c.syncMap != nil {
+		// ranging rather than calling Clear, which is recent (Go 1.23) and
+		// not offered by every runtime (i.e. TinyGo)
+		c.syncMap.Range(func(key, _ any) bool {
+			c.syncMap.Delete(key)
+			return true
+		})
+		return
+	}
 	c.mu.Lock()
 	c.items = map[K]Thunk[V]{}
 	c.mu.Unlock()
 }
+
+// InMemoryDataCache is an in memory implementation of DataCache interface.
+// Like InMemoryCache, it is well suited for a "per-request" dataloader.
+type InMemoryDataCache[K comparable, V any] struct {
+	items map[K]*Result[V]
+	mu    sync.RWMutex
+}
+
+// NewDataCache constructs a new InMemoryDataCache
+func NewDataCache[K comparable, V any]() *InMemoryDataCache[K, V] {
+	return &InMemoryDataCache[K, V]{
+		items: make(map[K]*Result[V]),
+	}
+}
+
+// Set sets the `result` at `key` in the cache
+func (c *InMemoryDataCache[K, V]) Set(_ context.Context, key K, result *Result[V]) {
+	c.mu.Lock()
+	c.items[key] = result
+	c.mu.Unlock()
+}
+
+// Get gets the result at `key` if it exists, returns result (or nil) and bool
+// indicating of result was found
+func (c *InMemoryDataCache[K, V]) Get(_ context.Context, key K) (*Result[V], bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result, found := c.items[key]
+	return result, found
+}
+
+// Delete deletes result at `key` from cache
+func (c *InMemoryDataCache[K, V]) Delete(_ context.Context, key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, found := c.items[key]
+	delete(c.items, key)
+	return found
+}
+
+// Clear clears the entire cache
+func (c *InMemoryDataCache[K, V]) Clear() {
+	c.mu.Lock()
+	c.items = map[K]*Result[V]{}
+	c.mu.Unlock()
+}
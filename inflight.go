@@ -0,0 +1,26 @@
+package dataloader
+
+import "context"
+
+// WithInFlightDedupe dedupes the loads of a key only while it's being loaded:
+// the loads of a key share its pending batch, but the key is forgotten once
+// its batch has resolved, so the next load of the key fetches it again. It
+// suits the data which must always be fresh, but must not be fetched twice
+// concurrently, which neither NoCache (fetching every load) nor a cache
+// (serving the loaded values) provide.
+//
+// The values primed by Prime are still cached, until they are cleared.
+func WithInFlightDedupe[K comparable, V any]() Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.inFlight = true
+	}
+}
+
+// settled forgets the key of a request before it's resolved, for
+// WithInFlightDedupe, so that the loads following its resolution don't share
+// its result.
+func (l *Loader[K, V]) settled(ctx context.Context, req *batchRequest[K, V]) {
+	if !req.uncached {
+		l.drop(ctx, req.key, req.epoch)
+	}
+}
@@ -0,0 +1,126 @@
+// Package ent provides the batch functions of the loaders of ent entities,
+// mapping the queried entities back to the keys of the batch. The helpers are
+// generic over the queries, so they work with the code generated for any
+// schema, and don't depend on ent itself.
+package ent
+
+import (
+	"context"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// Query queries the entities of the keys of a batch, in any order, i.e.
+//
+//	func(ctx context.Context, ids []int) ([]*ent.User, error) {
+//		return client.User.Query().Where(user.IDIn(ids...)).All(ctx)
+//	}
+type Query[K comparable, V any] func(ctx context.Context, keys []K) ([]V, error)
+
+// ByID returns a batch function loading the entities by ID: the entities are
+// mapped back to the keys by their ID field, and the keys without an entity
+// resolve with dataloader.ErrNotFound. If the query fails, every key
+// resolves with its error.
+//
+//	loader := dataloader.NewBatchedLoader(ent.ByID(
+//		func(ctx context.Context, ids []int) ([]*ent.User, error) {
+//			return client.User.Query().Where(user.IDIn(ids...)).All(ctx)
+//		},
+//		func(u *ent.User) int { return u.ID },
+//	))
+func ByID[K comparable, V any](query Query[K, V], id func(V) K) dataloader.BatchFunc[K, V] {
+	return func(ctx context.Context, keys []K) []*dataloader.Result[V] {
+		entities, err := query(ctx, keys)
+		if err != nil {
+			return failed[V](len(keys), err)
+		}
+		byID := make(map[K]V, len(entities))
+		for _, e := range entities {
+			byID[id(e)] = e
+		}
+		results := make([]*dataloader.Result[V], len(keys))
+		for i, key := range keys {
+			if e, ok := byID[key]; ok {
+				results[i] = &dataloader.Result[V]{Data: e}
+			} else {
+				results[i] = dataloader.NotFound[V]()
+			}
+		}
+		return results
+	}
+}
+
+// ByOwner returns a batch function loading the entities of a one-to-many
+// relation by the ID of their owner: the entities are grouped by their
+// foreign key, and the keys without an entity resolve with an empty slice.
+// If the query fails, every key resolves with its error.
+//
+//	loader := dataloader.NewBatchedLoader(ent.ByOwner(
+//		func(ctx context.Context, ids []int) ([]*ent.Post, error) {
+//			return client.Post.Query().Where(post.AuthorIDIn(ids...)).All(ctx)
+//		},
+//		func(p *ent.Post) int { return p.AuthorID },
+//	))
+func ByOwner[K comparable, V any](query Query[K, V], owner func(V) K) dataloader.BatchFunc[K, []V] {
+	return func(ctx context.Context, keys []K) []*dataloader.Result[[]V] {
+		entities, err := query(ctx, keys)
+		if err != nil {
+			return failed[[]V](len(keys), err)
+		}
+		byOwner := make(map[K][]V, len(keys))
+		for _, e := range entities {
+			key := owner(e)
+			byOwner[key] = append(byOwner[key], e)
+		}
+		return grouped(keys, byOwner)
+	}
+}
+
+// Edges returns a batch function loading the neighbors of the owners through
+// an edge eager loaded by the query, which works for the many-to-many
+// relations as well: the neighbors are mapped back to the keys by the ID
+// field of their owner, and the keys without an owner resolve with an empty
+// slice. If the query fails, every key resolves with its error.
+//
+//	loader := dataloader.NewBatchedLoader(ent.Edges(
+//		func(ctx context.Context, ids []int) ([]*ent.User, error) {
+//			return client.User.Query().Where(user.IDIn(ids...)).WithGroups().All(ctx)
+//		},
+//		func(u *ent.User) int { return u.ID },
+//		func(u *ent.User) []*ent.Group { return u.Edges.Groups },
+//	))
+func Edges[K comparable, O any, V any](query Query[K, O], id func(O) K, edge func(O) []V) dataloader.BatchFunc[K, []V] {
+	return func(ctx context.Context, keys []K) []*dataloader.Result[[]V] {
+		owners, err := query(ctx, keys)
+		if err != nil {
+			return failed[[]V](len(keys), err)
+		}
+		byOwner := make(map[K][]V, len(owners))
+		for _, o := range owners {
+			byOwner[id(o)] = edge(o)
+		}
+		return grouped(keys, byOwner)
+	}
+}
+
+// grouped returns the results of the keys of a relation.
+func grouped[K comparable, V any](keys []K, groups map[K][]V) []*dataloader.Result[[]V] {
+	results := make([]*dataloader.Result[[]V], len(keys))
+	for i, key := range keys {
+		group := groups[key]
+		if group == nil {
+			group = []V{}
+		}
+		results[i] = &dataloader.Result[[]V]{Data: group}
+	}
+	return results
+}
+
+// failed returns the results of the keys of a failed query.
+func failed[V any](n int, err error) []*dataloader.Result[V] {
+	results := make([]*dataloader.Result[V], n)
+	for i := range results {
+		results[i] = &dataloader.Result[V]{Error: err}
+	}
+	return results
+}
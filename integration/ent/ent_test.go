@@ -0,0 +1,102 @@
+package ent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+type user struct {
+	ID     int
+	Groups []string
+}
+
+type post struct {
+	ID       int
+	AuthorID int
+}
+
+var users = []*user{{ID: 1, Groups: []string{"admin", "staff"}}, {ID: 2}}
+
+// queryUsers returns the users of the IDs, in reverse order.
+func queryUsers(_ context.Context, ids []int) ([]*user, error) {
+	var found []*user
+	for i := len(ids) - 1; i >= 0; i-- {
+		for _, u := range users {
+			if u.ID == ids[i] {
+				found = append(found, u)
+			}
+		}
+	}
+	return found, nil
+}
+
+func TestByID(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("maps the entities back to the keys", func(t *testing.T) {
+		loader := dataloader.NewBatchedLoader(ByID(queryUsers, func(u *user) int { return u.ID }))
+		thunk := loader.LoadMany(ctx, []int{1, 2, 3})
+		found, errs := thunk()
+		if found[0] != users[0] || found[1] != users[1] {
+			t.Errorf("expected the users 1 and 2, got %v", found)
+		}
+		if errs == nil || errs[0] != nil || !dataloader.IsNotFound(errs[2]) {
+			t.Errorf("expected the user 3 not to be found, got %v", errs)
+		}
+	})
+
+	t.Run("fails every key with the error of the query", func(t *testing.T) {
+		fail := errors.New("the database is down")
+		loader := dataloader.NewBatchedLoader(ByID(func(context.Context, []int) ([]*user, error) {
+			return nil, fail
+		}, func(u *user) int { return u.ID }))
+		_, errs := loader.LoadMany(ctx, []int{1, 2})()
+		if len(errs) != 2 || !errors.Is(errs[0], fail) || !errors.Is(errs[1], fail) {
+			t.Errorf("expected the error of the query, got %v", errs)
+		}
+	})
+}
+
+func TestByOwner(t *testing.T) {
+	posts := []*post{{ID: 10, AuthorID: 1}, {ID: 11, AuthorID: 2}, {ID: 12, AuthorID: 1}}
+	loader := dataloader.NewBatchedLoader(ByOwner(func(_ context.Context, ids []int) ([]*post, error) {
+		var found []*post
+		for _, p := range posts {
+			for _, id := range ids {
+				if p.AuthorID == id {
+					found = append(found, p)
+				}
+			}
+		}
+		return found, nil
+	}, func(p *post) int { return p.AuthorID }))
+
+	found, errs := loader.LoadMany(context.Background(), []int{1, 2, 3})()
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	want := [][]*post{{posts[0], posts[2]}, {posts[1]}, {}}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("expected %v, got %v", want, found)
+	}
+}
+
+func TestEdges(t *testing.T) {
+	loader := dataloader.NewBatchedLoader(Edges(queryUsers,
+		func(u *user) int { return u.ID },
+		func(u *user) []string { return u.Groups },
+	))
+
+	found, errs := loader.LoadMany(context.Background(), []int{1, 2, 3})()
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	want := [][]string{{"admin", "staff"}, {}, {}}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("expected %v, got %v", want, found)
+	}
+}
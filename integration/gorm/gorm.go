@@ -0,0 +1,132 @@
+// Package gorm provides the batch functions of the loaders of GORM models,
+// querying the models of the keys of a batch with `WHERE column IN (?)`.
+//
+//	loader := dataloader.NewBatchedLoader(gorm.ByKey(db, "id",
+//		func(u *User) uint { return u.ID },
+//		gorm.WithPreload("Profile"),
+//	))
+package gorm
+
+import (
+	"context"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Option allows for configuration of the queries.
+type Option func(*config)
+
+type config struct {
+	scopes   []func(*gorm.DB) *gorm.DB
+	preloads []preload
+}
+
+type preload struct {
+	query string
+	args  []any
+}
+
+// WithScopes applies the scopes to the queries (i.e. a tenant or a soft
+// delete filter).
+func WithScopes(scopes ...func(*gorm.DB) *gorm.DB) Option {
+	return func(c *config) {
+		c.scopes = append(c.scopes, scopes...)
+	}
+}
+
+// WithPreload preloads the association of the models, with the conditions
+// of gorm.DB.Preload.
+func WithPreload(query string, args ...any) Option {
+	return func(c *config) {
+		c.preloads = append(c.preloads, preload{query, args})
+	}
+}
+
+// ByKey returns a batch function loading a model M per key from the column:
+// the models are mapped back to the keys by their key field, and the keys
+// without a model resolve with dataloader.ErrNotFound. If the query fails,
+// every key resolves with its error.
+func ByKey[K comparable, M any](db *gorm.DB, column string, key func(*M) K, opts ...Option) dataloader.BatchFunc[K, *M] {
+	query := find[K, M](db, column, opts)
+	return func(ctx context.Context, keys []K) []*dataloader.Result[*M] {
+		models, err := query(ctx, keys)
+		if err != nil {
+			return failed[*M](len(keys), err)
+		}
+		byKey := make(map[K]*M, len(models))
+		for _, m := range models {
+			byKey[key(m)] = m
+		}
+		results := make([]*dataloader.Result[*M], len(keys))
+		for i, k := range keys {
+			if m, ok := byKey[k]; ok {
+				results[i] = &dataloader.Result[*M]{Data: m}
+			} else {
+				results[i] = dataloader.NotFound[*M]()
+			}
+		}
+		return results
+	}
+}
+
+// GroupByKey returns a batch function loading the models M of each key from
+// the column (i.e. the models of a one-to-many relation, by their foreign
+// key): the models are grouped by their key field, and the keys without a
+// model resolve with an empty slice. If the query fails, every key resolves
+// with its error.
+func GroupByKey[K comparable, M any](db *gorm.DB, column string, key func(*M) K, opts ...Option) dataloader.BatchFunc[K, []*M] {
+	query := find[K, M](db, column, opts)
+	return func(ctx context.Context, keys []K) []*dataloader.Result[[]*M] {
+		models, err := query(ctx, keys)
+		if err != nil {
+			return failed[[]*M](len(keys), err)
+		}
+		byKey := make(map[K][]*M, len(keys))
+		for _, m := range models {
+			k := key(m)
+			byKey[k] = append(byKey[k], m)
+		}
+		results := make([]*dataloader.Result[[]*M], len(keys))
+		for i, k := range keys {
+			group := byKey[k]
+			if group == nil {
+				group = []*M{}
+			}
+			results[i] = &dataloader.Result[[]*M]{Data: group}
+		}
+		return results
+	}
+}
+
+// find returns the query of the models of the keys.
+func find[K comparable, M any](db *gorm.DB, column string, opts []Option) func(context.Context, []K) ([]*M, error) {
+	var conf config
+	for _, apply := range opts {
+		apply(&conf)
+	}
+	return func(ctx context.Context, keys []K) ([]*M, error) {
+		values := make([]any, len(keys))
+		for i, k := range keys {
+			values[i] = k
+		}
+		tx := db.WithContext(ctx).Scopes(conf.scopes...)
+		for _, p := range conf.preloads {
+			tx = tx.Preload(p.query, p.args...)
+		}
+		var models []*M
+		err := tx.Where(clause.IN{Column: clause.Column{Name: column}, Values: values}).Find(&models).Error
+		return models, err
+	}
+}
+
+// failed returns the results of the keys of a failed query.
+func failed[V any](n int, err error) []*dataloader.Result[V] {
+	results := make([]*dataloader.Result[V], n)
+	for i := range results {
+		results[i] = &dataloader.Result[V]{Error: err}
+	}
+	return results
+}
@@ -0,0 +1,93 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Author struct {
+	ID    uint
+	Name  string
+	Books []Book
+}
+
+type Book struct {
+	ID       uint
+	AuthorID uint
+	Title    string
+	Author   *Author
+}
+
+func open(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Author{}, &Book{}); err != nil {
+		t.Fatal(err)
+	}
+	authors := []Author{
+		{ID: 1, Name: "Ursula", Books: []Book{{ID: 10, Title: "The Dispossessed"}, {ID: 11, Title: "Lathe of Heaven"}}},
+		{ID: 2, Name: "Iain", Books: []Book{{ID: 20, Title: "Excession"}}},
+		{ID: 3, Name: "Unpublished"},
+	}
+	if err := db.Create(&authors).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestByKey(t *testing.T) {
+	ctx := context.Background()
+	db := open(t)
+
+	t.Run("maps the models back to the keys", func(t *testing.T) {
+		loader := dataloader.NewBatchedLoader(ByKey(db, "id", func(a *Author) uint { return a.ID }))
+		found, errs := loader.LoadMany(ctx, []uint{2, 1, 4})()
+		if found[0].Name != "Iain" || found[1].Name != "Ursula" {
+			t.Errorf("expected the authors 2 and 1, got %v", found)
+		}
+		if errs == nil || errs[0] != nil || !dataloader.IsNotFound(errs[2]) {
+			t.Errorf("expected the author 4 not to be found, got %v", errs)
+		}
+	})
+
+	t.Run("applies the scopes and the preloads", func(t *testing.T) {
+		loader := dataloader.NewBatchedLoader(ByKey(db, "id", func(b *Book) uint { return b.ID },
+			WithScopes(func(db *gorm.DB) *gorm.DB { return db.Where("title <> ?", "Excession") }),
+			WithPreload("Author"),
+		))
+		found, errs := loader.LoadMany(ctx, []uint{10, 20})()
+		if found[0].Author == nil || found[0].Author.Name != "Ursula" {
+			t.Errorf("expected the author to be preloaded, got %+v", found[0])
+		}
+		if errs == nil || !dataloader.IsNotFound(errs[1]) {
+			t.Errorf("expected the book 20 to be filtered out, got %v", errs)
+		}
+	})
+
+	t.Run("fails every key with the error of the query", func(t *testing.T) {
+		loader := dataloader.NewBatchedLoader(ByKey(db, "missing", func(a *Author) uint { return a.ID }))
+		_, errs := loader.LoadMany(ctx, []uint{1, 2})()
+		if len(errs) != 2 || errs[0] == nil || errs[1] == nil {
+			t.Errorf("expected the error of the query, got %v", errs)
+		}
+	})
+}
+
+func TestGroupByKey(t *testing.T) {
+	db := open(t)
+	loader := dataloader.NewBatchedLoader(GroupByKey(db, "author_id", func(b *Book) uint { return b.AuthorID }))
+	found, errs := loader.LoadMany(context.Background(), []uint{1, 2, 3})()
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if len(found[0]) != 2 || len(found[1]) != 1 || found[1][0].Title != "Excession" || len(found[2]) != 0 {
+		t.Errorf("expected the books of each author, got %v", found)
+	}
+}
@@ -0,0 +1,102 @@
+// Package grpc provides the gRPC server interceptors attaching a
+// dataloader.LoaderRegistry to the context of every RPC, so that the handlers
+// of a gateway service batch their downstream lookups with loaders which
+// don't outlive the RPC. The loaders are drained once the RPC completes.
+//
+//	server := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(dlgrpc.UnaryServerInterceptor()),
+//		grpc.ChainStreamInterceptor(dlgrpc.StreamServerInterceptor(dlgrpc.WithPerMessage())),
+//	)
+package grpc
+
+import (
+	"context"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"google.golang.org/grpc"
+)
+
+// Option allows for configuration of the interceptors.
+type Option func(*config)
+
+type config struct {
+	perMessage bool
+	onError    func(context.Context, error)
+}
+
+// WithPerMessage makes the stream interceptor reset the registry every time a
+// message is received, so that the loaders are drained and constructed again
+// for the window of each message rather than for the whole stream.
+func WithPerMessage() Option {
+	return func(c *config) {
+		c.perMessage = true
+	}
+}
+
+// WithErrorHandler sets the function called with the error draining the
+// loaders, i.e. when the context of the RPC is done before their batches
+// finish. By default the errors are ignored.
+func WithErrorHandler(onError func(context.Context, error)) Option {
+	return func(c *config) {
+		c.onError = onError
+	}
+}
+
+func newConfig(opts []Option) *config {
+	conf := &config{onError: func(context.Context, error) {}}
+	for _, apply := range opts {
+		apply(conf)
+	}
+	return conf
+}
+
+// UnaryServerInterceptor returns a unary server interceptor attaching a new
+// registry to the context of every RPC, and draining its loaders once the
+// handler returns.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	conf := newConfig(opts)
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, registry := dataloader.ContextWithLoaderRegistry(ctx)
+		defer conf.drain(ctx, registry.Drain)
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor attaching a new
+// registry to the context of every stream, and draining its loaders once the
+// handler returns (or once each message is received, see WithPerMessage).
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	conf := newConfig(opts)
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, registry := dataloader.ContextWithLoaderRegistry(ss.Context())
+		defer conf.drain(ctx, registry.Drain)
+		return handler(srv, &stream{ServerStream: ss, ctx: ctx, registry: registry, conf: conf})
+	}
+}
+
+// drain calls drain, and reports its error.
+func (c *config) drain(ctx context.Context, drain func(context.Context) error) {
+	if err := drain(ctx); err != nil {
+		c.onError(ctx, err)
+	}
+}
+
+// stream is a grpc.ServerStream whose context holds the registry.
+type stream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	registry *dataloader.LoaderRegistry
+	conf     *config
+}
+
+func (s *stream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *stream) RecvMsg(m any) error {
+	if s.conf.perMessage {
+		s.conf.drain(s.ctx, s.registry.Reset)
+	}
+	return s.ServerStream.RecvMsg(m)
+}
@@ -0,0 +1,99 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"google.golang.org/grpc"
+)
+
+var users = dataloader.NewLoaderKey(func(context.Context) *dataloader.Loader[string, string] {
+	return dataloader.NewBatchedLoader(func(_ context.Context, keys []string) []*dataloader.Result[string] {
+		results := make([]*dataloader.Result[string], len(keys))
+		for i, key := range keys {
+			results[i] = &dataloader.Result[string]{Data: key}
+		}
+		return results
+	}, dataloader.WithWait[string, string](time.Hour))
+})
+
+// fakeStream is a stream receiving a number of messages.
+type fakeStream struct {
+	grpc.ServerStream
+	messages int
+}
+
+func (s *fakeStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeStream) RecvMsg(any) error {
+	s.messages--
+	if s.messages < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var thunk dataloader.Thunk[string]
+	interceptor := UnaryServerInterceptor()
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+		if _, ok := dataloader.LoaderRegistryFromContext(ctx); !ok {
+			t.Error("expected a registry in the context")
+		}
+		thunk = users.From(ctx).Load(ctx, "1")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the batch was dispatched by the drain, way before the end of its window
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if v, err := thunk(); v != "1" || err != nil {
+			t.Errorf("expected 1, got %q, %v", v, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the loaders to be drained")
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		opts    []Option
+		loaders int
+	}{
+		{"a registry per stream", nil, 1},
+		{"a registry per message", []Option{WithPerMessage()}, 3},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			seen := make(map[*dataloader.Loader[string, string]]bool)
+			interceptor := StreamServerInterceptor(test.opts...)
+			err := interceptor(nil, &fakeStream{messages: 3}, &grpc.StreamServerInfo{}, func(_ any, ss grpc.ServerStream) error {
+				ctx := ss.Context()
+				for ss.RecvMsg(nil) == nil {
+					loader := users.From(ctx)
+					seen[loader] = true
+					loader.Load(ctx, "1")
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(seen) != test.loaders {
+				t.Errorf("expected %d loaders, got %d", test.loaders, len(seen))
+			}
+		})
+	}
+}
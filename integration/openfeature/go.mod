@@ -0,0 +1,15 @@
+module github.com/graph-gophers/dataloader/v7/integration/openfeature
+
+go 1.23
+
+require (
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+	github.com/open-feature/go-sdk v1.14.1
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	golang.org/x/exp v0.0.0-20240506185415-9bf2ced13842 // indirect
+)
+
+replace github.com/graph-gophers/dataloader/v7 => ../..
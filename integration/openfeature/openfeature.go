@@ -0,0 +1,48 @@
+// Package openfeature adapts the OpenFeature clients into a
+// dataloader.Flagger, so that the behaviors of the loaders are toggled by the
+// flags of any OpenFeature provider.
+//
+// The flags are evaluated by their name (i.e. "dataloader.cache", see
+// dataloader.Flag), with the evaluation context of the client and the
+// transaction context of the load (see openfeature.WithTransactionContext),
+// along with a "loader" attribute holding the name of the loader.
+package openfeature
+
+import (
+	"context"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/open-feature/go-sdk/openfeature"
+)
+
+// Client is the subset of the OpenFeature client used by Flagger.
+type Client interface {
+	BooleanValue(ctx context.Context, flag string, defaultValue bool, evalCtx openfeature.EvaluationContext, options ...openfeature.Option) (bool, error)
+}
+
+var _ Client = &openfeature.Client{}
+
+// Flagger implements the dataloader.Flagger interface with OpenFeature.
+type Flagger struct {
+	client Client
+}
+
+var _ dataloader.Flagger = &Flagger{}
+
+// New constructs a new Flagger evaluating the flags with the client, i.e.
+// openfeature.NewClient("dataloader").
+func New(client Client) *Flagger {
+	return &Flagger{client: client}
+}
+
+// Enabled evaluates the flag for the loader. If the evaluation fails, it
+// returns def.
+func (f *Flagger) Enabled(ctx context.Context, loader string, flag dataloader.Flag, def bool) bool {
+	evalCtx := openfeature.NewTargetlessEvaluationContext(map[string]any{"loader": loader})
+	enabled, err := f.client.BooleanValue(ctx, string(flag), def, evalCtx)
+	if err != nil {
+		return def
+	}
+	return enabled
+}
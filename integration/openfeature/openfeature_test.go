@@ -0,0 +1,55 @@
+package openfeature
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/open-feature/go-sdk/openfeature"
+	"github.com/open-feature/go-sdk/openfeature/memprovider"
+)
+
+func TestFlagger(t *testing.T) {
+	// the cache is off for the users loader only
+	evaluate := func(flag memprovider.InMemoryFlag, evalCtx openfeature.FlattenedContext) (any, openfeature.ProviderResolutionDetail) {
+		return evalCtx["loader"] != "users", openfeature.ProviderResolutionDetail{Reason: openfeature.TargetingMatchReason}
+	}
+	provider := memprovider.NewInMemoryProvider(map[string]memprovider.InMemoryFlag{
+		string(dataloader.FlagCache): {
+			Key:              string(dataloader.FlagCache),
+			State:            memprovider.Enabled,
+			DefaultVariant:   "on",
+			Variants:         map[string]any{"on": true},
+			ContextEvaluator: &evaluate,
+		},
+	})
+	if err := openfeature.SetNamedProviderAndWait("dataloader-test", provider); err != nil {
+		t.Fatal(err)
+	}
+	flagger := New(openfeature.NewClient("dataloader-test"))
+	ctx := context.Background()
+
+	if flagger.Enabled(ctx, "users", dataloader.FlagCache, true) {
+		t.Error("expected the cache of users to be off")
+	}
+	if !flagger.Enabled(ctx, "posts", dataloader.FlagCache, true) {
+		t.Error("expected the cache of posts to be on")
+	}
+	if flagger.Enabled(ctx, "users", dataloader.FlagBatching, false) {
+		t.Error("expected the default of an unknown flag")
+	}
+}
+
+type failingClient struct{}
+
+func (failingClient) BooleanValue(context.Context, string, bool, openfeature.EvaluationContext, ...openfeature.Option) (bool, error) {
+	return false, errors.New("the provider is down")
+}
+
+func TestFlaggerError(t *testing.T) {
+	if !New(failingClient{}).Enabled(context.Background(), "users", dataloader.FlagCache, true) {
+		t.Error("expected the default when the evaluation fails")
+	}
+}
@@ -0,0 +1,138 @@
+// Package pgx provides the batch functions of the loaders of PostgreSQL rows
+// queried with pgx, either with a single `= ANY($1)` query for the keys of a
+// batch, or with a query per key sent in a single round trip with pgx.Batch.
+//
+// The rows are scanned by a pgx.RowToFunc, i.e. pgx.RowToStructByName (with
+// github.com/jackc/pgx/v5 imported as pgxv5):
+//
+//	loader := dataloader.NewBatchedLoader(pgx.Any(pool,
+//		"SELECT id, name FROM users WHERE id = ANY($1)",
+//		pgxv5.RowToStructByName[User],
+//		func(u User) int64 { return u.ID },
+//	))
+package pgx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Querier is the subset of the pgx connections used by the batch functions,
+// implemented by *pgx.Conn, *pgxpool.Pool and pgx.Tx.
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// Any returns a batch function running the query with the keys of a batch as
+// its only argument (i.e. `SELECT * FROM users WHERE id = ANY($1)`): the rows
+// are mapped back to the keys by the field selected by key, and the keys
+// without a row resolve with dataloader.ErrNotFound. If the query fails,
+// every key resolves with its error.
+func Any[K comparable, V any](db Querier, sql string, scan pgx.RowToFunc[V], key func(V) K) dataloader.BatchFunc[K, V] {
+	return func(ctx context.Context, keys []K) []*dataloader.Result[V] {
+		values, err := collect(ctx, db, sql, keys, scan)
+		if err != nil {
+			return failed[V](len(keys), err)
+		}
+		byKey := make(map[K]V, len(values))
+		for _, v := range values {
+			byKey[key(v)] = v
+		}
+		results := make([]*dataloader.Result[V], len(keys))
+		for i, k := range keys {
+			if v, ok := byKey[k]; ok {
+				results[i] = &dataloader.Result[V]{Data: v}
+			} else {
+				results[i] = dataloader.NotFound[V]()
+			}
+		}
+		return results
+	}
+}
+
+// AnyGrouped returns a batch function running the query with the keys of a
+// batch as its only argument, like Any, for the rows of a one-to-many
+// relation: the rows are grouped by the field selected by key (i.e. a foreign
+// key), in the order of the query, and the keys without a row resolve with an
+// empty slice. If the query fails, every key resolves with its error.
+func AnyGrouped[K comparable, V any](db Querier, sql string, scan pgx.RowToFunc[V], key func(V) K) dataloader.BatchFunc[K, []V] {
+	return func(ctx context.Context, keys []K) []*dataloader.Result[[]V] {
+		values, err := collect(ctx, db, sql, keys, scan)
+		if err != nil {
+			return failed[[]V](len(keys), err)
+		}
+		byKey := make(map[K][]V, len(keys))
+		for _, v := range values {
+			k := key(v)
+			byKey[k] = append(byKey[k], v)
+		}
+		results := make([]*dataloader.Result[[]V], len(keys))
+		for i, k := range keys {
+			group := byKey[k]
+			if group == nil {
+				group = []V{}
+			}
+			results[i] = &dataloader.Result[[]V]{Data: group}
+		}
+		return results
+	}
+}
+
+// Batch returns a batch function queuing the query once per key in a
+// pgx.Batch, with the arguments returned by args, for the keys which can't be
+// loaded by a single query (i.e. the top rows of each key, or a function
+// call). The batch is sent in a single round trip: each key resolves with the
+// first row of its query, or with dataloader.ErrNotFound if there is none,
+// or with the error of its query.
+func Batch[K comparable, V any](db Querier, sql string, args func(K) []any, scan pgx.RowToFunc[V]) dataloader.BatchFunc[K, V] {
+	return func(ctx context.Context, keys []K) []*dataloader.Result[V] {
+		batch := &pgx.Batch{}
+		for _, k := range keys {
+			batch.Queue(sql, args(k)...)
+		}
+		br := db.SendBatch(ctx, batch)
+		defer br.Close()
+
+		results := make([]*dataloader.Result[V], len(keys))
+		for i := range keys {
+			rows, err := br.Query()
+			if err != nil {
+				results[i] = &dataloader.Result[V]{Error: err}
+				continue
+			}
+			v, err := pgx.CollectOneRow(rows, scan)
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				results[i] = dataloader.NotFound[V]()
+			case err != nil:
+				results[i] = &dataloader.Result[V]{Error: err}
+			default:
+				results[i] = &dataloader.Result[V]{Data: v}
+			}
+		}
+		return results
+	}
+}
+
+// collect runs the query with the keys, and scans its rows.
+func collect[K comparable, V any](ctx context.Context, db Querier, sql string, keys []K, scan pgx.RowToFunc[V]) ([]V, error) {
+	rows, err := db.Query(ctx, sql, keys)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scan)
+}
+
+// failed returns the results of the keys of a failed query.
+func failed[V any](n int, err error) []*dataloader.Result[V] {
+	results := make([]*dataloader.Result[V], n)
+	for i := range results {
+		results[i] = &dataloader.Result[V]{Error: err}
+	}
+	return results
+}
@@ -0,0 +1,161 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type book struct {
+	ID       int64
+	AuthorID int64
+}
+
+var books = []book{{ID: 1, AuthorID: 10}, {ID: 2, AuthorID: 20}, {ID: 3, AuthorID: 10}}
+
+func scanBook(row pgx.CollectableRow) (book, error) {
+	var b book
+	err := row.Scan(&b.ID, &b.AuthorID)
+	return b, err
+}
+
+// fakeRows are the rows of a query, scanned into *int64 destinations.
+type fakeRows struct {
+	rows   [][]int64
+	cursor int
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+func (r *fakeRows) Values() ([]any, error)                       { return nil, errors.New("not supported") }
+
+func (r *fakeRows) Next() bool {
+	r.cursor++
+	return r.cursor <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, d := range dest {
+		*d.(*int64) = r.rows[r.cursor-1][i]
+	}
+	return nil
+}
+
+// fakeDB answers the queries of the books of the IDs, or of the authors, the
+// single argument of the queries being the ID or the IDs.
+type fakeDB struct {
+	err     error
+	queries int
+}
+
+func (db *fakeDB) rows(sql string, arg any) *fakeRows {
+	ids, ok := arg.([]int64)
+	if !ok {
+		ids = []int64{arg.(int64)}
+	}
+	rows := &fakeRows{}
+	for _, b := range books {
+		for _, id := range ids {
+			if sql == "books" && b.ID == id || sql == "authors" && b.AuthorID == id {
+				rows.rows = append(rows.rows, []int64{b.ID, b.AuthorID})
+			}
+		}
+	}
+	return rows
+}
+
+func (db *fakeDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	db.queries++
+	if db.err != nil {
+		return nil, db.err
+	}
+	return db.rows(sql, args[0]), nil
+}
+
+func (db *fakeDB) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	db.queries++
+	return &fakeBatchResults{db: db, queued: b.QueuedQueries}
+}
+
+type fakeBatchResults struct {
+	db     *fakeDB
+	queued []*pgx.QueuedQuery
+}
+
+func (r *fakeBatchResults) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, nil }
+func (r *fakeBatchResults) QueryRow() pgx.Row                { return nil }
+func (r *fakeBatchResults) Close() error                     { return nil }
+
+func (r *fakeBatchResults) Query() (pgx.Rows, error) {
+	q := r.queued[0]
+	r.queued = r.queued[1:]
+	if q.Arguments[0].(int64) < 0 {
+		return nil, errors.New("invalid ID")
+	}
+	return r.db.rows(q.SQL, q.Arguments[0]), nil
+}
+
+func TestAny(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("maps the rows back to the keys", func(t *testing.T) {
+		db := &fakeDB{}
+		loader := dataloader.NewBatchedLoader(Any(db, "books", scanBook, func(b book) int64 { return b.ID }))
+		found, errs := loader.LoadMany(ctx, []int64{3, 1, 4})()
+		if found[0] != books[2] || found[1] != books[0] {
+			t.Errorf("expected the books 3 and 1, got %v", found)
+		}
+		if errs == nil || errs[0] != nil || !dataloader.IsNotFound(errs[2]) {
+			t.Errorf("expected the book 4 not to be found, got %v", errs)
+		}
+		if db.queries != 1 {
+			t.Errorf("expected a single query, got %d", db.queries)
+		}
+	})
+
+	t.Run("fails every key with the error of the query", func(t *testing.T) {
+		db := &fakeDB{err: errors.New("the database is down")}
+		loader := dataloader.NewBatchedLoader(Any(db, "books", scanBook, func(b book) int64 { return b.ID }))
+		_, errs := loader.LoadMany(ctx, []int64{1, 2})()
+		if len(errs) != 2 || !errors.Is(errs[0], db.err) || !errors.Is(errs[1], db.err) {
+			t.Errorf("expected the error of the query, got %v", errs)
+		}
+	})
+}
+
+func TestAnyGrouped(t *testing.T) {
+	loader := dataloader.NewBatchedLoader(AnyGrouped(&fakeDB{}, "authors", scanBook, func(b book) int64 { return b.AuthorID }))
+	found, errs := loader.LoadMany(context.Background(), []int64{10, 20, 30})()
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	want := [][]book{{books[0], books[2]}, {books[1]}, {}}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("expected %v, got %v", want, found)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	db := &fakeDB{}
+	loader := dataloader.NewBatchedLoader(Batch(db, "authors", func(id int64) []any { return []any{id} }, scanBook))
+	found, errs := loader.LoadMany(context.Background(), []int64{20, 10, 30, -1})()
+	if found[0] != books[1] || found[1] != books[0] {
+		t.Errorf("expected the first book of the authors 20 and 10, got %v", found)
+	}
+	if errs == nil || errs[1] != nil || !dataloader.IsNotFound(errs[2]) || errs[3] == nil || dataloader.IsNotFound(errs[3]) {
+		t.Errorf("expected the author 30 not to be found and -1 to fail, got %v", errs)
+	}
+	if db.queries != 1 {
+		t.Errorf("expected a single round trip, got %d", db.queries)
+	}
+}
@@ -0,0 +1,91 @@
+// Package sqlc adapts the queries generated by sqlc into batch functions.
+//
+// A query listing the rows of a slice of keys, i.e. for PostgreSQL:
+//
+//	-- name: ListUsersByIDs :many
+//	SELECT * FROM users WHERE id = ANY(@ids::bigint[]);
+//
+// or for MySQL and SQLite:
+//
+//	-- name: ListUsersByIDs :many
+//	SELECT * FROM users WHERE id IN (sqlc.slice('ids'));
+//
+// is generated as a method of the signature of Query, which List and
+// ListGrouped turn into a batch function:
+//
+//	loader := dataloader.NewBatchedLoader(sqlc.List(queries.ListUsersByIDs,
+//		func(u db.User) int64 { return u.ID }))
+package sqlc
+
+import (
+	"context"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// Query is a query generated by sqlc, listing the rows of the keys of a
+// batch in any order.
+type Query[K comparable, V any] func(ctx context.Context, keys []K) ([]V, error)
+
+// List returns a batch function loading a row per key: the rows are ordered
+// like the keys by the field selected by field, and the keys without a row
+// resolve with dataloader.ErrNotFound. If the query fails, every key resolves
+// with its error.
+func List[K comparable, V any](query Query[K, V], field func(V) K) dataloader.BatchFunc[K, V] {
+	return func(ctx context.Context, keys []K) []*dataloader.Result[V] {
+		rows, err := query(ctx, keys)
+		if err != nil {
+			return failed[V](len(keys), err)
+		}
+		byKey := make(map[K]V, len(rows))
+		for _, row := range rows {
+			byKey[field(row)] = row
+		}
+		results := make([]*dataloader.Result[V], len(keys))
+		for i, key := range keys {
+			if row, ok := byKey[key]; ok {
+				results[i] = &dataloader.Result[V]{Data: row}
+			} else {
+				results[i] = dataloader.NotFound[V]()
+			}
+		}
+		return results
+	}
+}
+
+// ListGrouped returns a batch function loading the rows of each key (i.e.
+// the rows of a one-to-many relation, by their foreign key): the rows are
+// grouped by the field selected by field, in the order of the query, and the
+// keys without a row resolve with an empty slice. If the query fails, every
+// key resolves with its error.
+func ListGrouped[K comparable, V any](query Query[K, V], field func(V) K) dataloader.BatchFunc[K, []V] {
+	return func(ctx context.Context, keys []K) []*dataloader.Result[[]V] {
+		rows, err := query(ctx, keys)
+		if err != nil {
+			return failed[[]V](len(keys), err)
+		}
+		byKey := make(map[K][]V, len(keys))
+		for _, row := range rows {
+			key := field(row)
+			byKey[key] = append(byKey[key], row)
+		}
+		results := make([]*dataloader.Result[[]V], len(keys))
+		for i, key := range keys {
+			group := byKey[key]
+			if group == nil {
+				group = []V{}
+			}
+			results[i] = &dataloader.Result[[]V]{Data: group}
+		}
+		return results
+	}
+}
+
+// failed returns the results of the keys of a failed query.
+func failed[V any](n int, err error) []*dataloader.Result[V] {
+	results := make([]*dataloader.Result[V], n)
+	for i := range results {
+		results[i] = &dataloader.Result[V]{Error: err}
+	}
+	return results
+}
@@ -0,0 +1,87 @@
+package sqlc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// Book is a row, like the structs generated by sqlc.
+type Book struct {
+	ID       int64
+	AuthorID int64
+}
+
+var books = []Book{{ID: 1, AuthorID: 10}, {ID: 2, AuthorID: 20}, {ID: 3, AuthorID: 10}}
+
+// queries is like the Queries type generated by sqlc.
+type queries struct {
+	err error
+}
+
+// ListBooksByIDs returns the books of the IDs, in the order of the table.
+func (q *queries) ListBooksByIDs(_ context.Context, ids []int64) ([]Book, error) {
+	return q.list(ids, func(b Book) int64 { return b.ID })
+}
+
+// ListBooksByAuthorIDs returns the books of the authors, in the order of the
+// table.
+func (q *queries) ListBooksByAuthorIDs(_ context.Context, ids []int64) ([]Book, error) {
+	return q.list(ids, func(b Book) int64 { return b.AuthorID })
+}
+
+func (q *queries) list(ids []int64, field func(Book) int64) ([]Book, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	var rows []Book
+	for _, b := range books {
+		for _, id := range ids {
+			if field(b) == id {
+				rows = append(rows, b)
+			}
+		}
+	}
+	return rows, nil
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("orders the rows like the keys", func(t *testing.T) {
+		q := &queries{}
+		loader := dataloader.NewBatchedLoader(List(q.ListBooksByIDs, func(b Book) int64 { return b.ID }))
+		found, errs := loader.LoadMany(ctx, []int64{3, 1, 4})()
+		if found[0] != books[2] || found[1] != books[0] {
+			t.Errorf("expected the books 3 and 1, got %v", found)
+		}
+		if errs == nil || errs[0] != nil || !dataloader.IsNotFound(errs[2]) {
+			t.Errorf("expected the book 4 not to be found, got %v", errs)
+		}
+	})
+
+	t.Run("fails every key with the error of the query", func(t *testing.T) {
+		q := &queries{err: errors.New("the database is down")}
+		loader := dataloader.NewBatchedLoader(List(q.ListBooksByIDs, func(b Book) int64 { return b.ID }))
+		_, errs := loader.LoadMany(ctx, []int64{1, 2})()
+		if len(errs) != 2 || !errors.Is(errs[0], q.err) || !errors.Is(errs[1], q.err) {
+			t.Errorf("expected the error of the query, got %v", errs)
+		}
+	})
+}
+
+func TestListGrouped(t *testing.T) {
+	q := &queries{}
+	loader := dataloader.NewBatchedLoader(ListGrouped(q.ListBooksByAuthorIDs, func(b Book) int64 { return b.AuthorID }))
+	found, errs := loader.LoadMany(context.Background(), []int64{10, 20, 30})()
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	want := [][]Book{{books[0], books[2]}, {books[1]}, {}}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("expected %v, got %v", want, found)
+	}
+}
@@ -0,0 +1,175 @@
+// Package invalidation propagates cache invalidations between the instances
+// of a service: an instance which changes a value publishes its key on a bus,
+// and every instance listening on the bus clears it from its loader.
+//
+// The nats and redisstream subpackages provide buses with at-least-once
+// delivery. Clearing a key twice is harmless, so redelivered invalidations
+// are too.
+package invalidation
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// Message is an invalidation. Keys are encoded with a KeyCodec.
+type Message struct {
+	// Keys are the keys to clear.
+	Keys []string `json:"keys,omitempty"`
+	// All is true when the whole cache is cleared.
+	All bool `json:"all,omitempty"`
+}
+
+// Marshal encodes the message for a bus.
+func (m Message) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// Unmarshal decodes a message of a bus.
+func Unmarshal(data []byte) (Message, error) {
+	var m Message
+	err := json.Unmarshal(data, &m)
+	return m, err
+}
+
+// The Publisher interface publishes invalidations on a bus.
+type Publisher interface {
+	Publish(context.Context, Message) error
+}
+
+// The Subscriber interface receives the invalidations of a bus. Subscribe
+// calls the handler with every message published after it was called, until
+// the context is done, then returns the error of the context. Buses which
+// can't tell whether they missed messages (i.e. while disconnected) call the
+// handler with a message clearing everything.
+type Subscriber interface {
+	Subscribe(ctx context.Context, handler func(Message)) error
+}
+
+// The Bus interface is a Publisher and a Subscriber.
+type Bus interface {
+	Publisher
+	Subscriber
+}
+
+// The KeyCodec interface encodes keys to the strings of messages and back.
+type KeyCodec[K comparable] interface {
+	EncodeKey(K) (string, error)
+	DecodeKey(string) (K, error)
+}
+
+// StringKeys is the KeyCodec of string keys.
+type StringKeys struct{}
+
+func (StringKeys) EncodeKey(key string) (string, error) { return key, nil }
+func (StringKeys) DecodeKey(s string) (string, error)   { return s, nil }
+
+// IntKeys is the KeyCodec of int keys.
+type IntKeys struct{}
+
+func (IntKeys) EncodeKey(key int) (string, error) { return strconv.Itoa(key), nil }
+func (IntKeys) DecodeKey(s string) (int, error)   { return strconv.Atoi(s) }
+
+// JSONKeys is the KeyCodec of keys encoded as JSON (i.e. structs).
+type JSONKeys[K comparable] struct{}
+
+func (JSONKeys[K]) EncodeKey(key K) (string, error) {
+	data, err := json.Marshal(key)
+	return string(data), err
+}
+
+func (JSONKeys[K]) DecodeKey(s string) (K, error) {
+	var key K
+	err := json.Unmarshal([]byte(s), &key)
+	return key, err
+}
+
+// Invalidator publishes the invalidations of keys.
+type Invalidator[K comparable] struct {
+	pub  Publisher
+	keys KeyCodec[K]
+}
+
+// NewInvalidator constructs a new Invalidator publishing on pub.
+func NewInvalidator[K comparable](pub Publisher, keys KeyCodec[K]) *Invalidator[K] {
+	return &Invalidator[K]{pub: pub, keys: keys}
+}
+
+// Clear publishes the invalidation of keys.
+func (i *Invalidator[K]) Clear(ctx context.Context, keys ...K) error {
+	m := Message{Keys: make([]string, len(keys))}
+	for j, key := range keys {
+		s, err := i.keys.EncodeKey(key)
+		if err != nil {
+			return err
+		}
+		m.Keys[j] = s
+	}
+	return i.pub.Publish(ctx, m)
+}
+
+// ClearAll publishes the invalidation of every key.
+func (i *Invalidator[K]) ClearAll(ctx context.Context) error {
+	return i.pub.Publish(ctx, Message{All: true})
+}
+
+// Listen clears the keys of the invalidations received from sub in loader,
+// until the context is done. A key which can't be decoded clears the whole
+// cache, since it can't be cleared alone.
+func Listen[K comparable, V any](ctx context.Context, sub Subscriber, keys KeyCodec[K], loader dataloader.Interface[K, V]) error {
+	return sub.Subscribe(ctx, func(m Message) {
+		if m.All {
+			loader.ClearAll()
+			return
+		}
+		for _, s := range m.Keys {
+			key, err := keys.DecodeKey(s)
+			if err != nil {
+				loader.ClearAll()
+				return
+			}
+			loader.Clear(ctx, key)
+		}
+	})
+}
+
+// LocalBus is a Bus within the process, i.e. to share invalidations between
+// the loaders of an instance or in tests.
+type LocalBus struct {
+	mu       sync.Mutex
+	handlers map[*func(Message)]struct{}
+}
+
+// NewLocalBus constructs a new LocalBus.
+func NewLocalBus() *LocalBus {
+	return &LocalBus{handlers: make(map[*func(Message)]struct{})}
+}
+
+// Publish calls the handlers of the current subscriptions with the message.
+func (b *LocalBus) Publish(_ context.Context, m Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for handler := range b.handlers {
+		(*handler)(m)
+	}
+	return nil
+}
+
+// Subscribe calls the handler with the published messages until the context
+// is done.
+func (b *LocalBus) Subscribe(ctx context.Context, handler func(Message)) error {
+	b.mu.Lock()
+	b.handlers[&handler] = struct{}{}
+	b.mu.Unlock()
+
+	<-ctx.Done()
+
+	b.mu.Lock()
+	delete(b.handlers, &handler)
+	b.mu.Unlock()
+	return ctx.Err()
+}
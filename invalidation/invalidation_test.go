@@ -0,0 +1,85 @@
+package invalidation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+func TestListen(t *testing.T) {
+	var calls int
+	loader := dataloader.NewBatchedLoader(func(_ context.Context, keys []int) []*dataloader.Result[int] {
+		calls++
+		results := make([]*dataloader.Result[int], len(keys))
+		for i, key := range keys {
+			results[i] = &dataloader.Result[int]{Data: key * calls}
+		}
+		return results
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := NewLocalBus()
+	done := make(chan error)
+	go func() {
+		done <- Listen[int, int](ctx, bus, IntKeys{}, loader)
+	}()
+	for {
+		bus.mu.Lock()
+		n := len(bus.handlers)
+		bus.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if v, _ := loader.Load(ctx, 2)(); v != 2 {
+		t.Fatalf("expected 2, got %d", v)
+	}
+	if err := NewInvalidator[int](bus, IntKeys{}).Clear(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := loader.Load(ctx, 2)(); v != 4 {
+		t.Errorf("expected the key to be reloaded, got %d", v)
+	}
+
+	// a key which can't be decoded clears everything
+	bus.Publish(ctx, Message{Keys: []string{"two"}})
+	if v, _ := loader.Load(ctx, 2)(); v != 6 {
+		t.Errorf("expected the cache to be cleared, got %d", v)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected Listen to end with the context, got %v", err)
+	}
+}
+
+func TestKeyCodecs(t *testing.T) {
+	type key struct {
+		Tenant string
+		ID     int
+	}
+	s, err := JSONKeys[key]{}.EncodeKey(key{"a", 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, err := (JSONKeys[key]{}).DecodeKey(s); err != nil || k != (key{"a", 1}) {
+		t.Errorf("expected the key to round trip, got %#v, %v", k, err)
+	}
+
+	m, err := Unmarshal(must(Message{Keys: []string{"1"}}.Marshal()))
+	if err != nil || len(m.Keys) != 1 || m.All {
+		t.Errorf("expected the message to round trip, got %#v, %v", m, err)
+	}
+}
+
+func must(data []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,159 @@
+// Package nats provides an invalidation Bus backed by a NATS JetStream
+// stream. Invalidations are acknowledged by the stream when published, and
+// by every subscriber once handled, so that they are delivered at least once
+// (i.e. redelivered to a subscriber which reconnects).
+package nats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7/invalidation"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Bus implements the invalidation.Bus interface with NATS JetStream.
+type Bus struct {
+	nc        *nats.Conn
+	js        jetstream.JetStream
+	stream    string
+	subject   string
+	maxAge    time.Duration
+	reconcile bool
+}
+
+var _ invalidation.Bus = &Bus{}
+
+// Option allows for configuration of Bus fields.
+type Option func(*Bus)
+
+// WithStream sets the name of the stream. Default is "DATALOADER".
+func WithStream(name string) Option {
+	return func(b *Bus) {
+		b.stream = name
+	}
+}
+
+// WithSubject sets the subject of the invalidations. Default is
+// "dataloader.invalidations".
+func WithSubject(subject string) Option {
+	return func(b *Bus) {
+		b.subject = subject
+	}
+}
+
+// WithMaxAge sets how long the stream retains the invalidations, which bounds
+// how long a subscriber can be disconnected without missing any. Default is
+// an hour.
+func WithMaxAge(d time.Duration) Option {
+	return func(b *Bus) {
+		b.maxAge = d
+	}
+}
+
+// WithReconcile makes the subscribers clear everything once they reconnect
+// to NATS, in case they missed invalidations while they were disconnected
+// (i.e. for longer than the max age of the stream).
+func WithReconcile() Option {
+	return func(b *Bus) {
+		b.reconcile = true
+	}
+}
+
+// New constructs a new Bus on the connection with given options. The stream
+// is created, or updated to the options.
+func New(ctx context.Context, nc *nats.Conn, opts ...Option) (*Bus, error) {
+	b := &Bus{
+		nc:      nc,
+		stream:  "DATALOADER",
+		subject: "dataloader.invalidations",
+		maxAge:  time.Hour,
+	}
+	for _, apply := range opts {
+		apply(b)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	b.js = js
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     b.stream,
+		Subjects: []string{b.subject},
+		MaxAge:   b.maxAge,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Publish publishes the message, and waits for the stream to acknowledge it.
+func (b *Bus) Publish(ctx context.Context, m invalidation.Message) error {
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = b.js.Publish(ctx, b.subject, data)
+	return err
+}
+
+// Subscribe calls the handler with the messages published after it was
+// called, until the context is done. Every subscription has its own consumer
+// of the stream, so that every subscriber receives every message. Messages
+// are acknowledged once the handler returns, and the handler isn't called
+// concurrently.
+func (b *Bus) Subscribe(ctx context.Context, handler func(invalidation.Message)) error {
+	consumer, err := b.js.CreateConsumer(ctx, b.stream, jetstream.ConsumerConfig{
+		DeliverPolicy:     jetstream.DeliverNewPolicy,
+		AckPolicy:         jetstream.AckExplicitPolicy,
+		FilterSubject:     b.subject,
+		InactiveThreshold: b.maxAge,
+	})
+	if err != nil {
+		return err
+	}
+	defer b.js.DeleteConsumer(context.WithoutCancel(ctx), b.stream, consumer.CachedInfo().Name)
+
+	var mu sync.Mutex
+	handle := func(m invalidation.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		handler(m)
+	}
+
+	cc, err := consumer.Consume(func(msg jetstream.Msg) {
+		m, err := invalidation.Unmarshal(msg.Data())
+		if err != nil {
+			// a message which can't be decoded could have cleared anything
+			m = invalidation.Message{All: true}
+		}
+		handle(m)
+		msg.Ack()
+	})
+	if err != nil {
+		return err
+	}
+	defer cc.Stop()
+
+	if !b.reconcile {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	// the channel isn't closed: the connection may be sending to it, and it
+	// doesn't block on it
+	status := b.nc.StatusChanged(nats.CONNECTED)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-status:
+			handle(invalidation.Message{All: true})
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package nats
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7/invalidation"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func runServer(t *testing.T, opts *server.Options) *server.Server {
+	t.Helper()
+	s, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("the server is not ready")
+	}
+	return s
+}
+
+func receive(t *testing.T, c <-chan invalidation.Message) invalidation.Message {
+	t.Helper()
+	select {
+	case m := <-c:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a message")
+		return invalidation.Message{}
+	}
+}
+
+// receiveAll receives messages until one clears everything, skipping the
+// redeliveries of the previous ones.
+func receiveAll(t *testing.T, c <-chan invalidation.Message) {
+	t.Helper()
+	for !receive(t, c).All {
+	}
+}
+
+func TestBus(t *testing.T) {
+	opts := &server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir(), NoSigs: true}
+	s := runServer(t, opts)
+	defer func() { s.Shutdown() }()
+
+	nc, err := nats.Connect(s.ClientURL(), nats.MaxReconnects(-1), nats.ReconnectWait(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus, err := New(ctx, nc, WithReconcile())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan invalidation.Message, 10)
+	subscribed := make(chan error)
+	go func() {
+		subscribed <- bus.Subscribe(ctx, func(m invalidation.Message) { c <- m })
+	}()
+
+	// the consumer only receives the messages published once it exists
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if err := bus.Publish(ctx, invalidation.Message{Keys: []string{"1"}}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case m := <-c:
+			if len(m.Keys) != 1 || m.Keys[0] != "1" {
+				t.Fatalf("unexpected message %#v", m)
+			}
+		case <-time.After(50 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatal("expected a message")
+			}
+			continue
+		}
+		break
+	}
+
+	if err := bus.Publish(ctx, invalidation.Message{All: true}); err != nil {
+		t.Fatal(err)
+	}
+	receiveAll(t, c)
+
+	t.Run("reconciles on reconnect", func(t *testing.T) {
+		opts.Port = s.Addr().(*net.TCPAddr).Port
+		s.Shutdown()
+		s.WaitForShutdown()
+		s = runServer(t, opts)
+
+		receiveAll(t, c)
+	})
+
+	cancel()
+	if err := <-subscribed; err != context.Canceled {
+		t.Errorf("expected the subscription to end with the context, got %v", err)
+	}
+}
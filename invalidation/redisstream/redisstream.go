@@ -0,0 +1,163 @@
+// Package redisstream provides an invalidation Bus backed by a Redis stream.
+// Every subscriber reads the stream from the last message it received, so that
+// the messages published while it was disconnected are delivered once it
+// reconnects: they are delivered at least once, as long as the stream retains
+// them.
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7/invalidation"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// field is the field of the stream entries holding the messages.
+const field = "message"
+
+// Bus implements the invalidation.Bus interface with a Redis stream.
+type Bus struct {
+	client    redis.UniversalClient
+	stream    string
+	maxLen    int64
+	reconcile bool
+	// how long a read blocks, which bounds how long Subscribe takes to return
+	// once its context is done
+	block time.Duration
+	// how long a subscriber waits to read again after an error
+	backoff time.Duration
+}
+
+var _ invalidation.Bus = &Bus{}
+
+// Option allows for configuration of Bus fields.
+type Option func(*Bus)
+
+// WithStream sets the key of the stream. Default is
+// "dataloader:invalidations".
+func WithStream(key string) Option {
+	return func(b *Bus) {
+		b.stream = key
+	}
+}
+
+// WithMaxLen sets the approximate number of messages the stream retains,
+// which bounds how many messages a subscriber can miss while it is
+// disconnected. Default is 10000.
+func WithMaxLen(n int64) Option {
+	return func(b *Bus) {
+		b.maxLen = n
+	}
+}
+
+// WithReconcile makes the subscribers clear everything once they read the
+// stream again after an error, in case they missed invalidations (i.e. if the
+// stream was trimmed in the meantime).
+func WithReconcile() Option {
+	return func(b *Bus) {
+		b.reconcile = true
+	}
+}
+
+// New constructs a new Bus with given options.
+func New(client redis.UniversalClient, opts ...Option) *Bus {
+	b := &Bus{
+		client:  client,
+		stream:  "dataloader:invalidations",
+		maxLen:  10000,
+		block:   time.Second,
+		backoff: time.Second,
+	}
+	for _, apply := range opts {
+		apply(b)
+	}
+	return b
+}
+
+// Publish appends the message to the stream.
+func (b *Bus) Publish(ctx context.Context, m invalidation.Message) error {
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	return b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.stream,
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: map[string]any{field: data},
+	}).Err()
+}
+
+// Subscribe calls the handler with the messages appended to the stream after
+// it was called, until the context is done. Errors of Redis are retried.
+func (b *Bus) Subscribe(ctx context.Context, handler func(invalidation.Message)) error {
+	last, err := b.lastID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed bool
+	for {
+		streams, err := b.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{b.stream, last},
+			Count:   100,
+			Block:   b.block,
+		}).Result()
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, redis.Nil):
+			// no message within the block duration
+		case err != nil:
+			failed = true
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(b.backoff):
+			}
+			continue
+		}
+
+		if failed && b.reconcile {
+			handler(invalidation.Message{All: true})
+		}
+		failed = false
+
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				last = msg.ID
+				handler(decode(msg))
+			}
+		}
+	}
+}
+
+// lastID returns the ID of the last message of the stream, or 0 if it is
+// empty.
+func (b *Bus) lastID(ctx context.Context) (string, error) {
+	msgs, err := b.client.XRevRangeN(ctx, b.stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(msgs) == 0 {
+		return "0", nil
+	}
+	return msgs[0].ID, nil
+}
+
+// decode returns the message of a stream entry. An entry which can't be
+// decoded could have cleared anything, so it clears everything.
+func decode(msg redis.XMessage) invalidation.Message {
+	data, ok := msg.Values[field].(string)
+	if !ok {
+		return invalidation.Message{All: true}
+	}
+	m, err := invalidation.Unmarshal([]byte(data))
+	if err != nil {
+		return invalidation.Message{All: true}
+	}
+	return m
+}
@@ -0,0 +1,86 @@
+package redisstream
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7/invalidation"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func receive(t *testing.T, c <-chan invalidation.Message) invalidation.Message {
+	t.Helper()
+	select {
+	case m := <-c:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a message")
+		return invalidation.Message{}
+	}
+}
+
+func TestBus(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	defer client.Close()
+
+	bus := New(client, WithReconcile())
+	bus.block = 10 * time.Millisecond
+	bus.backoff = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// published before the subscription
+	if err := bus.Publish(ctx, invalidation.Message{Keys: []string{"0"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan invalidation.Message, 10)
+	subscribed := make(chan error)
+	go func() {
+		subscribed <- bus.Subscribe(ctx, func(m invalidation.Message) { c <- m })
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := bus.Publish(ctx, invalidation.Message{Keys: []string{"1", "2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if m := receive(t, c); !reflect.DeepEqual(m, invalidation.Message{Keys: []string{"1", "2"}}) {
+		t.Errorf("unexpected message %#v", m)
+	}
+
+	t.Run("decodes invalid entries as clearing everything", func(t *testing.T) {
+		server.XAdd(bus.stream, "*", []string{"other", "field"})
+		if m := receive(t, c); !m.All {
+			t.Errorf("expected a message clearing everything, got %#v", m)
+		}
+	})
+
+	t.Run("reconciles after an error", func(t *testing.T) {
+		server.SetError("LOADING")
+		time.Sleep(50 * time.Millisecond)
+		server.SetError("")
+
+		if m := receive(t, c); !m.All {
+			t.Errorf("expected a message clearing everything, got %#v", m)
+		}
+	})
+
+	t.Run("resumes reading after an error", func(t *testing.T) {
+		if err := bus.Publish(ctx, invalidation.Message{Keys: []string{"3"}}); err != nil {
+			t.Fatal(err)
+		}
+		if m := receive(t, c); !reflect.DeepEqual(m.Keys, []string{"3"}) {
+			t.Errorf("unexpected message %#v", m)
+		}
+	})
+
+	cancel()
+	if err := <-subscribed; err != context.Canceled {
+		t.Errorf("expected the subscription to end with the context, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package dataloader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeyedError is the error of a key loaded by LoadMany, along with its index
+// in the loaded keys.
+type KeyedError[K comparable] struct {
+	Key   K
+	Index int
+	Err   error
+}
+
+func (e *KeyedError[K]) Error() string {
+	return fmt.Sprintf("key %v: %v", e.Key, e.Err)
+}
+
+// Unwrap returns the error of the key.
+func (e *KeyedError[K]) Unwrap() error {
+	return e.Err
+}
+
+// KeyedErrors aggregates the errors of the keys loaded by LoadMany. It
+// supports errors.Is and errors.As like the errors returned by errors.Join:
+// errors.As finds the *KeyedError of the first failed key.
+type KeyedErrors[K comparable] []*KeyedError[K]
+
+func (e KeyedErrors[K]) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors of the keys.
+func (e KeyedErrors[K]) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// JoinKeyed aggregates the errors returned by the ThunkMany of the given keys
+// into KeyedErrors. It returns nil if no key failed.
+//
+//	values, errs := loader.LoadMany(ctx, keys)()
+//	if err := dataloader.JoinKeyed(keys, errs); err != nil {
+//		return nil, err
+//	}
+func JoinKeyed[K comparable](keys []K, errs []error) error {
+	var joined KeyedErrors[K]
+	for i, err := range errs {
+		if err != nil && i < len(keys) {
+			joined = append(joined, &KeyedError[K]{Key: keys[i], Index: i, Err: err})
+		}
+	}
+	if len(joined) == 0 {
+		return nil
+	}
+	return joined
+}
+
+// ErrorSlice associates the errors returned by the ThunkMany of LoadMany with
+// their keys, so that the error of a key doesn't have to be found by its
+// position in the loaded keys.
+//
+//	values, errs := loader.LoadMany(ctx, ids)()
+//	e := dataloader.ErrorsOf(ids, errs)
+//	if err := e.ByKey(id); err != nil {
+//		// handle the error of id
+//	}
+type ErrorSlice[K comparable] struct {
+	keys []K
+	errs []error
+}
+
+// ErrorsOf returns the ErrorSlice of the errors returned by the ThunkMany of
+// the given keys. errs may be nil, when no key failed.
+func ErrorsOf[K comparable](keys []K, errs []error) ErrorSlice[K] {
+	return ErrorSlice[K]{keys: keys, errs: errs}
+}
+
+// ByKey returns the error of the key, or nil if it loaded (or wasn't loaded).
+// A key loaded several times has the same result, so the error of its first
+// position is returned.
+func (e ErrorSlice[K]) ByKey(key K) error {
+	for i, k := range e.keys {
+		if k == key && i < len(e.errs) {
+			return e.errs[i]
+		}
+	}
+	return nil
+}
+
+// First returns the error of the first failed key, or nil if no key failed.
+func (e ErrorSlice[K]) First() error {
+	for _, err := range e.errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Any reports whether any key failed.
+func (e ErrorSlice[K]) Any() bool {
+	return e.First() != nil
+}
+
+// Error returns the errors of the failed keys, like KeyedErrors.
+func (e ErrorSlice[K]) Error() string {
+	if err := JoinKeyed(e.keys, e.errs); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// Unwrap returns the errors of the failed keys, as *KeyedError, so that
+// errors.Is and errors.As see them like the errors of KeyedErrors.
+func (e ErrorSlice[K]) Unwrap() []error {
+	if err := JoinKeyed(e.keys, e.errs); err != nil {
+		return err.(KeyedErrors[K]).Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package dataloader
+
+import (
+	"fmt"
+)
+
+// ErrMissingResult is the error of a key for which a lenient loader's batch
+// function returned no result.
+type ErrMissingResult[K comparable] struct {
+	Key K
+}
+
+func (e *ErrMissingResult[K]) Error() string {
+	return fmt.Sprintf("dataloader: the batch function returned no result for key %v", e.Key)
+}
+
+// WithLenientResults makes the loader tolerate batch functions returning
+// fewer results than keys: the keys which got a result are resolved normally,
+// and the keys missing from the tail (or given a nil result) fail with an
+// *ErrMissingResult. By default, such a batch fails every key.
+func WithLenientResults[K comparable, V any]() Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.lenient = true
+	}
+}
+
+// complete fills the results missing from a short batch with errors.
+func complete[K comparable, V any](keys []K, results []*Result[V]) []*Result[V] {
+	completed := make([]*Result[V], len(keys))
+	copy(completed, results)
+	for i, key := range keys {
+		if completed[i] == nil {
+			completed[i] = &Result[V]{Error: &ErrMissingResult[K]{Key: key}}
+		}
+	}
+	return completed
+}
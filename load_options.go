@@ -0,0 +1,50 @@
+package dataloader
+
+// LoadOption is an option of a single call of LoadWith. Unlike the options of
+// the context (see ContextWithSkipCache and ContextWithPriority), it only
+// applies to the key of the call, and not to the other loads made with its
+// context.
+type LoadOption func(*loadConfig)
+
+type loadConfig struct {
+	skipCache   bool
+	refresh     bool
+	priority    int
+	prioritized bool
+}
+
+// SkipCache makes the load skip the cache, like ContextWithSkipCache does:
+// its key is neither looked up nor stored in the cache, and the cached value
+// (if any) is left untouched for the other loads.
+func SkipCache() LoadOption {
+	return func(c *loadConfig) {
+		c.skipCache = true
+	}
+}
+
+// ForceRefresh makes the load fetch its key again even if it's cached, and
+// replace the cached value with the fetched one: the loads of the key made
+// from then on share the refreshed value.
+func ForceRefresh() LoadOption {
+	return func(c *loadConfig) {
+		c.refresh = true
+	}
+}
+
+// Priority sets the priority of the load, overriding the one of its context
+// (see ContextWithPriority).
+func Priority(n int) LoadOption {
+	return func(c *loadConfig) {
+		c.priority = n
+		c.prioritized = true
+	}
+}
+
+// newLoadConfig returns the configuration of a load with the options.
+func newLoadConfig(opts []LoadOption) loadConfig {
+	var c loadConfig
+	for _, apply := range opts {
+		apply(&c)
+	}
+	return c
+}
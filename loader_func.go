@@ -0,0 +1,106 @@
+package dataloader
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// FetchFunc is a function, which when given a key, returns its value. It is
+// used by the loaders of backends which have no bulk endpoint.
+type FetchFunc[K comparable, V any] func(context.Context, K) (V, error)
+
+// NewLoaderFunc constructs a new Loader fetching keys one at a time with given
+// options. The keys of a batch are fetched concurrently, see
+// WithConcurrencyLimit. The loader still dedupes and caches the keys, like a
+// Loader constructed with NewBatchedLoader.
+func NewLoaderFunc[K comparable, V any](fetch FetchFunc[K, V], opts ...Option[K, V]) *Loader[K, V] {
+	var l *Loader[K, V]
+	l = NewBatchedLoader(func(ctx context.Context, keys []K) []*Result[V] {
+		return l.fetchAll(ctx, keys, fetch)
+	}, opts...)
+	return l
+}
+
+// MappedBatchFunc is a function, which when given a slice of keys, returns
+// their results by key, so that it doesn't have to return them in the order
+// of the keys. An error fails every key of the batch.
+type MappedBatchFunc[K comparable, V any] func(context.Context, []K) (map[K]*Result[V], error)
+
+// NewMappedLoader constructs a new Loader matching the results of the batch
+// function to the keys by key, rather than by position, with given options.
+// The keys missing from the returned map resolve with ErrNotFound.
+func NewMappedLoader[K comparable, V any](batchFn MappedBatchFunc[K, V], opts ...Option[K, V]) *Loader[K, V] {
+	return NewBatchedLoader(func(ctx context.Context, keys []K) []*Result[V] {
+		byKey, err := batchFn(ctx, keys)
+		results := make([]*Result[V], len(keys))
+		for i, key := range keys {
+			switch r := byKey[key]; {
+			case err != nil:
+				results[i] = &Result[V]{Error: err}
+			case r == nil:
+				results[i] = NotFound[V]()
+			default:
+				results[i] = r
+			}
+		}
+		return results
+	}, opts...)
+}
+
+// WithConcurrencyLimit sets the maximum number of keys fetched concurrently by
+// a Loader constructed with NewLoaderFunc, and the maximum number of calls of
+// the batch plans run concurrently (see WithBatchPlanner), across all of the
+// batches of the loader. Default is 0 (unbounded).
+func WithConcurrencyLimit[K comparable, V any](n int) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.fetchSem, l.planSem = nil, nil
+		if n > 0 {
+			l.fetchSem = make(chan struct{}, n)
+			l.planSem = make(chan struct{}, n)
+		}
+	}
+}
+
+// semaphores returns the semaphores bounding the keys fetched concurrently and
+// the calls of the batch plans run concurrently, nil if unbounded. They are
+// replaced by UpdateOptions, so every batch acquires and releases the ones it
+// started with.
+func (l *Loader[K, V]) semaphores() (fetch, plan chan struct{}) {
+	l.batchLock.Lock()
+	defer l.batchLock.Unlock()
+	return l.fetchSem, l.planSem
+}
+
+// fetchAll fetches the keys of a batch concurrently. A panic of the fetch
+// function only fails the key being fetched.
+func (l *Loader[K, V]) fetchAll(ctx context.Context, keys []K, fetch FetchFunc[K, V]) []*Result[V] {
+	results := make([]*Result[V], len(keys))
+	sem, _ := l.semaphores()
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		go func() {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			defer func() {
+				if r := recover(); r != nil {
+					err := newPanicError("fetch", r)
+					if !l.silent {
+						log.Printf("Dataloader: %v\n%s", err, err.Stack)
+					}
+					results[i] = &Result[V]{Error: &PanicErrorWrapper{panicError: err}}
+				}
+			}()
+			data, err := fetch(ctx, key)
+			results[i] = &Result[V]{Data: data, Error: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
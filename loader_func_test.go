@@ -0,0 +1,140 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMappedLoader(t *testing.T) {
+	t.Run("matches the results by key", func(t *testing.T) {
+		t.Parallel()
+		loader := NewMappedLoader(func(_ context.Context, keys []string) (map[string]*Result[string], error) {
+			results := make(map[string]*Result[string])
+			// the results are in no particular order, and "b" is missing
+			for _, key := range keys {
+				if key != "b" {
+					results[key] = &Result[string]{Data: key + "!"}
+				}
+			}
+			return results, nil
+		})
+		ctx := context.Background()
+
+		values, errs := loader.LoadMany(ctx, []string{"c", "b", "a"})()
+		if values[0] != "c!" || values[2] != "a!" || errs == nil || !IsNotFound(errs[1]) {
+			t.Errorf("expected c!, not found and a!, got %v, %v", values, errs)
+		}
+	})
+
+	t.Run("fails every key on error", func(t *testing.T) {
+		t.Parallel()
+		errBackend := errors.New("backend")
+		loader := NewMappedLoader(func(context.Context, []int) (map[int]*Result[int], error) {
+			return nil, errBackend
+		})
+		_, errs := loader.LoadMany(context.Background(), []int{1, 2})()
+		if len(errs) != 2 || !errors.Is(errs[0], errBackend) || !errors.Is(errs[1], errBackend) {
+			t.Errorf("expected every key to fail, got %v", errs)
+		}
+	})
+}
+
+func TestLoaderFunc(t *testing.T) {
+	t.Run("fetches every key once", func(t *testing.T) {
+		t.Parallel()
+		loader, fetches := FetchingLoader(0)
+		ctx := context.Background()
+
+		values, errs := loader.LoadMany(ctx, []string{"a", "b", "a", "c"})()
+		if errs != nil {
+			t.Fatalf("unexpected errors %v", errs)
+		}
+		if expected := []string{"a", "b", "a", "c"}; !reflect.DeepEqual(values, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, values)
+		}
+		loader.Load(ctx, "b")()
+
+		if calls, expected := fetches(), []string{"a", "b", "c"}; !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("respects the concurrency limit", func(t *testing.T) {
+		t.Parallel()
+		var running, peak atomic.Int32
+		loader := NewLoaderFunc(func(_ context.Context, key int) (int, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return key, nil
+		}, WithConcurrencyLimit[int, int](2))
+		ctx := context.Background()
+
+		keys := []int{1, 2, 3, 4, 5, 6}
+		if _, errs := loader.LoadMany(ctx, keys)(); errs != nil {
+			t.Fatalf("unexpected errors %v", errs)
+		}
+		if p := peak.Load(); p != 2 {
+			t.Errorf("expected at most 2 concurrent fetches, got %d", p)
+		}
+	})
+
+	t.Run("a panic only fails its key", func(t *testing.T) {
+		t.Parallel()
+		loader := NewLoaderFunc(func(_ context.Context, key string) (string, error) {
+			if key == "panic" {
+				panic("Programming error")
+			}
+			if key == "error" {
+				return "", errors.New("failed")
+			}
+			return key, nil
+		}, withSilentLogger[string, string]())
+		ctx := context.Background()
+
+		future1 := loader.Load(ctx, "1")
+		future2 := loader.Load(ctx, "panic")
+		future3 := loader.Load(ctx, "error")
+		if value, err := future1(); err != nil || value != "1" {
+			t.Errorf("expected 1, got %q, %v", value, err)
+		}
+		if _, err := future2(); err == nil || err.Error() != "Panic received in fetch function: Programming error" {
+			t.Errorf("expected the panic to be propagated as an error, got %v", err)
+		}
+		if _, err := future3(); err == nil || err.Error() != "failed" {
+			t.Errorf("expected the fetch error, got %v", err)
+		}
+	})
+}
+
+// FetchingLoader returns a loader fetching keys one at a time, and a function
+// returning the sorted keys fetched so far.
+func FetchingLoader(limit int) (*Loader[string, string], func() []string) {
+	var mu sync.Mutex
+	var fetches []string
+	loader := NewLoaderFunc(func(_ context.Context, key string) (string, error) {
+		mu.Lock()
+		fetches = append(fetches, key)
+		mu.Unlock()
+		return key, nil
+	}, WithConcurrencyLimit[string, string](limit))
+	return loader, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		calls := append([]string(nil), fetches...)
+		sort.Strings(calls)
+		return calls
+	}
+}
@@ -0,0 +1,118 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+)
+
+type loaderRegistryKey struct{}
+
+// LoaderRegistry holds the loaders of a request (or of an RPC), each
+// constructed the first time it is used during the request, so that their
+// caches don't outlive it. It is attached to the context of the request with
+// ContextWithLoaderRegistry (i.e. by the interceptors of integration/grpc),
+// and the loaders are looked up with LoaderKey.From.
+type LoaderRegistry struct {
+	mu      sync.Mutex
+	loaders map[any]registered
+	order   []registered
+}
+
+// registered is a loader of a LoaderRegistry.
+type registered interface {
+	Drainer
+	Dispatch()
+}
+
+// ContextWithLoaderRegistry returns a copy of ctx with a new LoaderRegistry,
+// along with the registry.
+func ContextWithLoaderRegistry(ctx context.Context) (context.Context, *LoaderRegistry) {
+	r := &LoaderRegistry{loaders: make(map[any]registered)}
+	return context.WithValue(ctx, loaderRegistryKey{}, r), r
+}
+
+// LoaderRegistryFromContext returns the LoaderRegistry of ctx, if any.
+func LoaderRegistryFromContext(ctx context.Context) (*LoaderRegistry, bool) {
+	r, ok := ctx.Value(loaderRegistryKey{}).(*LoaderRegistry)
+	return r, ok
+}
+
+// Dispatch dispatches the pending batches of the loaders constructed so far
+// (see Loader.Dispatch), i.e. once a GraphQL executor has run the resolvers of
+// a level of the query, rather than waiting for their batch windows to close.
+func (r *LoaderRegistry) Dispatch() {
+	r.mu.Lock()
+	loaders := r.order
+	r.mu.Unlock()
+
+	for _, l := range loaders {
+		l.Dispatch()
+	}
+}
+
+// Drain drains the loaders constructed so far (see Loader.Drain), and
+// returns the first error.
+func (r *LoaderRegistry) Drain(ctx context.Context) error {
+	r.mu.Lock()
+	loaders := r.order
+	r.mu.Unlock()
+
+	var err error
+	for _, l := range loaders {
+		if drainErr := l.Drain(ctx); err == nil {
+			err = drainErr
+		}
+	}
+	return err
+}
+
+// Reset drains the loaders constructed so far, and drops them, so that the
+// next lookups construct new ones (i.e. for each message of a stream).
+func (r *LoaderRegistry) Reset(ctx context.Context) error {
+	err := r.Drain(ctx)
+	r.mu.Lock()
+	clear(r.loaders)
+	r.order = nil
+	r.mu.Unlock()
+	return err
+}
+
+// LoaderKey identifies a loader of the LoaderRegistries, along with how it
+// is constructed. Keys are meant to be declared once, as package variables.
+type LoaderKey[K comparable, V any] struct {
+	newLoader func(ctx context.Context) *Loader[K, V]
+}
+
+// NewLoaderKey returns a new LoaderKey of the loaders constructed by
+// newLoader, which is called with the context of the first lookup.
+func NewLoaderKey[K comparable, V any](newLoader func(ctx context.Context) *Loader[K, V]) *LoaderKey[K, V] {
+	return &LoaderKey[K, V]{newLoader: newLoader}
+}
+
+// From returns the loader of the LoaderRegistry of ctx, constructing it if it
+// is the first lookup. If ctx has no registry, a new loader is returned on
+// every call.
+func (k *LoaderKey[K, V]) From(ctx context.Context) *Loader[K, V] {
+	r, ok := LoaderRegistryFromContext(ctx)
+	if !ok {
+		return k.newLoader(ctx)
+	}
+	r.mu.Lock()
+	l, ok := r.loaders[k]
+	r.mu.Unlock()
+	if ok {
+		return l.(*Loader[K, V])
+	}
+
+	// the loader is constructed without the lock held, since its construction
+	// may look up other loaders
+	loader := k.newLoader(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.loaders[k]; ok {
+		return l.(*Loader[K, V])
+	}
+	r.loaders[k] = loader
+	r.order = append(r.order, loader)
+	return loader
+}
@@ -0,0 +1,72 @@
+package dataloader
+
+import (
+	"context"
+	"time"
+)
+
+// WithCacheMaxAge rotates the cache to a new epoch every d, as if ClearAll
+// was called, so that a long lived loader serves values at most about d old
+// without ClearAll being called throughout the application. The batches of
+// the previous epoch still resolve their callers (see ClearAll). The cache is
+// rotated by the first load once the age is reached, so an idle loader has no
+// timer running; the age is randomized by WithTTLJitter. Default is 0
+// (disabled).
+func WithCacheMaxAge[K comparable, V any](d time.Duration) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.maxAge = d
+	}
+}
+
+// WithStaleFallback keeps the values loaded during the previous epoch of a
+// loader WithCacheMaxAge, and returns them to the loads of the keys which fail
+// in the current epoch (i.e. while the backend is down). The keys which
+// aren't found are not served from the previous epoch.
+func WithStaleFallback[K comparable, V any]() Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.staleFallback = true
+		l.addCacheHook(func(_ context.Context, key K, result *Result[V]) {
+			if result.Error != nil {
+				return
+			}
+			l.cacheLock.Lock()
+			if l.fresh == nil {
+				l.fresh = make(map[K]V)
+			}
+			l.fresh[key] = result.Data
+			l.cacheLock.Unlock()
+		})
+	}
+}
+
+// rotate starts a new cache epoch once the maximum age of the cache is
+// reached.
+func (l *Loader[K, V]) rotate() {
+	if l.maxAge <= 0 {
+		return
+	}
+	l.cacheLock.Lock()
+	now := l.clock.Now()
+	due := !l.rotateAt.IsZero() && !now.Before(l.rotateAt)
+	if l.rotateAt.IsZero() || due {
+		l.rotateAt = now.Add(JitterTTL(l.maxAge, l.ttlJitter))
+	}
+	if due {
+		l.epoch.Add(1)
+		l.clearCache(EvictRotated)
+		l.stale, l.fresh = l.fresh, nil
+	}
+	l.cacheLock.Unlock()
+	if due {
+		l.flushEvictions(context.Background())
+		l.rewatch(context.Background(), nil)
+	}
+}
+
+// staleOf returns the value of the key loaded during the previous epoch.
+func (l *Loader[K, V]) staleOf(key K) (V, bool) {
+	l.cacheLock.Lock()
+	defer l.cacheLock.Unlock()
+	v, ok := l.stale[key]
+	return v, ok
+}
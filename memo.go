@@ -0,0 +1,121 @@
+package dataloader
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithBatchMemo memoizes the results of whole batches for the given
+// duration, by the signature of their set of keys: a batch of the same keys
+// as a memoized one (in any order) skips the batch function, even when the
+// keys aren't cached individually (i.e. WithCache of a NoCache), which suits
+// the identical batches of polling dashboards. Up to size batches are
+// memoized; the batches with an error are not.
+func WithBatchMemo[K comparable, V any](ttl time.Duration, size int) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.memo = &batchMemo[K, V]{ttl: ttl, size: size, batches: make(map[string]*memoizedBatch[K, V])}
+	}
+}
+
+// batchMemo holds the results of the memoized batches by signature.
+type batchMemo[K comparable, V any] struct {
+	ttl  time.Duration
+	size int
+
+	mu      sync.Mutex
+	batches map[string]*memoizedBatch[K, V]
+}
+
+type memoizedBatch[K comparable, V any] struct {
+	results map[K]*Result[V]
+	expires time.Time
+}
+
+// signature returns the canonical signature of the set of keys.
+func signature[K comparable](keys []K) string {
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = keyString(key)
+	}
+	sort.Strings(ids)
+	var sb strings.Builder
+	for _, id := range ids {
+		// the identities are prefixed with their length, so that they can't
+		// run into each other
+		sb.WriteString(strconv.Itoa(len(id)))
+		sb.WriteByte(':')
+		sb.WriteString(id)
+	}
+	return sb.String()
+}
+
+// get returns the memoized results of the keys, in their order.
+func (m *batchMemo[K, V]) get(now time.Time, sig string, keys []K) ([]*Result[V], bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	batch, ok := m.batches[sig]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(batch.expires) {
+		delete(m.batches, sig)
+		return nil, false
+	}
+	results := make([]*Result[V], len(keys))
+	for i, key := range keys {
+		// the identities of distinct keys may collide
+		if results[i], ok = batch.results[key]; !ok {
+			return nil, false
+		}
+	}
+	return results, true
+}
+
+// set memoizes the results of the keys, unless one of them failed.
+func (m *batchMemo[K, V]) set(now time.Time, sig string, keys []K, results []*Result[V]) {
+	batch := &memoizedBatch[K, V]{results: make(map[K]*Result[V], len(keys)), expires: now.Add(m.ttl)}
+	for i, key := range keys {
+		if results[i] == nil || results[i].Error != nil {
+			return
+		}
+		batch.results[key] = results[i]
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.batches) >= m.size {
+		for sig, b := range m.batches {
+			if !now.Before(b.expires) {
+				delete(m.batches, sig)
+			}
+		}
+		// evict an arbitrary batch if none has expired
+		for sig := range m.batches {
+			if len(m.batches) < m.size {
+				break
+			}
+			delete(m.batches, sig)
+		}
+	}
+	m.batches[sig] = batch
+}
+
+// memoized returns the batch function serving the memoized batches, whose
+// ages are timed by clock.
+func (m *batchMemo[K, V]) memoized(batchFn BatchFunc[K, V], clock Clock) BatchFunc[K, V] {
+	return func(ctx context.Context, keys []K) []*Result[V] {
+		sig := signature(keys)
+		if results, ok := m.get(clock.Now(), sig, keys); ok {
+			return results
+		}
+		results := batchFn(ctx, keys)
+		if len(results) == len(keys) {
+			m.set(clock.Now(), sig, keys, results)
+		}
+		return results
+	}
+}
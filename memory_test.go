@@ -0,0 +1,143 @@
+package dataloader
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The tests of the memory model documented by the package are meant to be run
+// with the race detector, which reports the missing happens-before edges.
+
+// unsynchronizedCache is a cache without any synchronization of its own, which
+// relies on the loader calling its methods one at a time.
+type unsynchronizedCache[K comparable, V any] struct {
+	items map[K]Thunk[V]
+}
+
+func (c *unsynchronizedCache[K, V]) Get(_ context.Context, key K) (Thunk[V], bool) {
+	thunk, ok := c.items[key]
+	return thunk, ok
+}
+
+func (c *unsynchronizedCache[K, V]) Set(_ context.Context, key K, thunk Thunk[V]) {
+	c.items[key] = thunk
+}
+
+func (c *unsynchronizedCache[K, V]) Delete(_ context.Context, key K) bool {
+	_, ok := c.items[key]
+	delete(c.items, key)
+	return ok
+}
+
+func (c *unsynchronizedCache[K, V]) Clear() {
+	clear(c.items)
+}
+
+// record is a result written by the batch function, and read by the waiters.
+type record struct {
+	key    string
+	fields []int
+}
+
+func TestMemoryModel(t *testing.T) {
+	const goroutines = 32
+
+	t.Run("the results are visible to every waiter", func(t *testing.T) {
+		t.Parallel()
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[*record] {
+			results := make([]*Result[*record], len(keys))
+			for i, key := range keys {
+				r := &record{key: key}
+				for j := 0; j < 10; j++ {
+					r.fields = append(r.fields, j)
+				}
+				results[i] = &Result[*record]{Data: r}
+			}
+			return results
+		}, WithWait[string, *record](time.Millisecond))
+		ctx := context.Background()
+
+		var wg sync.WaitGroup
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				key := strconv.Itoa(i % 4)
+				thunk := loader.Load(ctx, key)
+				// the same thunk may be called concurrently, and many times
+				var inner sync.WaitGroup
+				for j := 0; j < 4; j++ {
+					inner.Add(1)
+					go func() {
+						defer inner.Done()
+						r, err := thunk()
+						if err != nil || r.key != key || len(r.fields) != 10 || r.fields[9] != 9 {
+							t.Errorf("expected the whole result of %s, got %+v, %v", key, r, err)
+						}
+					}()
+				}
+				inner.Wait()
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("the primed values are visible to the following loads", func(t *testing.T) {
+		t.Parallel()
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[*record] {
+			results := make([]*Result[*record], len(keys))
+			for i := range keys {
+				results[i] = &Result[*record]{Data: &record{key: "loaded"}}
+			}
+			return results
+		})
+		ctx := context.Background()
+
+		var wg sync.WaitGroup
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				key := strconv.Itoa(i)
+				loader.Prime(ctx, key, &record{key: key, fields: []int{i}})
+				r, err := loader.Load(ctx, key)()
+				if err != nil || r.key != key || r.fields[0] != i {
+					t.Errorf("expected the primed value of %s, got %+v, %v", key, r, err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("the cache methods are called one at a time", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, _ := IDLoader[string](0)
+		WithWait[string, string](time.Millisecond)(identityLoader)
+		WithCache[string, string](&unsynchronizedCache[string, string]{items: map[string]Thunk[string]{}})(identityLoader)
+		ctx := context.Background()
+
+		var wg sync.WaitGroup
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				key := strconv.Itoa(i % 8)
+				switch i % 4 {
+				case 0:
+					identityLoader.Prime(ctx, key, key)
+				case 1:
+					identityLoader.Clear(ctx, key)
+				case 2:
+					identityLoader.ClearAll()
+				}
+				if v, err := identityLoader.Load(ctx, key)(); v != key || err != nil {
+					t.Errorf("expected %s, got %q, %v", key, v, err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,127 @@
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Metrics receives the measurements of the batching and the cache of a loader
+// (see metrics/otel). Its methods are called concurrently.
+type Metrics interface {
+	// ObserveBatch is called once a batch function has returned.
+	ObserveBatch(ctx context.Context, loader string, stats BatchStats)
+	// ObserveCache is called on every load looking up the cache, with whether
+	// its key was cached.
+	ObserveCache(ctx context.Context, loader string, hit bool)
+	// ObserveQueue is called with the change of the number of loads queued on
+	// the batch windows: positive when loads are queued, and negative when
+	// their batch is dispatched (see QueueDepth).
+	ObserveQueue(ctx context.Context, loader string, delta int)
+}
+
+// BatchStats are the measurements of a batch.
+type BatchStats struct {
+	// Size is the number of keys of the batch.
+	Size int
+	// Window is how long the batch window was open, from the first load of
+	// the batch until its dispatch.
+	Window time.Duration
+	// Latency is the duration of the call of the batch function.
+	Latency time.Duration
+	// Errors is the number of keys which resolved with an error.
+	Errors int
+	// Reason is why the batch was dispatched.
+	Reason DispatchReason
+}
+
+// DispatchReason is why a batch was dispatched.
+type DispatchReason int
+
+const (
+	// DispatchWindow is a batch whose window elapsed (see WithWait).
+	DispatchWindow DispatchReason = iota
+	// DispatchCapacity is a batch which reached its capacity (see
+	// WithBatchCapacity, WithBatchCostLimit and OverflowRetry).
+	DispatchCapacity
+	// DispatchIdle is a batch to which no key was added for a while (see
+	// WithIdleFlush).
+	DispatchIdle
+	// DispatchDeadline is a batch which had to be dispatched early for one of
+	// its loads (see ContextWithWait and WithDeadlineAdmission).
+	DispatchDeadline
+	// DispatchImmediate is a batch of a loader WithImmediateDispatch.
+	DispatchImmediate
+	// DispatchManual is a batch dispatched by Dispatch (or Drain).
+	DispatchManual
+	// DispatchScheduler is a batch dispatched by the Scheduler of the loader.
+	DispatchScheduler
+)
+
+func (r DispatchReason) String() string {
+	switch r {
+	case DispatchWindow:
+		return "window"
+	case DispatchCapacity:
+		return "capacity"
+	case DispatchIdle:
+		return "idle"
+	case DispatchDeadline:
+		return "deadline"
+	case DispatchImmediate:
+		return "immediate"
+	case DispatchManual:
+		return "manual"
+	case DispatchScheduler:
+		return "scheduler"
+	}
+	return fmt.Sprintf("DispatchReason(%d)", int(r))
+}
+
+// WithMetrics sets the Metrics receiving the measurements of the loader.
+func WithMetrics[K comparable, V any](m Metrics) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.metrics = m
+	}
+}
+
+// QueueDepth returns the number of loads queued on the batch windows which
+// haven't been dispatched yet.
+func (l *Loader[K, V]) QueueDepth() int {
+	return int(l.queued.Load())
+}
+
+// observeCache reports a lookup of the cache to the metrics, if any.
+func (l *Loader[K, V]) observeCache(ctx context.Context, hit bool) {
+	if l.metrics != nil {
+		l.metrics.ObserveCache(ctx, l.name, hit)
+	}
+}
+
+// observeQueue updates the queue depth, and reports its change to the
+// metrics, if any.
+func (l *Loader[K, V]) observeQueue(ctx context.Context, delta int) {
+	l.queued.Add(int64(delta))
+	if l.metrics != nil {
+		l.metrics.ObserveQueue(ctx, l.name, delta)
+	}
+}
+
+// observeBatch reports a batch to the metrics, if any.
+func (b *batcher[K, V]) observeBatch(ctx context.Context, size int, window, latency time.Duration, items []*Result[V]) {
+	m := b.loader.metrics
+	if m == nil {
+		return
+	}
+	stats := BatchStats{Size: size, Window: window, Latency: latency, Reason: b.reason}
+	if len(items) != size {
+		stats.Errors = size
+	} else {
+		for _, item := range items {
+			if item == nil || item.Error != nil {
+				stats.Errors++
+			}
+		}
+	}
+	m.ObserveBatch(ctx, b.loader.name, stats)
+}
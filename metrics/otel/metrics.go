@@ -0,0 +1,131 @@
+// Package otel measures the batching and the cache of the loaders with
+// OpenTelemetry metrics.
+package otel
+
+import (
+	"context"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics implements dataloader.Metrics with OpenTelemetry instruments. The
+// measurements of a loader have its name as the dataloader.name attribute
+// (see dataloader.WithName).
+type Metrics struct {
+	batchSize     metric.Int64Histogram
+	batchDuration metric.Float64Histogram
+	batchWindow   metric.Float64Histogram
+	batchErrors   metric.Int64Counter
+	cacheHits     metric.Int64Counter
+	cacheMisses   metric.Int64Counter
+	queueDepth    metric.Int64UpDownCounter
+}
+
+// NewMetrics creates the instruments of the loaders with the meter provider,
+// or with the global one if mp is nil.
+func NewMetrics(mp metric.MeterProvider) (*Metrics, error) {
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter("graph-gophers/dataloader")
+
+	var (
+		m   Metrics
+		err error
+	)
+	if m.batchSize, err = meter.Int64Histogram("dataloader.batch.size",
+		metric.WithDescription("The number of keys of the batches."),
+		metric.WithUnit("{key}"),
+		metric.WithExplicitBucketBoundaries(1, 2, 5, 10, 20, 50, 100, 200, 500, 1000),
+	); err != nil {
+		return nil, err
+	}
+	if m.batchDuration, err = meter.Float64Histogram("dataloader.batch.duration",
+		metric.WithDescription("The duration of the calls of the batch functions."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+	if m.batchWindow, err = meter.Float64Histogram("dataloader.batch.window",
+		metric.WithDescription("How long the batch windows were open, from their first load until their dispatch."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+	if m.batchErrors, err = meter.Int64Counter("dataloader.batch.errors",
+		metric.WithDescription("The number of keys of the batches which resolved with an error."),
+		metric.WithUnit("{key}"),
+	); err != nil {
+		return nil, err
+	}
+	if m.cacheHits, err = meter.Int64Counter("dataloader.cache.hits",
+		metric.WithDescription("The number of loads of cached keys."),
+		metric.WithUnit("{load}"),
+	); err != nil {
+		return nil, err
+	}
+	if m.cacheMisses, err = meter.Int64Counter("dataloader.cache.misses",
+		metric.WithDescription("The number of loads of keys which weren't cached."),
+		metric.WithUnit("{load}"),
+	); err != nil {
+		return nil, err
+	}
+	if m.queueDepth, err = meter.Int64UpDownCounter("dataloader.queue.depth",
+		metric.WithDescription("The number of loads queued on the batch windows not yet dispatched."),
+		metric.WithUnit("{load}"),
+	); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// WithMeterProvider measures the loader with the instruments of the meter
+// provider, or of the global one if mp is nil. The errors creating the
+// instruments are handed to otel.Handle, and leave the loader unmeasured.
+func WithMeterProvider[K comparable, V any](mp metric.MeterProvider) dataloader.Option[K, V] {
+	m, err := NewMetrics(mp)
+	if err != nil {
+		otel.Handle(err)
+		return func(*dataloader.Loader[K, V]) {}
+	}
+	return dataloader.WithMetrics[K, V](m)
+}
+
+// ObserveBatch records the size, duration, window and errors of a batch,
+// along with the reason of its dispatch as the dataloader.dispatch.reason
+// attribute.
+func (m *Metrics) ObserveBatch(ctx context.Context, loader string, stats dataloader.BatchStats) {
+	attrs := metric.WithAttributeSet(attribute.NewSet(
+		attribute.String("dataloader.name", loader),
+		attribute.String("dataloader.dispatch.reason", stats.Reason.String()),
+	))
+	m.batchSize.Record(ctx, int64(stats.Size), attrs)
+	m.batchDuration.Record(ctx, stats.Latency.Seconds(), attrs)
+	m.batchWindow.Record(ctx, stats.Window.Seconds(), attrs)
+	if stats.Errors > 0 {
+		m.batchErrors.Add(ctx, int64(stats.Errors), attrs)
+	}
+}
+
+// ObserveCache counts a cache hit or miss.
+func (m *Metrics) ObserveCache(ctx context.Context, loader string, hit bool) {
+	if hit {
+		m.cacheHits.Add(ctx, 1, named(loader))
+	} else {
+		m.cacheMisses.Add(ctx, 1, named(loader))
+	}
+}
+
+// ObserveQueue updates the queue depth.
+func (m *Metrics) ObserveQueue(ctx context.Context, loader string, delta int) {
+	m.queueDepth.Add(ctx, int64(delta), named(loader))
+}
+
+// named returns the attributes of the measurements of a loader.
+func named(loader string) metric.MeasurementOption {
+	return metric.WithAttributeSet(attribute.NewSet(attribute.String("dataloader.name", loader)))
+}
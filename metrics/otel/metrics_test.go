@@ -0,0 +1,67 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestWithMeterProvider(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	loader := dataloader.NewBatchedLoader(func(_ context.Context, keys []string) []*dataloader.Result[string] {
+		results := make([]*dataloader.Result[string], len(keys))
+		for i, key := range keys {
+			results[i] = &dataloader.Result[string]{Data: key}
+		}
+		return results
+	}, dataloader.WithName[string, string]("users"), WithMeterProvider[string, string](mp))
+	ctx := context.Background()
+
+	if _, errs := loader.LoadMany(ctx, []string{"1", "2", "3"})(); errs != nil {
+		t.Fatal(errs)
+	}
+	if _, err := loader.Load(ctx, "1")(); err != nil {
+		t.Fatal(err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	collected := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			collected[m.Name] = m.Data
+		}
+	}
+
+	sizes, ok := collected["dataloader.batch.size"].(metricdata.Histogram[int64])
+	if !ok || len(sizes.DataPoints) != 1 || sizes.DataPoints[0].Count != 1 || sizes.DataPoints[0].Sum != 3 {
+		t.Errorf("expected a batch of 3 keys, got %+v", collected["dataloader.batch.size"])
+	} else if name, _ := sizes.DataPoints[0].Attributes.Value("dataloader.name"); name.AsString() != "users" {
+		t.Errorf("expected the batch of users, got %q", name.AsString())
+	} else if reason, _ := sizes.DataPoints[0].Attributes.Value("dataloader.dispatch.reason"); reason.AsString() != "window" {
+		t.Errorf("expected the batch to be dispatched by its window, got %q", reason.AsString())
+	}
+	for name, want := range map[string]int64{"dataloader.cache.hits": 1, "dataloader.cache.misses": 3} {
+		sum, ok := collected[name].(metricdata.Sum[int64])
+		if !ok || len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != want {
+			t.Errorf("expected %s to be %d, got %+v", name, want, collected[name])
+		}
+	}
+	depth, ok := collected["dataloader.queue.depth"].(metricdata.Sum[int64])
+	if !ok || len(depth.DataPoints) != 1 || depth.DataPoints[0].Value != 0 {
+		t.Errorf("expected an empty queue, got %+v", collected["dataloader.queue.depth"])
+	}
+	for _, name := range []string{"dataloader.batch.duration", "dataloader.batch.window"} {
+		if h, ok := collected[name].(metricdata.Histogram[float64]); !ok || len(h.DataPoints) != 1 {
+			t.Errorf("expected %s to be recorded, got %+v", name, collected[name])
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package prometheus reports the statistics of the loaders to Prometheus.
+package prometheus
+
+import (
+	"context"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector of the statistics of the loaders, which
+// implements dataloader.Metrics. The statistics of a loader are labeled with
+// its name (see dataloader.WithName), so that a single Collector, registered
+// once, is shared by every loader of a process (i.e. the loaders created per
+// request).
+type Collector struct {
+	loads         *prometheus.CounterVec
+	cacheHits     *prometheus.CounterVec
+	batches       *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+	queueDepth    *prometheus.GaugeVec
+	batchSize     *prometheus.HistogramVec
+	batchDuration *prometheus.HistogramVec
+}
+
+// CollectorOption configures a Collector.
+type CollectorOption func(*collectorConfig)
+
+type collectorConfig struct {
+	namespace       string
+	sizeBuckets     []float64
+	durationBuckets []float64
+}
+
+// WithNamespace prefixes the names of the metrics with the namespace, i.e.
+// myapp_dataloader_loads_total.
+func WithNamespace(namespace string) CollectorOption {
+	return func(c *collectorConfig) {
+		c.namespace = namespace
+	}
+}
+
+// WithSizeBuckets sets the buckets of the histogram of the batch sizes.
+func WithSizeBuckets(buckets ...float64) CollectorOption {
+	return func(c *collectorConfig) {
+		c.sizeBuckets = buckets
+	}
+}
+
+// WithDurationBuckets sets the buckets (in seconds) of the histogram of the
+// batch durations.
+func WithDurationBuckets(buckets ...float64) CollectorOption {
+	return func(c *collectorConfig) {
+		c.durationBuckets = buckets
+	}
+}
+
+// NewCollector constructs a new Collector with given options.
+func NewCollector(opts ...CollectorOption) *Collector {
+	config := collectorConfig{
+		sizeBuckets:     []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000},
+		durationBuckets: prometheus.DefBuckets,
+	}
+	for _, apply := range opts {
+		apply(&config)
+	}
+
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.namespace,
+			Subsystem: "dataloader",
+			Name:      name,
+			Help:      help,
+		}, labels)
+	}
+	histogram := func(name, help string, buckets []float64) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.namespace,
+			Subsystem: "dataloader",
+			Name:      name,
+			Help:      help,
+			Buckets:   buckets,
+		}, []string{"loader"})
+	}
+	return &Collector{
+		loads:     counter("loads_total", "The number of loads looking up the cache.", "loader"),
+		cacheHits: counter("cache_hits_total", "The number of loads of cached keys.", "loader"),
+		batches:   counter("batches_total", "The number of batches dispatched, by reason of their dispatch.", "loader", "reason"),
+		errors:    counter("errors_total", "The number of keys of the batches which resolved with an error.", "loader"),
+		queueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: config.namespace,
+			Subsystem: "dataloader",
+			Name:      "queue_depth",
+			Help:      "The number of loads queued on the batch windows not yet dispatched.",
+		}, []string{"loader"}),
+		batchSize:     histogram("batch_size", "The number of keys of the batches.", config.sizeBuckets),
+		batchDuration: histogram("batch_duration_seconds", "The duration of the calls of the batch functions.", config.durationBuckets),
+	}
+}
+
+// WithCollector reports the statistics of the loader to the collector.
+func WithCollector[K comparable, V any](c *Collector) dataloader.Option[K, V] {
+	return dataloader.WithMetrics[K, V](c)
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.collectors() {
+		m.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.collectors() {
+		m.Collect(ch)
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{c.loads, c.cacheHits, c.batches, c.errors, c.queueDepth, c.batchSize, c.batchDuration}
+}
+
+// ObserveBatch implements dataloader.Metrics.
+func (c *Collector) ObserveBatch(_ context.Context, loader string, stats dataloader.BatchStats) {
+	c.batches.WithLabelValues(loader, stats.Reason.String()).Inc()
+	c.batchSize.WithLabelValues(loader).Observe(float64(stats.Size))
+	c.batchDuration.WithLabelValues(loader).Observe(stats.Latency.Seconds())
+	if stats.Errors > 0 {
+		c.errors.WithLabelValues(loader).Add(float64(stats.Errors))
+	}
+}
+
+// ObserveCache implements dataloader.Metrics.
+func (c *Collector) ObserveCache(_ context.Context, loader string, hit bool) {
+	c.loads.WithLabelValues(loader).Inc()
+	if hit {
+		c.cacheHits.WithLabelValues(loader).Inc()
+	}
+}
+
+// ObserveQueue implements dataloader.Metrics.
+func (c *Collector) ObserveQueue(_ context.Context, loader string, delta int) {
+	c.queueDepth.WithLabelValues(loader).Add(float64(delta))
+}
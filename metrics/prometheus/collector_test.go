@@ -0,0 +1,57 @@
+package prometheus
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	collector := NewCollector(WithNamespace("test"))
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	loader := dataloader.NewBatchedLoader(func(_ context.Context, keys []string) []*dataloader.Result[string] {
+		results := make([]*dataloader.Result[string], len(keys))
+		for i, key := range keys {
+			results[i] = &dataloader.Result[string]{Data: key}
+		}
+		return results
+	}, dataloader.WithName[string, string]("users"), dataloader.WithManualDispatch[string, string](), WithCollector[string, string](collector))
+	ctx := context.Background()
+
+	thunk := loader.Load(ctx, "1")
+	loader.Load(ctx, "2")
+	loader.Load(ctx, "2")
+	loader.Dispatch()
+	if _, err := thunk(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP test_dataloader_batches_total The number of batches dispatched, by reason of their dispatch.
+# TYPE test_dataloader_batches_total counter
+test_dataloader_batches_total{loader="users",reason="manual"} 1
+# HELP test_dataloader_cache_hits_total The number of loads of cached keys.
+# TYPE test_dataloader_cache_hits_total counter
+test_dataloader_cache_hits_total{loader="users"} 1
+# HELP test_dataloader_loads_total The number of loads looking up the cache.
+# TYPE test_dataloader_loads_total counter
+test_dataloader_loads_total{loader="users"} 3
+# HELP test_dataloader_queue_depth The number of loads queued on the batch windows not yet dispatched.
+# TYPE test_dataloader_queue_depth gauge
+test_dataloader_queue_depth{loader="users"} 0
+`
+	names := []string{"test_dataloader_batches_total", "test_dataloader_cache_hits_total", "test_dataloader_loads_total", "test_dataloader_queue_depth"}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(collector, "test_dataloader_batch_size", "test_dataloader_batch_duration_seconds"); n != 2 {
+		t.Errorf("expected the histograms of the batch, got %d series", n)
+	}
+}
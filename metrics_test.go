@@ -0,0 +1,82 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// recordingMetrics records the measurements of a loader.
+type recordingMetrics struct {
+	mu      sync.Mutex
+	batches []BatchStats
+	hits    map[bool]int
+	names   map[string]bool
+	depth   int
+}
+
+func (m *recordingMetrics) ObserveBatch(_ context.Context, loader string, stats BatchStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches = append(m.batches, stats)
+	m.names[loader] = true
+}
+
+func (m *recordingMetrics) ObserveCache(_ context.Context, loader string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hits[hit]++
+	m.names[loader] = true
+}
+
+func (m *recordingMetrics) ObserveQueue(_ context.Context, _ string, delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.depth += delta
+}
+
+func TestMetrics(t *testing.T) {
+	m := &recordingMetrics{hits: map[bool]int{}, names: map[string]bool{}}
+	loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+		results := make([]*Result[string], len(keys))
+		for i, key := range keys {
+			if key == "bad" {
+				results[i] = &Result[string]{Error: errors.New("bad key")}
+			} else {
+				results[i] = &Result[string]{Data: key}
+			}
+		}
+		return results
+	}, WithName[string, string]("users"), WithMetrics[string, string](m), WithManualDispatch[string, string]())
+	ctx := context.Background()
+
+	first := loader.Load(ctx, "1")
+	loader.Load(ctx, "2")
+	loader.Load(ctx, "bad")
+	loader.Load(ctx, "1")
+	m.mu.Lock()
+	if depth := loader.QueueDepth(); depth != 3 || m.depth != 3 {
+		t.Errorf("expected 3 queued loads, got %d and %d", depth, m.depth)
+	}
+	m.mu.Unlock()
+	loader.Dispatch()
+	if _, err := first(); err != nil {
+		t.Fatal(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if depth := loader.QueueDepth(); depth != 0 || m.depth != 0 {
+		t.Errorf("expected no queued loads once dispatched, got %d and %d", depth, m.depth)
+	}
+	if len(m.batches) != 1 || m.batches[0].Size != 3 || m.batches[0].Errors != 1 || m.batches[0].Window <= 0 || m.batches[0].Reason != DispatchManual {
+		t.Errorf("expected a batch of 3 keys with an error, got %+v", m.batches)
+	}
+	if m.hits[true] != 1 || m.hits[false] != 3 {
+		t.Errorf("expected a hit and 3 misses, got %v", m.hits)
+	}
+	if !m.names["users"] || len(m.names) != 1 {
+		t.Errorf("expected the measurements to be named users, got %v", m.names)
+	}
+}
@@ -0,0 +1,131 @@
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// WithMutationDetection is a development-time guardrail against callers
+// mutating the values shared through the cache (i.e. the fields of a cached
+// pointer). A deep copy of every loaded or primed value is kept, and every time
+// a value is handed to a caller of Load, it is compared to its copy: report is
+// called with the key of a value which was mutated. If report is nil, the
+// loader panics instead.
+//
+// Deep copies only follow exported struct fields. Keeping and comparing them
+// is expensive: don't use this option in production.
+func WithMutationDetection[K comparable, V any](report func(key K)) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		if report == nil {
+			report = func(key K) {
+				panic(fmt.Sprintf("dataloader: the cached value of %v was mutated", key))
+			}
+		}
+		l.mutations = &mutationDetector[K, V]{
+			report:    report,
+			canonical: make(map[K]V),
+		}
+		l.addCacheHook(func(_ context.Context, key K, result *Result[V]) {
+			if result.Error == nil {
+				l.mutations.record(key, result.Data)
+			}
+		})
+	}
+}
+
+// mutationDetector keeps the deep copies of the cached values.
+type mutationDetector[K comparable, V any] struct {
+	report func(K)
+
+	mu        sync.Mutex
+	canonical map[K]V
+}
+
+// record keeps a deep copy of the value of key.
+func (d *mutationDetector[K, V]) record(key K, value V) {
+	clone := deepCopy(value)
+	d.mu.Lock()
+	d.canonical[key] = clone
+	d.mu.Unlock()
+}
+
+// check reports the key if its value differs from its deep copy.
+func (d *mutationDetector[K, V]) check(key K, value V) {
+	d.mu.Lock()
+	canonical, ok := d.canonical[key]
+	d.mu.Unlock()
+	if ok && !reflect.DeepEqual(canonical, value) {
+		d.report(key)
+	}
+}
+
+// deepCopy returns a deep copy of the value.
+func deepCopy[V any](value V) V {
+	src := reflect.ValueOf(&value).Elem()
+	dst := reflect.New(src.Type()).Elem()
+	copyValue(dst, src, make(map[uintptr]reflect.Value))
+	return dst.Interface().(V)
+}
+
+// copyValue deep copies src to dst. Pointers already copied are reused, so
+// that cyclic values can be copied.
+func copyValue(dst, src reflect.Value, copied map[uintptr]reflect.Value) {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		if p, ok := copied[src.Pointer()]; ok {
+			dst.Set(p)
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		copied[src.Pointer()] = p
+		copyValue(p.Elem(), src.Elem(), copied)
+		dst.Set(p)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		elem := reflect.New(src.Elem().Type()).Elem()
+		copyValue(elem, src.Elem(), copied)
+		dst.Set(elem)
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			copyValue(s.Index(i), src.Index(i), copied)
+		}
+		dst.Set(s)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			copyValue(dst.Index(i), src.Index(i), copied)
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			v := reflect.New(src.Type().Elem()).Elem()
+			copyValue(v, iter.Value(), copied)
+			m.SetMapIndex(iter.Key(), v)
+		}
+		dst.Set(m)
+	case reflect.Struct:
+		// unexported fields are copied as is
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				copyValue(dst.Field(i), src.Field(i), copied)
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,122 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ErrNotFound is the error of results for keys which don't exist. Batch
+// functions should use it (or wrap it) for missing rows, so that a missing
+// row is not mistaken for a failure of the backend: integrations can then
+// translate it into a null value, and metrics leave it out of error counts.
+var ErrNotFound = errors.New("dataloader: not found")
+
+// NotFound returns the result of a key which doesn't exist.
+func NotFound[V any]() *Result[V] {
+	return &Result[V]{Error: ErrNotFound}
+}
+
+// NotFound reports whether the result is for a key which doesn't exist.
+func (r *Result[V]) NotFound() bool {
+	return r != nil && IsNotFound(r.Error)
+}
+
+// IsNotFound reports whether err means that the key doesn't exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// WithNotFoundTTL sets how long the results of keys which don't exist are
+// cached. Once the duration has elapsed, the next load of the key goes to the
+// batch function again. Default is 0: not found results are cached like any
+// other result.
+func WithNotFoundTTL[K comparable, V any](d time.Duration) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		if d <= 0 {
+			return
+		}
+		l.addCacheHook(func(ctx context.Context, key K, result *Result[V]) {
+			if result.NotFound() {
+				l.expire(ctx, key, d)
+			}
+		})
+	}
+}
+
+// WithTTLJitter randomizes every TTL the loader applies to the cached keys
+// (i.e. WithNotFoundTTL) by up to the given fraction in either direction, so
+// that the keys cached together don't all expire at the same instant and hit
+// the batch function again at once. Default is 0 (disabled).
+func WithTTLJitter[K comparable, V any](fraction float64) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.ttlJitter = fraction
+	}
+}
+
+// JitterTTL randomizes the TTL by up to the given fraction in either direction
+// (i.e. 0.1 makes a TTL of 10 minutes last between 9 and 11 minutes). Cache
+// implementations expiring their entries can use it the way WithTTLJitter does.
+func JitterTTL(ttl time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || ttl <= 0 {
+		return ttl
+	}
+	ttl += time.Duration((rand.Float64()*2 - 1) * fraction * float64(ttl))
+	if ttl <= 0 {
+		return 1
+	}
+	return ttl
+}
+
+// expiry is the pending expiry of a cached key.
+type expiry struct {
+	stop func() bool
+}
+
+// expire clears the key from the cache once the given duration has elapsed on
+// the clock, unless it has been cleared or replaced in the meantime. The key
+// is cleared with ctx, less its cancellation: the batch of the key is usually
+// over by then.
+func (l *Loader[K, V]) expire(ctx context.Context, key K, d time.Duration) {
+	l.cacheLock.Lock()
+	defer l.cacheLock.Unlock()
+
+	if l.expiries == nil {
+		l.expiries = make(map[K]*expiry)
+	}
+	l.stopExpiry(key)
+
+	ctx = context.WithoutCancel(ctx)
+	e := &expiry{}
+	e.stop = afterFunc(l.clock, JitterTTL(d, l.ttlJitter), func() {
+		l.cacheLock.Lock()
+		if l.expiries[key] == e {
+			delete(l.expiries, key)
+			delete(l.versions, key)
+			l.cache.Delete(ctx, key)
+			l.evict(key, EvictExpired)
+		}
+		l.cacheLock.Unlock()
+		l.flushEvictions(ctx)
+	})
+	l.expiries[key] = e
+}
+
+// stopExpiry cancels the pending expiry of key, if any.
+// It must be called with the cacheLock held.
+func (l *Loader[K, V]) stopExpiry(key K) {
+	if e, ok := l.expiries[key]; ok {
+		e.stop()
+		delete(l.expiries, key)
+	}
+}
+
+// stopExpiries cancels every pending expiry.
+// It must be called with the cacheLock held.
+func (l *Loader[K, V]) stopExpiries() {
+	for key, e := range l.expiries {
+		e.stop()
+		delete(l.expiries, key)
+	}
+}
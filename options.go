@@ -0,0 +1,263 @@
+package dataloader
+
+import (
+	"context"
+	"time"
+)
+
+// OptionSet is a list of options built by its methods, which are named after
+// the With functions they call. Unlike these functions, the methods don't need
+// the type parameters of the loader, since the set already knows them:
+//
+//	loader := dataloader.NewBatchedLoader(batchFn, dataloader.OptionsFor(batchFn).
+//		BatchCapacity(100).
+//		Wait(time.Millisecond)...)
+type OptionSet[K comparable, V any] []Option[K, V]
+
+// Options returns an empty OptionSet for the loaders of K and V.
+func Options[K comparable, V any]() OptionSet[K, V] {
+	return nil
+}
+
+// OptionsFor returns an empty OptionSet for the loaders of the batch function,
+// inferring K and V from it.
+func OptionsFor[K comparable, V any](BatchFunc[K, V]) OptionSet[K, V] {
+	return nil
+}
+
+// With adds the given options, i.e. the options of other packages. Like the
+// other methods, it returns a new set: the sets built from a common base don't
+// share their options.
+func (o OptionSet[K, V]) With(opts ...Option[K, V]) OptionSet[K, V] {
+	return append(o[:len(o):len(o)], opts...)
+}
+
+// Name adds the option set by WithName.
+func (o OptionSet[K, V]) Name(name string) OptionSet[K, V] {
+	return o.With(WithName[K, V](name))
+}
+
+// Cache adds the option set by WithCache.
+func (o OptionSet[K, V]) Cache(c Cache[K, V]) OptionSet[K, V] {
+	return o.With(WithCache[K, V](c))
+}
+
+// ValueCache adds the option set by WithValueCache.
+func (o OptionSet[K, V]) ValueCache(cache DataCache[K, V]) OptionSet[K, V] {
+	return o.With(WithValueCache[K, V](cache))
+}
+
+// BatchCapacity adds the option set by WithBatchCapacity.
+func (o OptionSet[K, V]) BatchCapacity(c int) OptionSet[K, V] {
+	return o.With(WithBatchCapacity[K, V](c))
+}
+
+// InputCapacity adds the option set by WithInputCapacity.
+func (o OptionSet[K, V]) InputCapacity(c int) OptionSet[K, V] {
+	return o.With(WithInputCapacity[K, V](c))
+}
+
+// OverflowPolicy adds the option set by WithOverflowPolicy.
+func (o OptionSet[K, V]) OverflowPolicy(p OverflowPolicy) OptionSet[K, V] {
+	return o.With(WithOverflowPolicy[K, V](p))
+}
+
+// Wait adds the option set by WithWait.
+func (o OptionSet[K, V]) Wait(d time.Duration) OptionSet[K, V] {
+	return o.With(WithWait[K, V](d))
+}
+
+// IdleFlush adds the option set by WithIdleFlush.
+func (o OptionSet[K, V]) IdleFlush(d time.Duration) OptionSet[K, V] {
+	return o.With(WithIdleFlush[K, V](d))
+}
+
+// WaitJitter adds the option set by WithWaitJitter.
+func (o OptionSet[K, V]) WaitJitter(fraction float64) OptionSet[K, V] {
+	return o.With(WithWaitJitter[K, V](fraction))
+}
+
+// WindowAlignment adds the option set by WithWindowAlignment.
+func (o OptionSet[K, V]) WindowAlignment(d time.Duration) OptionSet[K, V] {
+	return o.With(WithWindowAlignment[K, V](d))
+}
+
+// MinBatchSize adds the option set by WithMinBatchSize.
+func (o OptionSet[K, V]) MinBatchSize(n int, maxExtraWait time.Duration) OptionSet[K, V] {
+	return o.With(WithMinBatchSize[K, V](n, maxExtraWait))
+}
+
+// ImmediateDispatch adds the option set by WithImmediateDispatch.
+func (o OptionSet[K, V]) ImmediateDispatch() OptionSet[K, V] {
+	return o.With(WithImmediateDispatch[K, V]())
+}
+
+// CacheMaxAge adds the option set by WithCacheMaxAge.
+func (o OptionSet[K, V]) CacheMaxAge(d time.Duration) OptionSet[K, V] {
+	return o.With(WithCacheMaxAge[K, V](d))
+}
+
+// StaleFallback adds the option set by WithStaleFallback.
+func (o OptionSet[K, V]) StaleFallback() OptionSet[K, V] {
+	return o.With(WithStaleFallback[K, V]())
+}
+
+// InFlightDedupe adds the option set by WithInFlightDedupe.
+func (o OptionSet[K, V]) InFlightDedupe() OptionSet[K, V] {
+	return o.With(WithInFlightDedupe[K, V]())
+}
+
+// OnEvict adds the option set by WithOnEvict.
+func (o OptionSet[K, V]) OnEvict(fn func(ctx context.Context, key K, value V, reason EvictReason)) OptionSet[K, V] {
+	return o.With(WithOnEvict[K, V](fn))
+}
+
+// BatchMemo adds the option set by WithBatchMemo.
+func (o OptionSet[K, V]) BatchMemo(ttl time.Duration, size int) OptionSet[K, V] {
+	return o.With(WithBatchMemo[K, V](ttl, size))
+}
+
+// DeadlineAdmission adds the option set by WithDeadlineAdmission.
+func (o OptionSet[K, V]) DeadlineAdmission(p DeadlinePolicy) OptionSet[K, V] {
+	return o.With(WithDeadlineAdmission[K, V](p))
+}
+
+// Flagger adds the option set by WithFlagger.
+func (o OptionSet[K, V]) Flagger(f Flagger) OptionSet[K, V] {
+	return o.With(WithFlagger[K, V](f))
+}
+
+// ManualDispatch adds the option set by WithManualDispatch.
+func (o OptionSet[K, V]) ManualDispatch() OptionSet[K, V] {
+	return o.With(WithManualDispatch[K, V]())
+}
+
+// AutoTune adds the option set by WithAutoTune.
+func (o OptionSet[K, V]) AutoTune(opts ...AutoTuneOption) OptionSet[K, V] {
+	return o.With(WithAutoTune[K, V](opts...))
+}
+
+// BatchCostLimit adds the option set by WithBatchCostLimit.
+func (o OptionSet[K, V]) BatchCostLimit(limit int, cost func(K) int) OptionSet[K, V] {
+	return o.With(WithBatchCostLimit[K, V](limit, cost))
+}
+
+// TenantCap adds the option set by WithTenantCap.
+func (o OptionSet[K, V]) TenantCap(tenantOf func(K) string, cap int) OptionSet[K, V] {
+	return o.With(WithTenantCap[K, V](tenantOf, cap))
+}
+
+// KeyBudget adds the option set by WithKeyBudget.
+func (o OptionSet[K, V]) KeyBudget(perContext int) OptionSet[K, V] {
+	return o.With(WithKeyBudget[K, V](perContext))
+}
+
+// ConcurrencyLimit adds the option set by WithConcurrencyLimit.
+func (o OptionSet[K, V]) ConcurrencyLimit(n int) OptionSet[K, V] {
+	return o.With(WithConcurrencyLimit[K, V](n))
+}
+
+// BatchPlanner adds the option set by WithBatchPlanner.
+func (o OptionSet[K, V]) BatchPlanner(plan func(ctx context.Context, keys []K) [][]K) OptionSet[K, V] {
+	return o.With(WithBatchPlanner[K, V](plan))
+}
+
+// Scheduler adds the option set by WithScheduler.
+func (o OptionSet[K, V]) Scheduler(s Scheduler) OptionSet[K, V] {
+	return o.With(WithScheduler[K, V](s))
+}
+
+// Prefetch adds the option set by WithPrefetch.
+func (o OptionSet[K, V]) Prefetch(prefetch func(context.Context, V) []K) OptionSet[K, V] {
+	return o.With(WithPrefetch[K, V](prefetch))
+}
+
+// SpeculativePrefetch adds the option set by WithSpeculativePrefetch.
+func (o OptionSet[K, V]) SpeculativePrefetch(predict func(ctx context.Context, key K) []K, budget int) OptionSet[K, V] {
+	return o.With(WithSpeculativePrefetch[K, V](predict, budget))
+}
+
+// CacheBypass adds the option set by WithCacheBypass.
+func (o OptionSet[K, V]) CacheBypass(bypass func(context.Context, K) bool) OptionSet[K, V] {
+	return o.With(WithCacheBypass[K, V](bypass))
+}
+
+// ClearCacheOnBatch adds the option set by WithClearCacheOnBatch.
+func (o OptionSet[K, V]) ClearCacheOnBatch() OptionSet[K, V] {
+	return o.With(WithClearCacheOnBatch[K, V]())
+}
+
+// NotFoundTTL adds the option set by WithNotFoundTTL.
+func (o OptionSet[K, V]) NotFoundTTL(d time.Duration) OptionSet[K, V] {
+	return o.With(WithNotFoundTTL[K, V](d))
+}
+
+// TTLJitter adds the option set by WithTTLJitter.
+func (o OptionSet[K, V]) TTLJitter(fraction float64) OptionSet[K, V] {
+	return o.With(WithTTLJitter[K, V](fraction))
+}
+
+// Version adds the option set by WithVersion.
+func (o OptionSet[K, V]) Version(versionOf func(V) int64) OptionSet[K, V] {
+	return o.With(WithVersion[K, V](versionOf))
+}
+
+// FailFastOnCanceledContext adds the option set by WithFailFastOnCanceledContext.
+func (o OptionSet[K, V]) FailFastOnCanceledContext() OptionSet[K, V] {
+	return o.With(WithFailFastOnCanceledContext[K, V]())
+}
+
+// Clock adds the option set by WithClock.
+func (o OptionSet[K, V]) Clock(c Clock) OptionSet[K, V] {
+	return o.With(WithClock[K, V](c))
+}
+
+// BatchContext adds the option set by WithBatchContext.
+func (o OptionSet[K, V]) BatchContext(fn func() context.Context) OptionSet[K, V] {
+	return o.With(WithBatchContext[K, V](fn))
+}
+
+// ResultTransform adds the option set by WithResultTransform.
+func (o OptionSet[K, V]) ResultTransform(transform func(context.Context, K, V) V) OptionSet[K, V] {
+	return o.With(WithResultTransform[K, V](transform))
+}
+
+// LenientResults adds the option set by WithLenientResults.
+func (o OptionSet[K, V]) LenientResults() OptionSet[K, V] {
+	return o.With(WithLenientResults[K, V]())
+}
+
+// MutationDetection adds the option set by WithMutationDetection.
+func (o OptionSet[K, V]) MutationDetection(report func(key K)) OptionSet[K, V] {
+	return o.With(WithMutationDetection[K, V](report))
+}
+
+// ShadowBatch adds the option set by WithShadowBatch.
+func (o OptionSet[K, V]) ShadowBatch(batchFn BatchFunc[K, V], compare func(key K, primary, shadow *Result[V])) OptionSet[K, V] {
+	return o.With(WithShadowBatch[K, V](batchFn, compare))
+}
+
+// ShadowSampleRate adds the option set by WithShadowSampleRate.
+func (o OptionSet[K, V]) ShadowSampleRate(rate float64) OptionSet[K, V] {
+	return o.With(WithShadowSampleRate[K, V](rate))
+}
+
+// Tracer adds the option set by WithTracer.
+func (o OptionSet[K, V]) Tracer(tracer Tracer[K, V]) OptionSet[K, V] {
+	return o.With(WithTracer[K, V](tracer))
+}
+
+// Metrics adds the option set by WithMetrics.
+func (o OptionSet[K, V]) Metrics(m Metrics) OptionSet[K, V] {
+	return o.With(WithMetrics[K, V](m))
+}
+
+// ThunkWatchdog adds the option set by WithThunkWatchdog.
+func (o OptionSet[K, V]) ThunkWatchdog(d time.Duration, onStuck func(K, time.Duration)) OptionSet[K, V] {
+	return o.With(WithThunkWatchdog[K, V](d, onStuck))
+}
+
+// ThunkTimeout adds the option set by WithThunkTimeout.
+func (o OptionSet[K, V]) ThunkTimeout(d time.Duration) OptionSet[K, V] {
+	return o.With(WithThunkTimeout[K, V](d))
+}
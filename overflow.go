@@ -0,0 +1,24 @@
+package dataloader
+
+import "errors"
+
+// ErrInputQueueFull is returned by the thunks of keys which could not be queued
+// because the input queue was full, when using the OverflowFail policy.
+var ErrInputQueueFull = errors.New("dataloader: input queue is full")
+
+// OverflowPolicy defines what Load does when the input queue of the current
+// batch is full. Overflow policies only apply to loaders with a positive
+// input capacity.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks Load until the batcher has made room in the input
+	// queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowFail doesn't queue the key, and resolves its thunk to
+	// ErrInputQueueFull. The key isn't cached.
+	OverflowFail
+	// OverflowRetry dispatches the current batch right away and queues the
+	// key on the next one.
+	OverflowRetry
+)
@@ -0,0 +1,246 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrInvalidPageKey is returned by the thunks of the page keys whose First is
+// negative.
+var ErrInvalidPageKey = errors.New("dataloader: invalid page key")
+
+// PageKey identifies a page of the connection of a parent (i.e. the first
+// 10 comments of a post, after a given cursor).
+type PageKey[P comparable] struct {
+	Parent P
+	// After is the cursor the page starts after. It is empty for the first page.
+	After string
+	// First is the number of edges in the page. It must not be negative.
+	First int
+}
+
+// Edge is a value of a page with its cursor.
+type Edge[V any] struct {
+	Cursor string
+	Node   V
+}
+
+// Page is a window of the connection of a parent.
+type Page[V any] struct {
+	Edges       []Edge[V]
+	HasNextPage bool
+}
+
+// PageBatchFunc is a function, which when given a slice of page keys, returns
+// a slice of results where the data of each result is the page for the key at
+// the same index.
+type PageBatchFunc[P comparable, V any] func(context.Context, []PageKey[P]) []*Result[*Page[V]]
+
+// PageLoader loads pages of connections. Instead of caching every page key
+// independently, it merges the pages it loads into contiguous windows of edges
+// per parent: a page which is covered by the cached windows is never fetched,
+// and a page which partially overlaps them only fetches the missing edges.
+type PageLoader[P comparable, V any] struct {
+	loader *Loader[PageKey[P], *Page[V]]
+
+	mu      sync.Mutex
+	windows map[P][]*pageWindow[V]
+	pending map[PageKey[P]]Thunk[*Page[V]]
+}
+
+// pageWindow is a contiguous run of edges following the after cursor.
+type pageWindow[V any] struct {
+	after string
+	edges []Edge[V]
+	// complete is true when the window reaches the end of the connection.
+	complete bool
+}
+
+// NewPageLoader constructs a new PageLoader with given options. The page
+// loader manages its own cache, so the cache options are ignored.
+func NewPageLoader[P comparable, V any](batchFn PageBatchFunc[P, V], opts ...Option[PageKey[P], *Page[V]]) *PageLoader[P, V] {
+	p := &PageLoader[P, V]{
+		windows: make(map[P][]*pageWindow[V]),
+		pending: make(map[PageKey[P]]Thunk[*Page[V]]),
+	}
+	opts = append(opts, WithCache[PageKey[P], *Page[V]](&NoCache[PageKey[P], *Page[V]]{}))
+	p.loader = NewBatchedLoader(func(ctx context.Context, keys []PageKey[P]) (results []*Result[*Page[V]]) {
+		// the pending keys are forgotten even if the batch function panics, so
+		// that the following loads don't get its error
+		defer func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			for i, key := range keys {
+				delete(p.pending, key)
+				if len(results) != len(keys) {
+					continue
+				}
+				if result := results[i]; result != nil && result.Error == nil && result.Data != nil {
+					p.store(key, result.Data)
+				}
+			}
+		}()
+		return batchFn(ctx, keys)
+	}, opts...)
+	return p
+}
+
+// LoadPage loads the page for the given key.
+func (p *PageLoader[P, V]) LoadPage(ctx context.Context, key PageKey[P]) Thunk[*Page[V]] {
+	if key.First < 0 {
+		return func() (*Page[V], error) {
+			return nil, ErrInvalidPageKey
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	page, missing, ok := p.lookup(key)
+	if ok {
+		return func() (*Page[V], error) {
+			return page, nil
+		}
+	}
+
+	thunk := p.load(ctx, missing)
+	if page == nil {
+		return thunk
+	}
+
+	// prepend the cached edges to the missing ones once they are loaded
+	return func() (*Page[V], error) {
+		rest, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+		edges := make([]Edge[V], 0, len(page.Edges)+len(rest.Edges))
+		edges = append(edges, page.Edges...)
+		edges = append(edges, rest.Edges...)
+		return &Page[V]{Edges: edges, HasNextPage: rest.HasNextPage}, nil
+	}
+}
+
+// ClearParent clears every cached page of parent. Returns self for method
+// chaining.
+func (p *PageLoader[P, V]) ClearParent(ctx context.Context, parent P) *PageLoader[P, V] {
+	p.mu.Lock()
+	delete(p.windows, parent)
+	p.mu.Unlock()
+	return p
+}
+
+// ClearAll clears every cached page. Returns self for method chaining.
+func (p *PageLoader[P, V]) ClearAll() *PageLoader[P, V] {
+	p.mu.Lock()
+	p.windows = make(map[P][]*pageWindow[V])
+	p.mu.Unlock()
+	return p
+}
+
+// load loads the given key, deduplicating concurrent loads of the same key.
+// It must be called with the lock held.
+func (p *PageLoader[P, V]) load(ctx context.Context, key PageKey[P]) Thunk[*Page[V]] {
+	if thunk, ok := p.pending[key]; ok {
+		return thunk
+	}
+	thunk := p.loader.Load(ctx, key)
+	p.pending[key] = thunk
+	return thunk
+}
+
+// lookup looks the key up in the cached windows of its parent. If the whole
+// page is cached, it is returned with ok set to true. Otherwise, the cached
+// beginning of the page (if any) is returned along with the key of the
+// missing edges. It must be called with the lock held.
+func (p *PageLoader[P, V]) lookup(key PageKey[P]) (page *Page[V], missing PageKey[P], ok bool) {
+	w, offset := p.find(key.Parent, key.After)
+	if w == nil {
+		return nil, key, false
+	}
+
+	available := w.edges[offset:]
+	switch {
+	case len(available) >= key.First:
+		return &Page[V]{
+			Edges:       available[:key.First:key.First],
+			HasNextPage: len(available) > key.First || !w.complete,
+		}, key, true
+	case w.complete:
+		return &Page[V]{Edges: available[:len(available):len(available)]}, key, true
+	case len(available) == 0:
+		return nil, key, false
+	}
+
+	missing = PageKey[P]{
+		Parent: key.Parent,
+		After:  available[len(available)-1].Cursor,
+		First:  key.First - len(available),
+	}
+	return &Page[V]{Edges: available[:len(available):len(available)]}, missing, false
+}
+
+// find returns the cached window of parent holding the edges following the
+// after cursor, along with the offset of the first of these edges.
+func (p *PageLoader[P, V]) find(parent P, after string) (*pageWindow[V], int) {
+	for _, w := range p.windows[parent] {
+		if w.after == after {
+			return w, 0
+		}
+		for i, edge := range w.edges {
+			if edge.Cursor == after {
+				return w, i + 1
+			}
+		}
+	}
+	return nil, 0
+}
+
+// store merges a loaded page in the cached windows of its parent.
+// It must be called with the lock held.
+func (p *PageLoader[P, V]) store(key PageKey[P], page *Page[V]) {
+	w, offset := p.find(key.Parent, key.After)
+	if w == nil {
+		w = &pageWindow[V]{after: key.After}
+		p.windows[key.Parent] = append(p.windows[key.Parent], w)
+	}
+
+	// the loaded edges are fresher than the cached ones they overlap
+	tail := w.edges[offset:]
+	edges := append(w.edges[:offset:offset], page.Edges...)
+	switch {
+	case !page.HasNextPage:
+		w.complete = true
+	case len(tail) > len(page.Edges):
+		edges = append(edges, tail[len(page.Edges):]...)
+	default:
+		w.complete = false
+	}
+	w.edges = edges
+
+	// merge the windows which now follow each other
+	for merged := true; merged; {
+		merged = false
+		windows := p.windows[key.Parent]
+		for i, a := range windows {
+			if len(a.edges) == 0 || a.complete {
+				continue
+			}
+			last := a.edges[len(a.edges)-1].Cursor
+			for j, b := range windows {
+				if i == j || b.after != last {
+					continue
+				}
+				a.edges = append(a.edges, b.edges...)
+				a.complete = b.complete
+				p.windows[key.Parent] = append(windows[:j:j], windows[j+1:]...)
+				merged = true
+				break
+			}
+			if merged {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestPageLoader(t *testing.T) {
+	t.Run("serves pages covered by loaded windows", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := ConnectionLoader(10)
+
+		assertPage(t, loader, PageKey[string]{"p", "", 3}, "n0,n1,n2", true)
+		assertPage(t, loader, PageKey[string]{"p", "c2", 3}, "n3,n4,n5", true)
+		// adjacent pages are merged
+		assertPage(t, loader, PageKey[string]{"p", "", 6}, "n0,n1,n2,n3,n4,n5", true)
+		assertPage(t, loader, PageKey[string]{"p", "c1", 2}, "n2,n3", true)
+
+		expected := [][]PageKey[string]{{{"p", "", 3}}, {{"p", "c2", 3}}}
+		if calls := loadCalls(); !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("only fetches the missing edges of overlapping pages", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := ConnectionLoader(10)
+		ctx := context.Background()
+
+		assertPage(t, loader, PageKey[string]{"p", "", 3}, "n0,n1,n2", true)
+		assertPage(t, loader, PageKey[string]{"p", "", 5}, "n0,n1,n2,n3,n4", true)
+		assertPage(t, loader, PageKey[string]{"p", "c7", 5}, "n8,n9", false)
+		// the end of the connection is known
+		assertPage(t, loader, PageKey[string]{"p", "c8", 5}, "n9", false)
+
+		loader.ClearParent(ctx, "p")
+		assertPage(t, loader, PageKey[string]{"p", "", 1}, "n0", true)
+
+		expected := [][]PageKey[string]{
+			{{"p", "", 3}},
+			{{"p", "c2", 2}},
+			{{"p", "c7", 5}},
+			{{"p", "", 1}},
+		}
+		if calls := loadCalls(); !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("batches pages of different parents", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := ConnectionLoader(10)
+		ctx := context.Background()
+
+		future1 := loader.LoadPage(ctx, PageKey[string]{"a", "", 2})
+		future2 := loader.LoadPage(ctx, PageKey[string]{"b", "", 2})
+		future3 := loader.LoadPage(ctx, PageKey[string]{"a", "", 2})
+		for _, future := range []Thunk[*Page[string]]{future1, future2, future3} {
+			if _, err := future(); err != nil {
+				t.Error(err.Error())
+			}
+		}
+
+		expected := [][]PageKey[string]{{{"a", "", 2}, {"b", "", 2}}}
+		if calls := loadCalls(); !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("rejects the negative page sizes", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := ConnectionLoader(10)
+		ctx := context.Background()
+
+		assertPage(t, loader, PageKey[string]{"p", "", 3}, "n0,n1,n2", true)
+		if _, err := loader.LoadPage(ctx, PageKey[string]{"p", "", -1})(); !errors.Is(err, ErrInvalidPageKey) {
+			t.Errorf("expected ErrInvalidPageKey, got %v", err)
+		}
+		if _, err := loader.LoadPage(ctx, PageKey[string]{"q", "", -1})(); !errors.Is(err, ErrInvalidPageKey) {
+			t.Errorf("expected ErrInvalidPageKey, got %v", err)
+		}
+
+		expected := [][]PageKey[string]{{{"p", "", 3}}}
+		if calls := loadCalls(); !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("forgets the pending pages of a panicking batch", func(t *testing.T) {
+		t.Parallel()
+		var calls int
+		loader := NewPageLoader(func(_ context.Context, keys []PageKey[string]) []*Result[*Page[string]] {
+			calls++
+			if calls == 1 {
+				panic("boom")
+			}
+			results := make([]*Result[*Page[string]], len(keys))
+			for i := range keys {
+				results[i] = &Result[*Page[string]]{Data: &Page[string]{Edges: []Edge[string]{{Cursor: "c0", Node: "n0"}}}}
+			}
+			return results
+		}, withSilentLogger[PageKey[string], *Page[string]]())
+		ctx := context.Background()
+
+		key := PageKey[string]{"p", "", 1}
+		if _, err := loader.LoadPage(ctx, key)(); err == nil {
+			t.Fatal("expected the panic to be returned")
+		}
+		assertPage(t, loader, key, "n0", false)
+	})
+}
+
+func assertPage(t *testing.T, loader *PageLoader[string, string], key PageKey[string], nodes string, hasNextPage bool) {
+	t.Helper()
+	page, err := loader.LoadPage(context.Background(), key)()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var got string
+	for i, edge := range page.Edges {
+		if i > 0 {
+			got += ","
+		}
+		got += edge.Node
+	}
+	if got != nodes || page.HasNextPage != hasNextPage {
+		t.Errorf("page %+v: expected %s (next: %v), got %s (next: %v)", key, nodes, hasNextPage, got, page.HasNextPage)
+	}
+}
+
+// ConnectionLoader loads pages of a connection of the given size, where node
+// i has cursor ci.
+func ConnectionLoader(size int) (*PageLoader[string, string], func() [][]PageKey[string]) {
+	var mu sync.Mutex
+	var loadCalls [][]PageKey[string]
+	loader := NewPageLoader(func(_ context.Context, keys []PageKey[string]) []*Result[*Page[string]] {
+		var results []*Result[*Page[string]]
+		mu.Lock()
+		loadCalls = append(loadCalls, keys)
+		mu.Unlock()
+		for _, key := range keys {
+			start := 0
+			if key.After != "" {
+				start, _ = strconv.Atoi(key.After[1:])
+				start++
+			}
+			page := &Page[string]{}
+			for i := start; i < size && i < start+key.First; i++ {
+				page.Edges = append(page.Edges, Edge[string]{Cursor: fmt.Sprintf("c%d", i), Node: fmt.Sprintf("n%d", i)})
+			}
+			page.HasNextPage = start+key.First < size
+			results = append(results, &Result[*Page[string]]{Data: page})
+		}
+		return results
+	})
+	return loader, func() [][]PageKey[string] {
+		mu.Lock()
+		defer mu.Unlock()
+		return loadCalls
+	}
+}
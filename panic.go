@@ -0,0 +1,34 @@
+package dataloader
+
+import (
+	"fmt"
+)
+
+// PanicError is the error of the keys of a batch function which panicked. It
+// is wrapped by a PanicErrorWrapper: use errors.As to retrieve it.
+type PanicError struct {
+	// Value is the value recovered from the panic.
+	Value any
+	// Stack is the stack trace of the goroutine which panicked. It is empty
+	// when built with TinyGo, which can't capture it.
+	Stack []byte
+
+	// the kind of function which panicked (i.e. batch)
+	function string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("Panic received in %s function: %v", e.function, e.Value)
+}
+
+// Unwrap returns the recovered value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// newPanicError captures the stack trace of a panic of the given kind of
+// function. It must be called from the deferred function recovering it.
+func newPanicError(function string, value any) *PanicError {
+	return &PanicError{Value: value, Stack: stack(), function: function}
+}
@@ -0,0 +1,96 @@
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// WithBatchPlanner sets a function planning the calls of the batch function
+// for the keys of a batch, right before it is dispatched: it may reorder the
+// keys, or split them into several calls (i.e. grouping the keys of the same
+// partition). The calls of a plan run concurrently, up to the limit set by
+// WithConcurrencyLimit, and a panic of the batch function only fails the keys
+// of its call.
+//
+// The plan should partition the keys of the batch: a key left out of every
+// call resolves with an error, and a key planned in several calls resolves
+// with the result of one of them.
+func WithBatchPlanner[K comparable, V any](plan func(ctx context.Context, keys []K) [][]K) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.planner = plan
+	}
+}
+
+// planned runs the batch function for the plan of the keys, and returns the
+// results in the order of the keys.
+func (l *Loader[K, V]) planned(ctx context.Context, batchFn BatchFunc[K, V], keys []K) []*Result[V] {
+	plan := l.planner(ctx, keys)
+	_, sem := l.semaphores()
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		byKey = make(map[K]*Result[V], len(keys))
+	)
+	for _, call := range plan {
+		if len(call) == 0 {
+			continue
+		}
+		wg.Add(1)
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		go func() {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			results := l.call(ctx, batchFn, call)
+			mu.Lock()
+			defer mu.Unlock()
+			for i, key := range call {
+				byKey[key] = results[i]
+			}
+		}()
+	}
+	wg.Wait()
+
+	items := make([]*Result[V], len(keys))
+	for i, key := range keys {
+		result, ok := byKey[key]
+		if !ok {
+			result = &Result[V]{Error: fmt.Errorf("dataloader: the batch planner left out the key %v", key)}
+		}
+		items[i] = result
+	}
+	return items
+}
+
+// call runs the batch function for the keys of a call of a plan, returning as
+// many results as keys.
+func (l *Loader[K, V]) call(ctx context.Context, batchFn BatchFunc[K, V], keys []K) (results []*Result[V]) {
+	fail := func(err error) []*Result[V] {
+		results := make([]*Result[V], len(keys))
+		for i := range results {
+			results[i] = &Result[V]{Error: err}
+		}
+		return results
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err := newPanicError("batch", r)
+			if !l.silent {
+				log.Printf("Dataloader: %v\n%s", err, err.Stack)
+			}
+			results = fail(&PanicErrorWrapper{panicError: err})
+		}
+	}()
+
+	results = batchFn(ctx, keys)
+	if len(results) != len(keys) {
+		return fail(fmt.Errorf("the batch function returned %d results for a call of %d keys", len(results), len(keys)))
+	}
+	return results
+}
@@ -0,0 +1,76 @@
+package dataloader
+
+import (
+	"context"
+)
+
+// Promise is the struct form of a Thunk: besides blocking until the value
+// is loaded, it can be inspected without blocking.
+type Promise[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// NewPromise returns a promise resolving to the value of the thunk. The
+// thunk is called right away on its own goroutine.
+func NewPromise[V any](thunk Thunk[V]) *Promise[V] {
+	p := &Promise[V]{done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		p.value, p.err = thunk()
+	}()
+	return p
+}
+
+// Get blocks until the promise is resolved, and returns its value.
+func (p *Promise[V]) Get() (V, error) {
+	<-p.done
+	return p.value, p.err
+}
+
+// TryGet returns the value of the promise without blocking. ok is false if
+// the promise isn't resolved yet.
+func (p *Promise[V]) TryGet() (value V, ok bool, err error) {
+	select {
+	case <-p.done:
+		return p.value, true, p.err
+	default:
+		return value, false, nil
+	}
+}
+
+// Done returns a channel closed once the promise is resolved.
+func (p *Promise[V]) Done() <-chan struct{} {
+	return p.done
+}
+
+// IsResolved reports whether the promise is resolved.
+func (p *Promise[V]) IsResolved() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Thunk returns the promise as a Thunk.
+func (p *Promise[V]) Thunk() Thunk[V] {
+	return p.Get
+}
+
+// LoadP loads a key like Load, returning a Promise instead of a Thunk.
+func (l *Loader[K, V]) LoadP(ctx context.Context, key K) *Promise[V] {
+	return NewPromise(l.Load(ctx, key))
+}
+
+// LoadManyP loads multiple keys, returning a Promise for each of them, in the
+// order of the keys.
+func (l *Loader[K, V]) LoadManyP(ctx context.Context, keys []K) []*Promise[V] {
+	promises := make([]*Promise[V], len(keys))
+	for i, key := range keys {
+		promises[i] = l.LoadP(ctx, key)
+	}
+	return promises
+}
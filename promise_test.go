@@ -0,0 +1,67 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPromise(t *testing.T) {
+	t.Run("resolves to the value of the key", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, _ := IDLoader[string](0)
+		ctx := context.Background()
+
+		p := identityLoader.LoadP(ctx, "1")
+		select {
+		case <-p.Done():
+		case <-time.After(time.Second):
+			t.Fatal("expected the promise to be resolved")
+		}
+		if !p.IsResolved() {
+			t.Error("expected the promise to be resolved")
+		}
+		if value, ok, err := p.TryGet(); !ok || err != nil || value != "1" {
+			t.Errorf("expected 1, got %q, %v, %v", value, ok, err)
+		}
+		if value, err := p.Get(); err != nil || value != "1" {
+			t.Errorf("expected 1, got %q, %v", value, err)
+		}
+	})
+
+	t.Run("is pending until the thunk returns", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		p := NewPromise(func() (int, error) {
+			<-release
+			return 0, errors.New("failed")
+		})
+		if p.IsResolved() {
+			t.Error("expected the promise to be pending")
+		}
+		if _, ok, err := p.TryGet(); ok || err != nil {
+			t.Errorf("expected the promise to be pending, got %v, %v", ok, err)
+		}
+		close(release)
+		if _, err := p.Thunk()(); err == nil || err.Error() != "failed" {
+			t.Errorf("expected the error of the thunk, got %v", err)
+		}
+	})
+
+	t.Run("LoadManyP returns a promise per key", func(t *testing.T) {
+		t.Parallel()
+		identityLoader, loadCalls := IDLoader[string](0)
+		ctx := context.Background()
+
+		keys := []string{"1", "2", "3"}
+		for i, p := range identityLoader.LoadManyP(ctx, keys) {
+			if value, err := p.Get(); err != nil || value != keys[i] {
+				t.Errorf("expected %s, got %q, %v", keys[i], value, err)
+			}
+		}
+		if calls := *loadCalls; len(calls) != 1 {
+			t.Errorf("expected a single batch, got %#v", calls)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package dataloader
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Spec declares the wiring of a loader, so that it can be set in a
+// configuration file rather than in code (i.e. decoded from JSON or YAML).
+type Spec struct {
+	// Config holds the tunables of the loader.
+	Config Config `json:"config"`
+	// Cache is the cache of the loader. If its name is empty, the loader has
+	// the default cache.
+	Cache ComponentSpec `json:"cache"`
+	// Tracer is the tracer of the loader. If its name is empty, the loader
+	// has the default (noop) tracer.
+	Tracer ComponentSpec `json:"tracer"`
+	// Options are the other components of the loader (i.e. its metrics),
+	// applied in order.
+	Options []ComponentSpec `json:"options"`
+}
+
+// ComponentSpec names a registered component, along with its parameters.
+type ComponentSpec struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params"`
+}
+
+// CacheFactory constructs a cache from its parameters.
+type CacheFactory[K comparable, V any] func(params map[string]string) (Cache[K, V], error)
+
+// TracerFactory constructs a tracer from its parameters.
+type TracerFactory[K comparable, V any] func(params map[string]string) (Tracer[K, V], error)
+
+// OptionFactory constructs an option from its parameters, for the components
+// which are neither a cache nor a tracer (i.e. metrics).
+type OptionFactory[K comparable, V any] func(params map[string]string) (Option[K, V], error)
+
+// registryKey identifies a factory: the factories of different types of keys
+// and values don't collide.
+type registryKey struct {
+	name    string
+	factory reflect.Type
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[registryKey]any{}
+)
+
+// RegisterCache makes a cache available by name to the loaders of K and V
+// constructed by NewFromSpec. The "memory", "sync-map" and "none" caches (see
+// NewCache, WithSyncMap and NoCache) are always available. It panics if the
+// factory is nil or if a cache is already registered with the same name for K
+// and V.
+func RegisterCache[K comparable, V any](name string, factory CacheFactory[K, V]) {
+	register(name, factory, factory == nil)
+}
+
+// RegisterTracer makes a tracer available by name to the loaders of K and V
+// constructed by NewFromSpec. The "noop" tracer (see NoopTracer) is always
+// available. It panics if the factory is nil or if a tracer is already
+// registered with the same name for K and V.
+func RegisterTracer[K comparable, V any](name string, factory TracerFactory[K, V]) {
+	register(name, factory, factory == nil)
+}
+
+// RegisterOption makes an option available by name to the loaders of K and V
+// constructed by NewFromSpec. It panics if the factory is nil or if an option
+// is already registered with the same name for K and V.
+func RegisterOption[K comparable, V any](name string, factory OptionFactory[K, V]) {
+	register(name, factory, factory == nil)
+}
+
+func register(name string, factory any, isNil bool) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	typ := reflect.TypeOf(factory)
+	if isNil {
+		panic(fmt.Sprintf("dataloader: Register %v is nil", typ))
+	}
+	key := registryKey{name, typ}
+	if _, dup := registry[key]; dup {
+		panic(fmt.Sprintf("dataloader: Register called twice for %v %s", typ, name))
+	}
+	registry[key] = factory
+}
+
+// lookup returns the factory of type F registered by name.
+func lookup[F any](name string) (F, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	factory, ok := registry[registryKey{name, reflect.TypeFor[F]()}].(F)
+	return factory, ok
+}
+
+// NewFromSpec constructs a new Loader from the specification, followed by the
+// given options. The components of the specification must be registered for K
+// and V, and the configuration is validated like NewBatchedLoaderE does.
+func NewFromSpec[K comparable, V any](batchFn BatchFunc[K, V], spec Spec, opts ...Option[K, V]) (*Loader[K, V], error) {
+	specOpts := ConfigOptions[K, V](spec.Config)
+
+	if spec.Cache.Name != "" {
+		cache, err := specCache[K, V](spec.Cache)
+		if err != nil {
+			return nil, err
+		}
+		specOpts = append(specOpts, WithCache[K, V](cache))
+	}
+
+	if spec.Tracer.Name != "" {
+		tracer, err := specTracer[K, V](spec.Tracer)
+		if err != nil {
+			return nil, err
+		}
+		specOpts = append(specOpts, WithTracer[K, V](tracer))
+	}
+
+	for _, c := range spec.Options {
+		factory, ok := lookup[OptionFactory[K, V]](c.Name)
+		if !ok {
+			return nil, fmt.Errorf("dataloader: unknown option %q for %v (forgotten registration?)", c.Name, reflect.TypeFor[*Loader[K, V]]())
+		}
+		opt, err := factory(c.Params)
+		if err != nil {
+			return nil, fmt.Errorf("dataloader: invalid option %q: %w", c.Name, err)
+		}
+		specOpts = append(specOpts, opt)
+	}
+
+	return NewBatchedLoaderE(batchFn, append(specOpts, opts...)...)
+}
+
+// specCache constructs the cache of a specification.
+func specCache[K comparable, V any](c ComponentSpec) (Cache[K, V], error) {
+	switch c.Name {
+	case "memory":
+		return NewCache[K, V](), nil
+	case "sync-map":
+		return NewCache[K, V](WithSyncMap()), nil
+	case "none":
+		return &NoCache[K, V]{}, nil
+	}
+	factory, ok := lookup[CacheFactory[K, V]](c.Name)
+	if !ok {
+		return nil, fmt.Errorf("dataloader: unknown cache %q for %v (forgotten registration?)", c.Name, reflect.TypeFor[*Loader[K, V]]())
+	}
+	cache, err := factory(c.Params)
+	if err != nil {
+		return nil, fmt.Errorf("dataloader: invalid cache %q: %w", c.Name, err)
+	}
+	return cache, nil
+}
+
+// specTracer constructs the tracer of a specification.
+func specTracer[K comparable, V any](c ComponentSpec) (Tracer[K, V], error) {
+	if c.Name == "noop" {
+		return NoopTracer[K, V]{}, nil
+	}
+	factory, ok := lookup[TracerFactory[K, V]](c.Name)
+	if !ok {
+		return nil, fmt.Errorf("dataloader: unknown tracer %q for %v (forgotten registration?)", c.Name, reflect.TypeFor[*Loader[K, V]]())
+	}
+	tracer, err := factory(c.Params)
+	if err != nil {
+		return nil, fmt.Errorf("dataloader: invalid tracer %q: %w", c.Name, err)
+	}
+	return tracer, nil
+}
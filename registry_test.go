@@ -0,0 +1,104 @@
+package dataloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// specTestCache is the cache constructed by the "test" cache factory.
+type specTestCache struct {
+	*InMemoryCache[string, string]
+	params map[string]string
+}
+
+func init() {
+	RegisterCache("test", func(params map[string]string) (Cache[string, string], error) {
+		if params["size"] == "" {
+			return nil, errors.New("the size is missing")
+		}
+		return &specTestCache{NewCache[string, string](), params}, nil
+	})
+	RegisterTracer("test", func(map[string]string) (Tracer[string, string], error) {
+		return NoopTracer[string, string]{}, nil
+	})
+	RegisterOption("lenient", func(map[string]string) (Option[string, string], error) {
+		return WithLenientResults[string, string](), nil
+	})
+}
+
+func TestNewFromSpec(t *testing.T) {
+	t.Run("constructs a loader from the specification", func(t *testing.T) {
+		var spec Spec
+		err := json.Unmarshal([]byte(`{
+			"config": {"Name": "users", "BatchCapacity": 10, "InputCapacity": 1000},
+			"cache": {"name": "test", "params": {"size": "100"}},
+			"tracer": {"name": "test"},
+			"options": [{"name": "lenient"}]
+		}`), &spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loader, err := NewFromSpec(batchIdentity[string], spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cache, ok := loader.cache.(*specTestCache)
+		if !ok || cache.params["size"] != "100" {
+			t.Fatalf("expected the registered cache, got %#v", loader.cache)
+		}
+		if loader.Name() != "users" || loader.batchCap != 10 || !loader.lenient {
+			t.Errorf("expected the specification to be applied, got %q, %d and %v", loader.Name(), loader.batchCap, loader.lenient)
+		}
+		if v, err := loader.Load(context.Background(), "1")(); v != "1" || err != nil {
+			t.Errorf("expected 1, got %q, %v", v, err)
+		}
+	})
+
+	t.Run("reports unknown and invalid components", func(t *testing.T) {
+		for _, test := range []struct {
+			spec Spec
+			err  string
+		}{
+			{Spec{Config: DefaultConfig(), Cache: ComponentSpec{Name: "redis"}}, `unknown cache "redis"`},
+			{Spec{Config: DefaultConfig(), Cache: ComponentSpec{Name: "test"}}, "the size is missing"},
+			{Spec{Config: DefaultConfig(), Tracer: ComponentSpec{Name: "otel"}}, `unknown tracer "otel"`},
+			{Spec{Config: DefaultConfig(), Options: []ComponentSpec{{Name: "metrics"}}}, `unknown option "metrics"`},
+		} {
+			if _, err := NewFromSpec(batchIdentity[string], test.spec); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected %q to be reported, got %v", test.err, err)
+			}
+		}
+
+		// the components are registered for the types of the loader
+		spec := Spec{Config: DefaultConfig(), Cache: ComponentSpec{Name: "test"}}
+		if _, err := NewFromSpec(batchIdentity[int], spec); err == nil || !strings.Contains(err.Error(), "unknown cache") {
+			t.Errorf("expected the cache of other types to be unknown, got %v", err)
+		}
+	})
+
+	t.Run("provides the built-in components", func(t *testing.T) {
+		spec := Spec{Config: DefaultConfig(), Cache: ComponentSpec{Name: "none"}, Tracer: ComponentSpec{Name: "noop"}}
+		loader, err := NewFromSpec(batchIdentity[int], spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := loader.cache.(*NoCache[int, int]); !ok {
+			t.Errorf("expected NoCache, got %T", loader.cache)
+		}
+	})
+
+	t.Run("panics on duplicate registrations", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		RegisterOption("lenient", func(map[string]string) (Option[string, string], error) {
+			return nil, nil
+		})
+	})
+}
@@ -0,0 +1,47 @@
+package dataloader
+
+// OkResult returns the result of a loaded value.
+func OkResult[V any](v V) *Result[V] {
+	return &Result[V]{Data: v}
+}
+
+// ErrResult returns the result of a key which failed to load.
+func ErrResult[V any](err error) *Result[V] {
+	return &Result[V]{Error: err}
+}
+
+// ResultsFromMap returns the results of the keys, in order, from the values
+// loaded by key (i.e. the rows of a query indexed by ID). The result of a key
+// missing from m is returned by notFound; if notFound is nil, it is NotFound.
+func ResultsFromMap[K comparable, V any](keys []K, m map[K]V, notFound func(K) *Result[V]) []*Result[V] {
+	results := make([]*Result[V], len(keys))
+	for i, key := range keys {
+		switch v, ok := m[key]; {
+		case ok:
+			results[i] = &Result[V]{Data: v}
+		case notFound != nil:
+			results[i] = notFound(key)
+		default:
+			results[i] = NotFound[V]()
+		}
+	}
+	return results
+}
+
+// MapResults transforms the values of the results by fn, i.e. to adapt the
+// batch function of a loader to the values of another. The errors are kept
+// as is, and fn is only called for the results without an error. A nil
+// result stays nil.
+func MapResults[V, W any](results []*Result[V], fn func(V) W) []*Result[W] {
+	mapped := make([]*Result[W], len(results))
+	for i, r := range results {
+		switch {
+		case r == nil:
+		case r.Error != nil:
+			mapped[i] = &Result[W]{Error: r.Error}
+		default:
+			mapped[i] = &Result[W]{Data: fn(r.Data)}
+		}
+	}
+	return mapped
+}
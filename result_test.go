@@ -0,0 +1,32 @@
+package dataloader
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestResults(t *testing.T) {
+	t.Run("constructs the results from a map", func(t *testing.T) {
+		m := map[int]string{1: "one", 3: "three"}
+		results := ResultsFromMap([]int{1, 2, 3}, m, nil)
+		if results[0].Data != "one" || !results[1].NotFound() || results[2].Data != "three" {
+			t.Errorf("expected one, not found and three, got %v, %v and %v", results[0], results[1], results[2])
+		}
+
+		results = ResultsFromMap([]int{2}, m, func(key int) *Result[string] {
+			return OkResult("default " + strconv.Itoa(key))
+		})
+		if results[0].Data != "default 2" || results[0].Error != nil {
+			t.Errorf("expected the result of notFound, got %v", results[0])
+		}
+	})
+
+	t.Run("maps the values of the results", func(t *testing.T) {
+		errBackend := errors.New("backend")
+		results := MapResults([]*Result[int]{OkResult(4), ErrResult[int](errBackend), nil}, strconv.Itoa)
+		if results[0].Data != "4" || !errors.Is(results[1].Error, errBackend) || results[2] != nil {
+			t.Errorf("expected 4, the error and nil, got %v, %v and %v", results[0], results[1], results[2])
+		}
+	})
+}
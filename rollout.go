@@ -0,0 +1,152 @@
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RolloutSource is one of the two batch functions of a RolloutLoader.
+type RolloutSource int
+
+const (
+	// SourceLegacy is the batch function the keys are migrated from.
+	SourceLegacy RolloutSource = iota
+	// SourceNext is the batch function the keys are migrated to.
+	SourceNext
+)
+
+// RolloutStats are the metrics of a source of a RolloutLoader.
+type RolloutStats struct {
+	// Calls is the number of calls of the batch function.
+	Calls int64
+	// Keys is the number of keys loaded.
+	Keys int64
+	// Errors is the number of keys which resolved with an error.
+	Errors int64
+	// Latency is the total duration of the calls of the batch function.
+	Latency time.Duration
+}
+
+// rolloutCounters are the counters of RolloutStats.
+type rolloutCounters struct {
+	calls, keys, errors, latency atomic.Int64
+}
+
+// RolloutLoader is a Loader which routes a percentage of the keys to a new
+// batch function, and the rest of them to the legacy one, for the gradual
+// cutover to a new datastore. A key is routed by the hash of its identity, so
+// it keeps loading from the same source as long as the percentage is
+// unchanged, and raising the percentage only moves keys to the new source.
+//
+// The keys of a batch routed to each source are loaded by a call of its batch
+// function, and both calls run concurrently.
+type RolloutLoader[K comparable, V any] struct {
+	*Loader[K, V]
+
+	legacy, next BatchFunc[K, V]
+	identity     KeyIdentity[K]
+	// the percentage of the keys routed to next, in basis points
+	basisPoints atomic.Int64
+	stats       [2]rolloutCounters
+}
+
+// NewRolloutLoader constructs a new RolloutLoader routing percent (in
+// [0, 100]) of the keys to next, and the rest of them to legacy, with given
+// options. If identity is nil, the identity of a key is its default format
+// (see fmt.Sprint).
+func NewRolloutLoader[K comparable, V any](legacy, next BatchFunc[K, V], percent float64, identity KeyIdentity[K], opts ...Option[K, V]) *RolloutLoader[K, V] {
+	if identity == nil {
+		identity = keyString[K]
+	}
+	r := &RolloutLoader[K, V]{
+		legacy:   legacy,
+		next:     next,
+		identity: identity,
+	}
+	r.SetPercent(percent)
+	r.Loader = NewBatchedLoader(r.batch, opts...)
+	return r
+}
+
+// SetPercent sets the percentage (in [0, 100]) of the keys routed to the new
+// batch function, for the batches dispatched from now on. The keys already
+// cached are not loaded again. It panics if percent is out of range.
+func (r *RolloutLoader[K, V]) SetPercent(percent float64) {
+	if percent < 0 || percent > 100 {
+		panic(fmt.Sprintf("dataloader: the rollout percentage must be in [0, 100]: %v", percent))
+	}
+	r.basisPoints.Store(int64(percent * 100))
+}
+
+// Percent returns the percentage of the keys routed to the new batch function.
+func (r *RolloutLoader[K, V]) Percent() float64 {
+	return float64(r.basisPoints.Load()) / 100
+}
+
+// Source returns the source the key is routed to.
+func (r *RolloutLoader[K, V]) Source(key K) RolloutSource {
+	if int64(hashString(r.identity(key))%10000) < r.basisPoints.Load() {
+		return SourceNext
+	}
+	return SourceLegacy
+}
+
+// Stats returns the metrics of the source.
+func (r *RolloutLoader[K, V]) Stats(source RolloutSource) RolloutStats {
+	c := &r.stats[source]
+	return RolloutStats{
+		Calls:   c.calls.Load(),
+		Keys:    c.keys.Load(),
+		Errors:  c.errors.Load(),
+		Latency: time.Duration(c.latency.Load()),
+	}
+}
+
+// batch splits the keys by source, and loads them from both at once.
+func (r *RolloutLoader[K, V]) batch(ctx context.Context, keys []K) []*Result[V] {
+	var parts [2]struct {
+		keys    []K
+		indexes []int
+	}
+	routed := r.enabled(ctx, FlagRollout)
+	for i, key := range keys {
+		source := SourceLegacy
+		if routed {
+			source = r.Source(key)
+		}
+		p := &parts[source]
+		p.keys = append(p.keys, key)
+		p.indexes = append(p.indexes, i)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*Result[V], len(keys))
+	for source, batchFn := range [2]BatchFunc[K, V]{r.legacy, r.next} {
+		p := parts[source]
+		if len(p.keys) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started := time.Now()
+			items := r.call(ctx, batchFn, p.keys)
+
+			c := &r.stats[source]
+			c.calls.Add(1)
+			c.keys.Add(int64(len(p.keys)))
+			c.latency.Add(int64(time.Since(started)))
+			for j, i := range p.indexes {
+				if items[j] == nil || items[j].Error != nil {
+					c.errors.Add(1)
+				}
+				results[i] = items[j]
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
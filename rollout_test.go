@@ -0,0 +1,130 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// SourceLoader returns a rollout loader of the key with the source appended.
+func SourceLoader(percent float64) *RolloutLoader[string, string] {
+	source := func(name string) BatchFunc[string, string] {
+		return func(_ context.Context, keys []string) []*Result[string] {
+			results := make([]*Result[string], len(keys))
+			for i, key := range keys {
+				if key == "error" {
+					results[i] = &Result[string]{Error: errors.New("boom")}
+					continue
+				}
+				results[i] = &Result[string]{Data: key + "@" + name}
+			}
+			return results
+		}
+	}
+	return NewRolloutLoader(source("legacy"), source("next"), percent, nil)
+}
+
+func TestRolloutLoader(t *testing.T) {
+	t.Run("routes keys to their source", func(t *testing.T) {
+		t.Parallel()
+		loader := SourceLoader(50)
+		ctx := context.Background()
+
+		keys := make([]string, 100)
+		for i := range keys {
+			keys[i] = strconv.Itoa(i)
+		}
+		values, errs := loader.LoadMany(ctx, keys)()
+		if errs != nil {
+			t.Fatal(errs)
+		}
+		var next int64
+		for i, key := range keys {
+			name := "legacy"
+			if loader.Source(key) == SourceNext {
+				name = "next"
+				next++
+			}
+			if want := key + "@" + name; values[i] != want {
+				t.Errorf("expected %s, got %s", want, values[i])
+			}
+		}
+
+		legacy, stats := loader.Stats(SourceLegacy), loader.Stats(SourceNext)
+		if legacy.Calls != 1 || stats.Calls != 1 {
+			t.Errorf("expected a call of each source, got %d and %d", legacy.Calls, stats.Calls)
+		}
+		if stats.Keys != next || legacy.Keys != 100-next {
+			t.Errorf("expected %d and %d keys, got %d and %d", 100-next, next, legacy.Keys, stats.Keys)
+		}
+	})
+
+	t.Run("routes the percentage of the keys to the new source", func(t *testing.T) {
+		t.Parallel()
+		loader := SourceLoader(10)
+
+		count := func() (next int) {
+			for i := 0; i < 10000; i++ {
+				if loader.Source(strconv.Itoa(i)) == SourceNext {
+					next++
+				}
+			}
+			return next
+		}
+		if next := count(); math.Abs(float64(next)-1000) > 150 {
+			t.Errorf("expected about 1000 keys routed to the new source, got %d", next)
+		}
+
+		// raising the percentage only moves keys to the new source
+		var moved []string
+		for i := 0; i < 10000; i++ {
+			if key := strconv.Itoa(i); loader.Source(key) == SourceNext {
+				moved = append(moved, key)
+			}
+		}
+		loader.SetPercent(30)
+		for _, key := range moved {
+			if loader.Source(key) != SourceNext {
+				t.Fatalf("expected %s to stay on the new source", key)
+			}
+		}
+		if next := count(); math.Abs(float64(next)-3000) > 250 {
+			t.Errorf("expected about 3000 keys routed to the new source, got %d", next)
+		}
+	})
+
+	t.Run("counts the errors of each source", func(t *testing.T) {
+		t.Parallel()
+		loader := SourceLoader(0)
+		if _, err := loader.Load(context.Background(), "error")(); err == nil {
+			t.Fatal("expected an error")
+		}
+		if stats := loader.Stats(SourceLegacy); stats.Errors != 1 {
+			t.Errorf("expected an error, got %d", stats.Errors)
+		}
+		if stats := loader.Stats(SourceNext); stats != (RolloutStats{}) {
+			t.Errorf("expected no load from the new source, got %+v", stats)
+		}
+	})
+}
+
+func TestRolloutLoaderFlagger(t *testing.T) {
+	type offKey struct{}
+	loader := SourceLoader(100)
+	WithFlagger[string, string](FlaggerFunc(func(ctx context.Context, _ string, flag Flag, def bool) bool {
+		if flag == FlagRollout && ctx.Value(offKey{}) != nil {
+			return false
+		}
+		return def
+	}))(loader.Loader)
+
+	ctx := context.Background()
+	if v, err := loader.Load(ctx, "1")(); v != "1@next" || err != nil {
+		t.Errorf("expected 1@next, got %q, %v", v, err)
+	}
+	if v, err := loader.Load(context.WithValue(ctx, offKey{}, true), "2")(); v != "2@legacy" || err != nil {
+		t.Errorf("expected the routing to be off, got %q, %v", v, err)
+	}
+}
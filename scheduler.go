@@ -0,0 +1,87 @@
+package dataloader
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Scheduler decides when a batch window closes. By default the Loader closes
+// its batch windows after a fixed wait (see WithWait); a Scheduler can be set
+// with WithScheduler to replace that behavior.
+type Scheduler interface {
+	// Schedule is called every time a new batch window opens, with the context
+	// of the first Load of the window. The Scheduler must call dispatch once
+	// it wants the window to close, which it may do from within Schedule.
+	// Schedule must not block.
+	//
+	// The window may already have been closed (i.e. because the batch capacity
+	// was reached) by the time dispatch is called, in which case dispatch
+	// is a noop.
+	Schedule(ctx context.Context, dispatch func())
+}
+
+// YieldScheduler closes a batch window after the goroutine which opened it
+// has yielded the processor a number of times, giving every other runnable
+// goroutine the opportunity to queue its keys first. It is the closest
+// equivalent to the microtask scheduling of the JavaScript DataLoader and
+// makes batching work without any wall-clock wait.
+type YieldScheduler struct {
+	yields int
+}
+
+// NewYieldScheduler constructs a YieldScheduler which yields the given number
+// of times before dispatching a window. Values lower than 1 default to 1.
+func NewYieldScheduler(yields int) *YieldScheduler {
+	if yields < 1 {
+		yields = 1
+	}
+	return &YieldScheduler{yields: yields}
+}
+
+// Schedule implements Scheduler.
+func (s *YieldScheduler) Schedule(_ context.Context, dispatch func()) {
+	go func() {
+		for i := 0; i < s.yields; i++ {
+			runtime.Gosched()
+		}
+		dispatch()
+	}()
+}
+
+// TickScheduler closes batch windows at explicit yield points: every window
+// opened since the previous call to Tick is dispatched when Tick is called.
+// It is meant to be driven by an executor which knows when a wave of
+// resolvers has finished queueing keys (i.e. a GraphQL executor between two
+// levels of a query). A single TickScheduler may be shared by many loaders.
+//
+// Windows are never closed when Tick isn't called, so every Load must be
+// followed by a Tick before its thunk is resolved.
+type TickScheduler struct {
+	mu      sync.Mutex
+	pending []func()
+}
+
+// NewTickScheduler constructs a new TickScheduler.
+func NewTickScheduler() *TickScheduler {
+	return &TickScheduler{}
+}
+
+// Schedule implements Scheduler.
+func (s *TickScheduler) Schedule(_ context.Context, dispatch func()) {
+	s.mu.Lock()
+	s.pending = append(s.pending, dispatch)
+	s.mu.Unlock()
+}
+
+// Tick dispatches every window opened since the previous call to Tick.
+func (s *TickScheduler) Tick() {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	for _, dispatch := range pending {
+		dispatch()
+	}
+}
@@ -0,0 +1,127 @@
+package dataloader
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	t.Run("tick scheduler dispatches on Tick", func(t *testing.T) {
+		t.Parallel()
+		scheduler := NewTickScheduler()
+		loader, loadCalls := scheduledLoader(scheduler)
+		ctx := context.Background()
+
+		future1 := loader.Load(ctx, "1")
+		future2 := loader.Load(ctx, "2")
+
+		done := make(chan struct{})
+		go func() {
+			future1()
+			future2()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("batch was dispatched before Tick")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		scheduler.Tick()
+		<-done
+
+		expected := [][]string{{"1", "2"}}
+		if calls := loadCalls(); !reflect.DeepEqual(calls, expected) {
+			t.Errorf("did not batch queries. Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("tick scheduler is shared between loaders", func(t *testing.T) {
+		t.Parallel()
+		scheduler := NewTickScheduler()
+		loader1, _ := scheduledLoader(scheduler)
+		loader2, _ := scheduledLoader(scheduler)
+		ctx := context.Background()
+
+		future1 := loader1.Load(ctx, "1")
+		future2 := loader2.Load(ctx, "2")
+		scheduler.Tick()
+
+		if v, err := future1(); err != nil || v != "1" {
+			t.Errorf("unexpected result %q, %v", v, err)
+		}
+		if v, err := future2(); err != nil || v != "2" {
+			t.Errorf("unexpected result %q, %v", v, err)
+		}
+	})
+
+	t.Run("yield scheduler dispatches without waiting", func(t *testing.T) {
+		t.Parallel()
+		loader, loadCalls := scheduledLoader(NewYieldScheduler(1))
+		ctx := context.Background()
+
+		future1 := loader.Load(ctx, "1")
+		future2 := loader.Load(ctx, "2")
+		if _, err := future1(); err != nil {
+			t.Error(err.Error())
+		}
+		if _, err := future2(); err != nil {
+			t.Error(err.Error())
+		}
+
+		var keys int
+		for _, call := range loadCalls() {
+			keys += len(call)
+		}
+		if keys != 2 {
+			t.Errorf("expected 2 keys to be loaded, got %d", keys)
+		}
+	})
+
+	t.Run("scheduler may dispatch from within Schedule", func(t *testing.T) {
+		t.Parallel()
+		loader, _ := scheduledLoader(syncScheduler{})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if v, err := loader.Load(context.Background(), "1")(); v != "1" || err != nil {
+				t.Errorf("expected 1, got %q, %v", v, err)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected the window to be dispatched")
+		}
+	})
+}
+
+// syncScheduler dispatches every window as soon as it opens.
+type syncScheduler struct{}
+
+func (syncScheduler) Schedule(_ context.Context, dispatch func()) { dispatch() }
+
+func scheduledLoader(s Scheduler) (*Loader[string, string], func() [][]string) {
+	var mu sync.Mutex
+	var loadCalls [][]string
+	loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+		var results []*Result[string]
+		mu.Lock()
+		loadCalls = append(loadCalls, keys)
+		mu.Unlock()
+		for _, key := range keys {
+			results = append(results, &Result[string]{key, nil})
+		}
+		return results
+	}, WithScheduler[string, string](s))
+	return loader, func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return loadCalls
+	}
+}
@@ -0,0 +1,59 @@
+package dataloader
+
+import (
+	"context"
+	"math/rand"
+)
+
+// shadowBatch is the configuration of WithShadowBatch.
+type shadowBatch[K comparable, V any] struct {
+	batchFn BatchFunc[K, V]
+	compare func(key K, primary, shadow *Result[V])
+	rate    float64
+}
+
+// WithShadowBatch sets a secondary batch function, which is called in the
+// background with the keys of a sample of the batches (every batch unless
+// WithShadowSampleRate is set). Once it returns, compare is called for every
+// key with the results of the primary and of the shadow batch functions, to
+// report their divergences. The loads are always resolved with the results of
+// the primary batch function, so the shadow one can validate the migration to a
+// new datastore without affecting the callers.
+//
+// The shadow batch function is called with a context which is not canceled
+// along with the batch. Its panics are recovered, and if it doesn't return a
+// result per key, an error is compared for every key.
+func WithShadowBatch[K comparable, V any](batchFn BatchFunc[K, V], compare func(key K, primary, shadow *Result[V])) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		rate := 1.0
+		if l.shadow != nil {
+			rate = l.shadow.rate
+		}
+		l.shadow = &shadowBatch[K, V]{batchFn: batchFn, compare: compare, rate: rate}
+	}
+}
+
+// WithShadowSampleRate sets the fraction of the batches, in [0, 1], also loaded
+// by the batch function set with WithShadowBatch. Default is 1 (every batch).
+func WithShadowSampleRate[K comparable, V any](rate float64) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		if l.shadow == nil {
+			l.shadow = &shadowBatch[K, V]{}
+		}
+		l.shadow.rate = rate
+	}
+}
+
+// sampled reports whether a batch is loaded by the shadow batch function.
+func (s *shadowBatch[K, V]) sampled() bool {
+	return s.batchFn != nil && s.rate > 0 && (s.rate >= 1 || rand.Float64() < s.rate)
+}
+
+// shadowed loads the keys of a batch with the shadow batch function, and
+// compares its results with the results of the primary one.
+func (l *Loader[K, V]) shadowed(ctx context.Context, keys []K, primary []*Result[V]) {
+	results := l.call(context.WithoutCancel(ctx), l.shadow.batchFn, keys)
+	for i, key := range keys {
+		l.shadow.compare(key, primary[i], results[i])
+	}
+}
@@ -0,0 +1,184 @@
+package dataloader
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// shardReplicas is the number of points of each shard on the hash ring. The
+// more points, the more even the keys are spread across the shards.
+const shardReplicas = 128
+
+// ShardedLoader routes keys to one of several loaders by consistent hashing
+// (i.e. when each shard has its own connection pool, or region). The shards
+// are placed on the hash ring by their name, so that adding or removing any
+// shard only moves the keys of about one shard in N.
+//
+// It presents the API of a single loader: LoadMany splits its keys by shard,
+// loads them from every shard at once, and merges their results in the order
+// of the keys.
+type ShardedLoader[K comparable, V any] struct {
+	shards   []Interface[K, V]
+	identity KeyIdentity[K]
+	ring     []shardPoint
+}
+
+// shardPoint is a point of a shard on the hash ring.
+type shardPoint struct {
+	hash  uint64
+	shard int
+}
+
+var _ Interface[string, any] = &ShardedLoader[string, any]{}
+
+// NewShardedLoader constructs a new ShardedLoader routing keys to the shards
+// by the hash of their identity. The shards are keyed by a name which must
+// stay the same across deployments (i.e. the name of their region), rather
+// than by their position, so that the keys of the other shards stay in place
+// when one is removed. If identity is nil, the identity of a key is its
+// default format (see fmt.Sprint). It panics if there are no shards.
+func NewShardedLoader[K comparable, V any](shards map[string]Interface[K, V], identity KeyIdentity[K]) *ShardedLoader[K, V] {
+	if len(shards) == 0 {
+		panic("dataloader: a sharded loader needs at least one shard")
+	}
+	if identity == nil {
+		identity = keyString[K]
+	}
+
+	names := make([]string, 0, len(shards))
+	for name := range shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := &ShardedLoader[K, V]{
+		shards:   make([]Interface[K, V], len(names)),
+		identity: identity,
+		ring:     make([]shardPoint, 0, len(names)*shardReplicas),
+	}
+	for i, name := range names {
+		s.shards[i] = shards[name]
+		for r := 0; r < shardReplicas; r++ {
+			s.ring = append(s.ring, shardPoint{
+				hash:  hashString(name + "#" + strconv.Itoa(r)),
+				shard: i,
+			})
+		}
+	}
+	sort.Slice(s.ring, func(i, j int) bool { return s.ring[i].hash < s.ring[j].hash })
+	return s
+}
+
+// Shard returns the loader the key is routed to.
+func (s *ShardedLoader[K, V]) Shard(key K) Interface[K, V] {
+	return s.shards[s.shardOf(key)]
+}
+
+// Load loads a key from its shard, returning a `Thunk` for the value
+// represented by that key.
+func (s *ShardedLoader[K, V]) Load(ctx context.Context, key K) Thunk[V] {
+	return s.Shard(key).Load(ctx, key)
+}
+
+// LoadMany loads multiple keys from their shards, returning a thunk (type: ThunkMany) that will resolve the keys passed in.
+func (s *ShardedLoader[K, V]) LoadMany(ctx context.Context, keys []K) ThunkMany[V] {
+	type part struct {
+		keys    []K
+		indexes []int
+		thunk   ThunkMany[V]
+	}
+	parts := make(map[int]*part)
+	for i, key := range keys {
+		shard := s.shardOf(key)
+		p, ok := parts[shard]
+		if !ok {
+			p = &part{}
+			parts[shard] = p
+		}
+		p.keys = append(p.keys, key)
+		p.indexes = append(p.indexes, i)
+	}
+	for shard, p := range parts {
+		p.thunk = s.shards[shard].LoadMany(ctx, p.keys)
+	}
+
+	var (
+		once   sync.Once
+		data   []V
+		errors []error
+	)
+	return func() ([]V, []error) {
+		once.Do(func() {
+			data = make([]V, len(keys))
+			errs := make([]error, len(keys))
+			var failed bool
+			for _, p := range parts {
+				values, partErrs := p.thunk()
+				for j, i := range p.indexes {
+					if j < len(values) {
+						data[i] = values[j]
+					}
+					if j < len(partErrs) && partErrs[j] != nil {
+						errs[i] = partErrs[j]
+						failed = true
+					}
+				}
+			}
+			// errors is nil unless there exists a non-nil error, like LoadMany
+			if failed {
+				errors = errs
+			}
+		})
+		return data, errors
+	}
+}
+
+// Clear clears the key from its shard. Returns self for method chaining.
+func (s *ShardedLoader[K, V]) Clear(ctx context.Context, key K) Interface[K, V] {
+	s.Shard(key).Clear(ctx, key)
+	return s
+}
+
+// ClearAll clears the cache of every shard. Returns self for method chaining.
+func (s *ShardedLoader[K, V]) ClearAll() Interface[K, V] {
+	for _, shard := range s.shards {
+		shard.ClearAll()
+	}
+	return s
+}
+
+// Prime adds the provided key and value to the cache of its shard. Returns
+// self for method chaining.
+func (s *ShardedLoader[K, V]) Prime(ctx context.Context, key K, value V) Interface[K, V] {
+	s.Shard(key).Prime(ctx, key, value)
+	return s
+}
+
+// shardOf returns the index of the shard of key: the shard of the first point
+// of the ring following the hash of its identity.
+func (s *ShardedLoader[K, V]) shardOf(key K) int {
+	h := hashString(s.identity(key))
+	i := sort.Search(len(s.ring), func(i int) bool { return s.ring[i].hash >= h })
+	if i == len(s.ring) {
+		i = 0
+	}
+	return s.ring[i].shard
+}
+
+// hashString hashes s with FNV-1a, mixed so that similar strings are spread
+// across the ring.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	// the finalizer of splitmix64
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,182 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+// ShardLoaders returns n loaders named s0 to sn-1, of the key with the name of
+// their shard appended, and the keys batched by each of them.
+func ShardLoaders(n int) (map[string]Interface[string, string], map[string]*[][]string) {
+	shards := make(map[string]Interface[string, string], n)
+	calls := make(map[string]*[][]string, n)
+	for i := 0; i < n; i++ {
+		name := "s" + strconv.Itoa(i)
+		batched := new([][]string)
+		calls[name] = batched
+		shards[name] = NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			*batched = append(*batched, keys)
+			results := make([]*Result[string], len(keys))
+			for j, key := range keys {
+				if key == "error" {
+					results[j] = &Result[string]{Error: errors.New("boom")}
+					continue
+				}
+				results[j] = &Result[string]{Data: key + "@" + name}
+			}
+			return results
+		})
+	}
+	return shards, calls
+}
+
+func TestShardedLoader(t *testing.T) {
+	t.Run("routes keys to the same shard", func(t *testing.T) {
+		t.Parallel()
+		shards, _ := ShardLoaders(4)
+		loader := NewShardedLoader(shards, nil)
+		ctx := context.Background()
+
+		for i := 0; i < 100; i++ {
+			key := strconv.Itoa(i)
+			v, err := loader.Load(ctx, key)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := key + "@" + nameOf(shards, loader.Shard(key)); v != want {
+				t.Errorf("expected %s, got %s", want, v)
+			}
+		}
+	})
+
+	t.Run("spreads keys across shards", func(t *testing.T) {
+		t.Parallel()
+		shards, _ := ShardLoaders(4)
+		loader := NewShardedLoader(shards, nil)
+
+		counts := make(map[string]int)
+		for i := 0; i < 10000; i++ {
+			counts[nameOf(shards, loader.Shard(strconv.Itoa(i)))]++
+		}
+		for shard, count := range counts {
+			if count < 1500 || count > 3500 {
+				t.Errorf("expected about 2500 keys on shard %s, got %d", shard, count)
+			}
+		}
+	})
+
+	t.Run("only moves about one key in N when adding a shard", func(t *testing.T) {
+		t.Parallel()
+		shards, _ := ShardLoaders(5)
+		after := NewShardedLoader(shards, nil)
+		delete(shards, "s4")
+		before := NewShardedLoader(shards, nil)
+
+		var moved int
+		for i := 0; i < 10000; i++ {
+			key := strconv.Itoa(i)
+			if before.Shard(key) != after.Shard(key) {
+				moved++
+				if nameOf(shards, after.Shard(key)) != "" {
+					t.Fatalf("expected %s to move to the new shard", key)
+				}
+			}
+		}
+		if moved < 1000 || moved > 3000 {
+			t.Errorf("expected about 2000 keys to move, got %d", moved)
+		}
+	})
+
+	t.Run("only moves the keys of a removed middle shard", func(t *testing.T) {
+		t.Parallel()
+		shards, _ := ShardLoaders(5)
+		before := NewShardedLoader(shards, nil)
+		removed := shards["s2"]
+		delete(shards, "s2")
+		after := NewShardedLoader(shards, nil)
+
+		var moved int
+		for i := 0; i < 10000; i++ {
+			key := strconv.Itoa(i)
+			if before.Shard(key) != after.Shard(key) {
+				moved++
+				if before.Shard(key) != removed {
+					t.Fatalf("expected %s to stay on its shard", key)
+				}
+			}
+		}
+		if moved < 1000 || moved > 3000 {
+			t.Errorf("expected about 2000 keys to move, got %d", moved)
+		}
+	})
+
+	t.Run("merges LoadMany across shards", func(t *testing.T) {
+		t.Parallel()
+		shards, calls := ShardLoaders(3)
+		loader := NewShardedLoader(shards, nil)
+
+		keys := []string{"1", "2", "3", "4", "5", "6", "error"}
+		values, errs := loader.LoadMany(context.Background(), keys)()
+		for i, key := range keys {
+			if key == "error" {
+				if errs[i] == nil {
+					t.Errorf("expected an error for %s", key)
+				}
+				continue
+			}
+			if errs[i] != nil {
+				t.Errorf("unexpected error for %s: %v", key, errs[i])
+			}
+			if want := key + "@" + nameOf(shards, loader.Shard(key)); values[i] != want {
+				t.Errorf("expected %s, got %s", want, values[i])
+			}
+		}
+
+		var batched int
+		for _, c := range calls {
+			for _, keys := range *c {
+				batched += len(keys)
+			}
+		}
+		if batched != len(keys) {
+			t.Errorf("expected every key to be batched once, got %d", batched)
+		}
+
+		if _, errs := loader.LoadMany(context.Background(), []string{"1", "2"})(); errs != nil {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("clears and primes the shard of a key", func(t *testing.T) {
+		t.Parallel()
+		shards, calls := ShardLoaders(2)
+		loader := NewShardedLoader(shards, nil)
+		ctx := context.Background()
+
+		loader.Prime(ctx, "a", "primed")
+		if v, _ := loader.Load(ctx, "a")(); v != "primed" {
+			t.Errorf("expected the primed value, got %s", v)
+		}
+		loader.Clear(ctx, "a")
+		if v, _ := loader.Load(ctx, "a")(); v == "primed" {
+			t.Error("expected the key to be cleared")
+		}
+		loader.ClearAll()
+		loader.Load(ctx, "a")()
+		if n := len(*calls[nameOf(shards, loader.Shard("a"))]); n != 2 {
+			t.Errorf("expected the key to be loaded twice, got %d", n)
+		}
+	})
+}
+
+// nameOf returns the name of the shard, or "" if it's not one of shards.
+func nameOf(shards map[string]Interface[string, string], shard Interface[string, string]) string {
+	for name, s := range shards {
+		if s == shard {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package dataloader
+
+import "context"
+
+type speculativeKey struct{}
+
+// WithSpeculativePrefetch sets a function predicting the keys likely to be
+// loaded after a key (i.e. adjacent IDs, or the members of a user's team).
+// Loading a key which isn't cached also loads the predicted keys which aren't,
+// in the same batch when its window is still open, so that the later loads of
+// these keys hit the cache. The budget bounds the number of predicted keys
+// added to each batch.
+//
+// The keys are predicted with the context of the load, and the predicted keys
+// don't predict further keys.
+func WithSpeculativePrefetch[K comparable, V any](predict func(ctx context.Context, key K) []K, budget int) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.predict = predict
+		l.speculationBudget = budget
+	}
+}
+
+// speculate loads the keys predicted after key, within the budget of the
+// current batch.
+func (l *Loader[K, V]) speculate(ctx context.Context, key K) {
+	if l.predict == nil || l.speculationBudget <= 0 || ctx.Value(speculativeKey{}) != nil {
+		return
+	}
+	predicted := l.predict(ctx, key)
+	if len(predicted) == 0 {
+		return
+	}
+
+	ctx = context.WithValue(ctx, speculativeKey{}, true)
+	for _, k := range predicted {
+		if k == key || l.cached(ctx, k) {
+			continue
+		}
+		if !l.spend(ctx) {
+			return
+		}
+		l.Load(ctx, k)
+	}
+}
+
+// cached reports whether the key is cached.
+func (l *Loader[K, V]) cached(ctx context.Context, key K) bool {
+	l.cacheLock.Lock()
+	defer l.cacheLock.Unlock()
+	_, ok := l.cache.Get(ctx, key)
+	return ok
+}
+
+// spend spends a predicted key of the budget of the current batch, and
+// reports whether the budget allowed it. Once the batch is dispatched, no
+// more keys are predicted until the next load.
+func (l *Loader[K, V]) spend(ctx context.Context) bool {
+	l.batchLock.Lock()
+	defer l.batchLock.Unlock()
+	b := l.current(batchGroupOf(ctx))
+	if b == nil || b.speculated >= l.speculationBudget {
+		return false
+	}
+	b.speculated++
+	return true
+}
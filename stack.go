@@ -0,0 +1,12 @@
+//go:build !tinygo
+
+package dataloader
+
+import "runtime"
+
+// stack returns the stack trace of the current goroutine.
+func stack() []byte {
+	const size = 64 << 10
+	buf := make([]byte, size)
+	return buf[:runtime.Stack(buf, false)]
+}
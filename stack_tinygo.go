@@ -0,0 +1,8 @@
+//go:build tinygo
+
+package dataloader
+
+// stack returns nil, since TinyGo doesn't capture stack traces.
+func stack() []byte {
+	return nil
+}
@@ -0,0 +1,116 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+)
+
+// ReadPolicy is how a TieredCache reads its tiers.
+type ReadPolicy int
+
+const (
+	// ReadLocalThenRemote reads the local tier, then the remote tier on a
+	// miss. Results read from the remote tier are stored in the local tier.
+	ReadLocalThenRemote ReadPolicy = iota
+	// ReadLocalOnly only reads the local tier, for latency sensitive paths.
+	ReadLocalOnly
+	// ReadRemoteRefreshAsync reads the local tier, like ReadLocalThenRemote,
+	// but also refreshes the results it hits from the remote tier in the
+	// background: a result which was updated or deleted in the remote tier is
+	// updated or deleted in the local tier for the next reads.
+	ReadRemoteRefreshAsync
+)
+
+// TieredCache is a DataCache with a local tier (i.e. in memory) in front of a
+// remote one (i.e. Redis). Writes go to both tiers, and reads follow a
+// ReadPolicy: the policy of the cache, unless the context sets another one
+// (see ContextWithReadPolicy). Since each loader has its own cache, loaders
+// sharing the same tiers can read them with different policies.
+type TieredCache[K comparable, V any] struct {
+	local  DataCache[K, V]
+	remote DataCache[K, V]
+	policy ReadPolicy
+
+	// the keys being refreshed
+	mu         sync.Mutex
+	refreshing map[K]struct{}
+}
+
+// NewTieredCache constructs a new TieredCache reading its tiers with the
+// given policy.
+func NewTieredCache[K comparable, V any](local, remote DataCache[K, V], policy ReadPolicy) *TieredCache[K, V] {
+	return &TieredCache[K, V]{
+		local:      local,
+		remote:     remote,
+		policy:     policy,
+		refreshing: make(map[K]struct{}),
+	}
+}
+
+// Get gets the result of key, following the read policy.
+func (c *TieredCache[K, V]) Get(ctx context.Context, key K) (*Result[V], bool) {
+	policy := c.policy
+	if p, ok := readPolicyOf(ctx); ok {
+		policy = p
+	}
+
+	if result, ok := c.local.Get(ctx, key); ok {
+		if policy == ReadRemoteRefreshAsync {
+			c.refresh(ctx, key)
+		}
+		return result, true
+	}
+	if policy == ReadLocalOnly {
+		return nil, false
+	}
+
+	result, ok := c.remote.Get(ctx, key)
+	if ok {
+		c.local.Set(ctx, key, result)
+	}
+	return result, ok
+}
+
+// Set sets the result of key in both tiers.
+func (c *TieredCache[K, V]) Set(ctx context.Context, key K, result *Result[V]) {
+	c.remote.Set(ctx, key, result)
+	c.local.Set(ctx, key, result)
+}
+
+// Delete deletes the result of key from both tiers.
+func (c *TieredCache[K, V]) Delete(ctx context.Context, key K) bool {
+	remote := c.remote.Delete(ctx, key)
+	return c.local.Delete(ctx, key) || remote
+}
+
+// Clear clears both tiers.
+func (c *TieredCache[K, V]) Clear() {
+	c.remote.Clear()
+	c.local.Clear()
+}
+
+// refresh reads the result of key from the remote tier in the background, and
+// updates the local tier with it. Only one refresh of a key runs at a time.
+func (c *TieredCache[K, V]) refresh(ctx context.Context, key K) {
+	c.mu.Lock()
+	if _, ok := c.refreshing[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = struct{}{}
+	c.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+		if result, ok := c.remote.Get(ctx, key); ok {
+			c.local.Set(ctx, key, result)
+			return
+		}
+		c.local.Delete(ctx, key)
+	}()
+}
@@ -0,0 +1,102 @@
+package dataloader
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+	result := func(v string) *Result[string] { return &Result[string]{Data: v} }
+
+	t.Run("reads the local tier then the remote one", func(t *testing.T) {
+		t.Parallel()
+		local, remote := NewDataCache[string, string](), NewDataCache[string, string]()
+		c := NewTieredCache[string, string](local, remote, ReadLocalThenRemote)
+
+		remote.Set(ctx, "a", result("remote"))
+		if r, ok := c.Get(ctx, "a"); !ok || r.Data != "remote" {
+			t.Fatalf("expected the remote result, got %#v", r)
+		}
+		if r, ok := local.Get(ctx, "a"); !ok || r.Data != "remote" {
+			t.Errorf("expected the remote result to be stored locally, got %#v", r)
+		}
+
+		c.Set(ctx, "b", result("b"))
+		if _, ok := remote.Get(ctx, "b"); !ok {
+			t.Error("expected writes to go to the remote tier")
+		}
+		if !c.Delete(ctx, "b") || c.Delete(ctx, "b") {
+			t.Error("expected the key to be deleted once")
+		}
+	})
+
+	t.Run("reads the local tier only", func(t *testing.T) {
+		t.Parallel()
+		local, remote := NewDataCache[string, string](), NewDataCache[string, string]()
+		c := NewTieredCache[string, string](local, remote, ReadLocalOnly)
+
+		remote.Set(ctx, "a", result("remote"))
+		if _, ok := c.Get(ctx, "a"); ok {
+			t.Error("expected the remote tier to be skipped")
+		}
+		if r, ok := c.Get(ContextWithReadPolicy(ctx, ReadLocalThenRemote), "a"); !ok || r.Data != "remote" {
+			t.Errorf("expected the context to override the policy, got %#v", r)
+		}
+	})
+
+	t.Run("refreshes local hits from the remote tier", func(t *testing.T) {
+		t.Parallel()
+		local, remote := NewDataCache[string, string](), NewDataCache[string, string]()
+		c := NewTieredCache[string, string](local, remote, ReadRemoteRefreshAsync)
+
+		c.Set(ctx, "a", result("old"))
+		c.Set(ctx, "b", result("b"))
+		remote.Set(ctx, "a", result("new"))
+		remote.Delete(ctx, "b")
+
+		if r, _ := c.Get(ctx, "a"); r.Data != "old" {
+			t.Errorf("expected the local result to be served, got %#v", r)
+		}
+		c.Get(ctx, "b")
+		eventually(t, func() bool {
+			r, _ := local.Get(ctx, "a")
+			_, ok := local.Get(ctx, "b")
+			return r.Data == "new" && !ok
+		})
+	})
+
+	t.Run("with a loader", func(t *testing.T) {
+		t.Parallel()
+		local, remote := NewDataCache[string, string](), NewDataCache[string, string]()
+		loadCalls := 0
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			loadCalls++
+			results := make([]*Result[string], len(keys))
+			for i, key := range keys {
+				results[i] = result(key)
+			}
+			return results
+		}, WithValueCache[string, string](NewTieredCache[string, string](local, remote, ReadLocalThenRemote)))
+
+		remote.Set(ctx, "a", result("remote"))
+		if v, _ := loader.Load(ctx, "a")(); v != "remote" || loadCalls != 0 {
+			t.Errorf("expected the remote result, got %q after %d calls", v, loadCalls)
+		}
+		if v, _ := loader.Load(ContextWithReadPolicy(ctx, ReadLocalOnly), "b")(); v != "b" || loadCalls != 1 {
+			t.Errorf("expected b to be loaded, got %q after %d calls", v, loadCalls)
+		}
+	})
+}
+
+// eventually fails the test unless cond becomes true within a second.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
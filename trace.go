@@ -4,8 +4,17 @@ import (
 	"context"
 )
 
-type TraceLoadFinishFunc[V any] func(Thunk[V])
-type TraceLoadManyFinishFunc[V any] func(ThunkMany[V])
+// TraceLoadFinishFunc finishes the trace of a call to Load. It is called
+// with the result of the key once it has resolved, from a goroutine of its
+// own.
+type TraceLoadFinishFunc[V any] func(*Result[V])
+
+// TraceLoadManyFinishFunc finishes the trace of a call to LoadMany. It is
+// called with the results of the keys once they have all resolved.
+type TraceLoadManyFinishFunc[V any] func(*ResultMany[V])
+
+// TraceBatchFinishFunc finishes the trace of a batch. It is called with the
+// results of the batch function once it has returned (none if it panicked).
 type TraceBatchFinishFunc[V any] func([]*Result[V])
 
 // Tracer is an interface that may be used to implement tracing.
@@ -23,12 +32,12 @@ type NoopTracer[K comparable, V any] struct{}
 
 // TraceLoad is a noop function
 func (NoopTracer[K, V]) TraceLoad(ctx context.Context, key K) (context.Context, TraceLoadFinishFunc[V]) {
-	return ctx, func(Thunk[V]) {}
+	return ctx, func(*Result[V]) {}
 }
 
 // TraceLoadMany is a noop function
 func (NoopTracer[K, V]) TraceLoadMany(ctx context.Context, keys []K) (context.Context, TraceLoadManyFinishFunc[V]) {
-	return ctx, func(ThunkMany[V]) {}
+	return ctx, func(*ResultMany[V]) {}
 }
 
 // TraceBatch is a noop function
@@ -0,0 +1,10 @@
+module github.com/graph-gophers/dataloader/v7/trace/opentracing
+
+go 1.23
+
+require (
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+	github.com/opentracing/opentracing-go v1.2.0
+)
+
+replace github.com/graph-gophers/dataloader/v7 => ../..
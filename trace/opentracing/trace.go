@@ -7,6 +7,7 @@ import (
 	"github.com/graph-gophers/dataloader/v7"
 
 	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 )
 
 // Tracer implements a tracer that can be used with the Open Tracing standard.
@@ -18,7 +19,10 @@ func (Tracer[K, V]) TraceLoad(ctx context.Context, key K) (context.Context, data
 
 	span.SetTag("dataloader.key", fmt.Sprintf("%v", key))
 
-	return spanCtx, func(thunk dataloader.Thunk[V]) {
+	return spanCtx, func(r *dataloader.Result[V]) {
+		if r.Error != nil {
+			ext.LogError(span, r.Error)
+		}
 		span.Finish()
 	}
 }
@@ -29,7 +33,12 @@ func (Tracer[K, V]) TraceLoadMany(ctx context.Context, keys []K) (context.Contex
 
 	span.SetTag("dataloader.keys", fmt.Sprintf("%v", keys))
 
-	return spanCtx, func(thunk dataloader.ThunkMany[V]) {
+	return spanCtx, func(r *dataloader.ResultMany[V]) {
+		for _, err := range r.Error {
+			if err != nil {
+				ext.LogError(span, err)
+			}
+		}
 		span.Finish()
 	}
 }
@@ -0,0 +1,16 @@
+module github.com/graph-gophers/dataloader/v7/trace/otel
+
+go 1.23
+
+require (
+	github.com/graph-gophers/dataloader/v7 v7.1.0
+	go.opentelemetry.io/otel v1.6.3
+	go.opentelemetry.io/otel/trace v1.6.3
+)
+
+require (
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+)
+
+replace github.com/graph-gophers/dataloader/v7 => ../..
@@ -8,6 +8,7 @@ import (
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -33,7 +34,11 @@ func (t Tracer[K, V]) TraceLoad(ctx context.Context, key K) (context.Context, da
 
 	span.SetAttributes(attribute.String("dataloader.key", fmt.Sprintf("%v", key)))
 
-	return spanCtx, func(thunk dataloader.Thunk[V]) {
+	return spanCtx, func(r *dataloader.Result[V]) {
+		if r.Error != nil {
+			span.RecordError(r.Error)
+			span.SetStatus(codes.Error, r.Error.Error())
+		}
 		span.End()
 	}
 }
@@ -44,7 +49,17 @@ func (t Tracer[K, V]) TraceLoadMany(ctx context.Context, keys []K) (context.Cont
 
 	span.SetAttributes(attribute.String("dataloader.keys", fmt.Sprintf("%v", keys)))
 
-	return spanCtx, func(thunk dataloader.ThunkMany[V]) {
+	return spanCtx, func(r *dataloader.ResultMany[V]) {
+		var failed int
+		for _, err := range r.Error {
+			if err != nil {
+				span.RecordError(err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			span.SetStatus(codes.Error, fmt.Sprintf("%d of %d keys failed", failed, len(r.Error)))
+		}
 		span.End()
 	}
 }
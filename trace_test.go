@@ -0,0 +1,107 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingTracer records the results handed to the finish functions.
+type recordingTracer struct {
+	mu      sync.Mutex
+	loads   []*Result[string]
+	many    []*ResultMany[string]
+	batches [][]*Result[string]
+	done    chan struct{}
+}
+
+func (t *recordingTracer) TraceLoad(ctx context.Context, _ string) (context.Context, TraceLoadFinishFunc[string]) {
+	return ctx, func(r *Result[string]) {
+		t.mu.Lock()
+		t.loads = append(t.loads, r)
+		t.mu.Unlock()
+		t.done <- struct{}{}
+	}
+}
+
+func (t *recordingTracer) TraceLoadMany(ctx context.Context, _ []string) (context.Context, TraceLoadManyFinishFunc[string]) {
+	return ctx, func(r *ResultMany[string]) {
+		t.mu.Lock()
+		t.many = append(t.many, r)
+		t.mu.Unlock()
+	}
+}
+
+func (t *recordingTracer) TraceBatch(ctx context.Context, _ []string) (context.Context, TraceBatchFinishFunc[string]) {
+	return ctx, func(items []*Result[string]) {
+		t.mu.Lock()
+		t.batches = append(t.batches, items)
+		t.mu.Unlock()
+	}
+}
+
+func TestTracer(t *testing.T) {
+	t.Run("the load is finished with its result", func(t *testing.T) {
+		tracer := &recordingTracer{done: make(chan struct{}, 2)}
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			results := make([]*Result[string], len(keys))
+			for i, key := range keys {
+				if key == "bad" {
+					results[i] = &Result[string]{Error: errors.New("bad key")}
+				} else {
+					results[i] = &Result[string]{Data: key}
+				}
+			}
+			return results
+		}, WithTracer[string, string](tracer), WithManualDispatch[string, string]())
+		ctx := context.Background()
+
+		// the thunks are never called: the tracer doesn't depend on the caller
+		loader.Load(ctx, "1")
+		loader.Load(ctx, "bad")
+		loader.Dispatch()
+		for i := 0; i < 2; i++ {
+			select {
+			case <-tracer.done:
+			case <-time.After(time.Second):
+				t.Fatal("expected the loads to be finished")
+			}
+		}
+
+		tracer.mu.Lock()
+		defer tracer.mu.Unlock()
+		var failed int
+		for _, r := range tracer.loads {
+			if r.Error != nil {
+				failed++
+			} else if r.Data != "1" {
+				t.Errorf("expected 1, got %q", r.Data)
+			}
+		}
+		if failed != 1 {
+			t.Errorf("expected one failed load, got %d", failed)
+		}
+		if len(tracer.batches) != 1 || len(tracer.batches[0]) != 2 {
+			t.Errorf("expected the batch to be finished with its 2 results, got %v", tracer.batches)
+		}
+	})
+
+	t.Run("the load of many keys is finished with their results", func(t *testing.T) {
+		tracer := &recordingTracer{done: make(chan struct{}, 2)}
+		identityLoader, _ := IDLoader[string](0)
+		WithTracer[string, string](tracer)(identityLoader)
+
+		data, errs := identityLoader.LoadMany(context.Background(), []string{"1", "2"})()
+		if len(errs) != 0 || len(data) != 2 {
+			t.Fatalf("expected 2 values, got %v, %v", data, errs)
+		}
+
+		tracer.mu.Lock()
+		defer tracer.mu.Unlock()
+		if len(tracer.many) != 1 || len(tracer.many[0].Data) != 2 || tracer.many[0].Data[1] != "2" {
+			t.Errorf("expected the results of the keys, got %v", tracer.many)
+		}
+	})
+}
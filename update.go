@@ -0,0 +1,85 @@
+package dataloader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RuntimeOption is an option which can be updated on a running Loader, see
+// UpdateOptions.
+type RuntimeOption func(*runtimeOptions)
+
+// runtimeOptions are the options updated by a call of UpdateOptions; a nil
+// field is left unchanged.
+type runtimeOptions struct {
+	wait        *time.Duration
+	batchCap    *int
+	concurrency *int
+}
+
+// UpdateWait updates the amount of time to wait before triggering a batch,
+// see WithWait.
+func UpdateWait(d time.Duration) RuntimeOption {
+	return func(o *runtimeOptions) {
+		o.wait = &d
+	}
+}
+
+// UpdateBatchCapacity updates the batch capacity, see WithBatchCapacity.
+func UpdateBatchCapacity(c int) RuntimeOption {
+	return func(o *runtimeOptions) {
+		o.batchCap = &c
+	}
+}
+
+// UpdateConcurrencyLimit updates the concurrency limit, see
+// WithConcurrencyLimit.
+func UpdateConcurrencyLimit(n int) RuntimeOption {
+	return func(o *runtimeOptions) {
+		o.concurrency = &n
+	}
+}
+
+// UpdateOptions updates the given options of a running loader at once (i.e.
+// from a feature flag system), without recreating it and losing its cache.
+// The updates apply to the batches opened from now on: the current batch
+// keeps its wait and capacity, and the running batches release their
+// concurrency slots to the limit they started with.
+//
+// If an option is invalid, none is applied and an error is returned. Note
+// that WithAutoTune keeps adjusting the wait and the batch capacity from the
+// updated values.
+func (l *Loader[K, V]) UpdateOptions(opts ...RuntimeOption) error {
+	var o runtimeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var errs []error
+	if o.wait != nil && *o.wait < 0 {
+		errs = append(errs, fmt.Errorf("the wait duration is negative: %v", *o.wait))
+	}
+	if o.batchCap != nil && *o.batchCap < 0 {
+		errs = append(errs, fmt.Errorf("the batch capacity is negative: %d", *o.batchCap))
+	}
+	if o.concurrency != nil && *o.concurrency < 0 {
+		errs = append(errs, fmt.Errorf("the concurrency limit is negative: %d", *o.concurrency))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("dataloader: invalid options: %w", errors.Join(errs...))
+	}
+
+	l.batchLock.Lock()
+	defer l.batchLock.Unlock()
+	if o.wait != nil {
+		l.wait = *o.wait
+	}
+	if o.batchCap != nil {
+		l.batchCap = *o.batchCap
+	}
+	if o.concurrency != nil {
+		WithConcurrencyLimit[K, V](*o.concurrency)(l)
+	}
+	return nil
+}
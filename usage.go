@@ -0,0 +1,148 @@
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type usageKey struct{}
+
+// LoaderUsage is the usage of a loader during a request, see Usage.
+type LoaderUsage struct {
+	// Name is the name of the loader (see WithName), or its type if it has
+	// none.
+	Name string `json:"name"`
+	// Loads is the number of keys loaded.
+	Loads int64 `json:"loads"`
+	// UniqueKeys is the number of distinct keys loaded.
+	UniqueKeys int64 `json:"uniqueKeys"`
+	// CacheHits is the number of loads served by the cache.
+	CacheHits int64 `json:"cacheHits"`
+	// Batches is the number of batches which loaded keys of the request.
+	Batches int64 `json:"batches"`
+	// BackendTime is the total duration of these batches.
+	BackendTime time.Duration `json:"backendTime"`
+}
+
+// HitRatio returns the ratio of the loads served by the cache, in [0, 1].
+func (u LoaderUsage) HitRatio() float64 {
+	if u.Loads == 0 {
+		return 0
+	}
+	return float64(u.CacheHits) / float64(u.Loads)
+}
+
+// Usage collects the usage of the loaders during a request (i.e. a GraphQL
+// operation), for the platform teams to find its inefficient resolvers. It is
+// attached to the context of the request with ContextWithUsage, and every
+// loader records the loads made with that context, without being
+// instrumented. The loaders without a name of the same types are reported
+// together.
+type Usage struct {
+	mu      sync.Mutex
+	loaders map[string]*loaderUsage
+}
+
+// loaderUsage is the usage of a loader, along with its distinct keys.
+type loaderUsage struct {
+	LoaderUsage
+	keys map[any]struct{}
+}
+
+// ContextWithUsage returns a copy of ctx collecting the usage of the loaders
+// the loads made with it, along with the Usage reporting it.
+func ContextWithUsage(ctx context.Context) (context.Context, *Usage) {
+	u := &Usage{loaders: make(map[string]*loaderUsage)}
+	return context.WithValue(ctx, usageKey{}, u), u
+}
+
+// UsageFromContext returns the Usage collected with ctx, if any.
+func UsageFromContext(ctx context.Context) (*Usage, bool) {
+	u, ok := ctx.Value(usageKey{}).(*Usage)
+	return u, ok
+}
+
+// Report returns the usage of the loaders, ordered by name.
+func (u *Usage) Report() []LoaderUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	report := make([]LoaderUsage, 0, len(u.loaders))
+	for _, l := range u.loaders {
+		report = append(report, l.LoaderUsage)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Name < report[j].Name })
+	return report
+}
+
+// Extensions returns the report under the "dataloader" key, in a format
+// suitable for the extensions of a GraphQL response.
+func (u *Usage) Extensions() map[string]any {
+	return map[string]any{"dataloader": u.Report()}
+}
+
+// loader returns the usage of the named loader.
+// It must be called with the mu held.
+func (u *Usage) loader(name string) *loaderUsage {
+	l, ok := u.loaders[name]
+	if !ok {
+		l = &loaderUsage{LoaderUsage: LoaderUsage{Name: name}, keys: make(map[any]struct{})}
+		u.loaders[name] = l
+	}
+	return l
+}
+
+// load records the load of a key.
+func (u *Usage) load(name string, key any, hit bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	l := u.loader(name)
+	l.Loads++
+	if hit {
+		l.CacheHits++
+	}
+	if _, ok := l.keys[key]; !ok {
+		l.keys[key] = struct{}{}
+		l.UniqueKeys++
+	}
+}
+
+// batch records a batch which loaded keys of the request.
+func (u *Usage) batch(name string, d time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	l := u.loader(name)
+	l.Batches++
+	l.BackendTime += d
+}
+
+// usageName returns the name of the loader in the usage reports.
+func (l *Loader[K, V]) usageName() string {
+	if l.name != "" {
+		return l.name
+	}
+	return fmt.Sprintf("%T", l)
+}
+
+// recordUsage records a batch in the usage of the requests it loaded keys of.
+func (l *Loader[K, V]) recordUsage(reqs []*batchRequest[K, V], waiters map[K][]*batchRequest[K, V], d time.Duration) {
+	var recorded map[*Usage]bool
+	record := func(req *batchRequest[K, V]) {
+		if req.usage == nil || recorded[req.usage] {
+			return
+		}
+		if recorded == nil {
+			recorded = make(map[*Usage]bool)
+		}
+		recorded[req.usage] = true
+		req.usage.batch(l.usageName(), d)
+	}
+	for _, req := range reqs {
+		record(req)
+		for _, waiter := range waiters[req.key] {
+			record(waiter)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package dataloader
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NewBatchedLoaderE constructs a new Loader with given options, like
+// NewBatchedLoader, but returns an error describing every problem of the
+// configuration instead of a loader which would misbehave once used.
+func NewBatchedLoaderE[K comparable, V any](batchFn BatchFunc[K, V], opts ...Option[K, V]) (*Loader[K, V], error) {
+	l := NewBatchedLoader(batchFn, opts...)
+	if err := l.validate(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// validate checks the configuration of the loader.
+func (l *Loader[K, V]) validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if l.batchFn == nil {
+		invalid("the batch function is nil")
+	}
+	if l.batchCap < 0 {
+		invalid("the batch capacity is negative: %d", l.batchCap)
+	}
+	if l.inputCap < 0 {
+		invalid("the input capacity is negative: %d", l.inputCap)
+	}
+	switch l.overflow {
+	case OverflowBlock, OverflowFail, OverflowRetry:
+	default:
+		invalid("unknown overflow policy: %d", l.overflow)
+	}
+	switch l.deadlinePolicy {
+	case DeadlineIgnore, DeadlineDispatch, DeadlineFailFast:
+	default:
+		invalid("unknown deadline policy: %d", l.deadlinePolicy)
+	}
+	if l.wait < 0 {
+		invalid("the wait duration is negative: %v", l.wait)
+	}
+	if l.idleFlush < 0 {
+		invalid("the idle flush duration is negative: %v", l.idleFlush)
+	}
+	if l.waitJitter < 0 || l.waitJitter >= 1 {
+		invalid("the wait jitter must be in [0, 1): %v", l.waitJitter)
+	}
+	if l.ttlJitter < 0 || l.ttlJitter >= 1 {
+		invalid("the TTL jitter must be in [0, 1): %v", l.ttlJitter)
+	}
+	if l.maxAge < 0 {
+		invalid("the cache max age is negative: %v", l.maxAge)
+	}
+	if l.alignment < 0 {
+		invalid("the window alignment is negative: %v", l.alignment)
+	}
+	if l.minBatchSize < 0 || l.minBatchWait < 0 {
+		invalid("the minimum batch size and its extra wait must not be negative")
+	}
+	if l.tenantCap < 0 || (l.tenantCap > 0 && l.tenantOf == nil) {
+		invalid("the tenant cap must not be negative and needs a tenant function")
+	}
+	if l.costLimit < 0 || (l.costLimit > 0 && l.costOf == nil) {
+		invalid("the batch cost limit must not be negative and needs a cost function")
+	}
+	if s := l.shadow; s != nil {
+		if s.batchFn == nil || s.compare == nil {
+			invalid("the shadow batch needs a batch function and a compare function")
+		}
+		if s.rate < 0 || s.rate > 1 {
+			invalid("the shadow sample rate must be in [0, 1]: %v", s.rate)
+		}
+	}
+	if m := l.memo; m != nil && (m.ttl <= 0 || m.size < 1) {
+		invalid("the batch memo needs a positive duration and size: %v and %d", m.ttl, m.size)
+	}
+	if t := l.tuner; t != nil {
+		if t.conf.minWait < 0 || t.conf.minWait > t.conf.maxWait {
+			invalid("the auto tuned wait bounds are invalid: [%v, %v]", t.conf.minWait, t.conf.maxWait)
+		}
+		if t.conf.minCap < 1 || t.conf.minCap > t.conf.maxCap {
+			invalid("the auto tuned capacity bounds are invalid: [%d, %d]", t.conf.minCap, t.conf.maxCap)
+		}
+		if t.conf.every < 1 {
+			invalid("the auto tuning period must be at least a batch: %d", t.conf.every)
+		}
+	}
+	if _, ok := l.cache.(*NoCache[K, V]); ok && l.clearCacheOnBatch {
+		invalid("WithClearCacheOnBatch has no effect with NoCache")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("dataloader: invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// WithValueCache sets the cache to store the resolved results of keys rather
+// than their thunks. The keys being loaded are tracked by the loader itself,
+// and their results are stored in the provided cache once their batch has
+// resolved. Results of batch functions which panicked are not stored.
+//
+// The DataCache is read by the batches rather than by Load, outside the locks
+// of the loader, so that a remote DataCache doesn't serialize the loads: the
+// keys of a batch found in the DataCache are served from it, and the batch
+// function is only called with the others. A DataCacheMany gets the keys of
+// a batch, and sets the results of its batch function, in a single round trip.
+//
+// It replaces the cache set by WithCache, and the other way around.
+func WithValueCache[K comparable, V any](cache DataCache[K, V]) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.cacheLock.Lock()
+		l.cache = newValueCache(cache)
+		l.cacheLock.Unlock()
+	}
+}
+
+// valueCache adapts a DataCache to the Cache interface. Thunks are kept as
+// pending until they resolve, then their result is moved to the DataCache.
+type valueCache[K comparable, V any] struct {
+	data DataCache[K, V]
+
+	mu      sync.Mutex
+	pending map[K]*pendingThunk[V]
+}
+
+// pendingThunk is the thunk of a key being loaded. Its result needs no
+// storing once stored is set, i.e. when its batch has found the key in the
+// DataCache or stored the results of the whole batch.
+type pendingThunk[V any] struct {
+	thunk  Thunk[V]
+	stored bool
+}
+
+func newValueCache[K comparable, V any](data DataCache[K, V]) *valueCache[K, V] {
+	return &valueCache[K, V]{
+		data:    data,
+		pending: make(map[K]*pendingThunk[V]),
+	}
+}
+
+// Get returns the thunk of a key being loaded. The results stored in the
+// DataCache are looked up by the batch of the key instead (see batched).
+func (c *valueCache[K, V]) Get(_ context.Context, key K) (Thunk[V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[key]
+	if !ok {
+		return nil, false
+	}
+	return p.thunk, true
+}
+
+func (c *valueCache[K, V]) Set(ctx context.Context, key K, thunk Thunk[V]) {
+	p := &pendingThunk[V]{thunk: thunk}
+	c.mu.Lock()
+	c.pending[key] = p
+	c.mu.Unlock()
+
+	go func() {
+		data, err := thunk()
+		var ev *PanicErrorWrapper
+		if errors.As(err, &ev) {
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// the key was cleared or replaced while it was loading
+		if c.pending[key] != p {
+			return
+		}
+		delete(c.pending, key)
+		if p.stored {
+			return
+		}
+		c.data.Set(context.WithoutCancel(ctx), key, &Result[V]{Data: data, Error: err})
+	}()
+}
+
+func (c *valueCache[K, V]) Delete(ctx context.Context, key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.pending[key]
+	delete(c.pending, key)
+	return c.data.Delete(ctx, key) || ok
+}
+
+func (c *valueCache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = make(map[K]*pendingThunk[V])
+	c.data.Clear()
+}
+
+// batched returns the batch function serving the keys of a batch from the
+// DataCache, and calling batchFn with the missing ones. Only the keys for
+// which cached is true are looked up (i.e. not the loads bypassing the cache).
+func (c *valueCache[K, V]) batched(batchFn BatchFunc[K, V], cached func(K) bool, lenient bool) BatchFunc[K, V] {
+	return func(ctx context.Context, keys []K) []*Result[V] {
+		pending := c.snapshot(keys)
+		results := c.lookup(ctx, keys, cached)
+		var missing []K
+		for i, key := range keys {
+			if results[i] == nil {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) == 0 {
+			return results
+		}
+		fetched := batchFn(ctx, missing)
+		if lenient && len(fetched) <= len(missing) {
+			fetched = complete(missing, fetched)
+		}
+		if len(fetched) != len(missing) {
+			// the loader reports the mismatch
+			return fetched
+		}
+		c.storeMany(ctx, missing, fetched, pending, cached)
+		if len(missing) == len(keys) {
+			return fetched
+		}
+		for i, j := 0, 0; i < len(keys); i++ {
+			if results[i] == nil {
+				results[i] = fetched[j]
+				j++
+			}
+		}
+		return results
+	}
+}
+
+// snapshot returns the pending thunks of the keys of a batch.
+func (c *valueCache[K, V]) snapshot(keys []K) map[K]*pendingThunk[V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending := make(map[K]*pendingThunk[V], len(keys))
+	for _, key := range keys {
+		if p, ok := c.pending[key]; ok {
+			pending[key] = p
+		}
+	}
+	return pending
+}
+
+// lookup returns the results of the keys found in the DataCache, whose thunks
+// are marked as stored. The result of a key which isn't found is nil.
+func (c *valueCache[K, V]) lookup(ctx context.Context, keys []K, cached func(K) bool) []*Result[V] {
+	var lookup []K
+	var indexes []int
+	for i, key := range keys {
+		if cached(key) {
+			lookup = append(lookup, key)
+			indexes = append(indexes, i)
+		}
+	}
+	results := make([]*Result[V], len(keys))
+	if len(lookup) == 0 {
+		return results
+	}
+
+	if many, ok := c.data.(DataCacheMany[K, V]); ok {
+		found := many.GetMany(ctx, lookup)
+		// a DataCacheMany not returning every result only misses
+		if len(found) == len(lookup) {
+			for j, i := range indexes {
+				results[i] = found[j]
+			}
+		}
+	} else {
+		var wg sync.WaitGroup
+		for j, i := range indexes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if result, ok := c.data.Get(ctx, lookup[j]); ok {
+					results[i] = result
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, key := range keys {
+		if p := c.pending[key]; p != nil && results[i] != nil {
+			p.stored = true
+		}
+	}
+	return results
+}
+
+// storeMany stores the results of the keys fetched by a batch at once, if the
+// DataCache is a DataCacheMany, rather than once the thunk of each key has
+// resolved. The keys cleared or loaded again since the start of the batch (see
+// snapshot) aren't stored.
+func (c *valueCache[K, V]) storeMany(ctx context.Context, keys []K, results []*Result[V], pending map[K]*pendingThunk[V], cached func(K) bool) {
+	many, ok := c.data.(DataCacheMany[K, V])
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var stored []K
+	var storedResults []*Result[V]
+	for i, key := range keys {
+		p := pending[key]
+		if p == nil || c.pending[key] != p || !cached(key) || results[i] == nil {
+			continue
+		}
+		p.stored = true
+		stored = append(stored, key)
+		storedResults = append(storedResults, results[i])
+	}
+	if len(stored) > 0 {
+		many.SetMany(context.WithoutCancel(ctx), stored, storedResults)
+	}
+}
+
+// valueCache returns the cache of the loader set by WithValueCache, if any.
+func (l *Loader[K, V]) valueCache() *valueCache[K, V] {
+	l.cacheLock.Lock()
+	defer l.cacheLock.Unlock()
+	vc, _ := l.cache.(*valueCache[K, V])
+	return vc
+}
+
+// cachedKeys reports the keys of a batch which are cached: the keys which no
+// request bypassing the cache waits for.
+func cachedKeys[K comparable, V any](reqs []*batchRequest[K, V], waiters map[K][]*batchRequest[K, V]) func(K) bool {
+	uncached := make(map[K]bool)
+	for _, req := range reqs {
+		for _, r := range append([]*batchRequest[K, V]{req}, waiters[req.key]...) {
+			if r.uncached {
+				uncached[r.key] = true
+			}
+		}
+	}
+	return func(key K) bool { return !uncached[key] }
+}
@@ -0,0 +1,48 @@
+package dataloader
+
+import (
+	"context"
+)
+
+// WithVersion sets a function returning the version of a value (i.e. its
+// revision number or its update timestamp). The versions of the loaded and
+// primed values are tracked, so that PrimeIfNewer can ignore stale values.
+func WithVersion[K comparable, V any](versionOf func(V) int64) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.versionOf = versionOf
+		l.addCacheHook(func(_ context.Context, key K, result *Result[V]) {
+			if result.Error != nil {
+				return
+			}
+			l.cacheLock.Lock()
+			defer l.cacheLock.Unlock()
+			// a value primed while the key was loading is kept in the cache
+			if _, ok := l.versions[key]; !ok {
+				l.setVersion(key, versionOf(result.Data))
+			}
+		})
+	}
+}
+
+// PrimeIfNewer adds the provided key and value to the cache, replacing the
+// cached value unless its version is greater than or equal to the given
+// version. Returns true if the value was primed.
+//
+// The version of the cached value is known if it was primed with
+// PrimeIfNewer, or if it was loaded or primed by a loader configured
+// WithVersion. A cached value of unknown version is always replaced.
+func (l *Loader[K, V]) PrimeIfNewer(ctx context.Context, key K, value V, version int64) bool {
+	return l.prime(ctx, key, &Result[V]{Data: value}, func(V) int64 { return version }, func() bool {
+		current, ok := l.versions[key]
+		return !ok || current < version
+	})
+}
+
+// setVersion records the version of the cached value of key.
+// It must be called with the cacheLock held.
+func (l *Loader[K, V]) setVersion(key K, version int64) {
+	if l.versions == nil {
+		l.versions = make(map[K]int64)
+	}
+	l.versions[key] = version
+}
@@ -0,0 +1,28 @@
+package dataloader
+
+import "context"
+
+// Wait waits for the thunk to resolve like calling it does, unless ctx is done
+// first: it then returns the error of ctx right away, so that the caller
+// isn't held past its own deadline by a slow batch. The batch keeps running
+// nonetheless, and the thunk still resolves for its other callers (and for a
+// later call of Wait).
+func (t Thunk[V]) Wait(ctx context.Context) (V, error) {
+	if err := ctx.Err(); err != nil {
+		var zero V
+		return zero, err
+	}
+
+	c := make(chan *Result[V], 1)
+	go func() {
+		data, err := t()
+		c <- &Result[V]{Data: data, Error: err}
+	}()
+	select {
+	case result := <-c:
+		return result.Data, result.Error
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
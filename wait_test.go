@@ -0,0 +1,49 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThunkWait(t *testing.T) {
+	t.Run("returns once the context is done", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		loader := NewBatchedLoader(func(_ context.Context, keys []string) []*Result[string] {
+			<-release
+			results := make([]*Result[string], len(keys))
+			for i, key := range keys {
+				results[i] = &Result[string]{Data: key}
+			}
+			return results
+		}, WithWait[string, string](time.Millisecond))
+		thunk := loader.Load(context.Background(), "1")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := thunk.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline to be exceeded, got %v", err)
+		}
+
+		// the batch still resolves the thunk for the other callers
+		close(release)
+		if v, err := thunk.Wait(context.Background()); v != "1" || err != nil {
+			t.Errorf("expected 1, got %q, %v", v, err)
+		}
+	})
+
+	t.Run("doesn't wait with a done context", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		thunk := Thunk[string](func() (string, error) {
+			t.Error("expected the thunk not to be called")
+			return "", nil
+		})
+		if _, err := thunk.Wait(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the context to be canceled, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package dataloader
+
+import (
+	"context"
+)
+
+// watcher receives the fresh values of a watched key.
+type watcher[V any] struct {
+	c chan V
+	// delivered is true once the watcher was sent a value
+	delivered bool
+}
+
+// Watch returns a channel which receives the value of key, then a fresh value
+// every time the key is primed or reloaded by a batch. When the key is cleared
+// from the cache, it is reloaded right away so that watchers receive its
+// fresh value. Errors are not delivered.
+//
+// The channel only holds the latest value: a value which has not been received
+// yet is replaced by a fresher one. The channel is closed once ctx is done.
+func (l *Loader[K, V]) Watch(ctx context.Context, key K) <-chan V {
+	l.watchOnce.Do(func() {
+		l.addHook(func(_ context.Context, key K, result *Result[V]) {
+			if result.Error == nil {
+				l.notify(key, result.Data)
+			}
+		})
+	})
+
+	w := &watcher[V]{c: make(chan V, 1)}
+	l.watchLock.Lock()
+	if l.watchers == nil {
+		l.watchers = make(map[K][]*watcher[V])
+	}
+	l.watchers[key] = append(l.watchers[key], w)
+	l.watchLock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		l.unwatch(key, w)
+	}()
+
+	// deliver the current value, unless a fresher one was delivered already
+	thunk := l.Load(ctx, key)
+	go func() {
+		if value, err := thunk(); err == nil {
+			l.watchLock.Lock()
+			if !w.delivered {
+				w.send(value)
+			}
+			l.watchLock.Unlock()
+		}
+	}()
+
+	return w.c
+}
+
+// unwatch removes the watcher of key and closes its channel.
+func (l *Loader[K, V]) unwatch(key K, w *watcher[V]) {
+	l.watchLock.Lock()
+	defer l.watchLock.Unlock()
+
+	watchers := l.watchers[key]
+	for i, other := range watchers {
+		if other == w {
+			watchers = append(watchers[:i:i], watchers[i+1:]...)
+			break
+		}
+	}
+	if len(watchers) == 0 {
+		delete(l.watchers, key)
+	} else {
+		l.watchers[key] = watchers
+	}
+	close(w.c)
+	// make sure the watcher isn't sent anything after it is closed
+	w.c = nil
+}
+
+// notify delivers a fresh value of key to its watchers.
+func (l *Loader[K, V]) notify(key K, value V) {
+	l.watchLock.Lock()
+	defer l.watchLock.Unlock()
+	for _, w := range l.watchers[key] {
+		w.send(value)
+	}
+}
+
+// rewatch reloads the given keys if they are watched, so that their watchers
+// receive their fresh value. All the watched keys are reloaded if keys is nil.
+func (l *Loader[K, V]) rewatch(ctx context.Context, keys []K) {
+	l.watchLock.Lock()
+	var watched []K
+	if keys == nil {
+		for key := range l.watchers {
+			watched = append(watched, key)
+		}
+	} else {
+		for _, key := range keys {
+			if len(l.watchers[key]) > 0 {
+				watched = append(watched, key)
+			}
+		}
+	}
+	l.watchLock.Unlock()
+
+	for _, key := range watched {
+		l.Load(ctx, key)
+	}
+}
+
+// send delivers value, replacing the value which has not been received yet.
+// It must be called with the watchLock held.
+func (w *watcher[V]) send(value V) {
+	if w.c == nil {
+		return
+	}
+	w.delivered = true
+	for {
+		select {
+		case w.c <- value:
+			return
+		default:
+		}
+		select {
+		case <-w.c:
+		default:
+		}
+	}
+}
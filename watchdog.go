@@ -0,0 +1,61 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrThunkTimeout is the error of the requests resolved by WithThunkTimeout.
+var ErrThunkTimeout = errors.New("dataloader: thunk timed out")
+
+// WithThunkWatchdog sets a function called with the key of every request left
+// unresolved for longer than d, which usually means that its batch window was
+// never dispatched or that its batch function is wedged. The request is left
+// pending; see WithThunkTimeout to resolve it.
+func WithThunkWatchdog[K comparable, V any](d time.Duration, onStuck func(K, time.Duration)) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.watchdogAfter = d
+		l.onStuck = onStuck
+	}
+}
+
+// WithThunkTimeout resolves the requests left unresolved for longer than d with
+// ErrThunkTimeout, so that a thunk never blocks for longer than d. The keys
+// which timed out are removed from the cache, and their result of the batch
+// function, if it ever comes, is discarded.
+func WithThunkTimeout[K comparable, V any](d time.Duration) Option[K, V] {
+	return func(l *Loader[K, V]) {
+		l.thunkTimeout = d
+	}
+}
+
+// guard arms the watchdog and the timeout of a request, if configured.
+func (l *Loader[K, V]) guard(ctx context.Context, req *batchRequest[K, V]) {
+	if l.watchdogAfter <= 0 && l.thunkTimeout <= 0 {
+		return
+	}
+
+	req.mu.Lock()
+	defer req.mu.Unlock()
+	if l.watchdogAfter > 0 && l.onStuck != nil {
+		d := l.watchdogAfter
+		req.timers = append(req.timers, afterFunc(l.clock, d, func() {
+			if req.pending() {
+				l.onStuck(req.key, d)
+			}
+		}))
+	}
+	if l.thunkTimeout > 0 {
+		req.timers = append(req.timers, afterFunc(l.clock, l.thunkTimeout, func() {
+			l.fail(ctx, req, ErrThunkTimeout)
+		}))
+	}
+}
+
+// pending reports whether the request is yet to be resolved.
+func (r *batchRequest[K, V]) pending() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.resolved
+}
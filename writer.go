@@ -0,0 +1,125 @@
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Write is a write of a value at a key, queued on a WriteLoader.
+type Write[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// WriteBatchFunc is a function, which when given a slice of writes, performs
+// them as a single bulk operation (i.e. an upsert) and returns the outcome of
+// each write. It's important that the length of the returned errors matches
+// the length of the writes; a nil error means the write at the same index
+// succeeded.
+//
+// The keys of the writes passed to this function are guaranteed to be unique:
+// writes to the same key within a batch are coalesced, and only the last
+// value is written.
+type WriteBatchFunc[K comparable, V any] func(context.Context, []Write[K, V]) []error
+
+// WriteThunk is a function that will block until the write it was returned
+// for is performed, and returns its outcome.
+type WriteThunk func() error
+
+// WriteLoader coalesces writes the same way a Loader coalesces reads: the
+// writes queued within a batch window are performed by a single call to the
+// batch function.
+type WriteLoader[K comparable, V any] struct {
+	batchFn WriteBatchFunc[K, V]
+	loader  *Loader[uint64, struct{}]
+
+	mu     sync.Mutex
+	seq    uint64
+	writes map[uint64]Write[K, V]
+}
+
+// WriterOption allows for configuration of WriteLoader fields.
+type WriterOption func(*Loader[uint64, struct{}])
+
+// WithWriteBatchCapacity sets the maximum number of writes in a batch.
+// Default is 0 (unbounded).
+func WithWriteBatchCapacity(c int) WriterOption {
+	return WriterOption(WithBatchCapacity[uint64, struct{}](c))
+}
+
+// WithWriteWait sets the amount of time to wait before triggering a batch.
+// Default duration is 16 milliseconds.
+func WithWriteWait(d time.Duration) WriterOption {
+	return WriterOption(WithWait[uint64, struct{}](d))
+}
+
+// NewBatchedWriter constructs a new WriteLoader with given options.
+func NewBatchedWriter[K comparable, V any](batchFn WriteBatchFunc[K, V], opts ...WriterOption) *WriteLoader[K, V] {
+	w := &WriteLoader[K, V]{
+		batchFn: batchFn,
+		writes:  make(map[uint64]Write[K, V]),
+	}
+	loaderOpts := []Option[uint64, struct{}]{WithCache[uint64, struct{}](&NoCache[uint64, struct{}]{})}
+	for _, opt := range opts {
+		loaderOpts = append(loaderOpts, Option[uint64, struct{}](opt))
+	}
+	w.loader = NewBatchedLoader(w.batch, loaderOpts...)
+	return w
+}
+
+// Write queues the write of value at key, returning a thunk that will resolve
+// to the outcome of the write.
+func (w *WriteLoader[K, V]) Write(ctx context.Context, key K, value V) WriteThunk {
+	w.mu.Lock()
+	w.seq++
+	seq := w.seq
+	w.writes[seq] = Write[K, V]{Key: key, Value: value}
+	w.mu.Unlock()
+
+	thunk := w.loader.Load(ctx, seq)
+	return func() error {
+		_, err := thunk()
+		return err
+	}
+}
+
+// batch coalesces the queued writes and performs them.
+func (w *WriteLoader[K, V]) batch(ctx context.Context, seqs []uint64) []*Result[struct{}] {
+	var (
+		writes  []Write[K, V]
+		indexes = make(map[K]int)
+		keys    = make([]K, len(seqs))
+	)
+
+	w.mu.Lock()
+	for i, seq := range seqs {
+		write := w.writes[seq]
+		delete(w.writes, seq)
+		keys[i] = write.Key
+		// the last write to a key wins
+		if j, ok := indexes[write.Key]; ok {
+			writes[j].Value = write.Value
+			continue
+		}
+		indexes[write.Key] = len(writes)
+		writes = append(writes, write)
+	}
+	w.mu.Unlock()
+
+	errs := w.batchFn(ctx, writes)
+	if n := len(errs); n != len(writes) {
+		err := fmt.Errorf("the write batch function returned %d outcomes for %d writes", n, len(writes))
+		errs = make([]error, len(writes))
+		for i := range errs {
+			errs[i] = err
+		}
+	}
+
+	results := make([]*Result[struct{}], len(seqs))
+	for i, key := range keys {
+		results[i] = &Result[struct{}]{Error: errs[indexes[key]]}
+	}
+	return results
+}
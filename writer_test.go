@@ -0,0 +1,94 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestWriteLoader(t *testing.T) {
+	t.Run("coalesces writes in a single batch", func(t *testing.T) {
+		t.Parallel()
+		writer, writeCalls := RecordingWriter(nil)
+		ctx := context.Background()
+
+		thunk1 := writer.Write(ctx, "a", 1)
+		thunk2 := writer.Write(ctx, "b", 2)
+		thunk3 := writer.Write(ctx, "a", 3)
+		for _, thunk := range []WriteThunk{thunk1, thunk2, thunk3} {
+			if err := thunk(); err != nil {
+				t.Error(err.Error())
+			}
+		}
+
+		expected := [][]Write[string, int]{{{"a", 3}, {"b", 2}}}
+		if calls := writeCalls(); !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected %#v, got %#v", expected, calls)
+		}
+	})
+
+	t.Run("resolves writes with their outcome", func(t *testing.T) {
+		t.Parallel()
+		writer, _ := RecordingWriter(errors.New("conflict"))
+		ctx := context.Background()
+
+		thunk1 := writer.Write(ctx, "a", 1)
+		thunk2 := writer.Write(ctx, "fail", 2)
+		thunk3 := writer.Write(ctx, "fail", 3)
+		if err := thunk1(); err != nil {
+			t.Error(err.Error())
+		}
+		if err := thunk2(); err == nil || err.Error() != "conflict" {
+			t.Errorf("expected write to fail, got %v", err)
+		}
+		if err := thunk3(); err == nil || err.Error() != "conflict" {
+			t.Errorf("expected coalesced write to fail, got %v", err)
+		}
+	})
+
+	t.Run("responds to max batch size", func(t *testing.T) {
+		t.Parallel()
+		writer, writeCalls := RecordingWriter(nil, WithWriteBatchCapacity(2))
+		ctx := context.Background()
+
+		thunk1 := writer.Write(ctx, "a", 1)
+		thunk2 := writer.Write(ctx, "b", 2)
+		thunk3 := writer.Write(ctx, "c", 3)
+		for _, thunk := range []WriteThunk{thunk1, thunk2, thunk3} {
+			if err := thunk(); err != nil {
+				t.Error(err.Error())
+			}
+		}
+
+		expected := [][]Write[string, int]{{{"a", 1}, {"b", 2}}, {{"c", 3}}}
+		if calls := writeCalls(); !reflect.DeepEqual(calls, expected) {
+			t.Errorf("did not respect max batch size. Expected %#v, got %#v", expected, calls)
+		}
+	})
+}
+
+// RecordingWriter records its write batches, and fails the writes to the
+// "fail" key with the given error.
+func RecordingWriter(fail error, opts ...WriterOption) (*WriteLoader[string, int], func() [][]Write[string, int]) {
+	var mu sync.Mutex
+	var writeCalls [][]Write[string, int]
+	writer := NewBatchedWriter(func(_ context.Context, writes []Write[string, int]) []error {
+		mu.Lock()
+		writeCalls = append(writeCalls, writes)
+		mu.Unlock()
+		errs := make([]error, len(writes))
+		for i, write := range writes {
+			if write.Key == "fail" {
+				errs[i] = fail
+			}
+		}
+		return errs
+	}, opts...)
+	return writer, func() [][]Write[string, int] {
+		mu.Lock()
+		defer mu.Unlock()
+		return writeCalls
+	}
+}